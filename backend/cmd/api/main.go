@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"mmrrdikub/internal/handlers"
+	"mmrrdikub/internal/services"
 	"mmrrdikub/pkg/database"
+	"mmrrdikub/pkg/money"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -23,6 +25,9 @@ func main() {
 	database.ConnectDB()
 	log.Println("✅ Database Connected!")
 
+	// ตั้งค่ารูปแบบ JSON ของตัวเลขเงิน (ต้องหลังโหลด .env ใน ConnectDB)
+	money.ConfigureJSON()
+
 	// ============================================
 	// ส่วนที่ 2: Migrate Tables
 	// ============================================
@@ -32,8 +37,14 @@ func main() {
 	if err := handlers.MigrateTradeModels(); err != nil {
 		log.Printf("⚠️ Trade Migration: %v", err)
 	}
+	if err := handlers.MigrateAssetModels(); err != nil {
+		log.Printf("⚠️ Asset Migration: %v", err)
+	}
 	log.Println("✅ Tables Ready!")
 
+	// ลบถาวร Trade ที่อยู่ในถังขยะเกินกำหนด (งานเบื้องหลัง)
+	handlers.StartTrashPurger()
+
 	// ============================================
 	// ส่วนที่ 3: สร้าง Fiber App
 	// ============================================
@@ -45,6 +56,18 @@ func main() {
 		DisableStartupMessage: false,
 		// ป้องกัน Prefork issues บน Render/Docker
 		Prefork: false,
+		// อยู่หลัง Proxy ของ Render: อ่าน IP ผู้ใช้จาก X-Forwarded-For เฉพาะที่มาจาก Proxy ที่เชื่อถือ (ENV TRUSTED_PROXIES)
+		// ไม่งั้นทุก Request มี IP ของ Proxy และ Throttle ต่อ IP ล็อกทุกคนพร้อมกัน (Handler ใช้ handlers.clientIP)
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          handlers.TrustedProxies(),
+		EnableIPValidation:      true,
+		// Body เกิน 4MB ไม่ถูกพักไว้ใน Memory แต่ส่งเป็น Stream ให้ handlers.LimitBody ตัดที่ 4MB
+		// ยกเว้น Restore Backup (POST /api/account/import) ที่อ่าน Stream เองได้ถึง handlers.AccountImportBodyLimit
+		// multipart ไม่ Pre-parse ลง Disk ก่อนเข้า Handler (อ่านหลังผ่าน LimitBody/JWT แล้วเท่านั้น)
+		BodyLimit:                    handlers.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 		// Error Handler แบบสวยๆ
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -70,6 +93,9 @@ func main() {
 	// Recover - กัน Panic crash
 	app.Use(recover.New())
 
+	// จำกัด Body ของทุก Route ไว้ที่ 4MB เหมือนเดิม ยกเว้นการ Restore Backup
+	app.Use(handlers.LimitBody(handlers.DefaultBodyLimit, handlers.AccountImportPath))
+
 	// 🔥 FIX #2: CORS - สำคัญที่สุด!
 	// ใช้ AllowOriginsFunc เพื่อ dynamic check
 	app.Use(cors.New(cors.Config{
@@ -133,6 +159,26 @@ func main() {
 	api.Post("/auth/forgot-password/request", handlers.ForgotPasswordRequest)
 	api.Post("/auth/forgot-password/verify", handlers.ForgotPasswordVerify)
 	api.Post("/auth/forgot-password/reset", handlers.ForgotPasswordReset)
+	api.Post("/auth/refresh", handlers.RefreshToken)                        // POST /api/auth/refresh (หมุน Refresh Token)
+	api.Post("/auth/logout", handlers.Logout)                               // POST /api/auth/logout (refresh_token หรือ Bearer)
+	api.Post("/auth/2fa/verify", handlers.VerifyTwoFactorLogin)             // POST /api/auth/2fa/verify (Login ขั้นที่สองเมื่อเปิด 2FA)
+	api.Post("/auth/verify-email", handlers.VerifyEmail)                    // POST /api/auth/verify-email (email + code)
+	api.Post("/auth/verify-email/resend", handlers.ResendEmailVerification) // POST /api/auth/verify-email/resend
+	api.Get("/auth/verify-email/policy", handlers.GetVerificationPolicy)    // GET  /api/auth/verify-email/policy (ฟีเจอร์ที่ต้องยืนยันอีเมลก่อน)
+
+	// Session Routes (Protected - ต้อง Login)
+	sessions := api.Group("/auth/sessions", handlers.JWTMiddleware)
+	sessions.Get("/", handlers.GetSessions)            // GET    /api/auth/sessions
+	sessions.Delete("/", handlers.RevokeOtherSessions) // DELETE /api/auth/sessions (ทุกอุปกรณ์ยกเว้นเครื่องนี้)
+	sessions.Delete("/:id", handlers.RevokeSession)    // DELETE /api/auth/sessions/:id
+
+	// Two-Factor Routes (Protected - ต้อง Login)
+	twoFactor := api.Group("/auth/2fa", handlers.JWTMiddleware)
+	twoFactor.Get("/", handlers.GetTwoFactorStatus)                     // GET  /api/auth/2fa
+	twoFactor.Post("/setup", handlers.SetupTwoFactor)                   // POST /api/auth/2fa/setup (Secret + otpauth URI)
+	twoFactor.Post("/confirm", handlers.ConfirmTwoFactor)               // POST /api/auth/2fa/confirm (เปิด 2FA + Recovery Code)
+	twoFactor.Post("/disable", handlers.DisableTwoFactor)               // POST /api/auth/2fa/disable (password + code)
+	twoFactor.Post("/recovery-codes", handlers.RegenerateRecoveryCodes) // POST /api/auth/2fa/recovery-codes (password + code)
 
 	// Compatibility Routes (กันพลาด): ถ้า client ยิงมาแบบไม่มี /api
 	// เพื่อไม่ให้เจอ 404: POST /login หรือ POST /register
@@ -143,13 +189,64 @@ func main() {
 	trades := api.Group("/trades", handlers.JWTMiddleware)
 	trades.Post("/", handlers.CreateTrade)
 	trades.Get("/", handlers.GetTrades)
+	trades.Get("/trash", handlers.GetTrashedTrades)                                                      // ต้องอยู่ก่อน /:id
+	trades.Get("/export", handlers.RequireVerifiedEmail(services.FeatureExport), handlers.ExportTrades)  // GET /api/trades/export?format=csv (ต้องอยู่ก่อน /:id)
+	trades.Post("/import", handlers.RequireVerifiedEmail(services.FeatureImport), handlers.ImportTrades) // POST /api/trades/import (multipart: file, source, dry_run)
+	trades.Get("/imports", handlers.GetTradeImports)                                                     // GET /api/trades/imports (ต้องอยู่ก่อน /:id)
+	trades.Get("/importers", handlers.GetTradeImporters)                                                 // GET /api/trades/importers (ต้องอยู่ก่อน /:id)
 	trades.Get("/:id", handlers.GetTrade)
 	trades.Put("/:id", handlers.UpdateTrade)
 	trades.Delete("/:id", handlers.DeleteTrade)
+	trades.Post("/:id/restore", handlers.RestoreTrade)   // POST   /api/trades/:id/restore
+	trades.Delete("/:id/permanent", handlers.PurgeTrade) // DELETE /api/trades/:id/permanent
+
+	// Partial Exits (ทยอยปิดไม้)
+	trades.Get("/:id/exits", handlers.GetTradeExits)                     // GET  /api/trades/:id/exits
+	trades.Post("/:id/exits", handlers.PlanTradeExit)                    // POST /api/trades/:id/exits
+	trades.Post("/:id/exits/:exitId/execute", handlers.ExecuteTradeExit) // POST /api/trades/:id/exits/:exitId/execute
+	trades.Post("/:id/exits/:exitId/cancel", handlers.CancelTradeExit)   // POST /api/trades/:id/exits/:exitId/cancel
+
+	// Entry Fills (ทยอยเข้าไม้ / DCA)
+	trades.Get("/:id/entries", handlers.GetTradeEntries)              // GET    /api/trades/:id/entries
+	trades.Post("/:id/entries", handlers.AddTradeEntry)               // POST   /api/trades/:id/entries
+	trades.Delete("/:id/entries/:entryId", handlers.DeleteTradeEntry) // DELETE /api/trades/:id/entries/:entryId
+
+	// ประวัติการแก้ไข (Audit Trail)
+	trades.Get("/:id/history", handlers.GetTradeHistory)                 // GET  /api/trades/:id/history
+	trades.Post("/:id/history/:revisionId/revert", handlers.RevertTrade) // POST /api/trades/:id/history/:revisionId/revert
+
+	// Calculator Routes (Protected - ต้อง Login)
+	calculator := api.Group("/calculator", handlers.JWTMiddleware)
+	calculator.Post("/position-size", handlers.CalculatePositionSize) // POST /api/calculator/position-size
+	calculator.Post("/scale-in", handlers.CalculateScaleIn)           // POST /api/calculator/scale-in
+
+	// Analytics Routes (Protected - ต้อง Login)
+	analytics := api.Group("/analytics", handlers.JWTMiddleware)
+	analytics.Get("/summary", handlers.GetAnalyticsSummary)     // GET /api/analytics/summary
+	analytics.Get("/equity", handlers.GetEquityCurve)           // GET /api/analytics/equity
+	analytics.Get("/breakdown", handlers.GetAnalyticsBreakdown) // GET /api/analytics/breakdown?dimension=
+	analytics.Get("/calendar", handlers.GetAnalyticsCalendar)   // GET /api/analytics/calendar?month=YYYY-MM
+	analytics.Get("/r-multiples", handlers.GetRDistribution)    // GET /api/analytics/r-multiples?bucket_size=
+
+	// Account Routes (Protected - ต้อง Login)
+	account := api.Group("/account", handlers.JWTMiddleware)
+	account.Get("/settings", handlers.GetAccountSettings)                                                  // GET /api/account/settings
+	account.Put("/settings", handlers.UpdateAccountSettings)                                               // PUT /api/account/settings
+	account.Get("/export", handlers.RequireVerifiedEmail(services.FeatureBackup), handlers.ExportAccount)  // GET /api/account/export (Backup JSON)
+	account.Post("/import", handlers.RequireVerifiedEmail(services.FeatureBackup), handlers.ImportAccount) // POST /api/account/import?conflict=skip|duplicate&dry_run=
+
+	// Admin Routes (Protected - ต้อง Login และเป็น Role admin)
+	admin := api.Group("/admin", handlers.JWTMiddleware, handlers.RequireRole(services.RoleAdmin))
+	admin.Get("/users", handlers.GetAdminUsers)                                // GET  /api/admin/users?search=&role=&status=
+	admin.Get("/users/:id", handlers.GetAdminUser)                             // GET  /api/admin/users/:id
+	admin.Post("/users/:id/disable", handlers.DisableUser)                     // POST /api/admin/users/:id/disable
+	admin.Post("/users/:id/enable", handlers.EnableUser)                       // POST /api/admin/users/:id/enable
+	admin.Post("/users/:id/force-password-reset", handlers.ForcePasswordReset) // POST /api/admin/users/:id/force-password-reset
+	admin.Get("/stats", handlers.GetAdminStats)                                // GET  /api/admin/stats
 
 	// AI Routes (Protected - ต้อง Login)
 	// เส้นทางสำหรับฟีเจอร์ AI Risk Analyst และ Chatbot
-	aiRoutes := api.Group("/ai", handlers.JWTMiddleware)
+	aiRoutes := api.Group("/ai", handlers.JWTMiddleware, handlers.RequireVerifiedEmail(services.FeatureAI))
 	aiRoutes.Post("/analyze", handlers.AnalyzeTrade)  // POST /api/ai/analyze
 	aiRoutes.Post("/chat", handlers.AIChat)           // POST /api/ai/chat
 	aiRoutes.Get("/insights", handlers.GetAIInsights) // GET /api/ai/insights
@@ -178,8 +275,31 @@ func main() {
 	log.Println("   POST /api/register     - สมัครสมาชิก")
 	log.Println("   POST /api/login        - เข้าสู่ระบบ")
 	log.Println("   POST /api/auth/forgot-password/* - ลืมรหัสผ่าน")
+	log.Println("   POST /api/auth/verify-email - ยืนยันอีเมล (+ /resend ขอรหัสใหม่)")
+	log.Println("   POST /api/auth/refresh - ต่ออายุ Token")
+	log.Println("   POST /api/auth/logout  - ออกจากระบบ")
+	log.Println("   *    /api/auth/sessions - อุปกรณ์ที่ Login อยู่/ยกเลิก (Auth)")
+	log.Println("   *    /api/auth/2fa  - เปิด/ปิด 2FA (TOTP), Recovery Code, Login ขั้นที่สอง")
 	log.Println("   POST /api/trades       - สร้างเทรด (Auth)")
 	log.Println("   GET  /api/trades       - ดูประวัติ (Auth)")
+	log.Println("   *    /api/trades/:id/exits - ทยอยปิดไม้ (Auth)")
+	log.Println("   *    /api/trades/:id/entries - ทยอยเข้าไม้ (Auth)")
+	log.Println("   *    /api/trades/:id/history - ประวัติการแก้ไข/ย้อนกลับ (Auth)")
+	log.Println("   GET  /api/trades/trash - ถังขยะ กู้คืน/ลบถาวร (Auth)")
+	log.Println("   GET  /api/trades/export - ส่งออก Journal เป็น CSV (Auth)")
+	log.Println("   POST /api/trades/import - นำเข้าจาก Binance/Bybit/OKX/Bitkub/MT4/MT5/CSV (Auth)")
+	log.Println("   GET  /api/analytics/summary - สถิติผลการเทรด (Auth)")
+	log.Println("   GET  /api/analytics/equity - กราฟเงินทุน/Drawdown (Auth)")
+	log.Println("   GET  /api/analytics/breakdown - สถิติแยกกลุ่ม (Auth)")
+	log.Println("   GET  /api/analytics/calendar - PnL รายวัน (Heatmap) (Auth)")
+	log.Println("   GET  /api/analytics/r-multiples - การกระจายของ R / SQN (Auth)")
+	log.Println("   *    /api/account/settings - ตั้งค่าบัญชี/Timezone (Auth)")
+	log.Println("   GET  /api/account/export - Backup ข้อมูลทั้งบัญชีเป็น JSON (Auth)")
+	log.Println("   POST /api/account/import - Restore Backup (Auth)")
+	log.Println("   POST /api/calculator/position-size - คำนวณขนาดไม้ (Auth)")
+	log.Println("   POST /api/calculator/scale-in - คำนวณการเติมไม้ (Auth)")
+	log.Println("   *    /api/admin/users - จัดการผู้ใช้ ระงับ/รีเซ็ตรหัสผ่าน (Admin)")
+	log.Println("   GET  /api/admin/stats - สถิติการใช้งานทั้งระบบ (Admin)")
 	log.Println("   POST /api/ai/analyze   - AI Risk Analyst (Auth) 🤖")
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
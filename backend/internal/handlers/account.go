@@ -0,0 +1,108 @@
+// Package handlers - Account Settings
+// ตั้งค่าบัญชีของผู้ใช้ (Timezone, ยอดพอร์ต)
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// AccountSettingsRequest - ข้อมูลที่แก้ได้ (ส่งมาเฉพาะฟิลด์ที่ต้องการแก้)
+type AccountSettingsRequest struct {
+	Timezone         *string          `json:"timezone"`
+	PortfolioBalance *decimal.Decimal `json:"portfolio_balance"`
+}
+
+// accountSettings - ค่าที่ส่งกลับให้ Client
+func accountSettings(user User) fiber.Map {
+	timezone := user.Timezone
+	if timezone == "" {
+		timezone = services.DefaultTimezone
+	}
+	return fiber.Map{
+		"timezone":          timezone,
+		"portfolio_balance": user.PortfolioBalance,
+	}
+}
+
+// GetAccountSettings - ดูการตั้งค่าบัญชี
+// GET /api/account/settings
+func GetAccountSettings(c *fiber.Ctx) error {
+	var user User
+	if err := database.DB.First(&user, GetCurrentUserID(c)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบข้อมูล User",
+		})
+	}
+	return c.JSON(fiber.Map{"settings": accountSettings(user)})
+}
+
+// UpdateAccountSettings - แก้การตั้งค่าบัญชี
+// PUT /api/account/settings
+func UpdateAccountSettings(c *fiber.Ctx) error {
+	var req AccountSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"message": err.Error(),
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Timezone != nil {
+		loc, err := services.LoadTimezone(*req.Timezone)
+		if err != nil {
+			_, respErr := respondValidationError(c, err)
+			return respErr
+		}
+		updates["timezone"] = loc.String()
+	}
+	if req.PortfolioBalance != nil {
+		if !req.PortfolioBalance.IsPositive() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ยอดพอร์ตต้องมากกว่า 0",
+				"field": "portfolio_balance",
+				"code":  "invalid_portfolio_balance",
+			})
+		}
+		updates["portfolio_balance"] = *req.PortfolioBalance
+	}
+
+	var user User
+	if err := database.DB.First(&user, GetCurrentUserID(c)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบข้อมูล User",
+		})
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "ไม่สามารถบันทึกการตั้งค่าได้",
+				"message": err.Error(),
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "บันทึกการตั้งค่าสำเร็จ",
+		"settings": accountSettings(user),
+	})
+}
+
+// userLocation - Timezone ของผู้ใช้ (ยังไม่ตั้ง/ตั้งค่าผิด = UTC)
+func userLocation(userID uint) *time.Location {
+	var user User
+	if err := database.DB.Select("timezone").First(&user, userID).Error; err != nil {
+		return time.UTC
+	}
+	loc, err := services.LoadTimezone(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,540 @@
+// Package handlers - Backup/Restore ข้อมูลทั้งบัญชี (JSON)
+// Export: โปรไฟล์ + การตั้งค่า + ไม้ทั้งหมด (รวมถังขยะ) พร้อม Exit/Entry + ประวัติการนำเข้า
+// Import: ลงบัญชีเดิมหรือบัญชีอื่นได้ ID ทุกตัวสร้างใหม่แล้ว Map ความสัมพันธ์ตาม ID ในไฟล์
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// วิธีจัดการไม้ใน Backup ที่ซ้ำกับไม้ที่มีอยู่แล้ว (Pair, Side, ราคาเข้า, จำนวน, เวลาสร้าง ตรงกัน)
+const (
+	ArchiveConflictSkip      = "skip"      // ข้าม (Default) Restore ไฟล์เดิมซ้ำได้โดยไม่เกิดไม้ซ้ำ
+	ArchiveConflictDuplicate = "duplicate" // นำเข้าเป็นไม้ใหม่อีกไม้
+)
+
+// ขนาด Body สูงสุด: Route ทั่วไปใช้ DefaultBodyLimit (เท่ากับ Default ของ Fiber) ส่วน Backup ทั้งบัญชีใหญ่กว่านั้นได้
+// fiber.Config.BodyLimit คง DefaultBodyLimit ไว้และเปิด StreamRequestBody: Body ที่ใหญ่กว่านั้นไม่ถูกพักไว้ใน Memory ก่อนเข้า Handler
+// LimitBody อ่าน Route ทั่วไปไม่เกิน DefaultBodyLimit ส่วน Restore อ่านแบบ Stream ไม่เกิน AccountImportBodyLimit
+const (
+	DefaultBodyLimit       = 4 * 1024 * 1024
+	AccountImportBodyLimit = 64 * 1024 * 1024
+	AccountImportPath      = "/api/account/import"
+)
+
+// สถานะของแต่ละไม้ในผล Restore
+const (
+	ArchiveTradeNew      = "new"
+	ArchiveTradeConflict = "conflict"
+	ArchiveTradeInvalid  = "invalid"
+)
+
+// AccountArchive - ไฟล์ Backup ทั้งบัญชี
+// ไม่มีประวัติแชท AI: Server ไม่เก็บแชท (Client ส่งบทสนทนาทั้งหมดมาทุกครั้งที่ถาม) จึงไม่มีอะไรให้สำรอง
+// ไฟล์เก่าที่มี "chat_history" ยัง Restore ได้ (ฟิลด์ที่ไม่รู้จักถูกข้ามไป)
+type AccountArchive struct {
+	Format        string          `json:"format"`  // services.AccountArchiveFormat
+	Version       int             `json:"version"` // services.AccountArchiveVersion
+	ExportedAt    time.Time       `json:"exported_at"`
+	Profile       ArchiveProfile  `json:"profile"`
+	Settings      ArchiveSettings `json:"settings"`
+	Tags          []string        `json:"tags"` // Tag ทั้งหมดที่ใช้ (อ่านอย่างเดียว ของจริงอยู่ใน trades[].tags)
+	Trades        []ArchiveTrade  `json:"trades"`
+	Imports       []TradeImport   `json:"imports"`        // ประวัติการนำเข้าจาก Exchange
+	ImportedFills []ImportedFill  `json:"imported_fills"` // Trade ID ของ Exchange ที่นำเข้าแล้ว (กันนำเข้าไฟล์ Exchange ซ้ำหลัง Restore)
+}
+
+// ArchiveProfile - ข้อมูลโปรไฟล์ (ไว้อ้างอิง Restore ไม่แก้ Username/Email ของบัญชีปลายทาง)
+type ArchiveProfile struct {
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArchiveSettings - การตั้งค่าบัญชี
+type ArchiveSettings struct {
+	Timezone         string          `json:"timezone"`
+	PortfolioBalance decimal.Decimal `json:"portfolio_balance"`
+}
+
+// ArchiveTrade - ไม้หนึ่งไม้ พร้อม exits/entries และเวลาที่ลบ (อยู่ในถังขยะ)
+type ArchiveTrade struct {
+	Trade
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ArchiveTradeResult - ผล Restore ของแต่ละไม้
+type ArchiveTradeResult struct {
+	ArchiveID uint   `json:"archive_id"` // ID ในไฟล์
+	Pair      string `json:"pair"`
+	Status    string `json:"status"`             // new, conflict, invalid
+	TradeID   *uint  `json:"trade_id,omitempty"` // ID ใหม่ (เฉพาะตอน Commit)
+	Reason    string `json:"reason,omitempty"`
+}
+
+// archiveRestoreCounts - จำนวนที่บันทึกจริงตอน Restore
+type archiveRestoreCounts struct {
+	Trades        int `json:"trades"`
+	Exits         int `json:"exits"`
+	Entries       int `json:"entries"`
+	Imports       int `json:"imports"`
+	ImportedFills int `json:"imported_fills"`
+}
+
+// ExportAccount - ดาวน์โหลด Backup ทั้งบัญชี
+// GET /api/account/export
+func ExportAccount(c *fiber.Ctx) error {
+	userID := GetCurrentUserID(c)
+	var user User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบข้อมูล User",
+		})
+	}
+
+	archive := AccountArchive{
+		Format:     services.AccountArchiveFormat,
+		Version:    services.AccountArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Profile:    ArchiveProfile{Username: user.Username, Email: user.Email, CreatedAt: user.CreatedAt},
+		Settings:   ArchiveSettings{Timezone: user.Timezone, PortfolioBalance: user.PortfolioBalance},
+		Trades:     []ArchiveTrade{},
+	}
+
+	var trades []Trade
+	err := database.DB.Unscoped().Where("user_id = ?", userID).
+		Preload("Exits", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Order("id ASC").Find(&trades).Error
+	if err == nil {
+		err = database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&archive.Imports).Error
+	}
+	if err == nil {
+		err = database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&archive.ImportedFills).Error
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	tags := make([]string, len(trades))
+	for i, trade := range trades {
+		item := ArchiveTrade{Trade: trade}
+		if trade.DeletedAt.Valid {
+			deletedAt := trade.DeletedAt.Time
+			item.DeletedAt = &deletedAt
+		}
+		archive.Trades = append(archive.Trades, item)
+		tags[i] = trade.Tags
+	}
+	archive.Tags = services.ArchiveTags(tags)
+
+	log.Printf("📦 ExportAccount: user %d, %d trades", userID, len(archive.Trades))
+	filename := fmt.Sprintf("mmrrdikub-%s-%s.json", user.Username, archive.ExportedAt.Format("20060102-150405"))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.JSON(archive)
+}
+
+// errBodyTooLarge - Body เกินขนาดที่รับได้ (ตอบ 413)
+var errBodyTooLarge = errors.New("body too large")
+
+// LimitBody - Middleware จำกัดขนาด Body (ตอบ 413) ยกเว้น Path ใน except ที่ Handler อ่าน Stream เอง
+// ใช้คู่กับ fiber.Config.StreamRequestBody: อ่าน Stream ไม่เกิน limit แล้วเก็บเป็น Body ปกติให้ Handler ถัดไป
+func LimitBody(limit int, except ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, path := range except {
+			if c.Path() == path {
+				return c.Next()
+			}
+		}
+		if c.Request().Header.ContentLength() > limit {
+			return respondBodyTooLarge(c, limit)
+		}
+		if stream := c.Context().RequestBodyStream(); stream != nil {
+			body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   "อ่านข้อมูลไม่ได้",
+					"message": err.Error(),
+				})
+			}
+			if len(body) > limit {
+				return respondBodyTooLarge(c, limit)
+			}
+			c.Request().SetBody(body)
+		} else if len(c.Request().Body()) > limit {
+			return respondBodyTooLarge(c, limit)
+		}
+		return c.Next()
+	}
+}
+
+// respondBodyTooLarge - ตอบ 413 พร้อมขนาดสูงสุด แล้วปิด Connection
+// (Body ที่เหลือใน Stream ยังไม่ได้อ่าน ถ้าใช้ Connection ต่อจะถูกอ่านเป็น Request ถัดไป)
+func respondBodyTooLarge(c *fiber.Ctx, limit int) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error": fmt.Sprintf("ข้อมูลใหญ่เกินไป (สูงสุด %d MB)", limit/(1024*1024)),
+		"code":  "body_too_large",
+	})
+}
+
+// maxBytesReader - อ่านได้ไม่เกิน n Byte เกินแล้วคืน errBodyTooLarge (ไม่ตัดเงียบๆ แบบ io.LimitReader)
+type maxBytesReader struct {
+	r io.Reader
+	n int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > m.n+1 {
+		p = p[:m.n+1]
+	}
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+	if m.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// readAccountImport - อ่าน Backup จาก Body แบบ Stream (ไม่พักทั้งไฟล์ไว้ก่อน) ไม่เกิน limit Byte
+// Body = JSON ตรงๆ หรือ multipart/form-data ช่อง file (ช่องอื่นที่มาก่อน file คืนใน form)
+func readAccountImport(c *fiber.Ctx, limit int64) (archive AccountArchive, form map[string]string, err error) {
+	if c.Request().Header.ContentLength() > int(limit) {
+		return archive, nil, errBodyTooLarge
+	}
+	// ไม่ได้เปิด StreamRequestBody (หรือ Body ว่าง) = Body อยู่ใน Memory แล้ว
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Request().Body())
+	}
+	body = &maxBytesReader{r: body, n: limit}
+
+	form = map[string]string{}
+	mediaType, params, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if mediaType != fiber.MIMEMultipartForm {
+		return archive, form, json.NewDecoder(body).Decode(&archive)
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return archive, form, errors.New("ไม่พบช่อง file")
+		}
+		if err != nil {
+			return archive, form, err
+		}
+		if part.FormName() == "file" {
+			return archive, form, json.NewDecoder(part).Decode(&archive)
+		}
+		value, err := io.ReadAll(io.LimitReader(part, 1024))
+		if err != nil {
+			return archive, form, err
+		}
+		form[part.FormName()] = string(value)
+	}
+}
+
+// ImportAccount - Restore Backup ลงบัญชีที่ Login อยู่
+// POST /api/account/import (Body = ไฟล์ JSON หรือ multipart/form-data ช่อง file ไม่เกิน AccountImportBodyLimit)
+// ตัวเลือกส่งทาง Query หรือช่องใน Form ที่อยู่ก่อน file (อ่าน Body แบบ Stream ครั้งเดียว)
+//
+//	conflict = skip (Default: ข้ามไม้ที่มีอยู่แล้ว) | duplicate (นำเข้าเป็นไม้ใหม่)
+//	settings = true (Default: ใช้ Timezone/ยอดพอร์ตจากไฟล์) | false
+//	dry_run  = true (Default: แสดงตัวอย่างอย่างเดียว) | false (บันทึกจริง)
+func ImportAccount(c *fiber.Ctx) error {
+	userID := GetCurrentUserID(c)
+	archive, form, err := readAccountImport(c, AccountImportBodyLimit)
+	if errors.Is(err, errBodyTooLarge) {
+		return respondBodyTooLarge(c, AccountImportBodyLimit)
+	}
+	if err != nil {
+		return respondInvalidArchive(c, err)
+	}
+	formValue := func(key, fallback string) string {
+		if value, ok := form[key]; ok {
+			return value
+		}
+		return fallback
+	}
+
+	conflict := c.Query("conflict", formValue("conflict", ArchiveConflictSkip))
+	if conflict != ArchiveConflictSkip && conflict != ArchiveConflictDuplicate {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "conflict ต้องเป็น skip หรือ duplicate",
+			"field": "conflict",
+			"code":  "invalid_conflict",
+		})
+	}
+	dryRun, err := strconv.ParseBool(c.Query("dry_run", formValue("dry_run", "true")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "dry_run ต้องเป็น true หรือ false",
+			"field": "dry_run",
+			"code":  "invalid_dry_run",
+		})
+	}
+	applySettings, err := strconv.ParseBool(c.Query("settings", formValue("settings", "true")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "settings ต้องเป็น true หรือ false",
+			"field": "settings",
+			"code":  "invalid_settings",
+		})
+	}
+	return restoreAccountArchive(c, userID, archive, conflict, applySettings, dryRun)
+}
+
+// respondInvalidArchive - อ่าน JSON ไม่ได้
+func respondInvalidArchive(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "ไฟล์ Backup ไม่ถูกต้อง",
+		"field":   "file",
+		"code":    "invalid_json",
+		"message": err.Error(),
+	})
+}
+
+// restoreAccountArchive - ตรวจไฟล์ แยกไม้ใหม่/ซ้ำ/ไม่ถูกต้อง แล้ว (ถ้าไม่ใช่ Dry-run) บันทึกใน Transaction เดียว
+func restoreAccountArchive(c *fiber.Ctx, userID uint, archive AccountArchive, conflict string, applySettings, dryRun bool) error {
+	if err := services.CheckAccountArchive(archive.Format, archive.Version); err != nil {
+		_, respErr := respondValidationError(c, err)
+		return respErr
+	}
+	importIDs := make([]uint, len(archive.Imports))
+	for i, batch := range archive.Imports {
+		importIDs[i] = batch.ID
+	}
+	fillImportIDs := make([]uint, len(archive.ImportedFills))
+	for i, fill := range archive.ImportedFills {
+		fillImportIDs[i] = fill.ImportID
+	}
+	if err := services.CheckArchiveImportRefs(importIDs, fillImportIDs); err != nil {
+		_, respErr := respondValidationError(c, err)
+		return respErr
+	}
+
+	var warnings []string
+	settings := map[string]interface{}{}
+	if applySettings {
+		if archive.Settings.Timezone != "" {
+			if loc, err := services.LoadTimezone(archive.Settings.Timezone); err == nil {
+				settings["timezone"] = loc.String()
+			} else {
+				warnings = append(warnings, fmt.Sprintf("Timezone %q ไม่ถูกต้อง ไม่ได้ใช้", archive.Settings.Timezone))
+			}
+		}
+		if archive.Settings.PortfolioBalance.IsPositive() {
+			settings["portfolio_balance"] = archive.Settings.PortfolioBalance
+		}
+	}
+
+	results := make([]ArchiveTradeResult, len(archive.Trades))
+	counts := map[string]int{ArchiveTradeNew: 0, ArchiveTradeConflict: 0, ArchiveTradeInvalid: 0}
+	var restored archiveRestoreCounts
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			// ใช้ Lock เดียวกับการนำเข้าจาก Exchange เพราะสร้าง Trade/ImportedFill ชุดเดียวกัน
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", importLockNamespace, userID).Error; err != nil {
+				return err
+			}
+		}
+		existing, err := existingTradeFingerprints(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool)
+		for i, item := range archive.Trades {
+			result := ArchiveTradeResult{ArchiveID: item.ID, Pair: item.Pair, Status: ArchiveTradeNew}
+			fingerprint := services.TradeFingerprint(item.Pair, item.Side, item.EntryPrice, item.Quantity, item.CreatedAt)
+			switch {
+			case seen[item.ID]:
+				result.Status, result.Reason = ArchiveTradeInvalid, "id ซ้ำในไฟล์"
+			default:
+				if reason := validateArchiveTrade(item); reason != "" {
+					result.Status, result.Reason = ArchiveTradeInvalid, reason
+				} else if existing[fingerprint] {
+					result.Status = ArchiveTradeConflict
+				}
+			}
+			seen[item.ID] = true
+			counts[result.Status]++
+			results[i] = result
+		}
+		if dryRun {
+			return nil
+		}
+
+		tradeIDs := make(map[uint]uint) // ID ในไฟล์ → ID ใหม่
+		for i, item := range archive.Trades {
+			create := results[i].Status == ArchiveTradeNew ||
+				(results[i].Status == ArchiveTradeConflict && conflict == ArchiveConflictDuplicate)
+			if !create {
+				continue
+			}
+			trade, exits, entries, err := restoreArchiveTrade(tx, c, userID, item)
+			if err != nil {
+				return err
+			}
+			tradeIDs[item.ID] = trade.ID
+			results[i].TradeID = &trade.ID
+			restored.Trades++
+			restored.Exits += exits
+			restored.Entries += entries
+		}
+
+		// ประวัติการนำเข้า: Restore เฉพาะครั้งที่มี Fill ของไม้ที่ Restore ในรอบนี้ (Restore ไฟล์เดิมซ้ำจะไม่ได้ประวัติซ้ำ)
+		var fills []ImportedFill
+		usedImports := make(map[uint]bool)
+		for _, fill := range archive.ImportedFills {
+			tradeID, ok := tradeIDs[fill.TradeID]
+			if !ok {
+				continue // ไม้ที่ข้ามไป (ซ้ำของเดิม ซึ่งมี Fill อยู่แล้ว) หรือไม่มีในไฟล์
+			}
+			usedImports[fill.ImportID] = true
+			fills = append(fills, ImportedFill{UserID: userID, Source: fill.Source, ExternalID: fill.ExternalID, TradeID: tradeID, ImportID: fill.ImportID})
+		}
+		newImportIDs := make(map[uint]uint) // ID ในไฟล์ → ID ใหม่ (ทุก Fill มีชุดในไฟล์แล้วจาก CheckArchiveImportRefs)
+		for _, batch := range archive.Imports {
+			if !usedImports[batch.ID] {
+				continue
+			}
+			archiveID := batch.ID
+			batch.ID, batch.UserID = 0, userID
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+			newImportIDs[archiveID] = batch.ID
+			restored.Imports++
+		}
+		for i := range fills {
+			fills[i].ImportID = newImportIDs[fills[i].ImportID]
+		}
+		if len(fills) > 0 {
+			// Fill ที่บัญชีนี้นำเข้าไว้แล้ว (conflict=duplicate) คงของเดิม
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&fills, 500)
+			if created.Error != nil {
+				return created.Error
+			}
+			restored.ImportedFills = int(created.RowsAffected)
+		}
+
+		if len(settings) > 0 {
+			return tx.Model(&User{}).Where("id = ?", userID).Updates(settings).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return respondTradeTxError(c, "ImportAccount", err)
+	}
+
+	if !dryRun {
+		log.Printf("📦 ImportAccount: user %d, %d trades restored, %d conflicts (%s)", userID, restored.Trades, counts[ArchiveTradeConflict], conflict)
+	}
+	return c.JSON(fiber.Map{
+		"dry_run":          dryRun,
+		"conflict":         conflict,
+		"source_profile":   archive.Profile,
+		"counts":           counts,
+		"trades":           results,
+		"restored":         restored,
+		"settings_applied": settings,
+		"warnings":         warnings,
+	})
+}
+
+// existingTradeFingerprints - Fingerprint ของไม้ที่บัญชีนี้มีอยู่แล้ว (รวมถังขยะ)
+func existingTradeFingerprints(tx *gorm.DB, userID uint) (map[string]bool, error) {
+	var trades []Trade
+	if err := tx.Unscoped().Select("pair", "side", "entry_price", "quantity", "created_at").
+		Where("user_id = ?", userID).Find(&trades).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(trades))
+	for _, trade := range trades {
+		existing[services.TradeFingerprint(trade.Pair, trade.Side, trade.EntryPrice, trade.Quantity, trade.CreatedAt)] = true
+	}
+	return existing, nil
+}
+
+// validateArchiveTrade - ตรวจข้อมูลขั้นต่ำที่ระบบต้องใช้ คืนเหตุผลถ้าไม่ถูกต้อง ("" = ผ่าน)
+func validateArchiveTrade(item ArchiveTrade) string {
+	switch {
+	case strings.TrimSpace(item.Pair) == "":
+		return "ไม่มี pair"
+	case item.Side != "LONG" && item.Side != "SHORT":
+		return fmt.Sprintf("side %q ไม่ถูกต้อง", item.Side)
+	case item.CreatedAt.IsZero():
+		return "ไม่มี created_at"
+	}
+	if _, ok := services.NormalizeTradeStatus(item.Status); !ok {
+		return fmt.Sprintf("status %q ไม่ถูกต้อง", item.Status)
+	}
+	return ""
+}
+
+// restoreArchiveTrade - สร้างไม้จาก Backup ด้วย ID ใหม่ แล้วผูก Exit/Entry เข้ากับไม้ใหม่
+// ค่าที่คำนวณไว้แล้ว (PnL, R-Multiple, สถานะ, เวลา) ใช้ตามไฟล์ ไม่คำนวณซ้ำ
+func restoreArchiveTrade(tx *gorm.DB, c *fiber.Ctx, userID uint, item ArchiveTrade) (Trade, int, int, error) {
+	trade := item.Trade
+	exits, entries := trade.Exits, trade.Entries
+	trade.ID, trade.UserID, trade.Exits, trade.Entries = 0, userID, nil, nil
+	trade.Status, _ = services.NormalizeTradeStatus(trade.Status)
+	if item.DeletedAt != nil {
+		trade.DeletedAt = gorm.DeletedAt{Time: *item.DeletedAt, Valid: true}
+	}
+	// Backup ก่อนมี initial_risk ใช้ Risk ที่บันทึกไว้ในไฟล์
+	if trade.InitialRisk == nil && trade.Status != services.StatusPlanned {
+		risk := openingRisk(trade)
+		trade.InitialRisk = &risk
+	}
+	if err := tx.Omit(clause.Associations).Create(&trade).Error; err != nil {
+		return trade, 0, 0, err
+	}
+
+	for i := range exits {
+		exits[i].ID, exits[i].TradeID, exits[i].UserID = 0, trade.ID, userID
+	}
+	for i := range entries {
+		entries[i].ID, entries[i].TradeID, entries[i].UserID = 0, trade.ID, userID
+	}
+	if len(exits) > 0 {
+		if err := tx.Create(&exits).Error; err != nil {
+			return trade, 0, 0, err
+		}
+	}
+	if len(entries) > 0 {
+		if err := tx.Create(&entries).Error; err != nil {
+			return trade, 0, 0, err
+		}
+	}
+
+	_, err := recordTradeRevision(tx, c, RevisionCreate, nil, trade)
+	return trade, len(exits), len(entries), err
+}
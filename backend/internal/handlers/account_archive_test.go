@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamingApp - Fiber ตั้งค่าแบบเดียวกับ main.go (Body ใหญ่กว่า bodyLimit ส่งเป็น Stream)
+func streamingApp(bodyLimit int) *fiber.App {
+	return fiber.New(fiber.Config{
+		BodyLimit:                    bodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+}
+
+// TestLimitBody - Route ทั่วไปถูกจำกัดขนาด Body ส่วน Path ที่ยกเว้นอ่าน Stream เองได้ใหญ่กว่า fiber.Config.BodyLimit
+func TestLimitBody(t *testing.T) {
+	app := streamingApp(16)
+	app.Use(LimitBody(16, "/import"))
+	echo := func(c *fiber.Ctx) error { return c.SendString(string(c.Body())) }
+	app.Post("/import", echo)
+	app.Post("/trades", echo)
+
+	tests := []struct {
+		path string
+		size int
+		want int
+	}{
+		{"/trades", 16, fiber.StatusOK},
+		{"/trades", 17, fiber.StatusRequestEntityTooLarge},
+		{"/trades", 4096, fiber.StatusRequestEntityTooLarge},
+		{"/import", 512, fiber.StatusOK},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("POST", tc.path, bytes.NewReader(bytes.Repeat([]byte("a"), tc.size)))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("POST %s (%d bytes) = %d, want %d", tc.path, tc.size, resp.StatusCode, tc.want)
+		}
+		if tc.want == fiber.StatusOK && resp.ContentLength != int64(tc.size) {
+			t.Errorf("POST %s: Handler ได้ Body %d bytes, want %d", tc.path, resp.ContentLength, tc.size)
+		}
+	}
+}
+
+// TestReadAccountImport - อ่าน Backup แบบ Stream ได้ทั้ง JSON ตรงๆ และ multipart และเกิน limit ได้ errBodyTooLarge
+func TestReadAccountImport(t *testing.T) {
+	const archiveJSON = `{"format":"mmrrdikub-account","version":1}`
+
+	var multipartBody bytes.Buffer
+	writer := multipart.NewWriter(&multipartBody)
+	writer.WriteField("conflict", "duplicate")
+	file, _ := writer.CreateFormFile("file", "backup.json")
+	file.Write([]byte(archiveJSON))
+	writer.Close()
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		limit        int64
+		wantConflict string
+		wantErr      error
+	}{
+		{"JSON ตรงๆ", fiber.MIMEApplicationJSON, archiveJSON, 1024, "", nil},
+		{"multipart ช่อง file", writer.FormDataContentType(), multipartBody.String(), 1024, "duplicate", nil},
+		{"ใหญ่กว่า limit", fiber.MIMEApplicationJSON, archiveJSON + strings.Repeat(" ", 1024), 64, "", errBodyTooLarge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := streamingApp(16)
+			var got AccountArchive
+			var form map[string]string
+			var readErr error
+			app.Post("/import", func(c *fiber.Ctx) error {
+				got, form, readErr = readAccountImport(c, tc.limit)
+				if errors.Is(readErr, errBodyTooLarge) {
+					return respondBodyTooLarge(c, int(tc.limit))
+				}
+				return c.SendStatus(fiber.StatusOK)
+			})
+			req := httptest.NewRequest("POST", "/import", strings.NewReader(tc.body))
+			req.Header.Set(fiber.HeaderContentType, tc.contentType)
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(readErr, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", readErr, tc.wantErr)
+				}
+				return
+			}
+			if readErr != nil {
+				t.Fatalf("readAccountImport: %v", readErr)
+			}
+			if got.Format != "mmrrdikub-account" || got.Version != 1 {
+				t.Errorf("archive = %+v", got)
+			}
+			if form["conflict"] != tc.wantConflict {
+				t.Errorf("conflict = %q, want %q", form["conflict"], tc.wantConflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Package handlers - Admin API
+// ดู/ค้นหาผู้ใช้ ระงับบัญชี สั่งรีเซ็ตรหัสผ่าน และดูสถิติการใช้งานทั้งระบบ (เฉพาะ Role admin)
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// AdminUser - ผู้ใช้ในหน้า Admin พร้อมจำนวน Trade และเวลาที่ใช้งานล่าสุด
+type AdminUser struct {
+	User
+	TradeCount   int64      `json:"trade_count"`
+	LastActiveAt *time.Time `json:"last_active_at"` // ใช้งานล่าสุดจาก Session (nil = ยังไม่เคย Login)
+}
+
+// AdminStats - สถิติการใช้งานทั้งระบบ
+type AdminStats struct {
+	TotalUsers          int64 `json:"total_users"`
+	ActiveUsers         int64 `json:"active_users"`
+	DisabledUsers       int64 `json:"disabled_users"`
+	Admins              int64 `json:"admins"`
+	PendingResets       int64 `json:"pending_password_resets"`
+	NewUsers7d          int64 `json:"new_users_7d"`
+	NewUsers30d         int64 `json:"new_users_30d"`
+	ActiveUsers30d      int64 `json:"active_users_30d"` // มี Session ที่ใช้งานภายใน 30 วัน
+	ActiveSessions      int64 `json:"active_sessions"`
+	TotalTrades         int64 `json:"total_trades"`
+	OpenTrades          int64 `json:"open_trades"`
+	ClosedTrades        int64 `json:"closed_trades"`
+	TradesCreated30d    int64 `json:"trades_created_30d"`
+	TrashedTrades       int64 `json:"trashed_trades"`
+	TradeImports        int64 `json:"trade_imports"`
+	TradesImportedTotal int64 `json:"trades_imported_total"`
+}
+
+// adminUserColumns - คอลัมน์ของ User + ตัวเลขประกอบ (Subquery ต่อแถว ใช้กับหน้าละไม่เกิน 100 คน)
+const adminUserColumns = `users.*,
+	(SELECT COUNT(*) FROM trades WHERE trades.user_id = users.id AND trades.deleted_at IS NULL) AS trade_count,
+	(SELECT MAX(last_used_at) FROM user_sessions WHERE user_sessions.user_id = users.id) AS last_active_at`
+
+// adminStatsSQL - สถิติทั้งระบบใน Query เดียว
+const adminStatsSQL = `
+SELECT
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL) AS total_users,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND is_active) AS active_users,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND NOT is_active) AS disabled_users,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND role = @admin) AS admins,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND must_reset_password) AS pending_resets,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND created_at >= @since7d) AS new_users7d,
+	(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND created_at >= @since30d) AS new_users30d,
+	(SELECT COUNT(DISTINCT user_id) FROM user_sessions WHERE last_used_at >= @since30d) AS active_users30d,
+	(SELECT COUNT(*) FROM user_sessions WHERE revoked_at IS NULL AND expires_at > @now) AS active_sessions,
+	(SELECT COUNT(*) FROM trades WHERE deleted_at IS NULL) AS total_trades,
+	(SELECT COUNT(*) FROM trades WHERE deleted_at IS NULL AND status = @open) AS open_trades,
+	(SELECT COUNT(*) FROM trades WHERE deleted_at IS NULL AND status = @closed) AS closed_trades,
+	(SELECT COUNT(*) FROM trades WHERE deleted_at IS NULL AND created_at >= @since30d) AS trades_created30d,
+	(SELECT COUNT(*) FROM trades WHERE deleted_at IS NOT NULL) AS trashed_trades,
+	(SELECT COUNT(*) FROM trade_imports) AS trade_imports,
+	(SELECT COALESCE(SUM(trades_created), 0) FROM trade_imports) AS trades_imported_total
+`
+
+// ============================================
+// Handler Functions
+// ============================================
+
+// GetAdminUsers - รายชื่อผู้ใช้ (ค้นหาจาก Username/Email, กรอง Role/สถานะ) ใหม่สุดก่อน
+// GET /api/admin/users?search=&role=user|admin&status=active|disabled|reset_required&limit=&offset=
+func GetAdminUsers(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := database.DB.Model(&User{})
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := services.LikePattern(search)
+		query = query.Where("users.username ILIKE ? OR users.email ILIKE ?", pattern, pattern)
+	}
+	if raw := c.Query("role"); raw != "" {
+		role, ok := services.NormalizeRole(raw)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "role ต้องเป็น user หรือ admin",
+				"field": "role",
+				"code":  "invalid_role",
+			})
+		}
+		query = query.Where("users.role = ?", role)
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("users.is_active")
+	case "disabled":
+		query = query.Where("NOT users.is_active")
+	case "reset_required":
+		query = query.Where("users.must_reset_password")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status ต้องเป็น active, disabled หรือ reset_required",
+			"field": "status",
+			"code":  "invalid_status",
+		})
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return adminError(c, "ไม่สามารถดึงรายชื่อผู้ใช้ได้", err)
+	}
+
+	var users []AdminUser
+	err := query.Select(adminUserColumns).
+		Order("users.created_at DESC, users.id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&users).Error
+	if err != nil {
+		return adminError(c, "ไม่สามารถดึงรายชื่อผู้ใช้ได้", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"users": users,
+		"total": total,
+	})
+}
+
+// GetAdminUser - ข้อมูลผู้ใช้คนเดียว
+// GET /api/admin/users/:id
+func GetAdminUser(c *fiber.Ctx) error {
+	var user AdminUser
+	result := database.DB.Model(&User{}).Select(adminUserColumns).
+		Where("users.id = ?", c.Params("id")).
+		Limit(1).Scan(&user)
+	if result.Error != nil {
+		return adminError(c, "ไม่สามารถดึงข้อมูลผู้ใช้ได้", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return adminUserNotFound(c)
+	}
+	return c.JSON(fiber.Map{"user": user})
+}
+
+// DisableUser - ระงับบัญชี (Login/Refresh ไม่ได้ และออกจากระบบทุกอุปกรณ์ทันที)
+// POST /api/admin/users/:id/disable
+func DisableUser(c *fiber.Ctx) error {
+	return updateUserByAdmin(c, "DisableUser", map[string]interface{}{"is_active": false}, true)
+}
+
+// EnableUser - ยกเลิกการระงับบัญชี
+// POST /api/admin/users/:id/enable
+func EnableUser(c *fiber.Ctx) error {
+	return updateUserByAdmin(c, "EnableUser", map[string]interface{}{"is_active": true}, false)
+}
+
+// ForcePasswordReset - บังคับให้ตั้งรหัสผ่านใหม่ผ่าน Forgot Password (ออกจากระบบทุกอุปกรณ์ทันที)
+// POST /api/admin/users/:id/force-password-reset
+func ForcePasswordReset(c *fiber.Ctx) error {
+	return updateUserByAdmin(c, "ForcePasswordReset", map[string]interface{}{"must_reset_password": true}, true)
+}
+
+// GetAdminStats - สถิติการใช้งานทั้งระบบ
+// GET /api/admin/stats
+func GetAdminStats(c *fiber.Ctx) error {
+	now := time.Now()
+	var stats AdminStats
+	err := database.DB.Raw(adminStatsSQL, map[string]interface{}{
+		"admin":    services.RoleAdmin,
+		"open":     services.StatusOpen,
+		"closed":   services.StatusClosed,
+		"now":      now,
+		"since7d":  now.AddDate(0, 0, -7),
+		"since30d": now.AddDate(0, 0, -30),
+	}).Scan(&stats).Error
+	if err != nil {
+		return adminError(c, "ไม่สามารถคำนวณสถิติได้", err)
+	}
+	return c.JSON(fiber.Map{
+		"stats":        stats,
+		"generated_at": now,
+	})
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// updateUserByAdmin - แก้สถานะบัญชีของผู้ใช้ :id (ห้ามแก้บัญชีตัวเอง กัน Admin ล็อกตัวเองออก)
+// revokeSessions = ยกเลิกทุก Session ของผู้ใช้ใน Transaction เดียวกัน
+func updateUserByAdmin(c *fiber.Ctx, action string, updates map[string]interface{}, revokeSessions bool) error {
+	var user User
+	var revoked int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, c.Params("id")).Error; err != nil {
+			return err
+		}
+		if user.ID == GetCurrentUserID(c) {
+			return errAdminSelf
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if revokeSessions {
+			var err error
+			if revoked, err = revokeUserSessions(tx, user.ID, 0); err != nil {
+				return err
+			}
+		}
+		return tx.First(&user, user.ID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return adminUserNotFound(c)
+	}
+	if errors.Is(err, errAdminSelf) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "cannot_modify_self",
+		})
+	}
+	if err != nil {
+		return adminError(c, "ไม่สามารถแก้ไขบัญชีได้", err)
+	}
+
+	log.Printf("🛡️ ADMIN %s: admin=%d user=%d revoked_sessions=%d", action, GetCurrentUserID(c), user.ID, revoked)
+	return c.JSON(fiber.Map{
+		"message":          "อัปเดตบัญชีแล้ว",
+		"user":             user,
+		"revoked_sessions": revoked,
+	})
+}
+
+// errAdminSelf - Admin แก้สถานะบัญชีของตัวเอง
+var errAdminSelf = errors.New("ไม่สามารถแก้สถานะบัญชีของตัวเองได้")
+
+// checkAccountUsable - บัญชีใช้ Login/Refresh ได้หรือไม่ (ดู services.CheckAccountStatus)
+func checkAccountUsable(user User) error {
+	return services.CheckAccountStatus(user.IsActive, user.MustResetPassword)
+}
+
+// respondAccountBlocked - ตอบ 403 พร้อม code ให้ Frontend พาไปหน้าที่ถูกต้อง (เช่นหน้าลืมรหัสผ่าน)
+func respondAccountBlocked(c *fiber.Ctx, err error) error {
+	body := fiber.Map{"error": err.Error()}
+	var vErr *services.ValidationError
+	if errors.As(err, &vErr) {
+		body["code"] = vErr.Code
+	}
+	return c.Status(fiber.StatusForbidden).JSON(body)
+}
+
+// userRole - Role ของ User (Role ที่ไม่รู้จักลดเป็น user กันได้สิทธิ์เกิน)
+func userRole(user User) string {
+	role, ok := services.NormalizeRole(user.Role)
+	if !ok {
+		return services.RoleUser
+	}
+	return role
+}
+
+// promoteAdmins - ตั้ง Role admin ให้ Username ที่ระบุ (คั่นด้วย ,)
+func promoteAdmins(usernames string) error {
+	var names []string
+	for _, name := range strings.Split(usernames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	result := database.DB.Model(&User{}).
+		Where("username IN ? AND role <> ?", names, services.RoleAdmin).
+		Update("role", services.RoleAdmin)
+	if result.RowsAffected > 0 {
+		log.Printf("🛡️ Promoted %d user(s) to admin from ADMIN_USERNAMES", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// adminUserNotFound - ตอบ 404 เมื่อไม่พบผู้ใช้
+func adminUserNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "ไม่พบผู้ใช้",
+	})
+}
+
+// adminError - ตอบ 500 พร้อม Log
+func adminError(c *fiber.Ctx, message string, err error) error {
+	log.Printf("❌ Admin error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   message,
+		"message": err.Error(),
+	})
+}
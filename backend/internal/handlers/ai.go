@@ -97,7 +97,7 @@ func AnalyzeTrade(c *fiber.Ctx) error {
 
 	history := ""
 	for i, t := range trades {
-		history += fmt.Sprintf("ไม้%d:%s PnL=%.0f ", i+1, t.Status, t.PnL)
+		history += fmt.Sprintf("ไม้%d:%s PnL=%s ", i+1, t.ResultLabel(), t.PnL.StringFixed(0))
 	}
 	if history == "" {
 		history = "ไม่เคยเทรด"
@@ -296,7 +296,7 @@ func GetAIInsights(c *fiber.Ctx) error {
 
 	history := ""
 	for i, t := range trades {
-		history += fmt.Sprintf("Trade %d: %s %s Entry=%.2f PnL=%.2f Status=%s\n", i+1, t.Pair, t.Side, t.EntryPrice, t.PnL, t.Status)
+		history += fmt.Sprintf("Trade %d: %s %s Entry=%s PnL=%s Status=%s\n", i+1, t.Pair, t.Side, t.EntryPrice, t.PnL.StringFixed(2), t.ResultLabel())
 	}
 
 	prompt := fmt.Sprintf(`Analyze the following 20 recent trades of a user and provide exactly 3 critical trading behavior insights in JSON array format.
@@ -0,0 +1,298 @@
+// Package handlers - Performance Analytics
+// สถิติผลการเทรดจากไม้ที่ปิดแล้ว (กรองด้วย Pair/Side/ช่วงวันที่ แบบเดียวกับ GET /api/trades)
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// performanceAggregateColumns - ตัวเลขรวมตาม services.PerformanceAggregate (ใช้ร่วมกันทั้ง Summary และ Breakdown)
+const performanceAggregateColumns = `
+	COUNT(*) AS total_trades,
+	COUNT(*) FILTER (WHERE outcome = @win) AS wins,
+	COUNT(*) FILTER (WHERE outcome = @loss) AS losses,
+	COUNT(*) FILTER (WHERE outcome = @break_even) AS break_evens,
+	COALESCE(SUM(pnl), 0) AS net_pnl,
+	COALESCE(SUM(pnl) FILTER (WHERE outcome = @win), 0) AS gross_profit,
+	COALESCE(-SUM(pnl) FILTER (WHERE outcome = @loss), 0) AS gross_loss,
+	COALESCE(MAX(pnl) FILTER (WHERE outcome = @win), 0) AS largest_win,
+	COALESCE(MIN(pnl) FILTER (WHERE outcome = @loss), 0) AS largest_loss,
+	COALESCE(SUM(r_multiple), 0) AS sum_r,
+	COUNT(r_multiple) AS r_count,
+	COALESCE(STDDEV_SAMP(r_multiple), 0) AS std_dev_r`
+
+// performanceSummarySQL - นับ/รวม/หา Streak ใน Query เดียว
+// Streak ใช้เทคนิค Gaps-and-Islands: ลำดับรวม − ลำดับในกลุ่มผลเดียวกัน = เลขกลุ่มของไม้ที่ติดกัน
+const performanceSummarySQL = `
+WITH closed AS (@closed_trades),
+ordered AS (
+	SELECT outcome,
+		ROW_NUMBER() OVER (ORDER BY closed_time, id) -
+		ROW_NUMBER() OVER (PARTITION BY outcome ORDER BY closed_time, id) AS streak_group
+	FROM closed
+),
+streaks AS (
+	SELECT outcome, COUNT(*) AS streak_length FROM ordered GROUP BY outcome, streak_group
+)
+SELECT` + performanceAggregateColumns + `,
+	(SELECT COALESCE(MAX(streak_length), 0) FROM streaks WHERE outcome = @win) AS longest_win_streak,
+	(SELECT COALESCE(MAX(streak_length), 0) FROM streaks WHERE outcome = @loss) AS longest_loss_streak
+FROM closed
+`
+
+// GetAnalyticsSummary - สถิติผลการเทรด (Win Rate, Expectancy, Profit Factor, Streak ฯลฯ)
+// GET /api/analytics/summary?pair=&side=&date_from=&date_to=
+func GetAnalyticsSummary(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+
+	var agg services.PerformanceAggregate
+	err = database.DB.Raw(performanceSummarySQL, analyticsArgs(GetCurrentUserID(c), filter)...).Scan(&agg).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถคำนวณสถิติได้",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"summary": services.SummarizePerformance(agg),
+		"filter":  filter,
+	})
+}
+
+// GetAnalyticsBreakdown - สถิติแยกกลุ่มตามมิติที่เลือก
+// GET /api/analytics/breakdown?dimension=pair|side|tag|setup_score|weekday|hour|holding_time&pair=&side=&date_from=&date_to=
+func GetAnalyticsBreakdown(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+	dimension := strings.ToLower(strings.TrimSpace(c.Query("dimension", services.DimensionPair)))
+	if !services.IsBreakdownDimension(dimension) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ไม่รู้จัก dimension นี้ (pair, side, tag, setup_score, weekday, hour, holding_time)",
+			"field": "dimension",
+			"code":  "invalid_dimension",
+		})
+	}
+
+	groupExpr, join := breakdownGroupExpression(dimension)
+	query := fmt.Sprintf("SELECT %s AS group_key,%s\nFROM (@closed_trades) AS closed %s\nGROUP BY 1", groupExpr, performanceAggregateColumns, join)
+
+	var groups []services.BreakdownAggregate
+	if err := database.DB.Raw(query, analyticsArgs(GetCurrentUserID(c), filter)...).Scan(&groups).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถคำนวณสถิติได้",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"dimension": dimension,
+		"groups":    services.SummarizeBreakdown(dimension, groups),
+		"filter":    filter,
+	})
+}
+
+// breakdownGroupExpression - SQL ของค่าที่ใช้แบ่งกลุ่ม (และ JOIN ที่ต้องใช้) ของแต่ละมิติ
+// เวลาเข้าไม้ = opened_time (วัน/ชั่วโมงตาม Timezone ของผู้ใช้), ระยะเวลาถือ = closed_time − opened_time
+func breakdownGroupExpression(dimension string) (string, string) {
+	switch dimension {
+	case services.DimensionSide:
+		return "closed.side", ""
+	case services.DimensionTag:
+		// Tags เก็บเป็น "breakout,trend" ไม้หนึ่งนับในทุก Tag ที่มี
+		return "COALESCE(LOWER(TRIM(tag.name)), '')",
+			"LEFT JOIN LATERAL unnest(string_to_array(closed.tags, ',')) AS tag(name) ON true"
+	case services.DimensionSetupScore:
+		return "closed.setup_score::text", ""
+	case services.DimensionWeekday:
+		return "EXTRACT(ISODOW FROM closed.opened_time AT TIME ZONE @tz)::int::text", ""
+	case services.DimensionHour:
+		return "EXTRACT(HOUR FROM closed.opened_time AT TIME ZONE @tz)::int::text", ""
+	case services.DimensionHoldingTime:
+		var expr strings.Builder
+		expr.WriteString("CASE")
+		for i, bucket := range services.HoldingTimeBuckets {
+			if bucket.Upper == 0 {
+				fmt.Fprintf(&expr, " ELSE '%d'", i)
+				break
+			}
+			fmt.Fprintf(&expr, " WHEN closed.closed_time - closed.opened_time < interval '%d seconds' THEN '%d'", int64(bucket.Upper.Seconds()), i)
+		}
+		expr.WriteString(" END")
+		return expr.String(), ""
+	}
+	return "closed.pair", ""
+}
+
+// analyticsArgs - Named parameters ของ Query สถิติ
+func analyticsArgs(userID uint, filter TradeFilter) []interface{} {
+	return []interface{}{
+		sql.Named("closed_trades", closedTradesQuery(userID, filter)),
+		sql.Named("tz", filter.location().String()),
+		sql.Named("win", services.OutcomeWin),
+		sql.Named("loss", services.OutcomeLoss),
+		sql.Named("break_even", services.OutcomeBreakEven),
+	}
+}
+
+// GetEquityCurve - กราฟเงินทุนสะสมและ Drawdown จากไม้ที่ปิดแล้ว เริ่มจากยอดพอร์ตของ User
+// GET /api/analytics/equity?bucket=trade|day|week|month&starting_balance=&pair=&side=&date_from=&date_to=
+func GetEquityCurve(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+	userID := GetCurrentUserID(c)
+
+	// ยอดเริ่มต้น: ส่งมาเอง หรือใช้ยอดพอร์ตที่ตั้งไว้
+	var startingBalance decimal.Decimal
+	if raw := c.Query("starting_balance"); raw != "" {
+		balance, err := decimal.NewFromString(raw)
+		if err != nil || balance.IsNegative() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "starting_balance ต้องเป็นตัวเลขที่ไม่ติดลบ",
+				"field": "starting_balance",
+				"code":  "invalid_starting_balance",
+			})
+		}
+		startingBalance = balance
+	} else {
+		var user User
+		if err := database.DB.Select("portfolio_balance").First(&user, userID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "ไม่พบข้อมูล User",
+			})
+		}
+		startingBalance = user.PortfolioBalance
+	}
+
+	var trades []services.EquityTrade
+	if err := database.DB.Table("(?) AS closed", closedTradesQuery(userID, filter)).
+		Select("closed_time, pnl").
+		Order("closed_time ASC, id ASC").
+		Scan(&trades).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	curve, err := services.BuildEquityCurve(startingBalance, trades, c.Query("bucket"), filter.location(), time.Now())
+	if err != nil {
+		if handled, respErr := respondValidationError(c, err); handled {
+			return respErr
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถสร้างกราฟได้",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"equity": curve,
+		"filter": filter,
+	})
+}
+
+// GetAnalyticsCalendar - PnL รายวันของเดือน (Heatmap) แบ่งวันตาม Timezone ของผู้ใช้
+// GET /api/analytics/calendar?month=YYYY-MM&pair=&side=  (ไม่ส่ง month = เดือนปัจจุบัน)
+func GetAnalyticsCalendar(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+	loc := filter.location()
+	month := c.Query("month", time.Now().In(loc).Format("2006-01"))
+	start, end, err := services.ParseMonth(month, loc)
+	if err != nil {
+		_, respErr := respondValidationError(c, err)
+		return respErr
+	}
+
+	var trades []services.CalendarTrade
+	if err := database.DB.Table("(?) AS closed", closedTradesQuery(GetCurrentUserID(c), filter)).
+		Select("closed_time, pnl, outcome").
+		Where("closed_time >= ? AND closed_time < ?", start, end).
+		Order("closed_time ASC").
+		Scan(&trades).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"calendar": services.BuildCalendar(start, trades),
+		"filter":   filter,
+	})
+}
+
+// GetRDistribution - การกระจายของ R-Multiple (Histogram) พร้อม Expectancy ในหน่วย R และ SQN
+// GET /api/analytics/r-multiples?bucket_size=0.5&pair=&side=&date_from=&date_to=
+func GetRDistribution(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+
+	var bucketSize decimal.Decimal
+	if raw := c.Query("bucket_size"); raw != "" {
+		if bucketSize, err = decimal.NewFromString(raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "bucket_size ต้องเป็นตัวเลข",
+				"field": "bucket_size",
+				"code":  "invalid_bucket_size",
+			})
+		}
+	}
+
+	var rs []decimal.Decimal
+	if err := database.DB.Table("(?) AS closed", closedTradesQuery(GetCurrentUserID(c), filter)).
+		Where("r_multiple IS NOT NULL").
+		Pluck("r_multiple", &rs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	distribution, err := services.BuildRDistribution(rs, bucketSize)
+	if err != nil {
+		return respondTradeTxError(c, "Analytics", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"distribution": distribution,
+		"filter":       filter,
+	})
+}
+
+// closedTradesQuery - Subquery ไม้ที่ปิดแล้วของ User ตาม Filter
+// opened_time = เวลาเข้าไม้ (ข้อมูลเก่าที่ไม่มี entry_time ใช้ opened_at หรือ created_at แทน)
+// closed_time = เวลาปิดไม้ (ข้อมูลเก่าที่ไม่มี closed_at ใช้ exit_time หรือ updated_at แทน) date_from/date_to กรองด้วยค่านี้
+// r_multiple = PnL / Risk ตอนเข้า ที่บันทึกไว้ตอนปิดไม้ (NULL ถ้าไม่รู้ Risk)
+// pnl = NULL อ่านเป็น 0 (decimal.Decimal ใน services.EquityTrade/CalendarTrade อ่าน NULL ไม่ได้)
+func closedTradesQuery(userID uint, filter TradeFilter) *gorm.DB {
+	query := database.DB.Model(&Trade{}).
+		Select(`id, pair, side, tags, setup_score, COALESCE(pnl, 0) AS pnl, outcome,
+			r_multiple,
+			COALESCE(entry_time, opened_at, created_at) AS opened_time,
+			COALESCE(closed_at, exit_time, updated_at) AS closed_time`).
+		Where("user_id = ? AND status = ?", userID, services.StatusClosed)
+	return applyTradeFilters(query, filter, closedTradeDateColumn)
+}
@@ -3,15 +3,20 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"mmrrdikub/internal/services"
 	"mmrrdikub/pkg/database"
 )
 
@@ -21,15 +26,30 @@ import (
 // GORM จะสร้าง Table "users" ให้อัตโนมัติ
 // 🔥 FIX: ใช้ gorm tag "column" ให้ตรงกับ schema.sql
 type User struct {
-	ID                uint           `gorm:"primaryKey" json:"id"`
-	Username          string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
-	Email             string         `gorm:"uniqueIndex;size:100;not null" json:"email"` // 🔥 ADDED: email field
-	Password          string         `gorm:"column:password_hash;not null" json:"-"`     // 🔥 FIX: column ชื่อ password_hash
-	ResetOTP          *string        `gorm:"type:varchar(6)" json:"-"`
-	ResetOTPExpiresAt *time.Time     `json:"-"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint            `gorm:"primaryKey" json:"id"`
+	Username           string          `gorm:"uniqueIndex;size:50;not null" json:"username"`
+	Email              string          `gorm:"uniqueIndex;size:100;not null" json:"email"` // 🔥 ADDED: email field
+	Password           string          `gorm:"column:password_hash;not null" json:"-"`     // 🔥 FIX: column ชื่อ password_hash
+	ResetOTP           *string         `gorm:"type:varchar(6)" json:"-"`
+	ResetOTPExpiresAt  *time.Time      `json:"-"`
+	ResetOTPAttempts   int             `gorm:"default:0" json:"-"`                                       // ใส่ OTP ผิดกี่ครั้งแล้ว (ครบ services.MaxOTPAttempts แล้ว OTP ใช้ไม่ได้)
+	PortfolioBalance   decimal.Decimal `gorm:"type:decimal(18,2);default:1000" json:"portfolio_balance"` // เงินในพอร์ต ใช้คิด PnL % ของบัญชี
+	Timezone           string          `gorm:"size:64;default:'UTC'" json:"timezone"`                    // IANA เช่น Asia/Bangkok ใช้แบ่งวันของสถิติ/ตัวกรองวันที่
+	Role               string          `gorm:"size:20;default:'user'" json:"role"`                       // user / admin (services.RoleUser, services.RoleAdmin)
+	IsActive           bool            `gorm:"default:true" json:"is_active"`                            // false = ถูก Admin ระงับบัญชี Login ไม่ได้
+	MustResetPassword  bool            `gorm:"default:false" json:"must_reset_password"`                 // Admin สั่งให้ตั้งรหัสผ่านใหม่ผ่าน Forgot Password ก่อน Login
+	TOTPSecret         *string         `gorm:"column:totp_secret;size:64" json:"-"`                      // Secret ของ 2FA ที่ยืนยันแล้ว
+	TOTPPendingSecret  *string         `gorm:"column:totp_pending_secret;size:64" json:"-"`              // Secret ที่รอยืนยันด้วยรหัสแรกตอนเปิด 2FA
+	TOTPEnabled        bool            `gorm:"column:totp_enabled;default:false" json:"two_factor_enabled"`
+	TOTPLastStep       int64           `gorm:"column:totp_last_step;default:0" json:"-"`            // รอบ TOTP ล่าสุดที่ใช้ไปแล้ว (กันใช้รหัสซ้ำ)
+	IsVerified         bool            `gorm:"column:is_verified;default:false" json:"is_verified"` // ยืนยันอีเมลแล้ว
+	VerifyOTP          *string         `gorm:"type:varchar(6)" json:"-"`                            // รหัสยืนยันอีเมลที่ส่งไปล่าสุด
+	VerifyOTPExpiresAt *time.Time      `json:"-"`
+	VerifyOTPAttempts  int             `gorm:"default:0" json:"-"`
+	VerifyOTPSentAt    *time.Time      `json:"-"` // ใช้จำกัดการขอรหัสใหม่ถี่เกินไป
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
 // ============================================
@@ -48,14 +68,19 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// AuthResponse - Response ที่ส่งกลับหลัง Login สำเร็จ
+// AuthResponse - Response ที่ส่งกลับหลัง Login/Refresh สำเร็จ
+// token = Access Token อายุสั้น, refresh_token = ใช้ขอ Access Token ใหม่ (เปลี่ยนทุกครั้งที่ Refresh)
 type AuthResponse struct {
-	Token     string `json:"token"`
-	ExpiresAt int64  `json:"expires_at"`
-	User      struct {
-		ID       uint   `json:"id"`
-		Username string `json:"username"`
-		Email    string `json:"email"` // 🔥 ADDED
+	Token            string `json:"token"`
+	ExpiresAt        int64  `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"`
+	User             struct {
+		ID         uint   `json:"id"`
+		Username   string `json:"username"`
+		Email      string `json:"email"` // 🔥 ADDED
+		Role       string `json:"role"`
+		IsVerified bool   `json:"is_verified"`
 	} `json:"user"`
 }
 
@@ -63,8 +88,10 @@ type AuthResponse struct {
 // JWT Claims - ข้อมูลที่เก็บใน Token
 // ============================================
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"` // Role ตอนออก Token (เปลี่ยน Role แล้วมีผลเมื่อ Refresh ครั้งถัดไป)
+	SessionID uint   `json:"sid"`  // Session ใน Database (ยกเลิก Session แล้ว Token ใช้ไม่ได้ทันที)
 	jwt.RegisteredClaims
 }
 
@@ -98,16 +125,30 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// เช็ครูปแบบอีเมล (ใช้กู้คืนรหัสผ่าน ต้องเป็นอีเมลจริง)
+	email, err := services.NormalizeEmail(req.Email)
+	if err != nil {
+		_, respErr := respondValidationError(c, err)
+		return respErr
+	}
+
+	// จำกัดการสมัครต่อ IP (นับทุกครั้ง เพราะทุกครั้งส่งอีเมล และกันไล่เช็คอีเมลที่มีบัญชี)
+	now := time.Now()
+	ipKey := registerIPKey(c)
+	if retry := lockedFor(now, ipKey); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+	recordFailure(now, ipKey)
+
 	// เช็คว่า Username ซ้ำมั้ย
 	var existingUser User
 	if err := database.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "Username นี้ถูกใช้แล้ว",
-		})
+		return respondUsernameTaken(c)
 	}
 
 	// Hash Password ด้วย bcrypt (ความปลอดภัย - ไม่เก็บ Password ตรงๆ)
 	// Cost 12 คือระดับความซับซ้อน ยิ่งสูงยิ่งปลอดภัยแต่ช้าขึ้น
+	// Hash ก่อนเช็คอีเมลซ้ำ ให้ใช้เวลาเท่ากันทั้งอีเมลใหม่และอีเมลที่มีบัญชีแล้ว
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 12)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -115,32 +156,75 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// อีเมลซ้ำ (ไม่สนตัวพิมพ์): ไม่สร้างบัญชี แต่ตอบเหมือนสมัครสำเร็จ (กันไล่เช็คว่าอีเมลไหนมีบัญชี)
+	if err := database.DB.Unscoped().Where("LOWER(email) = ?", emailLookup(email)).First(&existingUser).Error; err == nil {
+		log.Printf("⚠️ Register with existing email: user=%d", existingUser.ID)
+		return respondRegistered(c, email)
+	}
+
 	// สร้าง User ใหม่
 	user := User{
 		Username: req.Username,
-		Email:    req.Email,
+		Email:    email,
 		Password: string(hashedPassword),
 	}
 
-	// บันทึกลง Database
+	// บันทึกลง Database (สมัครพร้อมกัน Unique Index จะกันไว้)
 	if err := database.DB.Create(&user).Error; err != nil {
+		switch uniqueViolation(err) {
+		case "email":
+			log.Printf("⚠️ Register race on email: %v", err)
+			return respondRegistered(c, email)
+		case "username":
+			return respondUsernameTaken(c)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "ไม่สามารถสร้างบัญชีได้",
 			"message": err.Error(),
 		})
 	}
 
-	// ส่ง Response สำเร็จ (ไม่ส่ง Password กลับ เพราะ json:"-")
+	// ส่งรหัสยืนยันอีเมล (ส่งไม่สำเร็จก็สมัครสำเร็จ ขอรหัสใหม่ได้ที่ POST /api/auth/verify-email/resend)
+	if err := sendEmailVerification(database.DB, &user); err != nil {
+		log.Printf("❌ Verification email error: user=%d err=%v", user.ID, err)
+	}
+
+	// ส่ง Response สำเร็จ (ไม่ส่ง id/ข้อมูลบัญชีกลับ ให้เหมือนกรณีอีเมลซ้ำ)
+	return respondRegistered(c, email)
+}
+
+// respondRegistered - Response ของการสมัคร เหมือนกันทั้งอีเมลใหม่และอีเมลที่มีบัญชีแล้ว
+func respondRegistered(c *fiber.Ctx, email string) error {
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "สมัครสมาชิกสำเร็จ! 🎉",
-		"user": fiber.Map{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-		},
+		"message":               "หากอีเมลนี้ใช้สมัครได้ เราได้ส่งรหัสยืนยันไปให้แล้ว กรุณายืนยันอีเมลก่อนใช้งาน",
+		"email":                 email,
+		"verification_required": true,
+	})
+}
+
+// respondUsernameTaken - Username ซ้ำ (Username เป็นชื่อสาธารณะ บอกได้ ต่างจากอีเมล)
+func respondUsernameTaken(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "Username นี้ถูกใช้แล้ว",
+		"field": "username",
+		"code":  "username_taken",
 	})
 }
 
+// uniqueViolation - ชื่อคอลัมน์ของ users ที่ชน Unique Index ("email", "username" หรือ "" = ไม่ใช่ Error นี้)
+func uniqueViolation(err error) string {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return ""
+	}
+	for _, column := range []string{"email", "username"} {
+		if strings.Contains(pgErr.ConstraintName, column) {
+			return column
+		}
+	}
+	return ""
+}
+
 // Login - เข้าสู่ระบบ
 // POST /api/login
 func Login(c *fiber.Ctx) error {
@@ -163,12 +247,22 @@ func Login(c *fiber.Ctx) error {
 	// 🔥 DEBUG: Log ข้อมูลที่รับมา
 	log.Printf("🔐 LOGIN ATTEMPT: username=%s", req.Username)
 
+	// ล็อกเมื่อผิดบ่อยเกิน (ต่อบัญชี + ต่อ IP) นับเหมือนกันทั้ง Username ที่มี/ไม่มีจริง
+	now := time.Now()
+	attempts := []attemptKey{loginAccountKey(req.Username), loginIPKey(c)}
+	if retry := lockedFor(now, attempts...); retry > 0 {
+		log.Printf("🚫 LOGIN LOCKED: username=%s ip=%s retry=%s", req.Username, clientIP(c), retry)
+		return respondTooManyAttempts(c, retry)
+	}
+
 	// ค้นหา User จาก Database
 	// 🔥 FIX: ใช้ Unscoped() เพื่อข้าม soft delete check (กรณี table ไม่มี deleted_at column)
 	var user User
 	if err := database.DB.Unscoped().Where("username = ?", req.Username).First(&user).Error; err != nil {
 		// 🔥 DEBUG: Log error ที่เกิด
 		log.Printf("❌ USER NOT FOUND: %v", err)
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		recordFailure(now, attempts...)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Username หรือ Password ไม่ถูกต้อง",
 		})
@@ -182,54 +276,46 @@ func Login(c *fiber.Ctx) error {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		// 🔥 DEBUG: Log เมื่อ password ไม่ตรง
 		log.Printf("❌ PASSWORD MISMATCH: %v", err)
+		recordFailure(now, attempts...)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Username หรือ Password ไม่ถูกต้อง",
 		})
 	}
 
-	log.Printf("✅ PASSWORD MATCH! Creating JWT...")
-
-	// === สร้าง JWT Token ===
-	// กำหนดเวลาหมดอายุ 24 ชั่วโมง
-	expiresAt := time.Now().Add(24 * time.Hour)
-
-	// สร้าง Claims (ข้อมูลที่อยู่ใน Token)
-	claims := JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "mmrrdikub",
-		},
+	// รหัสผ่านถูกแล้วล้างตัวนับของบัญชี (ตัวนับต่อ IP ไม่ล้าง กันผู้โจมตีใช้บัญชีตัวเองรีเซ็ตตัวนับ)
+	resetAttempts(attempts[0])
+
+	// บัญชีที่ Admin ระงับ/สั่งรีเซ็ตรหัสผ่าน (เช็คหลัง Password ถูกเท่านั้น กันการเดาว่าบัญชีไหนถูกระงับ)
+	if err := checkAccountUsable(user); err != nil {
+		return respondAccountBlocked(c, err)
 	}
 
-	// สร้าง Token ด้วย HS256 Algorithm
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	log.Printf("✅ PASSWORD MATCH! Creating session...")
+
+	// เปิด 2FA ไว้: ยังไม่ออก Token ให้ Challenge Token ไปยืนยันรหัสที่ POST /api/auth/2fa/verify
+	if user.TOTPEnabled {
+		challenge, err := issueTwoFactorChallenge(user)
+		if err != nil {
+			log.Printf("❌ 2FA CHALLENGE ERROR: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ไม่สามารถสร้าง Token ได้",
+			})
+		}
+		return c.JSON(challenge)
+	}
 
-	// Sign Token ด้วย Secret Key (ดึงจาก Environment)
-	jwtSecret := getJWTSecret()
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	// === สร้าง Session + JWT Token ===
+	// Access Token อายุสั้น (ACCESS_TOKEN_TTL) ต่ออายุด้วย Refresh Token ที่ POST /api/auth/refresh
+	response, err := startSession(c, user)
 	if err != nil {
+		log.Printf("❌ SESSION ERROR: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "ไม่สามารถสร้าง Token ได้",
 		})
 	}
 
 	// ส่ง Response พร้อม Token กลับไป
-	return c.JSON(AuthResponse{
-		Token:     tokenString,
-		ExpiresAt: expiresAt.Unix(),
-		User: struct {
-			ID       uint   `json:"id"`
-			Username string `json:"username"`
-			Email    string `json:"email"`
-		}{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-		},
-	})
+	return c.JSON(response)
 }
 
 // getJWTSecret - ดึง JWT Secret จาก Environment
@@ -249,7 +335,7 @@ func GetUserFromToken(tokenString string) (*JWTClaims, error) {
 	// Parse Token
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(getJWTSecret()), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -263,7 +349,27 @@ func GetUserFromToken(tokenString string) (*JWTClaims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
-// MigrateAuthModels - สร้าง Table users ใน Database
+// MigrateAuthModels - สร้าง Table users, user_sessions และ user_recovery_codes ใน Database
+// แล้วตั้ง Role admin ให้ Username ใน ENV "ADMIN_USERNAMES" (คั่นด้วย ,) ใช้สร้าง Admin คนแรก
 func MigrateAuthModels() error {
-	return database.DB.AutoMigrate(&User{})
+	// ยังไม่มีคอลัมน์ verify_otp_sent_at = ฐานข้อมูลก่อนมีการยืนยันอีเมล (เช็คก่อน AutoMigrate สร้างคอลัมน์)
+	backfillVerified := database.DB.Migrator().HasTable(&User{}) && !database.DB.Migrator().HasColumn(&User{}, "VerifyOTPSentAt")
+
+	if err := database.DB.AutoMigrate(&User{}, &UserSession{}, &UserRecoveryCode{}); err != nil {
+		return err
+	}
+
+	// บัญชีที่สมัครก่อนมีการยืนยันอีเมลถือว่ายืนยันแล้ว (ไม่งั้นโดนกันฟีเจอร์ทันทีที่ Deploy)
+	// ทำครั้งเดียวตอนเพิ่มคอลัมน์ เหมือน database/migrations/add_email_verification.sql
+	if backfillVerified {
+		result := database.DB.Unscoped().Model(&User{}).Where("is_verified IS NOT TRUE").Update("is_verified", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		log.Printf("✅ Email verification backfill: %d users", result.RowsAffected)
+	}
+	if err := backfillNullDecimals(&User{}); err != nil {
+		return err
+	}
+	return promoteAdmins(os.Getenv("ADMIN_USERNAMES"))
 }
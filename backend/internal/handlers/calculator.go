@@ -0,0 +1,167 @@
+// Package handlers - Position Size Calculator Handler
+// เปิด services.CalculatePositionSize ให้ Frontend เรียกผ่าน API ได้ ไม่ต้องเขียนสูตรซ้ำ
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/models"
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// respondValidationError - ตอบ 400 แบบมีโครงสร้างจาก services.ValidationError
+// ถ้าไม่ใช่ ValidationError จะคืน false ให้ผู้เรียกจัดการเอง
+func respondValidationError(c *fiber.Ctx, err error) (bool, error) {
+	var vErr *services.ValidationError
+	if !errors.As(err, &vErr) {
+		return false, nil
+	}
+	return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": vErr.Message,
+		"field": vErr.Field,
+		"code":  vErr.Code,
+	})
+}
+
+// CalculatePositionSize - คำนวณขนาดไม้ (รวม Fee + Weighted SL)
+// POST /api/calculator/position-size
+func CalculatePositionSize(c *fiber.Ctx) error {
+	var input services.CalculationInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"code":    "invalid_body",
+			"message": err.Error(),
+		})
+	}
+
+	// ถ้าระบุ Symbol มา ไปดึงกฎ Tick/Step จาก Asset catalog
+	if input.Symbol != "" {
+		spec, err := lookupAssetSpec(input.Symbol)
+		if err != nil {
+			return respondAssetLookupError(c, err)
+		}
+		input.Asset = spec
+	}
+
+	result, err := services.CalculatePositionSize(input)
+	if err != nil {
+		if handled, respErr := respondValidationError(c, err); handled {
+			return respErr
+		}
+		log.Printf("❌ CalculatePositionSize error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "คำนวณไม่สำเร็จ",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"result": result,
+	})
+}
+
+// CalculateScaleIn - เติมไม้ที่ราคา X ได้อีกกี่เหรียญ โดย Risk รวมยังเป็น N%
+// POST /api/calculator/scale-in
+// ส่ง trade_id มา จะใช้จำนวนเหรียญ/ราคาเฉลี่ย/SL ของไม้นั้นเป็นค่าตั้งต้น
+// Pair ของไม้ต้องมีใน Asset catalog เหมือนกับ symbol ที่ส่งมาเอง (ส่ง symbol อื่นมาแทนได้)
+func CalculateScaleIn(c *fiber.Ctx) error {
+	var input services.ScaleInInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"code":    "invalid_body",
+			"message": err.Error(),
+		})
+	}
+
+	if input.TradeID != 0 {
+		var trade Trade
+		if err := database.DB.Where("id = ? AND user_id = ?", input.TradeID, GetCurrentUserID(c)).First(&trade).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ไม่พบข้อมูลการเทรดนี้",
+				"field": "trade_id",
+				"code":  "unknown_trade",
+			})
+		}
+		input.CurrentQuantity = trade.RemainingQuantity
+		if !input.CurrentQuantity.IsPositive() {
+			input.CurrentQuantity = tradeQuantity(trade)
+		}
+		input.CurrentAvgEntry = trade.EntryPrice
+		if len(input.StopLosses) == 0 && trade.StopLoss.IsPositive() {
+			input.StopLosses = []services.StopLoss{{Price: trade.StopLoss, Weight: decimal.NewFromInt(1)}}
+		}
+		if input.Symbol == "" {
+			input.Symbol = trade.Pair
+		}
+	}
+
+	if input.Symbol != "" {
+		spec, err := lookupAssetSpec(input.Symbol)
+		if err != nil {
+			return respondAssetLookupError(c, err)
+		}
+		input.Asset = spec
+	}
+
+	result, err := services.CalculateScaleIn(input)
+	if err != nil {
+		if handled, respErr := respondValidationError(c, err); handled {
+			return respErr
+		}
+		log.Printf("❌ CalculateScaleIn error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "คำนวณไม่สำเร็จ",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"result": result,
+	})
+}
+
+// lookupAssetSpec - หา Asset จาก Symbol (รองรับทั้ง "BTC/USDT" และ "BTCUSDT")
+func lookupAssetSpec(symbol string) (*services.AssetSpec, error) {
+	var asset models.Asset
+	if err := database.DB.Where("symbol = ?", services.NormalizeSymbol(symbol)).First(&asset).Error; err != nil {
+		return nil, err
+	}
+	return &services.AssetSpec{
+		Symbol:       asset.Symbol,
+		TickSize:     asset.TickSize,
+		QuantityStep: asset.QuantityStep,
+		MinNotional:  asset.MinNotional,
+	}, nil
+}
+
+// respondAssetLookupError - หา Asset ไม่เจอตอบ 400 unknown_symbol ส่วน Database Error ตอบ 500
+func respondAssetLookupError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ไม่พบคู่เทรดนี้ใน Asset catalog",
+			"field": "symbol",
+			"code":  "unknown_symbol",
+		})
+	}
+	log.Printf("❌ Asset lookup error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "ไม่สามารถดึงข้อมูล Asset ได้",
+		"message": err.Error(),
+	})
+}
+
+// MigrateAssetModels - สร้าง/อัพเดท Table assets ใน Database
+func MigrateAssetModels() error {
+	if err := database.DB.AutoMigrate(&models.Asset{}); err != nil {
+		return err
+	}
+	return backfillNullDecimals(&models.Asset{}, &models.Exchange{})
+}
@@ -67,8 +67,8 @@ func AIChat(c *fiber.Ctx) error {
 	historyText := ""
 	if len(trades) > 0 {
 		for i, t := range trades {
-			historyText += fmt.Sprintf("ไม้%d:[%s] %s %s เข้า:%.2f ออก:%.2f PnL:%.2f$\n",
-				i+1, t.Status, t.Pair, t.Side, t.EntryPrice, t.ExitPrice, t.PnL)
+			historyText += fmt.Sprintf("ไม้%d:[%s] %s %s เข้า:%s ออก:%s PnL:%s$\n",
+				i+1, t.ResultLabel(), t.Pair, t.Side, t.EntryPrice, t.ExitPrice, t.PnL.StringFixed(2))
 		}
 	} else {
 		historyText = "ผู้ใช้ยังไม่เคยเทรดเลย"
@@ -0,0 +1,82 @@
+// Package handlers - เติมค่าให้คอลัมน์ตัวเลขเงินที่เป็น NULL ในข้อมูลเก่า (Default ของคอลัมน์ หรือ 0)
+// decimal.Decimal อ่าน NULL ไม่ได้ ("could not convert value '<nil>'") แถวที่สร้างนอก GORM
+// (SQL ตรงๆ/ระบบเดิม) หรือคอลัมน์ที่เพิ่มทีหลังแบบไม่มี Default ทำให้ทุก Query ที่อ่านแถวนั้นพัง
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/pkg/database"
+)
+
+// decimalType - ชนิดของคอลัมน์ที่อ่าน NULL ไม่ได้ (*decimal.Decimal อ่าน NULL เป็น nil ได้ จึงไม่นับ)
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+// decimalColumn - คอลัมน์ decimal.Decimal และค่าที่ใช้แทน NULL (Default ของคอลัมน์ ไม่มี = 0)
+type decimalColumn struct {
+	Name     string
+	Fallback decimal.Decimal
+}
+
+// nullableDecimalColumns - ตารางและคอลัมน์ decimal.Decimal ของ Model (ดึงจาก Schema ของ GORM ไม่ต้องไล่รายชื่อเอง
+// เพิ่มฟิลด์ใหม่แล้วได้ Backfill อัตโนมัติ)
+func nullableDecimalColumns(db *gorm.DB, model interface{}) (string, []decimalColumn, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", nil, err
+	}
+	var columns []decimalColumn
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || field.FieldType != decimalType {
+			continue
+		}
+		fallback, err := decimal.NewFromString(strings.Trim(field.DefaultValue, "'"))
+		if err != nil {
+			fallback = decimal.Zero
+		}
+		columns = append(columns, decimalColumn{Name: field.DBName, Fallback: fallback})
+	}
+	return stmt.Schema.Table, columns, nil
+}
+
+// nullDecimalBackfillSQL - UPDATE ที่แทน NULL ด้วยค่า Fallback ทุกคอลัมน์ (แก้เฉพาะแถวที่มี NULL)
+func nullDecimalBackfillSQL(table string, columns []decimalColumn) string {
+	sets := make([]string, len(columns))
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = fmt.Sprintf("%s = COALESCE(%s, %s)", column.Name, column.Name, column.Fallback)
+		conditions[i] = column.Name + " IS NULL"
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(sets, ", "), strings.Join(conditions, " OR "))
+}
+
+// backfillNullDecimals - เติมค่าให้คอลัมน์ decimal.Decimal ที่เป็น NULL ของทุก Model (เรียกหลัง AutoMigrate)
+// ตารางที่ยังไม่มี (เช่น exchanges ที่สร้างจาก seeds.sql) ข้ามไป
+func backfillNullDecimals(models ...interface{}) error {
+	for _, model := range models {
+		if !database.DB.Migrator().HasTable(model) {
+			continue
+		}
+		table, columns, err := nullableDecimalColumns(database.DB, model)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		result := database.DB.Exec(nullDecimalBackfillSQL(table, columns))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("🧹 Backfill NULL decimals: %s %d แถว", table, result.RowsAffected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// rowConnector - database/sql driver จำลองที่ตอบทุก Query ด้วยแถวเดียวตามที่กำหนด
+type rowConnector struct {
+	columns []string
+	values  []driver.Value
+}
+
+func (c rowConnector) Connect(context.Context) (driver.Conn, error) { return rowConn(c), nil }
+func (c rowConnector) Driver() driver.Driver                        { return c }
+func (c rowConnector) Open(string) (driver.Conn, error)             { return rowConn(c), nil }
+
+type rowConn rowConnector
+
+func (c rowConn) Prepare(string) (driver.Stmt, error) { return rowStmt(c), nil }
+func (c rowConn) Close() error                        { return nil }
+func (c rowConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type rowStmt rowConnector
+
+func (s rowStmt) Close() error                               { return nil }
+func (s rowStmt) NumInput() int                              { return -1 }
+func (s rowStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (s rowStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{columns: s.columns, values: s.values}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  []driver.Value
+	done    bool
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+// openRowDB - gorm.DB (Postgres dialect) ที่อ่านได้แถวเดียวตามที่กำหนด
+func openRowDB(t *testing.T, columns []string, values []driver.Value) *gorm.DB {
+	t.Helper()
+	sqlDB := sql.OpenDB(rowConnector{columns: columns, values: values})
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestNullableDecimalColumns - Backfill ครอบคลุมทุกคอลัมน์ decimal.Decimal และใช้ Default ของคอลัมน์แทน NULL
+func TestNullableDecimalColumns(t *testing.T) {
+	db := openRowDB(t, nil, nil)
+
+	table, columns, err := nullableDecimalColumns(db, &Trade{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table != "trades" {
+		t.Errorf("table = %q", table)
+	}
+	got := map[string]bool{}
+	for _, column := range columns {
+		got[column.Name] = true
+	}
+	for _, name := range []string{"entry_price", "exit_price", "stop_loss", "take_profit", "position_size", "quantity",
+		"risk_percent", "max_win", "max_loss", "risk_reward_ratio", "fee", "pnl", "pnl_percent",
+		"remaining_quantity", "realized_pnl", "avg_exit_price"} {
+		if !got[name] {
+			t.Errorf("ไม่มี %s ใน Backfill", name)
+		}
+	}
+	// *decimal.Decimal อ่าน NULL ได้ ไม่ต้องเติมค่า
+	for _, name := range []string{"r_multiple", "initial_risk"} {
+		if got[name] {
+			t.Errorf("%s เป็น Pointer ไม่ต้อง Backfill", name)
+		}
+	}
+
+	_, columns, err = nullableDecimalColumns(db, &User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := nullDecimalBackfillSQL("users", columns)
+	if !strings.Contains(sql, "portfolio_balance = COALESCE(portfolio_balance, 1000)") {
+		t.Errorf("portfolio_balance ต้องใช้ Default 1000: %s", sql)
+	}
+}
+
+// TestScanNullDecimals - NULL ใน decimal.Decimal อ่านไม่ได้เมื่อ Scan ตรง (Pluck/rows.Scan/Raw)
+// ส่วนแถวที่ Backfill แล้วอ่านได้ทั้งแบบ Scan ตรงและแบบ Model
+func TestScanNullDecimals(t *testing.T) {
+	columns := []string{"id", "pair", "exit_price", "stop_loss", "take_profit", "fee", "max_loss", "pnl"}
+
+	var pnls []decimal.Decimal
+	if err := openRowDB(t, []string{"pnl"}, []driver.Value{nil}).Model(&Trade{}).Pluck("pnl", &pnls).Error; err == nil {
+		t.Fatal("decimal.Decimal ต้องอ่าน NULL ไม่ได้ (ถ้าอ่านได้แล้ว Backfill ก็ไม่จำเป็น)")
+	}
+
+	// ค่าหลัง COALESCE(..., 0)
+	backfilled := openRowDB(t, columns, []driver.Value{int64(1), "BTCUSDT", "0", "0", "0", "0", "0", "0"})
+	pnls = nil
+	if err := openRowDB(t, []string{"pnl"}, []driver.Value{"0"}).Model(&Trade{}).Pluck("pnl", &pnls).Error; err != nil || len(pnls) != 1 || !pnls[0].IsZero() {
+		t.Fatalf("Pluck แถวที่ Backfill แล้ว = %v, %v", pnls, err)
+	}
+	var trade Trade
+	if err := backfilled.First(&trade).Error; err != nil {
+		t.Fatalf("อ่านแถวที่ Backfill แล้วไม่ได้: %v", err)
+	}
+	if trade.ID != 1 || !trade.ExitPrice.IsZero() || !trade.MaxLoss.IsZero() || !trade.PnL.IsZero() {
+		t.Errorf("trade = %+v", trade)
+	}
+}
@@ -0,0 +1,260 @@
+// Package handlers - ยืนยันอีเมล (Email Verification)
+// ส่งรหัส 6 หลักตอนสมัคร/ขอใหม่ผ่าน sendEmailCode และจำกัดฟีเจอร์บางอย่างจนกว่าจะยืนยันอีเมล
+package handlers
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// ค่าของรหัสยืนยันอีเมล
+const (
+	emailVerifyTTL            = 30 * time.Minute
+	emailVerifyResendCooldown = time.Minute // ขอรหัสใหม่ได้ไม่ถี่กว่านี้ต่อบัญชี
+)
+
+// verificationSentMessage - ตอบเหมือนกันทุกกรณีของการขอรหัสใหม่ (ไม่บอกว่าอีเมลมี/ไม่มี/ยืนยันแล้ว)
+const verificationSentMessage = "หากอีเมลนี้ยังไม่ได้ยืนยัน เราได้ส่งรหัสยืนยันไปให้แล้ว"
+
+// invalidVerifyCodeMessage - ตอบเหมือนกันทุกกรณี (ไม่พบอีเมล/รหัสผิด/หมดอายุ)
+const invalidVerifyCodeMessage = "อีเมลหรือรหัสยืนยันไม่ถูกต้อง หรือรหัสหมดอายุแล้ว กรุณาขอใหม่"
+
+// VerifyEmailRequest - Body ของการยืนยันอีเมล
+type VerifyEmailRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// ResendVerificationRequest - Body ของการขอรหัสยืนยันใหม่
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// verificationFeatures - ฟีเจอร์ที่ต้องยืนยันอีเมลก่อน จาก ENV "EMAIL_VERIFICATION_REQUIRED_FOR"
+// (คั่นด้วย , เช่น "ai,import,export,backup,password_reset", "none" = ไม่บังคับ, ไม่ตั้ง = services.DefaultVerificationFeatures)
+var verificationFeatures = sync.OnceValue(func() map[string]bool {
+	features, unknown := services.ParseVerificationFeatures(os.Getenv("EMAIL_VERIFICATION_REQUIRED_FOR"))
+	if len(unknown) > 0 {
+		log.Printf("⚠️ EMAIL_VERIFICATION_REQUIRED_FOR: ไม่รู้จัก %v", unknown)
+	}
+	return features
+})
+
+// requiresVerifiedEmail - ฟีเจอร์นี้ต้องยืนยันอีเมลก่อนหรือไม่
+func requiresVerifiedEmail(feature string) bool {
+	return verificationFeatures()[feature]
+}
+
+// ============================================
+// Middleware
+// ============================================
+
+// RequireVerifiedEmail - Middleware กันฟีเจอร์ที่ตั้งให้ต้องยืนยันอีเมลก่อน (ต้องใช้ต่อจาก JWTMiddleware)
+// ตัวอย่าง: api.Group("/ai", JWTMiddleware, RequireVerifiedEmail(services.FeatureAI))
+func RequireVerifiedEmail(feature string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !requiresVerifiedEmail(feature) {
+			return c.Next()
+		}
+		var user User
+		if err := database.DB.Select("id", "is_verified").First(&user, GetCurrentUserID(c)).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "ไม่พบบัญชีผู้ใช้ กรุณา Login ใหม่",
+			})
+		}
+		if !user.IsVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "กรุณายืนยันอีเมลก่อนใช้งานฟีเจอร์นี้",
+				"code":    "email_not_verified",
+				"feature": feature,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// ============================================
+// Handler Functions
+// ============================================
+
+// VerifyEmail - ยืนยันอีเมลด้วยรหัสที่ส่งไป
+// POST /api/auth/verify-email
+func VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง email และ code",
+		})
+	}
+
+	now := time.Now()
+	keys := verifyEmailKeys(c, req.Email)
+	if retry := lockedFor(now, keys...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+
+	var codeErr error // รหัสใช้ไม่ได้ (ยัง Commit ตัวนับครั้งที่ผิด)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("LOWER(email) = ?", emailLookup(req.Email)).
+			First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			codeErr = errInvalidVerifyCode
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ok, invalidate := services.CheckOTP(user.VerifyOTP, user.VerifyOTPExpiresAt, user.VerifyOTPAttempts, strings.TrimSpace(req.Code), now)
+		if ok {
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"is_verified":           true,
+				"verify_otp":            nil,
+				"verify_otp_expires_at": nil,
+				"verify_otp_attempts":   0,
+			}).Error
+		}
+
+		codeErr = errInvalidVerifyCode
+		if user.VerifyOTP == nil {
+			return nil
+		}
+		updates := map[string]interface{}{"verify_otp_attempts": user.VerifyOTPAttempts + 1}
+		if invalidate {
+			updates["verify_otp"] = nil
+			updates["verify_otp_expires_at"] = nil
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		log.Printf("❌ VerifyEmail error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถยืนยันอีเมลได้",
+		})
+	}
+	if codeErr != nil {
+		recordFailure(now, keys...)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": codeErr.Error(),
+			"code":  "invalid_code",
+		})
+	}
+	resetAttempts(keys[0])
+
+	return c.JSON(fiber.Map{"message": "ยืนยันอีเมลเรียบร้อยแล้ว ✅"})
+}
+
+// ResendEmailVerification - ขอรหัสยืนยันอีเมลใหม่ (จำกัดจำนวนครั้งต่ออีเมล/IP และเว้นช่วงต่อบัญชี)
+// POST /api/auth/verify-email/resend
+func ResendEmailVerification(c *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณากรอกอีเมล",
+		})
+	}
+
+	now := time.Now()
+	sendKeys := verifySendKeys(c, req.Email)
+	if retry := lockedFor(now, sendKeys...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+	recordFailure(now, sendKeys...)
+
+	var user User
+	err := database.DB.Where("LOWER(email) = ?", emailLookup(req.Email)).First(&user).Error
+	switch {
+	case err != nil:
+		log.Printf("⚠️ Resend verification for unknown email: %v", err)
+	case user.IsVerified:
+	case user.VerifyOTPSentAt != nil && now.Sub(*user.VerifyOTPSentAt) < emailVerifyResendCooldown:
+		log.Printf("⚠️ Resend verification too soon: user=%d", user.ID)
+	default:
+		if err := sendEmailVerification(database.DB, &user); err != nil {
+			log.Printf("❌ Verification email error: user=%d err=%v", user.ID, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": verificationSentMessage})
+}
+
+// GetVerificationPolicy - ฟีเจอร์ที่ต้องยืนยันอีเมลก่อนใช้ (ให้ Frontend แสดงเตือน)
+// GET /api/auth/verify-email/policy
+func GetVerificationPolicy(c *fiber.Ctx) error {
+	features := make([]string, 0, len(verificationFeatures()))
+	for feature := range verificationFeatures() {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	return c.JSON(fiber.Map{"required_for": features})
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// emailLookup - ค่าที่ใช้หาบัญชีจากอีเมลด้วย LOWER(email) = ? (NormalizeEmail แล้วเป็นตัวเล็กทั้งหมด)
+// ใช้ทุกที่ที่หาบัญชีจากอีเมลที่ผู้ใช้พิมพ์ อีเมลรูปแบบผิดใช้ค่าที่ตัดช่องว่าง (หาไม่เจอแต่ตอบเหมือนกรณีอื่น)
+func emailLookup(raw string) string {
+	email, err := services.NormalizeEmail(raw)
+	if err != nil {
+		email = strings.TrimSpace(raw)
+	}
+	return strings.ToLower(email)
+}
+
+// errInvalidVerifyCode - รหัสยืนยันใช้ไม่ได้ (ไม่บอกสาเหตุ)
+var errInvalidVerifyCode = errors.New(invalidVerifyCodeMessage)
+
+// sendEmailVerification - สร้างรหัสยืนยันใหม่ (เริ่มนับครั้งที่ผิดใหม่) บันทึก แล้วส่งอีเมล
+func sendEmailVerification(db *gorm.DB, user *User) error {
+	code, err := GenerateOTP()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	expires := now.Add(emailVerifyTTL)
+	err = db.Model(user).Updates(map[string]interface{}{
+		"verify_otp":            code,
+		"verify_otp_expires_at": expires,
+		"verify_otp_attempts":   0,
+		"verify_otp_sent_at":    now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return sendEmailCode(user.Email,
+		"[MMRRDIKUB] รหัสยืนยันอีเมลของคุณคือ: "+code,
+		"นี่คือรหัสยืนยันอีเมลสำหรับบัญชี "+user.Username+" (รหัสมีอายุ 30 นาที):",
+		code)
+}
+
+// verifyEmailKeys - Key ของการใส่รหัสยืนยันอีเมล (ต่ออีเมล + ต่อ IP ใช้กติกาเดียวกับ OTP ลืมรหัสผ่าน)
+func verifyEmailKeys(c *fiber.Ctx, email string) []attemptKey {
+	return []attemptKey{
+		{otpAccountThrottle, "verify:email:" + strings.ToLower(strings.TrimSpace(email))},
+		{otpIPThrottle, "verify:ip:" + clientIP(c)},
+	}
+}
+
+// verifySendKeys - Key ของการขอรหัสยืนยันใหม่ (ต่ออีเมล + ต่อ IP)
+func verifySendKeys(c *fiber.Ctx, email string) []attemptKey {
+	return []attemptKey{
+		{otpSendThrottle, "verify-send:email:" + strings.ToLower(strings.TrimSpace(email))},
+		{otpSendIPThrottle, "verify-send:ip:" + clientIP(c)},
+	}
+}
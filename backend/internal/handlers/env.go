@@ -0,0 +1,21 @@
+// Package handlers - อ่านค่าตั้งค่าจาก ENV ที่ใช้ร่วมกันหลายไฟล์
+package handlers
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"mmrrdikub/internal/services"
+)
+
+// durationEnv - อ่านระยะเวลา (เช่น 15m, 720h) จาก ENV ถ้าไม่มี/ไม่ถูกต้องใช้ค่า Default
+// ใช้กับทุก ENV ที่เป็นระยะเวลา (ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL, TRASH_PURGE_INTERVAL)
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	value, ok := services.ParseDurationSetting(raw, fallback)
+	if !ok {
+		log.Printf("⚠️ %s=%q ไม่ถูกต้อง ใช้ค่า Default", name, raw)
+	}
+	return value
+}
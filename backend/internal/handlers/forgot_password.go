@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -10,12 +11,15 @@ import (
 	"os"
 	"time"
 
+	"mmrrdikub/internal/services"
 	"mmrrdikub/pkg/database"
 
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GenerateOTP สร้าง OTP ตัวเลข 6 หลัก
@@ -28,7 +32,16 @@ func GenerateOTP() (string, error) {
 	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
+// SendEmailOTP ส่ง OTP กู้คืนรหัสผ่าน
 func SendEmailOTP(toEmail, otp string) error {
+	return sendEmailCode(toEmail,
+		fmt.Sprintf("[MMRRDIKUB] รหัส OTP กู้คืนรหัสผ่านของคุณคือ: %s", otp),
+		"นี่คือรหัส OTP สำหรับกู้คืนรหัสผ่านของคุณ (รหัสมีอายุ 5 นาที):",
+		otp)
+}
+
+// sendEmailCode ส่งอีเมลที่มีรหัสตัวเลข (ใช้ร่วมกันระหว่าง OTP ลืมรหัสผ่าน และรหัสยืนยันอีเมล)
+func sendEmailCode(toEmail, subject, intro, otp string) error {
 	from := os.Getenv("EMAIL_USER")
 	password := os.Getenv("EMAIL_PASS")
 
@@ -45,8 +58,6 @@ func SendEmailOTP(toEmail, otp string) error {
 
 	auth := smtp.PlainAuth("", from, password, smtpHost)
 
-	subject := fmt.Sprintf("[MMRRDIKUB] รหัส OTP กู้คืนรหัสผ่านของคุณคือ: %s", otp)
-
 	// HTML Template email
 	body := fmt.Sprintf(`
 		<div style="font-family: sans-serif; max-width: 600px; margin: 0 auto; border: 1px solid #e0e0e0; border-radius: 8px; overflow: hidden;">
@@ -55,7 +66,7 @@ func SendEmailOTP(toEmail, otp string) error {
           </div>
           <div style="padding: 30px; background-color: #ffffff; color: #333333;">
             <p>สวัสดีครับ,</p>
-            <p>นี่คือรหัส OTP สำหรับกู้คืนรหัสผ่านของคุณ (รหัสมีอายุ 5 นาที):</p>
+            <p>%s</p>
             <h1 style="text-align: center; font-size: 32px; letter-spacing: 5px; color: #16a34a; background-color: #f0fdf4; padding: 15px; border-radius: 8px;">
               %s
             </h1>
@@ -73,7 +84,7 @@ func SendEmailOTP(toEmail, otp string) error {
             <p style="font-size: 14px;">ขอบคุณที่ทดลองใช้งานระบบของผมครับ!</p>
           </div>
         </div>
-	`, otp)
+	`, intro, otp)
 
 	headers := "MIME-version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";"
 	msg := []byte(fmt.Sprintf("To: %s\r\nSubject: %s\r\n%s\r\n\r\n%s", toEmail, subject, headers, body))
@@ -82,7 +93,7 @@ func SendEmailOTP(toEmail, otp string) error {
 	conn, err := net.DialTimeout("tcp", smtpHost+":"+smtpPort, 3*time.Second)
 	if err != nil {
 		log.Printf("⚠️ SMTP Port Blocked (Render Free Tier?): %v", err)
-		log.Printf("💡 [MOCK OTP] เนื่องจากส่งอีเมลจริงไม่ได้ รหัส OTP ของคุณคือ: %s", otp)
+		log.Printf("💡 [MOCK OTP] เนื่องจากส่งอีเมลจริงไม่ได้ รหัสที่ส่งไป %s คือ: %s", toEmail, otp)
 		// ถือว่าส่งอีเมลสำเร็จ (จำลอง) เพื่อให้ Frontend ทำงานต่อได้ไม่ค้าง
 		return nil
 	}
@@ -100,6 +111,12 @@ type ForgotPasswordRequestReq struct {
 	Contact string `json:"contact"`
 }
 
+// forgotPasswordSentMessage - ตอบเหมือนกันทุกกรณี (มี/ไม่มีอีเมลในระบบ) กันการไล่เช็คว่าอีเมลไหนสมัครไว้
+const forgotPasswordSentMessage = "หากอีเมลนี้มีอยู่ในระบบ เราได้ส่ง OTP ไปให้แล้ว"
+
+// invalidOTPMessage - ตอบเหมือนกันทุกกรณี (ไม่พบอีเมล/OTP ผิด/หมดอายุ/ถูกยกเลิก)
+const invalidOTPMessage = "อีเมลหรือ OTP ไม่ถูกต้อง หรือ OTP หมดอายุแล้ว กรุณาขอใหม่"
+
 func ForgotPasswordRequest(c *fiber.Ctx) error {
 	var req ForgotPasswordRequestReq
 	if err := c.BodyParser(&req); err != nil {
@@ -110,10 +127,24 @@ func ForgotPasswordRequest(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "กรุณากรอกอีเมล"})
 	}
 
+	// จำกัดจำนวนครั้งที่ขอ OTP ต่ออีเมล/IP (นับทุกครั้ง ไม่ว่าอีเมลจะมีอยู่จริงหรือไม่)
+	now := time.Now()
+	sendKeys := otpSendKeys(c, req.Contact)
+	if retry := lockedFor(now, sendKeys...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+	recordFailure(now, sendKeys...)
+
 	var user User
-	if err := database.DB.Where("email = ?", req.Contact).First(&user).Error; err != nil {
-		// เปลี่ยนให้แจ้งเตือนชัดเจนว่าไม่มีอีเมลนี้ในระบบ
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "ไม่พบอีเมลนี้ในระบบ โปรดตรวจสอบอีกครั้ง"})
+	if err := database.DB.Where("LOWER(email) = ?", emailLookup(req.Contact)).First(&user).Error; err != nil {
+		log.Printf("⚠️ Forgot password for unknown email: %v", err)
+		return c.JSON(fiber.Map{"message": forgotPasswordSentMessage})
+	}
+
+	// ตั้งให้ส่ง OTP เฉพาะอีเมลที่ยืนยันแล้ว (อีเมลที่ยังไม่ยืนยันอาจพิมพ์ผิดเป็นของคนอื่น)
+	if !user.IsVerified && requiresVerifiedEmail(services.FeaturePasswordReset) {
+		log.Printf("⚠️ Forgot password for unverified email: user=%d", user.ID)
+		return c.JSON(fiber.Map{"message": forgotPasswordSentMessage})
 	}
 
 	otp, err := GenerateOTP()
@@ -121,23 +152,24 @@ func ForgotPasswordRequest(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "สร้าง OTP ไม่สำเร็จ"})
 	}
 
-	expires := time.Now().Add(5 * time.Minute)
+	// OTP ใหม่ = เริ่มนับครั้งที่ใส่ผิดใหม่
+	expires := now.Add(5 * time.Minute)
 	user.ResetOTP = &otp
 	user.ResetOTPExpiresAt = &expires
+	user.ResetOTPAttempts = 0
 
 	if err := database.DB.Save(&user).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "บันทึก OTP ไม่สำเร็จ"})
 	}
 
-	// ส่งอีเมลแบบรอผล (Synchronous) เพื่อให้เช็ค Error ได้
-	err = SendEmailOTP(user.Email, otp)
-	if err != nil {
+	// ส่งอีเมลแบบรอผล (Synchronous) ส่งไม่สำเร็จก็ตอบเหมือนเดิม (Error ตอบเฉพาะอีเมลที่มีจริงจะเผยว่าอีเมลนี้สมัครไว้)
+	if err := SendEmailOTP(user.Email, otp); err != nil {
 		log.Printf("❌ Email Error: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "ระบบส่งอีเมลขัดข้อง กรุณาลองใหม่ภายหลัง"})
+	} else {
+		log.Printf("✅ OTP sent successfully to %s", user.Email)
 	}
-	log.Printf("✅ OTP sent successfully to %s", user.Email)
 
-	return c.JSON(fiber.Map{"message": "ระบบส่ง OTP ไปยังอีเมลของท่านแล้ว"})
+	return c.JSON(fiber.Map{"message": forgotPasswordSentMessage})
 }
 
 type ForgotPasswordVerifyReq struct {
@@ -151,17 +183,17 @@ func ForgotPasswordVerify(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "ข้อมูลไม่ถูกต้อง"})
 	}
 
-	var user User
-	if err := database.DB.Where("email = ?", req.Contact).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "อีเมลหรือ OTP ไม่ถูกต้อง"})
-	}
-
-	if user.ResetOTP == nil || *user.ResetOTP != req.OTP {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "OTP ไม่ถูกต้อง"})
+	var otpErr error // OTP ใช้ไม่ได้ (ยัง Commit ตัวนับครั้งที่ผิด)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		_, otpErr, err = verifyResetOTP(tx, c, req.Contact, req.OTP)
+		return err
+	})
+	if err == nil {
+		err = otpErr
 	}
-
-	if user.ResetOTPExpiresAt == nil || user.ResetOTPExpiresAt.Before(time.Now()) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "OTP หมดอายุแล้ว กรุณาขอใหม่"})
+	if err != nil {
+		return respondOTPError(c, err)
 	}
 
 	return c.JSON(fiber.Map{"message": "OTP ถูกต้อง"})
@@ -183,31 +215,104 @@ func ForgotPasswordReset(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "รหัสผ่านใหม่ต้องมี 6 ตัวอักษรขึ้นไป"})
 	}
 
+	// เปลี่ยนรหัสผ่านแล้วต้อง Logout ทุกอุปกรณ์ (กันคนที่ได้ Token ไปก่อนหน้านี้)
+	// Hash รหัสผ่านใหม่ (bcrypt ใช้ CPU มาก) หลังเช็คล็อกและ OTP ผ่านแล้วเท่านั้น กันยิง OTP ผิดรัวๆ ให้ Server ทำงานหนัก
 	var user User
-	if err := database.DB.Where("email = ?", req.Contact).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "อีเมลหรือ OTP ไม่ถูกต้อง"})
+	var otpErr error // OTP ใช้ไม่ได้ (ยัง Commit ตัวนับครั้งที่ผิด)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, otpErr, err = verifyResetOTP(tx, c, req.Contact, req.OTP)
+		if err != nil || otpErr != nil {
+			return err
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
+		if err != nil {
+			return err
+		}
+		user.Password = string(hashedPassword)
+		user.ResetOTP = nil
+		user.ResetOTPExpiresAt = nil
+		user.ResetOTPAttempts = 0
+		user.MustResetPassword = false
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		_, err = revokeUserSessions(tx, user.ID, 0)
+		return err
+	})
+	if err == nil {
+		err = otpErr
 	}
-
-	if user.ResetOTP == nil || *user.ResetOTP != req.OTP {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "OTP ไม่ถูกต้อง"})
+	if err != nil {
+		return respondOTPError(c, err)
 	}
 
-	if user.ResetOTPExpiresAt == nil || user.ResetOTPExpiresAt.Before(time.Now()) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "OTP หมดอายุแล้ว กรุณาขอใหม่"})
+	// ตั้งรหัสผ่านใหม่แล้ว ปลดล็อก Login และ OTP ของบัญชีนี้ด้วย
+	resetAttempts(loginAccountKey(user.Username), otpKeys(c, req.Contact)[0])
+
+	return c.JSON(fiber.Map{"message": "เปลี่ยนรหัสผ่านเรียบร้อยแล้ว"})
+}
+
+// errInvalidOTP - OTP ใช้ไม่ได้ (ไม่บอกสาเหตุ กันการไล่เช็คอีเมล)
+var errInvalidOTP = errors.New(invalidOTPMessage)
+
+// errOTPLocked - ใส่ OTP ผิดบ่อยเกินจนถูกล็อก
+type errOTPLocked struct {
+	retry time.Duration
+}
+
+func (e *errOTPLocked) Error() string {
+	return "OTP locked"
+}
+
+// verifyResetOTP - ตรวจ OTP ของอีเมล (Lock แถว User กันยิงพร้อมกันหลาย Request ให้เกินจำนวนครั้ง)
+// ผิดแล้วเพิ่ม reset_otp_attempts ครบ services.MaxOTPAttempts แล้วลบ OTP ทิ้ง ต้องขอใหม่
+// otpErr = OTP ใช้ไม่ได้ (Transaction ต้อง Commit เพื่อเก็บตัวนับ), err = Error ของ Database
+func verifyResetOTP(tx *gorm.DB, c *fiber.Ctx, contact, otp string) (user User, otpErr, err error) {
+	now := time.Now()
+	keys := otpKeys(c, contact)
+	if retry := lockedFor(now, keys...); retry > 0 {
+		return User{}, &errOTPLocked{retry: retry}, nil
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "ไม่สามารถรีเซ็ตรหัสผ่านได้"})
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("LOWER(email) = ?", emailLookup(contact)).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return User{}, nil, err
+		}
+		recordFailure(now, keys...)
+		return User{}, errInvalidOTP, nil
 	}
 
-	user.Password = string(hashedPassword)
-	user.ResetOTP = nil
-	user.ResetOTPExpiresAt = nil
+	ok, invalidate := services.CheckOTP(user.ResetOTP, user.ResetOTPExpiresAt, user.ResetOTPAttempts, otp, now)
+	if ok {
+		return user, nil, nil
+	}
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "บันทึกรหัสผ่านใหม่ไม่สำเร็จ"})
+	recordFailure(now, keys...)
+	if user.ResetOTP != nil {
+		updates := map[string]interface{}{"reset_otp_attempts": user.ResetOTPAttempts + 1}
+		if invalidate {
+			log.Printf("⚠️ Reset OTP invalidated: user=%d ip=%s", user.ID, clientIP(c))
+			updates["reset_otp"] = nil
+			updates["reset_otp_expires_at"] = nil
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return User{}, nil, err
+		}
 	}
+	return User{}, errInvalidOTP, nil
+}
 
-	return c.JSON(fiber.Map{"message": "เปลี่ยนรหัสผ่านเรียบร้อยแล้ว"})
+// respondOTPError - ตอบ Error ของ OTP แบบเดียวกันทุกกรณี
+func respondOTPError(c *fiber.Ctx, err error) error {
+	var locked *errOTPLocked
+	switch {
+	case errors.As(err, &locked):
+		return respondTooManyAttempts(c, locked.retry)
+	case errors.Is(err, errInvalidOTP):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": invalidOTPMessage, "code": "invalid_otp"})
+	}
+	log.Printf("❌ OTP error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "ไม่สามารถตรวจสอบ OTP ได้"})
 }
@@ -3,52 +3,91 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+
+	"mmrrdikub/internal/services"
 )
 
 // JWTMiddleware - Middleware สำหรับเช็ค JWT Token
 // ใช้กับ Route ที่ต้องการ Authentication
 func JWTMiddleware(c *fiber.Ctx) error {
-	// ดึง Authorization Header
-	authHeader := c.Get("Authorization")
-
-	// เช็คว่ามี Header มั้ย
-	if authHeader == "" {
+	// ตรวจสอบและ Parse Token จาก Authorization Header
+	claims, err := bearerClaims(c)
+	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "กรุณา Login ก่อน (ไม่มี Authorization Header)",
+			"error": err.Error(),
 		})
 	}
 
-	// เช็ครูปแบบ "Bearer <token>"
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "รูปแบบ Token ไม่ถูกต้อง (ต้องเป็น: Bearer <token>)",
-		})
+	// Token ต้องผูกกับ Session ที่ยังไม่ถูกยกเลิก (Logout/เปลี่ยนรหัสผ่าน/ยกเลิกอุปกรณ์)
+	// Token รุ่นเก่าที่ไม่มี Session ต้อง Login ใหม่
+	active := false
+	if claims.SessionID != 0 {
+		if active, err = sessionActive(claims.SessionID, claims.UserID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ไม่สามารถตรวจสอบ Session ได้",
+			})
+		}
 	}
-
-	tokenString := parts[1]
-
-	// ตรวจสอบและ Parse Token
-	claims, err := GetUserFromToken(tokenString)
-	if err != nil {
+	if !active {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Token ไม่ถูกต้องหรือหมดอายุ",
-			"message": err.Error(),
+			"error": "Session สิ้นสุดแล้ว กรุณา Login ใหม่",
+			"code":  "session_revoked",
 		})
 	}
 
 	// เก็บข้อมูล User ไว้ใน Context เพื่อใช้ใน Handler ถัดไป
-	// สามารถเรียกใช้ได้ด้วย c.Locals("userID"), c.Locals("username")
+	// สามารถเรียกใช้ได้ด้วย c.Locals("userID"), c.Locals("username"), c.Locals("role")
 	c.Locals("userID", claims.UserID)
 	c.Locals("username", claims.Username)
+	c.Locals("role", claims.Role)
+	c.Locals("sessionID", claims.SessionID)
 
 	// ผ่านไป Handler ถัดไป
 	return c.Next()
 }
 
+// RequireRole - Middleware จำกัดสิทธิ์ตาม Role (ต้องใช้ต่อจาก JWTMiddleware)
+// ตัวอย่าง: api.Group("/admin", JWTMiddleware, RequireRole(services.RoleAdmin))
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !services.HasRole(GetCurrentRole(c), roles...) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "ไม่มีสิทธิ์เข้าถึง",
+				"code":  "forbidden",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// bearerClaims - ดึงและตรวจ Token จาก Header "Authorization: Bearer <token>" (ยังไม่เช็ค Session)
+func bearerClaims(c *fiber.Ctx) (*JWTClaims, error) {
+	// ดึง Authorization Header
+	authHeader := c.Get("Authorization")
+
+	// เช็คว่ามี Header มั้ย
+	if authHeader == "" {
+		return nil, errors.New("กรุณา Login ก่อน (ไม่มี Authorization Header)")
+	}
+
+	// เช็ครูปแบบ "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errors.New("รูปแบบ Token ไม่ถูกต้อง (ต้องเป็น: Bearer <token>)")
+	}
+
+	claims, err := GetUserFromToken(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("Token ไม่ถูกต้องหรือหมดอายุ: %w", err)
+	}
+	return claims, nil
+}
+
 // GetCurrentUserID - Helper function ดึง User ID จาก Context
 // ใช้ใน Protected Routes
 func GetCurrentUserID(c *fiber.Ctx) uint {
@@ -67,3 +106,21 @@ func GetCurrentUsername(c *fiber.Ctx) string {
 	}
 	return username
 }
+
+// GetCurrentSessionID - Helper function ดึง Session ID ของ Token ที่เรียกอยู่
+func GetCurrentSessionID(c *fiber.Ctx) uint {
+	sessionID, ok := c.Locals("sessionID").(uint)
+	if !ok {
+		return 0
+	}
+	return sessionID
+}
+
+// GetCurrentRole - Helper function ดึง Role ของ User จาก Context
+func GetCurrentRole(c *fiber.Ctx) string {
+	role, ok := c.Locals("role").(string)
+	if !ok {
+		return ""
+	}
+	return role
+}
@@ -0,0 +1,102 @@
+// Package handlers - IP จริงของผู้ใช้เมื่อรันหลัง Reverse Proxy (เช่น Load Balancer ของ Render)
+// ใช้เป็น Key ของ Throttle ต่อ IP ถ้าใช้ IP ของ Proxy ทุกคนจะโดนล็อกพร้อมกัน
+package handlers
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultTrustedProxies - Proxy ที่เชื่อ X-Forwarded-For เมื่อไม่ได้ตั้ง ENV (Loopback + Private Network ที่ Load Balancer ของ Render/Docker ใช้)
+var defaultTrustedProxies = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// TrustedProxies - IP/CIDR ของ Proxy ที่เชื่อ X-Forwarded-For จาก ENV "TRUSTED_PROXIES" (คั่นด้วย ,)
+// "none" = ไม่เชื่อ Header เลย (รันโดยไม่มี Proxy), ไม่ตั้ง = defaultTrustedProxies
+// ใช้ตั้ง fiber.Config.TrustedProxies ใน main.go
+var TrustedProxies = sync.OnceValue(func() []string {
+	return parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+})
+
+// trustedProxyNets - TrustedProxies ที่แปลงเป็น *net.IPNet แล้ว (IP เดี่ยวเป็น /32 หรือ /128)
+var trustedProxyNets = sync.OnceValue(func() []*net.IPNet {
+	return proxyNets(TrustedProxies())
+})
+
+// parseTrustedProxies - แยกรายการ Proxy จากค่า ENV
+func parseTrustedProxies(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultTrustedProxies
+	}
+	proxies := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !strings.EqualFold(item, "none") {
+			proxies = append(proxies, item)
+		}
+	}
+	return proxies
+}
+
+// proxyNets - แปลงรายการ IP/CIDR เป็น *net.IPNet (ข้ามค่าที่ไม่ถูกต้อง)
+func proxyNets(proxies []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// isTrustedProxy - IP นี้เป็น Proxy ที่เชื่อถือหรือไม่
+func isTrustedProxy(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP - IP จริงของผู้ใช้ (ใช้แทน c.IP() ทุกที่)
+// เชื่อ X-Forwarded-For เฉพาะเมื่อเชื่อมต่อมาจาก Proxy ที่เชื่อถือ และอ่านจากขวาไปซ้าย
+// เอา IP แรกที่ไม่ใช่ Proxy ที่เชื่อถือ (ค่าทางซ้ายผู้ใช้ใส่มาเองได้ ใช้เป็น Key ไม่ได้)
+func clientIP(c *fiber.Ctx) string {
+	remote := c.Context().RemoteIP().String()
+	nets := trustedProxyNets()
+	if !isTrustedProxy(nets, remote) {
+		return remote
+	}
+
+	forwarded := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(ip) == nil {
+			break // ค่าไม่ถูกต้อง: ค่าทางซ้ายจากนี้เชื่อไม่ได้
+		}
+		if !isTrustedProxy(nets, ip) {
+			return ip
+		}
+		remote = ip
+	}
+	return remote
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// withTrustedProxies - ตั้ง Proxy ที่เชื่อถือชั่วคราวระหว่าง Test
+func withTrustedProxies(t *testing.T, proxies ...string) {
+	t.Helper()
+	prev := trustedProxyNets
+	trustedProxyNets = func() []*net.IPNet { return proxyNets(proxies) }
+	t.Cleanup(func() { trustedProxyNets = prev })
+}
+
+// ipKeyOf - ยิง Request ผ่าน Fiber (Remote IP ของ app.Test คือ 0.0.0.0) แล้วคืน Key ของ Login ต่อ IP
+func ipKeyOf(t *testing.T, forwardedFor string) string {
+	t.Helper()
+	var key string
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		key = loginIPKey(c).key
+		return nil
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	if forwardedFor != "" {
+		req.Header.Set(fiber.HeaderXForwardedFor, forwardedFor)
+	}
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	return key
+}
+
+// TestLoginIPKeyUsesForwardedClientIP - หลัง Proxy ที่เชื่อถือ Key มาจาก IP ผู้ใช้ใน X-Forwarded-For ไม่ใช่ IP ของ Proxy
+func TestLoginIPKeyUsesForwardedClientIP(t *testing.T) {
+	withTrustedProxies(t, "0.0.0.0", "10.0.0.0/8")
+
+	if got := ipKeyOf(t, "203.0.113.7"); got != "login:ip:203.0.113.7" {
+		t.Errorf("single hop: got %q", got)
+	}
+	// ค่าทางซ้ายผู้ใช้ปลอมมาได้ เอา IP แรกจากขวาที่ไม่ใช่ Proxy ที่เชื่อถือ
+	if got := ipKeyOf(t, "1.2.3.4, 203.0.113.7, 10.1.2.3"); got != "login:ip:203.0.113.7" {
+		t.Errorf("spoofed chain: got %q", got)
+	}
+	if got := ipKeyOf(t, ""); got != "login:ip:0.0.0.0" {
+		t.Errorf("no header: got %q", got)
+	}
+
+	// ผู้ใช้สองคนหลัง Proxy เดียวกันต้องได้คนละ Key (คนหนึ่งผิดบ่อยไม่ล็อกอีกคน)
+	a, b := ipKeyOf(t, "203.0.113.7"), ipKeyOf(t, "198.51.100.9")
+	now := time.Now()
+	for i := 0; i < loginIPPolicy.MaxFailures; i++ {
+		loginIPThrottle.Fail(a, now)
+	}
+	t.Cleanup(func() { loginIPThrottle.Reset(a) })
+	if loginIPThrottle.Check(a, now) == 0 || loginIPThrottle.Check(b, now) != 0 {
+		t.Errorf("lockout should only apply to %s", a)
+	}
+}
+
+// TestLoginIPKeyIgnoresUntrustedForwardedFor - ต่อตรงไม่ผ่าน Proxy ที่เชื่อถือ ไม่เชื่อ Header (กันปลอม IP หนีการล็อก)
+func TestLoginIPKeyIgnoresUntrustedForwardedFor(t *testing.T) {
+	withTrustedProxies(t, "10.0.0.0/8")
+
+	if got := ipKeyOf(t, "203.0.113.7"); got != "login:ip:0.0.0.0" {
+		t.Errorf("untrusted remote: got %q", got)
+	}
+}
+
+// TestParseTrustedProxies - Default, none และรายการจาก ENV
+func TestParseTrustedProxies(t *testing.T) {
+	if got := parseTrustedProxies(""); len(got) != len(defaultTrustedProxies) {
+		t.Errorf("default: got %v", got)
+	}
+	if got := parseTrustedProxies("none"); len(got) != 0 {
+		t.Errorf("none: got %v", got)
+	}
+	nets := proxyNets(parseTrustedProxies(" 10.0.0.0/8, 192.0.2.1 ,bogus"))
+	if len(nets) != 2 || !isTrustedProxy(nets, "10.9.8.7") || !isTrustedProxy(nets, "192.0.2.1") || isTrustedProxy(nets, "192.0.2.2") {
+		t.Errorf("list: got %v", nets)
+	}
+}
@@ -0,0 +1,317 @@
+// Package handlers - Session ของการ Login (Refresh Token / Logout / จัดการอุปกรณ์)
+// Access Token อายุสั้นผูกกับ Session ที่เก็บใน Database ยกเลิก Session แล้ว Token ใช้ไม่ได้ทันที
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// อายุของ Token (ตั้งค่าได้ด้วย ENV "ACCESS_TOKEN_TTL" / "REFRESH_TOKEN_TTL" เช่น 10m, 720h)
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// UserSession - Session ของการ Login หนึ่งครั้ง (หนึ่งอุปกรณ์/Browser)
+// เก็บเฉพาะ Hash ของ Refresh Token และ Hash ของ Token ก่อนหน้าไว้ตรวจจับการใช้ซ้ำ
+type UserSession struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"index;not null" json:"-"`
+	RefreshTokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	PreviousTokenHash *string    `gorm:"size:64;index" json:"-"`
+	UserAgent         string     `gorm:"size:255" json:"user_agent"`
+	IP                string     `gorm:"size:64" json:"ip"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RotatedAt         *time.Time `json:"-"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	Current           bool       `gorm:"-" json:"current"` // Session ของ Token ที่เรียก API นี้
+}
+
+// RefreshRequest - Body ของ Refresh/Logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ============================================
+// Token
+// ============================================
+
+// startSession - สร้าง Session ใหม่ (หลัง Login สำเร็จ) แล้วออก Access + Refresh Token
+func startSession(c *fiber.Ctx, user User) (AuthResponse, error) {
+	refreshToken, err := services.NewRefreshToken()
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	now := time.Now()
+	session := UserSession{
+		UserID:           user.ID,
+		RefreshTokenHash: services.HashRefreshToken(refreshToken),
+		UserAgent:        services.TruncateRunes(c.Get(fiber.HeaderUserAgent), 255),
+		IP:               services.TruncateRunes(clientIP(c), 64),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// ล้าง Session ที่หมดอายุแล้วของ User นี้ไปด้วย (Session ที่ถูกยกเลิกจะหมดอายุเองภายใน REFRESH_TOKEN_TTL)
+		if err := tx.Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&UserSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	return issueTokens(user, session, refreshToken)
+}
+
+// issueTokens - เซ็น Access Token ของ Session และประกอบ Response
+func issueTokens(user User, session UserSession, refreshToken string) (AuthResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL())
+	claims := JWTClaims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      userRole(user),
+		SessionID: session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "mmrrdikub",
+		},
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(getJWTSecret()))
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	response := AuthResponse{
+		Token:            tokenString,
+		ExpiresAt:        expiresAt.Unix(),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt.Unix(),
+	}
+	response.User.ID = user.ID
+	response.User.Username = user.Username
+	response.User.Email = user.Email
+	response.User.Role = userRole(user)
+	response.User.IsVerified = user.IsVerified
+	return response, nil
+}
+
+// sessionActive - Session ยังใช้ได้ (ยังไม่ถูกยกเลิก/หมดอายุ) ใช้ใน JWTMiddleware ทุก Request
+func sessionActive(sessionID, userID uint) (bool, error) {
+	var count int64
+	err := database.DB.Model(&UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// revokeUserSessions - ยกเลิกทุก Session ของ User (ยกเว้น exceptID ถ้าไม่ใช่ 0)
+func revokeUserSessions(tx *gorm.DB, userID, exceptID uint) (int64, error) {
+	query := tx.Model(&UserSession{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+	result := query.Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// ============================================
+// Handler Functions
+// ============================================
+
+// RefreshToken - แลก Refresh Token เป็น Access Token ใหม่ (Refresh Token เดิมใช้ไม่ได้อีก)
+// POST /api/auth/refresh
+func RefreshToken(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.RefreshToken) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง refresh_token",
+		})
+	}
+	hash := services.HashRefreshToken(strings.TrimSpace(req.RefreshToken))
+
+	var response AuthResponse
+	var refreshErr error // ปฏิเสธการ Refresh (ยัง Commit การยกเลิก Session ที่ Token ถูกใช้ซ้ำ)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var session UserSession
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			refreshErr = errors.New("Refresh Token ไม่ถูกต้อง กรุณา Login ใหม่")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		revoke, err := services.CheckRefresh(services.RefreshSession{
+			ExpiresAt: session.ExpiresAt,
+			RevokedAt: session.RevokedAt,
+			RotatedAt: session.RotatedAt,
+		}, session.RefreshTokenHash != hash, now)
+		if revoke {
+			log.Printf("⚠️ REFRESH TOKEN REUSED: user=%d session=%d ip=%s", session.UserID, session.ID, clientIP(c))
+			if err := tx.Model(&session).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			refreshErr = err
+			return nil
+		}
+
+		var user User
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			refreshErr = errors.New("ไม่พบบัญชีผู้ใช้ กรุณา Login ใหม่")
+			return tx.Model(&session).Update("revoked_at", now).Error
+		}
+		if err := checkAccountUsable(user); err != nil {
+			refreshErr = err
+			return tx.Model(&session).Update("revoked_at", now).Error
+		}
+
+		refreshToken, err := services.NewRefreshToken()
+		if err != nil {
+			return err
+		}
+		previous := session.RefreshTokenHash
+		session.PreviousTokenHash = &previous
+		session.RefreshTokenHash = services.HashRefreshToken(refreshToken)
+		session.RotatedAt = &now
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(refreshTokenTTL())
+		session.UserAgent = services.TruncateRunes(c.Get(fiber.HeaderUserAgent), 255)
+		session.IP = services.TruncateRunes(clientIP(c), 64)
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+		response, err = issueTokens(user, session, refreshToken)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถต่ออายุ Token ได้",
+		})
+	}
+	if refreshErr != nil {
+		body := fiber.Map{"error": refreshErr.Error()}
+		var vErr *services.ValidationError
+		if errors.As(refreshErr, &vErr) {
+			body["code"] = vErr.Code
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(body)
+	}
+	return c.JSON(response)
+}
+
+// Logout - ออกจากระบบ (ยกเลิก Session ของ refresh_token ใน Body หรือของ Access Token ใน Header)
+// Access Token หมดอายุแล้วก็ Logout ได้ด้วย refresh_token
+// POST /api/auth/logout
+func Logout(c *fiber.Ctx) error {
+	var req RefreshRequest
+	_ = c.BodyParser(&req) // Body ว่างได้ (ใช้ Authorization Header แทน)
+
+	query := database.DB.Model(&UserSession{}).Where("revoked_at IS NULL")
+	if token := strings.TrimSpace(req.RefreshToken); token != "" {
+		hash := services.HashRefreshToken(token)
+		query = query.Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash)
+	} else if claims, err := bearerClaims(c); err == nil && claims.SessionID != 0 {
+		query = query.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID)
+	} else {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง refresh_token หรือ Authorization Header",
+		})
+	}
+
+	// Session ที่ไม่พบ/ถูกยกเลิกไปแล้วก็ถือว่า Logout สำเร็จ (เรียกซ้ำได้)
+	if err := query.Update("revoked_at", time.Now()).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถ Logout ได้",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "ออกจากระบบแล้ว"})
+}
+
+// GetSessions - อุปกรณ์ที่ Login อยู่ทั้งหมด (ล่าสุดก่อน)
+// GET /api/auth/sessions
+func GetSessions(c *fiber.Ctx) error {
+	var sessions []UserSession
+	err := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", GetCurrentUserID(c), time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงรายการ Session ได้",
+		})
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == GetCurrentSessionID(c)
+	}
+	return c.JSON(fiber.Map{"sessions": sessions})
+}
+
+// RevokeSession - ออกจากระบบบนอุปกรณ์ที่เลือก
+// DELETE /api/auth/sessions/:id
+func RevokeSession(c *fiber.Ctx) error {
+	result := database.DB.Model(&UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), GetCurrentUserID(c)).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถยกเลิก Session ได้",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบ Session",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "ยกเลิก Session แล้ว"})
+}
+
+// RevokeOtherSessions - ออกจากระบบทุกอุปกรณ์ยกเว้นเครื่องนี้
+// DELETE /api/auth/sessions
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	revoked, err := revokeUserSessions(database.DB, GetCurrentUserID(c), GetCurrentSessionID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถยกเลิก Session ได้",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "ออกจากระบบอุปกรณ์อื่นแล้ว", "revoked": revoked})
+}
+
+// ============================================
+// Config
+// ============================================
+
+// accessTokenTTL - อายุ Access Token จาก ENV "ACCESS_TOKEN_TTL" (Default 15 นาที)
+func accessTokenTTL() time.Duration {
+	return durationEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// refreshTokenTTL - อายุ Session นับจากการใช้ครั้งล่าสุด จาก ENV "REFRESH_TOKEN_TTL" (Default 30 วัน)
+func refreshTokenTTL() time.Duration {
+	return durationEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
@@ -0,0 +1,132 @@
+// Package handlers - ป้องกัน Brute-force ของ Login, สมัครสมาชิก, รหัส 2FA และ OTP ลืมรหัสผ่าน
+// นับครั้งที่ผิดทั้งต่อบัญชีและต่อ IP ผิดครบแล้วล็อกแบบทวีคูณ (ดู services.Throttle)
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+
+	"mmrrdikub/internal/services"
+)
+
+// กติกาการล็อกแต่ละแบบ (ต่อ IP ผ่อนกว่าเพราะหลายคนอาจใช้ IP เดียวกัน)
+var (
+	loginAccountPolicy = services.ThrottlePolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: 24 * time.Hour}
+	loginIPPolicy      = services.ThrottlePolicy{MaxFailures: 20, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: 24 * time.Hour}
+	otpAccountPolicy   = services.ThrottlePolicy{MaxFailures: services.MaxOTPAttempts, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: 2 * time.Hour, ResetAfter: 24 * time.Hour}
+	otpIPPolicy        = services.ThrottlePolicy{MaxFailures: 20, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: 2 * time.Hour, ResetAfter: 24 * time.Hour}
+	otpSendPolicy      = services.ThrottlePolicy{MaxFailures: 3, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: time.Hour, ResetAfter: 24 * time.Hour} // นับทุกครั้งที่ขอ OTP (กันยิงอีเมลรัวๆ)
+	otpSendIPPolicy    = services.ThrottlePolicy{MaxFailures: 10, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: time.Hour, ResetAfter: 24 * time.Hour}
+	twoFactorPolicy    = services.ThrottlePolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: 24 * time.Hour}
+	registerIPPolicy   = services.ThrottlePolicy{MaxFailures: 10, Window: time.Hour, BaseLockout: 15 * time.Minute, MaxLockout: 24 * time.Hour, ResetAfter: 24 * time.Hour} // นับทุกครั้งที่สมัคร
+)
+
+// Throttle ที่ใช้งานอยู่ (Key ขึ้นต้นด้วยชื่อแต่ละแบบ จึงใช้ Store ร่วมกันได้)
+var (
+	loginAccountThrottle = services.NewThrottle(nil, loginAccountPolicy)
+	loginIPThrottle      = services.NewThrottle(nil, loginIPPolicy)
+	otpAccountThrottle   = services.NewThrottle(nil, otpAccountPolicy)
+	otpIPThrottle        = services.NewThrottle(nil, otpIPPolicy)
+	otpSendThrottle      = services.NewThrottle(nil, otpSendPolicy)
+	otpSendIPThrottle    = services.NewThrottle(nil, otpSendIPPolicy)
+	twoFactorThrottle    = services.NewThrottle(nil, twoFactorPolicy)
+	registerIPThrottle   = services.NewThrottle(nil, registerIPPolicy)
+)
+
+// SetAttemptStore - เปลี่ยนที่เก็บตัวนับของทุก Throttle (เช่น Redis เมื่อรันหลาย Instance)
+// เรียกก่อนเปิด Server เท่านั้น (Default = services.MemoryAttemptStore ต่อ Throttle)
+func SetAttemptStore(store services.AttemptStore) {
+	for _, t := range []*services.Throttle{loginAccountThrottle, loginIPThrottle, otpAccountThrottle, otpIPThrottle, otpSendThrottle, otpSendIPThrottle, twoFactorThrottle, registerIPThrottle} {
+		t.Store = store
+	}
+}
+
+// attemptKey - Key ของ Throttle หนึ่งตัว
+type attemptKey struct {
+	throttle *services.Throttle
+	key      string
+}
+
+// loginAccountKey / loginIPKey - Key ของ Login (Username ไม่สนตัวพิมพ์ กันเลี่ยงด้วย BoB/bob)
+func loginAccountKey(username string) attemptKey {
+	return attemptKey{loginAccountThrottle, "login:user:" + strings.ToLower(strings.TrimSpace(username))}
+}
+
+func loginIPKey(c *fiber.Ctx) attemptKey {
+	return attemptKey{loginIPThrottle, "login:ip:" + clientIP(c)}
+}
+
+// twoFactorKey - Key ของการใส่รหัส 2FA ต่อบัญชี (ใช้ร่วมกับ loginIPKey)
+func twoFactorKey(userID uint) attemptKey {
+	return attemptKey{twoFactorThrottle, "2fa:user:" + strconv.FormatUint(uint64(userID), 10)}
+}
+
+// registerIPKey - Key ของการสมัครสมาชิกต่อ IP
+func registerIPKey(c *fiber.Ctx) attemptKey {
+	return attemptKey{registerIPThrottle, "register:ip:" + clientIP(c)}
+}
+
+// otpKeys - Key ของการใส่ OTP (ต่ออีเมล + ต่อ IP)
+func otpKeys(c *fiber.Ctx, email string) []attemptKey {
+	return []attemptKey{
+		{otpAccountThrottle, "otp:email:" + strings.ToLower(strings.TrimSpace(email))},
+		{otpIPThrottle, "otp:ip:" + clientIP(c)},
+	}
+}
+
+// otpSendKeys - Key ของการขอส่ง OTP (ต่ออีเมล + ต่อ IP)
+func otpSendKeys(c *fiber.Ctx, email string) []attemptKey {
+	return []attemptKey{
+		{otpSendThrottle, "otp-send:email:" + strings.ToLower(strings.TrimSpace(email))},
+		{otpSendIPThrottle, "otp-send:ip:" + clientIP(c)},
+	}
+}
+
+// lockedFor - เวลาที่ยังถูกล็อก (นานสุดของทุก Key, 0 = ผ่าน)
+func lockedFor(now time.Time, keys ...attemptKey) time.Duration {
+	var longest time.Duration
+	for _, k := range keys {
+		if d := k.throttle.Check(k.key, now); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// recordFailure - นับความพยายามที่ล้มเหลวของทุก Key
+func recordFailure(now time.Time, keys ...attemptKey) {
+	for _, k := range keys {
+		k.throttle.Fail(k.key, now)
+	}
+}
+
+// resetAttempts - ล้างตัวนับของ Key ที่ระบุ
+func resetAttempts(keys ...attemptKey) {
+	for _, k := range keys {
+		k.throttle.Reset(k.key)
+	}
+}
+
+// respondTooManyAttempts - ตอบ 429 พร้อม Retry-After (วินาที ปัดขึ้น)
+// ข้อความเหมือนกันทุกกรณี ไม่บอกว่าบัญชีมีอยู่จริงหรือไม่
+func respondTooManyAttempts(c *fiber.Ctx, retry time.Duration) error {
+	seconds := int((retry + time.Second - 1) / time.Second)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "พยายามหลายครั้งเกินไป กรุณาลองใหม่ภายหลัง",
+		"message":     "พยายามหลายครั้งเกินไป กรุณาลองใหม่ภายหลัง",
+		"code":        "too_many_attempts",
+		"retry_after": seconds,
+	})
+}
+
+// dummyPasswordHash - Hash ไว้เทียบเมื่อไม่พบ Username ให้ใช้เวลาเท่ากับกรณีรหัสผ่านผิด (กันเดาบัญชีจากเวลาตอบ)
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("mmrrdikub-dummy-password"), 12)
+	return hash
+})
@@ -4,13 +4,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 
+	"mmrrdikub/internal/services"
 	"mmrrdikub/pkg/database"
 )
 
@@ -27,33 +30,45 @@ type Trade struct {
 	Side string `gorm:"size:10;not null" json:"side"` // LONG หรือ SHORT
 
 	// === ราคา (Fixed overflow: precision 24, scale 8 to match DB schema) ===
-	EntryPrice float64 `gorm:"type:decimal(24,8);not null" json:"entry_price"`
-	ExitPrice  float64 `gorm:"type:decimal(24,8)" json:"exit_price"`
-	StopLoss   float64 `gorm:"type:decimal(24,8)" json:"stop_loss"`
-	TakeProfit float64 `gorm:"type:decimal(24,8)" json:"take_profit"`
+	// 🔥 ใช้ decimal.Decimal แทน float64 ทั้งหมด อ่าน/เขียน DB ตรงกับ numeric ไม่เพี้ยน
+	EntryPrice decimal.Decimal `gorm:"type:decimal(24,8);not null" json:"entry_price"`
+	ExitPrice  decimal.Decimal `gorm:"type:decimal(24,8)" json:"exit_price"`
+	StopLoss   decimal.Decimal `gorm:"type:decimal(24,8)" json:"stop_loss"`
+	TakeProfit decimal.Decimal `gorm:"type:decimal(24,8)" json:"take_profit"`
 
 	// === ขนาดไม้ (Fixed overflow: precision 18, scale 4) ===
-	PositionSize float64 `gorm:"type:decimal(18,4);not null" json:"position_size"` // มูลค่า USD
-	Quantity     float64 `gorm:"type:decimal(24,12)" json:"quantity"`              // จำนวนเหรียญ (ตัวเลขเล็กมากๆ)
-	Leverage     int     `gorm:"default:1" json:"leverage"`
+	PositionSize decimal.Decimal `gorm:"type:decimal(18,4);not null" json:"position_size"` // มูลค่า USD
+	Quantity     decimal.Decimal `gorm:"type:decimal(24,12)" json:"quantity"`              // จำนวนเหรียญ (ตัวเลขเล็กมากๆ)
+	Leverage     int             `gorm:"default:1" json:"leverage"`
 
 	// === Advanced Risk Management (Fixed overflow) ===
-	RiskPercent     float64 `gorm:"type:decimal(10,4)" json:"risk_percent"`      // เช่น 1.5 (หมายถึง 1.5%)
-	MaxWin          float64 `gorm:"type:decimal(18,4)" json:"max_win"`           // กำไรสูงสุดถ้าชนะ (USD)
-	MaxLoss         float64 `gorm:"type:decimal(18,4)" json:"max_loss"`          // ขาดทุนถ้าโดน SL (USD)
-	RiskRewardRatio float64 `gorm:"type:decimal(10,4)" json:"risk_reward_ratio"` // เช่น 2.5 (R:R = 1:2.5)
+	RiskPercent     decimal.Decimal `gorm:"type:decimal(10,4)" json:"risk_percent"`      // เช่น 1.5 (หมายถึง 1.5%)
+	MaxWin          decimal.Decimal `gorm:"type:decimal(18,4)" json:"max_win"`           // กำไรสูงสุดถ้าชนะ (USD)
+	MaxLoss         decimal.Decimal `gorm:"type:decimal(18,4)" json:"max_loss"`          // ขาดทุนถ้าโดน SL (USD)
+	RiskRewardRatio decimal.Decimal `gorm:"type:decimal(10,4)" json:"risk_reward_ratio"` // เช่น 2.5 (R:R = 1:2.5)
 
 	// === Trading Fees ===
-	Fee float64 `gorm:"type:decimal(18,4)" json:"fee"` // ค่าธรรมเนียม (USD)
+	Fee decimal.Decimal `gorm:"type:decimal(18,4)" json:"fee"` // ค่าธรรมเนียม (USD)
 
 	// === Analysis & Reason ===
 	EntryReason string `gorm:"type:text" json:"entry_reason"` // เหตุผลเข้าเทรด
 	SetupScore  int    `gorm:"default:0" json:"setup_score"`  // คะแนน 1-5 ดาว
 
 	// === ผลลัพธ์ (Fixed overflow) ===
-	PnL        float64 `gorm:"column:pnl;type:decimal(18,4)" json:"pnl"`                 // กำไร/ขาดทุนจริง (USD)
-	PnLPercent float64 `gorm:"column:pnl_percent;type:decimal(10,4)" json:"pnl_percent"` // กำไร/ขาดทุน (%)
-	Status     string  `gorm:"size:20;default:'OPEN'" json:"status"`                     // OPEN, WIN, LOSS, BREAK_EVEN
+	PnL        decimal.Decimal  `gorm:"column:pnl;type:decimal(18,4)" json:"pnl"`                 // กำไร/ขาดทุนจริง (USD)
+	PnLPercent decimal.Decimal  `gorm:"column:pnl_percent;type:decimal(10,4)" json:"pnl_percent"` // กำไร/ขาดทุน (%)
+	Status     string           `gorm:"size:20;default:'OPEN'" json:"status"`                     // PLANNED, OPEN, PARTIALLY_CLOSED, CLOSED, CANCELLED
+	Outcome    string           `gorm:"size:20" json:"outcome"`                                   // ผลของไม้ที่ปิดแล้ว: WIN, LOSS, BREAK_EVEN
+	RMultiple  *decimal.Decimal `gorm:"column:r_multiple;type:decimal(10,4)" json:"r_multiple"`   // PnL / Risk ตอนเข้า (nil = ยังไม่ปิด หรือไม่รู้ Risk)
+	// Risk ตอนเปิดไม้ (USD) ตัวหารของ R-Multiple ไม่เปลี่ยนตาม MaxLoss ที่คำนวณใหม่ตอนเติมไม้ (nil = ยังไม่เปิด)
+	InitialRisk *decimal.Decimal `gorm:"column:initial_risk;type:decimal(18,4)" json:"initial_risk"`
+
+	// === ทยอยปิดไม้ (Partial Exits) - อัพเดทอัตโนมัติทุกครั้งที่ขายออก ===
+	RemainingQuantity decimal.Decimal `gorm:"type:decimal(24,12);default:0" json:"remaining_quantity"`              // เหรียญที่ยังถืออยู่
+	RealizedPnL       decimal.Decimal `gorm:"column:realized_pnl;type:decimal(18,4);default:0" json:"realized_pnl"` // กำไรสุทธิที่รับรู้แล้ว (USD)
+	AvgExitPrice      decimal.Decimal `gorm:"type:decimal(24,8);default:0" json:"avg_exit_price"`                   // ราคาออกเฉลี่ยของส่วนที่ขายแล้ว
+	Exits             []TradeExit     `gorm:"foreignKey:TradeID" json:"exits,omitempty"`
+	Entries           []TradeEntry    `gorm:"foreignKey:TradeID" json:"entries,omitempty"` // Fill ฝั่ง Entry (ถ้าเคยเติมไม้)
 
 	// === ข้อมูลเพิ่มเติม ===
 	Notes string `gorm:"type:text" json:"notes"` // บันทึกเพิ่มเติม
@@ -63,12 +78,15 @@ type Trade struct {
 	EntryTime *time.Time `json:"entry_time"` // เวลาเข้าเทรด
 	ExitTime  *time.Time `json:"exit_time"`  // เวลาออกเทรด
 
-	// === System Timestamps ===
-	OpenedAt  *time.Time     `json:"opened_at"` // เวลาเปิดออเดอร์
-	ClosedAt  *time.Time     `json:"closed_at"` // เวลาปิดออเดอร์
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// === System Timestamps (บันทึกทุกครั้งที่เปลี่ยนสถานะ) ===
+	PlannedAt         *time.Time     `json:"planned_at"`          // เวลาวางแผนไม้
+	OpenedAt          *time.Time     `json:"opened_at"`           // เวลาเปิดออเดอร์
+	PartiallyClosedAt *time.Time     `json:"partially_closed_at"` // เวลาทยอยปิดครั้งแรก
+	ClosedAt          *time.Time     `json:"closed_at"`           // เวลาปิดออเดอร์
+	CancelledAt       *time.Time     `json:"cancelled_at"`        // เวลายกเลิก
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // ============================================
@@ -82,21 +100,27 @@ type CreateTradeRequest struct {
 	Side string `json:"side" validate:"required,oneof=LONG SHORT"`
 
 	// Prices
-	EntryPrice float64 `json:"entry_price" validate:"required,gt=0"`
-	StopLoss   float64 `json:"stop_loss"`
-	TakeProfit float64 `json:"take_profit"`
+	EntryPrice decimal.Decimal `json:"entry_price" validate:"required,gt=0"`
+	StopLoss   decimal.Decimal `json:"stop_loss"`
+	TakeProfit decimal.Decimal `json:"take_profit"`
 
 	// Position Sizing
-	PositionSize float64 `json:"position_size" validate:"required,gt=0"`
-	Quantity     float64 `json:"quantity"`
-	Leverage     int     `json:"leverage"`
+	PositionSize decimal.Decimal `json:"position_size" validate:"required,gt=0"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	Leverage     int             `json:"leverage"`
 
 	// 🔥 NEW: Risk Management
-	RiskPercent     float64 `json:"risk_percent"`
-	MaxWin          float64 `json:"max_win"`
-	MaxLoss         float64 `json:"max_loss"`
-	RiskRewardRatio float64 `json:"risk_reward_ratio"`
-	Fee             float64 `json:"fee"`
+	RiskPercent     decimal.Decimal `json:"risk_percent"`
+	MaxWin          decimal.Decimal `json:"max_win"`
+	MaxLoss         decimal.Decimal `json:"max_loss"`
+	RiskRewardRatio decimal.Decimal `json:"risk_reward_ratio"`
+	Fee             decimal.Decimal `json:"fee"`
+
+	// SL/TP หลายจุด (ไม่บังคับ) - ถ้าไม่ส่งมาจะใช้ StopLoss/TakeProfit จุดเดียวแทน
+	// ใช้คำนวณ RiskRewardRatio ฝั่ง Server
+	StopLosses  []services.StopLoss   `json:"stop_losses"`
+	TakeProfits []services.TakeProfit `json:"take_profits"`
+	FeeRate     decimal.Decimal       `json:"fee_rate"`
 
 	// 🔥 NEW: Analysis
 	EntryReason string `json:"entry_reason"`
@@ -109,30 +133,47 @@ type CreateTradeRequest struct {
 	// Time (optional)
 	EntryTime *time.Time `json:"entry_time"`
 	OpenedAt  *time.Time `json:"opened_at"`
+
+	// สถานะเริ่มต้น: OPEN (Default) หรือ PLANNED (วางแผนไว้ ยังไม่เข้า)
+	Status string `json:"status"`
 }
 
 // UpdateTradeRequest - ข้อมูลสำหรับปิดหรือแก้ไขออเดอร์ (UPGRADED)
 type UpdateTradeRequest struct {
-	ExitPrice  float64    `json:"exit_price"`
-	PnL        float64    `json:"pnl"`
-	PnLPercent float64    `json:"pnl_percent"`
-	Status     string     `json:"status"` // WIN, LOSS, BREAK_EVEN
-	Notes      string     `json:"notes"`
-	ExitTime   *time.Time `json:"exit_time"`
-	ClosedAt   *time.Time `json:"closed_at"`
+	ExitPrice  decimal.Decimal `json:"exit_price"`
+	PnL        decimal.Decimal `json:"pnl"`
+	PnLPercent decimal.Decimal `json:"pnl_percent"`
+	Status     string          `json:"status"`  // สถานะปลายทาง (OPEN, CLOSED, CANCELLED) หรือแบบเดิม WIN/LOSS/BREAK_EVEN = ปิดไม้
+	Outcome    string          `json:"outcome"` // ผลของไม้ (WIN, LOSS, BREAK_EVEN) ใช้เมื่อ OverridePnL = true (ไม่ส่ง = ดูจาก pnl)
+	Notes      string          `json:"notes"`
+	ExitTime   *time.Time      `json:"exit_time"`
+	ClosedAt   *time.Time      `json:"closed_at"`
+
+	// 🔥 Server คำนวณ PnL/Status เองจาก ExitPrice
+	// ค่า pnl, pnl_percent, outcome ที่ส่งมาจะถูกใช้ก็ต่อเมื่อ OverridePnL = true เท่านั้น
+	OverridePnL               bool                `json:"override_pnl"`
+	FeeRate                   decimal.Decimal     `json:"fee_rate"`                     // Fee ต่อครั้ง (ใช้เมื่อ Trade ไม่มี Fee)
+	Exchange                  string              `json:"exchange"`                     // ชื่อ Exchange ตามตาราง exchanges (เช่น Binance, OKX ไม่สนตัวพิมพ์) ใช้ Taker Fee
+	BreakEvenTolerancePercent decimal.NullDecimal `json:"break_even_tolerance_percent"` // ช่วงเสมอตัว (% ของมูลค่าไม้)
 }
 
 // TradeFilter - ตัวกรองสำหรับค้นหา
 type TradeFilter struct {
-	Status   string `query:"status"`    // OPEN, WIN, LOSS, all
+	Status   string `query:"status"`    // PLANNED, OPEN, PARTIALLY_CLOSED, CLOSED, CANCELLED, WIN, LOSS, BREAK_EVEN, all
+	Outcome  string `query:"outcome"`   // WIN, LOSS, BREAK_EVEN = ไม้ที่ปิดแล้วและได้ผลนั้น
 	Pair     string `query:"pair"`      // เช่น BTC/USDT
 	Side     string `query:"side"`      // LONG, SHORT
-	DateFrom string `query:"date_from"` // 🔥 NEW: Filter by date range
+	DateFrom string `query:"date_from"` // 🔥 NEW: Filter by date range (รายการเทรด/Export ใช้ created_at, Analytics ใช้วันที่ปิดไม้)
 	DateTo   string `query:"date_to"`
 	Limit    int    `query:"limit"`
 	Offset   int    `query:"offset"`
 	SortBy   string `query:"sort_by"`  // 🔥 NEW: created_at, pnl, position_size
 	SortDir  string `query:"sort_dir"` // ASC, DESC
+
+	// คำนวณใน parseTradeFilter: ช่วงเวลา [dateFrom, dateTo) ตาม Timezone ของผู้ใช้
+	loc      *time.Location
+	dateFrom *time.Time
+	dateTo   *time.Time
 }
 
 // ============================================
@@ -160,10 +201,10 @@ func CreateTrade(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Printf("📊 CreateTrade: user=%d, pair=%s, side=%s, size=%.2f", userID, req.Pair, req.Side, req.PositionSize)
+	log.Printf("📊 CreateTrade: user=%d, pair=%s, side=%s, size=%s", userID, req.Pair, req.Side, req.PositionSize)
 
 	// Validate ข้อมูลพื้นฐาน
-	if req.Pair == "" || req.Side == "" || req.EntryPrice <= 0 || req.PositionSize <= 0 {
+	if req.Pair == "" || req.Side == "" || !req.EntryPrice.IsPositive() || !req.PositionSize.IsPositive() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "กรุณากรอกข้อมูลให้ครบ (Pair, Side, EntryPrice, PositionSize)",
 		})
@@ -188,23 +229,47 @@ func CreateTrade(c *fiber.Ctx) error {
 		})
 	}
 
+	// สถานะเริ่มต้นได้แค่ OPEN หรือ PLANNED
+	status := services.StatusOpen
+	if req.Status != "" {
+		status, _ = services.NormalizeTradeStatus(req.Status)
+		if status != services.StatusOpen && status != services.StatusPlanned {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "สร้างไม้ได้แค่สถานะ OPEN หรือ PLANNED",
+				"field": "status",
+				"code":  "invalid_status",
+			})
+		}
+	}
+
 	// กำหนดค่า Default
 	if req.Leverage <= 0 {
 		req.Leverage = 1
 	}
-	// Set EntryTime to now if not provided
 	now := time.Now()
-	if req.EntryTime == nil {
-		req.EntryTime = &now
+	var plannedAt *time.Time
+	if status == services.StatusPlanned {
+		// ไม้ที่วางแผนไว้ยังไม่มีเวลาเข้า จะได้ตอนเปลี่ยนเป็น OPEN
+		plannedAt = &now
+	} else {
+		// Set EntryTime to now if not provided
+		if req.EntryTime == nil {
+			req.EntryTime = &now
+		}
+		// Set OpenedAt to now if not provided
+		if req.OpenedAt == nil {
+			req.OpenedAt = &now
+		}
 	}
-	// Set OpenedAt to now if not provided
-	if req.OpenedAt == nil {
-		req.OpenedAt = &now
+
+	// 🔥 คำนวณ R:R ฝั่ง Server (ไม่เชื่อค่าจาก Client ถ้ามี SL/TP ให้คำนวณได้)
+	if rr, ok := serverRiskReward(req); ok {
+		req.RiskRewardRatio = rr
 	}
 
 	// 🔥 คำนวณ Setup Score อัตโนมัติ (ถ้าไม่ได้ส่งมา)
-	if req.SetupScore == 0 && req.RiskRewardRatio > 0 {
-		req.SetupScore = calculateSetupScore(req.RiskRewardRatio, req.RiskPercent)
+	if req.SetupScore == 0 && req.RiskRewardRatio.IsPositive() {
+		req.SetupScore = calculateSetupScore(req.RiskRewardRatio.InexactFloat64(), req.RiskPercent.InexactFloat64())
 	}
 
 	// สร้าง Trade Object
@@ -228,12 +293,25 @@ func CreateTrade(c *fiber.Ctx) error {
 		Notes:           req.Notes,
 		Tags:            req.Tags,
 		EntryTime:       req.EntryTime,
+		PlannedAt:       plannedAt,
 		OpenedAt:        req.OpenedAt,
-		Status:          "OPEN",
+		Status:          status,
+	}
+	trade.RemainingQuantity = tradeQuantity(trade)
+	if status != services.StatusPlanned {
+		risk := openingRisk(trade)
+		trade.InitialRisk = &risk
 	}
 
-	// บันทึกลง Database
-	if err := database.DB.Create(&trade).Error; err != nil {
+	// บันทึกลง Database พร้อม Revision แรก
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&trade).Error; err != nil {
+			return err
+		}
+		_, err := recordTradeRevision(tx, c, RevisionCreate, nil, trade)
+		return err
+	})
+	if err != nil {
 		log.Printf("❌ DB Create error: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "ไม่สามารถบันทึกได้",
@@ -249,6 +327,29 @@ func CreateTrade(c *fiber.Ctx) error {
 	})
 }
 
+// serverRiskReward - คำนวณ R:R แบบรวม Fee จาก SL/TP ที่ส่งมา
+// คืน false ถ้าข้อมูลไม่พอหรือไม่ถูกต้อง (จะใช้ค่าที่ Client ส่งมาตามเดิม)
+func serverRiskReward(req CreateTradeRequest) (decimal.Decimal, bool) {
+	stopLosses := req.StopLosses
+	if len(stopLosses) == 0 && req.StopLoss.IsPositive() {
+		stopLosses = []services.StopLoss{{Price: req.StopLoss, Weight: decimal.NewFromInt(1)}}
+	}
+	takeProfits := req.TakeProfits
+	if len(takeProfits) == 0 && req.TakeProfit.IsPositive() {
+		takeProfits = []services.TakeProfit{{Price: req.TakeProfit, Weight: decimal.NewFromInt(1)}}
+	}
+	if len(stopLosses) == 0 || len(takeProfits) == 0 {
+		return decimal.Zero, false
+	}
+
+	rr, err := services.BlendedRiskReward(req.EntryPrice, stopLosses, takeProfits, req.FeeRate)
+	if err != nil {
+		log.Printf("⚠️ serverRiskReward: %v", err)
+		return decimal.Zero, false
+	}
+	return rr, true
+}
+
 // calculateSetupScore - คำนวณคะแนน Setup 1-5 ดาว
 // Logic: RR > 3 = 5 stars, RR > 2 = 4 stars, RR > 1.5 = 3 stars
 //
@@ -293,11 +394,9 @@ func GetTrades(c *fiber.Ctx) error {
 		})
 	}
 
-	var filter TradeFilter
-	if err := c.QueryParser(&filter); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Query parameters ไม่ถูกต้อง",
-		})
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "GetTrades", err)
 	}
 
 	// Default values
@@ -316,27 +415,18 @@ func GetTrades(c *fiber.Ctx) error {
 	query := database.DB.Where("user_id = ?", userID)
 
 	// Filters
-	if filter.Status != "" && filter.Status != "all" {
-		query = query.Where("status = ?", filter.Status)
-	}
-	if filter.Pair != "" {
-		query = query.Where("pair ILIKE ?", "%"+filter.Pair+"%")
-	}
-	if filter.Side != "" {
-		query = query.Where("side = ?", filter.Side)
-	}
-
-	// 🔥 Date Range Filter
-	if filter.DateFrom != "" {
-		if dateFrom, err := time.Parse("2006-01-02", filter.DateFrom); err == nil {
-			query = query.Where("created_at >= ?", dateFrom)
-		}
+	query, ok := applyTradeStatusFilter(query, filter.Status)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ไม่รู้จักสถานะนี้",
+			"field": "status",
+			"code":  "invalid_status",
+		})
 	}
-	if filter.DateTo != "" {
-		if dateTo, err := time.Parse("2006-01-02", filter.DateTo); err == nil {
-			query = query.Where("created_at <= ?", dateTo.Add(24*time.Hour))
-		}
+	if query, ok = applyTradeOutcomeFilter(query, filter.Outcome); !ok {
+		return respondInvalidOutcome(c)
 	}
+	query = applyTradeFilters(query, filter, tradeListDateColumn)
 
 	// 🔥 Sorting
 	orderClause := filter.SortBy + " " + filter.SortDir
@@ -356,24 +446,28 @@ func GetTrades(c *fiber.Ctx) error {
 
 	// Stats - คำนวณจาก trades ทั้งหมดของ user (ไม่สนใจ filter)
 	var stats struct {
-		TotalPnL  float64 `json:"total_pnl"`
-		WinCount  int64   `json:"win_count"`
-		LossCount int64   `json:"loss_count"`
-		OpenCount int64   `json:"open_count"`
-		AvgRR     float64 `json:"avg_rr"`
+		TotalPnL  decimal.Decimal `gorm:"column:total_pnl" json:"total_pnl"`
+		WinCount  int64           `json:"win_count"`
+		LossCount int64           `json:"loss_count"`
+		OpenCount int64           `json:"open_count"`
+		AvgRR     decimal.Decimal `json:"avg_rr"`
 	}
 
-	// คำนวณ Total PnL จากทุก trade ที่ปิดแล้ว (WIN, LOSS, BREAK_EVEN)
+	// คำนวณทุกตัวใน Query เดียว (PnL/R:R เฉพาะไม้ที่ปิดแล้ว)
 	database.DB.Model(&Trade{}).
-		Where("user_id = ? AND status IN (?)", userID, []string{"WIN", "LOSS", "BREAK_EVEN"}).
-		Select("COALESCE(SUM(pnl), 0) as total_pnl, COALESCE(AVG(risk_reward_ratio), 0) as avg_rr").
+		Where("user_id = ?", userID).
+		Select(`COALESCE(SUM(pnl) FILTER (WHERE status = @closed), 0) AS total_pnl,
+			COALESCE(AVG(risk_reward_ratio) FILTER (WHERE status = @closed), 0) AS avg_rr,
+			COUNT(*) FILTER (WHERE status = @closed AND outcome = @win) AS win_count,
+			COUNT(*) FILTER (WHERE status = @closed AND outcome = @loss) AS loss_count,
+			COUNT(*) FILTER (WHERE status IN @open) AS open_count`,
+			sql.Named("closed", services.StatusClosed),
+			sql.Named("win", services.OutcomeWin),
+			sql.Named("loss", services.OutcomeLoss),
+			sql.Named("open", []string{services.StatusOpen, services.StatusPartiallyClosed})).
 		Scan(&stats)
 
-	database.DB.Model(&Trade{}).Where("user_id = ? AND status = ?", userID, "WIN").Count(&stats.WinCount)
-	database.DB.Model(&Trade{}).Where("user_id = ? AND status = ?", userID, "LOSS").Count(&stats.LossCount)
-	database.DB.Model(&Trade{}).Where("user_id = ? AND status = ?", userID, "OPEN").Count(&stats.OpenCount)
-
-	log.Printf("📊 Stats for user %d: Total PnL=%.2f, Win=%d, Loss=%d, Open=%d", userID, stats.TotalPnL, stats.WinCount, stats.LossCount, stats.OpenCount)
+	log.Printf("📊 Stats for user %d: Total PnL=%s, Win=%d, Loss=%d, Open=%d", userID, stats.TotalPnL, stats.WinCount, stats.LossCount, stats.OpenCount)
 
 	return c.JSON(fiber.Map{
 		"trades": trades,
@@ -383,6 +477,104 @@ func GetTrades(c *fiber.Ctx) error {
 	})
 }
 
+// parseTradeFilter - อ่าน TradeFilter จาก Query แล้วแปลง date_from/date_to เป็นเวลาตาม Timezone ของผู้ใช้
+// (date_to รวมทั้งวัน: ถึงก่อนเที่ยงคืนของวันถัดไป)
+func parseTradeFilter(c *fiber.Ctx) (TradeFilter, error) {
+	var filter TradeFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return filter, &services.ValidationError{Field: "query", Code: "invalid_query", Message: "Query parameters ไม่ถูกต้อง"}
+	}
+
+	filter.loc = userLocation(GetCurrentUserID(c))
+	if filter.DateFrom != "" {
+		from, err := services.ParseLocalDate(filter.DateFrom, filter.loc)
+		if err != nil {
+			return filter, &services.ValidationError{Field: "date_from", Code: "invalid_date", Message: err.Error()}
+		}
+		filter.dateFrom = &from
+	}
+	if filter.DateTo != "" {
+		to, err := services.ParseLocalDate(filter.DateTo, filter.loc)
+		if err != nil {
+			return filter, &services.ValidationError{Field: "date_to", Code: "invalid_date", Message: err.Error()}
+		}
+		end := to.AddDate(0, 0, 1)
+		filter.dateTo = &end
+	}
+	return filter, nil
+}
+
+// location - Timezone ของผู้ใช้ที่ใช้กับ Filter นี้ (Default UTC)
+func (f TradeFilter) location() *time.Location {
+	if f.loc == nil {
+		return time.UTC
+	}
+	return f.loc
+}
+
+// applyTradeStatusFilter - กรองสถานะ ("" หรือ "all" = ทุกสถานะ) false = ไม่รู้จักสถานะนี้
+// ส่งผลลัพธ์ (WIN/LOSS/BREAK_EVEN) มา = ไม้ที่ปิดแล้วและได้ผลนั้น
+func applyTradeStatusFilter(query *gorm.DB, status string) (*gorm.DB, bool) {
+	if status == "" || status == "all" {
+		return query, true
+	}
+	if services.IsOutcome(status) {
+		return applyTradeOutcomeFilter(query, status)
+	}
+	if normalized, ok := services.NormalizeTradeStatus(status); ok {
+		return query.Where("status = ?", normalized), true
+	}
+	return query, false
+}
+
+// applyTradeOutcomeFilter - กรองไม้ที่ปิดแล้วตามผล ("" = ไม่กรอง) false = ไม่ใช่ WIN/LOSS/BREAK_EVEN
+func applyTradeOutcomeFilter(query *gorm.DB, outcome string) (*gorm.DB, bool) {
+	if outcome == "" {
+		return query, true
+	}
+	if !services.IsOutcome(outcome) {
+		return query, false
+	}
+	return query.Where("status = ? AND outcome = ?", services.StatusClosed, strings.ToUpper(outcome)), true
+}
+
+// respondInvalidOutcome - outcome ไม่ใช่ WIN/LOSS/BREAK_EVEN
+func respondInvalidOutcome(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "outcome ต้องเป็น WIN, LOSS หรือ BREAK_EVEN",
+		"field": "outcome",
+		"code":  "invalid_outcome",
+	})
+}
+
+// คอลัมน์ที่ตัวกรองช่วงวันที่ (date_from/date_to) ใช้
+const (
+	// รายการเทรด/Export: วันที่บันทึกไม้ (ไม้ที่นำเข้าตั้ง created_at เป็นวันที่เทรดจริง)
+	tradeListDateColumn = "created_at"
+	// Analytics: วันที่ปิดไม้ ตรงกับ closed_time ที่ Calendar/Equity ใช้แบ่งวัน
+	closedTradeDateColumn = "COALESCE(closed_at, exit_time, updated_at)"
+)
+
+// applyTradeFilters - ตัวกรอง Pair/Side/ช่วงวันที่ของ TradeFilter (ใช้ร่วมกับ Analytics)
+// dateColumn = คอลัมน์ที่ใช้กรองช่วงวันที่ (tradeListDateColumn หรือ closedTradeDateColumn)
+func applyTradeFilters(query *gorm.DB, filter TradeFilter, dateColumn string) *gorm.DB {
+	if filter.Pair != "" {
+		query = query.Where("pair ILIKE ?", "%"+filter.Pair+"%")
+	}
+	if filter.Side != "" {
+		query = query.Where("side = ?", filter.Side)
+	}
+
+	// 🔥 Date Range Filter (ตาม Timezone ของผู้ใช้)
+	if filter.dateFrom != nil {
+		query = query.Where(dateColumn+" >= ?", *filter.dateFrom)
+	}
+	if filter.dateTo != nil {
+		query = query.Where(dateColumn+" < ?", *filter.dateTo)
+	}
+	return query
+}
+
 // GetTrade - ดึงข้อมูลเทรดเดียว
 // GET /api/trades/:id
 func GetTrade(c *fiber.Ctx) error {
@@ -390,7 +582,7 @@ func GetTrade(c *fiber.Ctx) error {
 	tradeID := c.Params("id")
 
 	var trade Trade
-	if err := database.DB.Where("id = ? AND user_id = ?", tradeID, userID).First(&trade).Error; err != nil {
+	if err := database.DB.Preload("Exits").Preload("Entries").Where("id = ? AND user_id = ?", tradeID, userID).First(&trade).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "ไม่พบข้อมูลการเทรดนี้",
 		})
@@ -420,22 +612,84 @@ func UpdateTrade(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Printf("📝 UpdateTrade: id=%d, status=%s, exit_price=%.2f, pnl=%.2f", trade.ID, req.Status, req.ExitPrice, req.PnL)
+	log.Printf("📝 UpdateTrade: id=%d, status=%s, exit_price=%s, pnl=%s", trade.ID, req.Status, req.ExitPrice, req.PnL)
+
+	// 🔥 Lifecycle: หาสถานะปลายทางก่อน แล้วเช็คว่าเปลี่ยนจากสถานะปัจจุบันได้ไหม
+	// status แบบเดิม (WIN/LOSS/BREAK_EVEN) = ขอปิดไม้ (CLOSED) พร้อมผลลัพธ์
+	target, outcome, ok := parseRequestedStatus(req.Status)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ไม่รู้จักสถานะนี้",
+			"field": "status",
+			"code":  "invalid_status",
+		})
+	}
+	if req.Outcome != "" {
+		if !services.IsOutcome(req.Outcome) {
+			return respondInvalidOutcome(c)
+		}
+		outcome = strings.ToUpper(req.Outcome)
+	}
+	if err := services.ValidateManualPnL(req.OverridePnL, req.PnL, req.PnLPercent, req.Outcome); err != nil {
+		_, respErr := respondValidationError(c, err)
+		return respErr
+	}
+	if !req.OverridePnL && req.ExitPrice.IsPositive() {
+		target = services.StatusClosed
+	}
+	if target == services.StatusClosed && !req.OverridePnL && !req.ExitPrice.IsPositive() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ต้องระบุ exit_price เพื่อปิดไม้ (หรือส่ง override_pnl: true ถ้าจะใส่ PnL เอง)",
+			"field": "exit_price",
+			"code":  "exit_price_required",
+		})
+	}
+	if target != "" {
+		if err := validateTradeTransition(trade, target); err != nil {
+			return respondTradeTxError(c, "UpdateTrade", err)
+		}
+	}
 
 	// Update fields
 	updates := make(map[string]interface{})
-	if req.ExitPrice > 0 {
+	var closing *services.ClosingResult
+	if req.ExitPrice.IsPositive() {
 		updates["exit_price"] = req.ExitPrice
 	}
-	if req.PnL != 0 {
-		updates["pnl"] = req.PnL
-	}
-	if req.PnLPercent != 0 {
-		updates["pnl_percent"] = req.PnLPercent
-	}
-	if req.Status != "" {
-		updates["status"] = req.Status
+
+	switch {
+	case req.OverridePnL:
+		// ผู้ใช้ยืนยันว่าจะใส่ตัวเลขเอง (เช่น ลอกจากหน้า Exchange ที่มี Funding ด้วย)
+		if !req.PnL.IsZero() {
+			updates["pnl"] = req.PnL
+		}
+		if !req.PnLPercent.IsZero() {
+			updates["pnl_percent"] = req.PnLPercent
+		}
+		if target == services.StatusClosed {
+			if outcome == "" {
+				outcome = outcomeFromPnL(req.PnL)
+			}
+			updates["outcome"] = outcome
+		}
+	case target == services.StatusClosed:
+		result, err := calculateTradeClosing(trade, req)
+		if err != nil {
+			if handled, respErr := respondValidationError(c, err); handled {
+				return respErr
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "คำนวณ PnL ไม่สำเร็จ",
+				"message": err.Error(),
+			})
+		}
+		closing = &result
+		updates["pnl"] = result.NetPnL
+		updates["pnl_percent"] = result.PnLPercentMargin
+		updates["fee"] = result.Fees
+		updates["outcome"] = result.Outcome
 	}
+
 	if req.Notes != "" {
 		updates["notes"] = req.Notes
 	}
@@ -446,33 +700,44 @@ func UpdateTrade(c *fiber.Ctx) error {
 		updates["closed_at"] = req.ClosedAt
 	}
 
-	// Auto-set ClosedAt if status is WIN/LOSS/BREAK_EVEN
-	if req.Status == "WIN" || req.Status == "LOSS" || req.Status == "BREAK_EVEN" {
-		now := time.Now()
-		if req.ClosedAt == nil {
-			updates["closed_at"] = &now
-		}
-		if req.ExitTime == nil {
-			updates["exit_time"] = &now
+	// บันทึกเวลาของการเปลี่ยนสถานะ (ถ้าส่งเวลามาเองจะใช้ค่าที่ส่งมา)
+	if target != "" && target != trade.Status {
+		for column, value := range transitionUpdates(target, time.Now()) {
+			if _, exists := updates[column]; !exists {
+				updates[column] = value
+			}
 		}
 	}
 
-	if err := database.DB.Model(&trade).Updates(updates).Error; err != nil {
+	before := trade
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&trade).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&trade, trade.ID).Error; err != nil {
+			return err
+		}
+		if err := syncTradeRMultiple(tx, &trade); err != nil {
+			return err
+		}
+		_, err := recordTradeRevision(tx, c, RevisionUpdate, &before, trade)
+		return err
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "ไม่สามารถอัพเดทได้",
 			"message": err.Error(),
 		})
 	}
 
-	database.DB.First(&trade, trade.ID)
-
 	return c.JSON(fiber.Map{
 		"message": "อัพเดทสำเร็จ! ✅",
 		"trade":   trade,
+		"closing": closing,
 	})
 }
 
-// DeleteTrade - ลบบันทึกการเทรด
+// DeleteTrade - ลบบันทึกการเทรด (Soft Delete ย้ายไปถังขยะ กู้คืนได้จนกว่าจะถูกลบถาวร)
 // DELETE /api/trades/:id
 func DeleteTrade(c *fiber.Ctx) error {
 	userID := GetCurrentUserID(c)
@@ -485,7 +750,14 @@ func DeleteTrade(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := database.DB.Delete(&trade).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&trade).Error; err != nil {
+			return err
+		}
+		_, err := recordTradeRevision(tx, c, RevisionDelete, &trade, trade)
+		return err
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "ไม่สามารถลบได้",
 			"message": err.Error(),
@@ -498,7 +770,85 @@ func DeleteTrade(c *fiber.Ctx) error {
 	})
 }
 
-// MigrateTradeModels - สร้าง Table trades ใน Database
+// MigrateTradeModels - สร้าง Table trades และตารางลูก (trade_exits, trade_entries, trade_revisions, trade_imports, imported_fills) ใน Database
 func MigrateTradeModels() error {
-	return database.DB.AutoMigrate(&Trade{})
+	// ยังไม่มีคอลัมน์ remaining_quantity = ฐานข้อมูลก่อนมีการทยอยปิดไม้ (เช็คก่อน AutoMigrate สร้างคอลัมน์เป็น 0 ทุกแถว)
+	backfillExits := database.DB.Migrator().HasTable(&Trade{}) && !database.DB.Migrator().HasColumn(&Trade{}, "RemainingQuantity")
+
+	if err := database.DB.AutoMigrate(&Trade{}, &TradeExit{}, &TradeEntry{}, &TradeRevision{}, &TradeImport{}, &ImportedFill{}); err != nil {
+		return err
+	}
+
+	// ข้อมูลเก่าเก็บผลไว้ใน status (WIN/LOSS/BREAK_EVEN) ย้ายไป outcome แล้วตั้ง status = CLOSED
+	// (ทำที่นี่ที่เดียว add_trade_lifecycle.sql เพิ่มแค่คอลัมน์/View)
+	if err := database.DB.Unscoped().Model(&Trade{}).
+		Where("status IN ?", []string{services.OutcomeWin, services.OutcomeLoss, services.OutcomeBreakEven}).
+		Updates(map[string]interface{}{"outcome": gorm.Expr("status"), "status": services.StatusClosed}).Error; err != nil {
+		return err
+	}
+
+	if err := backfillExitColumns(backfillExits); err != nil {
+		return err
+	}
+	if err := backfillNullDecimals(&Trade{}, &TradeExit{}, &TradeEntry{}, &TradeImport{}); err != nil {
+		return err
+	}
+	return backfillInitialRisk()
+}
+
+// exitColumnsBackfillSQL - ค่าตั้งต้นของคอลัมน์สรุปการขายออกสำหรับไม้ที่ไม่เคยขายผ่าน /exits
+// ไม้ที่ปิด/ยกเลิกแล้วไม่เหลือเหรียญ ไม้อื่นยังถือทั้งไม้ ราคาออกเฉลี่ย = exit_price ที่บันทึกไว้
+const exitColumnsBackfillSQL = `UPDATE trades SET
+	remaining_quantity = CASE WHEN status IN (?, ?) THEN 0
+		ELSE COALESCE(NULLIF(quantity, 0), position_size / NULLIF(entry_price, 0), 0) END,
+	avg_exit_price = COALESCE(exit_price, 0),
+	realized_pnl = COALESCE(realized_pnl, 0)`
+
+// backfillExitColumns - เติม remaining_quantity / avg_exit_price / realized_pnl ของไม้เก่า
+// (decimal.Decimal อ่าน NULL ไม่ได้ ถ้าเหลือ NULL ทุก Query ที่อ่านไม้นั้นพัง)
+// allRows = เพิ่งเพิ่มคอลัมน์ (ทุกแถวได้ 0 จาก Default) ไม่งั้นเติมเฉพาะแถวที่ยังเป็น NULL (เช่นเพิ่มคอลัมน์ด้วย SQL รุ่นเก่า)
+func backfillExitColumns(allRows bool) error {
+	sql := exitColumnsBackfillSQL
+	if !allRows {
+		sql += " WHERE remaining_quantity IS NULL OR avg_exit_price IS NULL OR realized_pnl IS NULL"
+	}
+	result := database.DB.Exec(sql, services.StatusClosed, services.StatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("📦 Backfill exit columns: %d trades", result.RowsAffected)
+	}
+	return nil
+}
+
+// backfillInitialRisk - ไม้ที่เปิดก่อนมีคอลัมน์ initial_risk ใช้ Risk ที่บันทึกไว้ตอนนี้เป็น Risk ตอนเปิด
+// แล้วคำนวณ R-Multiple ของไม้ที่ปิดแล้วที่ยังไม่มี (สูตรเดียวกับ tradeRMultiple ไม่ซ้ำไว้ใน SQL)
+func backfillInitialRisk() error {
+	var trades []Trade
+	filled := 0
+	err := database.DB.Unscoped().
+		Select("id", "status", "entry_price", "stop_loss", "quantity", "position_size", "max_loss", "pnl", "r_multiple").
+		Where("initial_risk IS NULL AND status <> ?", services.StatusPlanned).
+		FindInBatches(&trades, 500, func(_ *gorm.DB, _ int) error {
+			for _, trade := range trades {
+				risk := openingRisk(trade)
+				trade.InitialRisk = &risk
+				updates := map[string]interface{}{"initial_risk": risk}
+				if trade.RMultiple == nil {
+					if r := tradeRMultiple(trade); r != nil {
+						updates["r_multiple"] = *r
+					}
+				}
+				if err := database.DB.Unscoped().Model(&Trade{}).Where("id = ?", trade.ID).UpdateColumns(updates).Error; err != nil {
+					return err
+				}
+				filled++
+			}
+			return nil
+		}).Error
+	if filled > 0 {
+		log.Printf("📐 Backfill initial_risk: %d trades", filled)
+	}
+	return err
 }
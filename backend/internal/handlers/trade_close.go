@@ -0,0 +1,172 @@
+// Package handlers - ปิดไม้ (คำนวณ PnL ฝั่ง Server)
+// ใช้ services.CalculateClosingPnL แทนการเชื่อ pnl/status จาก Client
+package handlers
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/models"
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// closingInputForTrade - เตรียมข้อมูลคิด PnL จาก Trade + เงินในพอร์ตของ User
+func closingInputForTrade(trade Trade, exitPrice decimal.Decimal) services.ClosingInput {
+	input := services.ClosingInput{
+		Side:                      trade.Side,
+		EntryPrice:                trade.EntryPrice,
+		ExitPrice:                 exitPrice,
+		Quantity:                  trade.Quantity,
+		PositionSize:              trade.PositionSize,
+		Leverage:                  decimal.NewFromInt(int64(trade.Leverage)),
+		Fee:                       trade.Fee,
+		RiskAmount:                tradeInitialRisk(trade),
+		StopLoss:                  trade.StopLoss,
+		BreakEvenTolerancePercent: defaultBreakEvenTolerance(),
+	}
+
+	var user User
+	if err := database.DB.Select("portfolio_balance").First(&user, trade.UserID).Error; err == nil {
+		input.AccountBalance = user.PortfolioBalance
+	}
+	return input
+}
+
+// calculateTradeClosing - รวมข้อมูลจาก Trade + Request + User แล้วคำนวณ PnL ตอนปิด
+func calculateTradeClosing(trade Trade, req UpdateTradeRequest) (services.ClosingResult, error) {
+	// ถ้าทยอยขายไปแล้วบางส่วน ต้องปิดส่วนที่เหลือผ่าน /exits ไม่งั้น PnL จะนับซ้ำ
+	var executed int64
+	database.DB.Model(&TradeExit{}).Where("trade_id = ? AND status = ?", trade.ID, ExitStatusExecuted).Count(&executed)
+	if executed > 0 {
+		return services.ClosingResult{}, &services.ValidationError{
+			Field:   "exit_price",
+			Code:    "has_partial_exits",
+			Message: "ไม้นี้ทยอยปิดไปแล้วบางส่วน ให้ปิดส่วนที่เหลือผ่าน /api/trades/:id/exits",
+		}
+	}
+
+	input := closingInputForTrade(trade, req.ExitPrice)
+	if req.BreakEvenTolerancePercent.Valid {
+		input.BreakEvenTolerancePercent = req.BreakEvenTolerancePercent
+	}
+
+	// ถ้าส่ง Fee Rate หรือ Exchange มา ใช้อัตราจริงแทน Fee ที่ประมาณไว้ตอนเปิดไม้
+	if req.FeeRate.IsPositive() {
+		input.Fee = decimal.Zero
+		input.FeeRate = req.FeeRate
+	} else if req.Exchange != "" {
+		rate, err := lookupExchangeFeeRate(req.Exchange)
+		if err != nil {
+			return services.ClosingResult{}, &services.ValidationError{
+				Field:   "exchange",
+				Code:    "unknown_exchange",
+				Message: "ไม่พบ Exchange นี้",
+			}
+		}
+		input.Fee = decimal.Zero
+		input.FeeRate = rate
+	}
+
+	return services.CalculateClosingPnL(input)
+}
+
+// openingRisk - Risk จากค่าปัจจุบันของไม้ (MaxLoss หรือ |Entry − SL| × Quantity) ใช้บันทึกเป็น InitialRisk ตอนเปิดไม้
+func openingRisk(trade Trade) decimal.Decimal {
+	return services.InitialRisk(trade.MaxLoss, trade.EntryPrice, trade.StopLoss, tradeQuantity(trade)).Round(4)
+}
+
+// tradeInitialRisk - Risk ตอนเปิดไม้ที่บันทึกไว้ (ไม่เปลี่ยนตาม MaxLoss ที่ syncTradeEntries คำนวณใหม่ตอนเติมไม้)
+// ยังไม่ได้บันทึก หรือบันทึกเป็น 0 (เปิดโดยไม่มี SL) ใช้ค่าปัจจุบันแทน
+func tradeInitialRisk(trade Trade) decimal.Decimal {
+	if trade.InitialRisk != nil && trade.InitialRisk.IsPositive() {
+		return *trade.InitialRisk
+	}
+	return openingRisk(trade)
+}
+
+// recordInitialRisk - บันทึก InitialRisk ให้ไม้ที่เปิดแล้วแต่ยังไม่มี (หรือเป็น 0 และเพิ่งตั้ง SL) ค่าที่บันทึกแล้วไม่เปลี่ยนอีก
+func recordInitialRisk(tx *gorm.DB, trade *Trade) error {
+	if trade.Status == services.StatusPlanned {
+		return nil
+	}
+	if trade.InitialRisk != nil && (trade.InitialRisk.IsPositive() || !openingRisk(*trade).IsPositive()) {
+		return nil
+	}
+	risk := openingRisk(*trade)
+	if err := tx.Model(trade).UpdateColumn("initial_risk", risk).Error; err != nil {
+		return err
+	}
+	trade.InitialRisk = &risk
+	return nil
+}
+
+// tradeRMultiple - R-Multiple ของไม้ที่ปิดแล้ว (nil = ยังไม่ปิด หรือไม่รู้ Risk)
+func tradeRMultiple(trade Trade) *decimal.Decimal {
+	if trade.Status != services.StatusClosed {
+		return nil
+	}
+	return services.RMultiple(trade.PnL, tradeInitialRisk(trade))
+}
+
+// syncTradeRMultiple - คำนวณ R-Multiple ใหม่หลังแก้ไม้ (PnL, สถานะ อาจเปลี่ยน) แล้วบันทึกถ้าค่าเปลี่ยน
+// บันทึก InitialRisk ก่อนถ้าไม้เพิ่งเปิด (เช่น PLANNED → OPEN)
+func syncTradeRMultiple(tx *gorm.DB, trade *Trade) error {
+	if err := recordInitialRisk(tx, trade); err != nil {
+		return err
+	}
+	r := tradeRMultiple(*trade)
+	switch {
+	case r == nil && trade.RMultiple == nil:
+		return nil
+	case r != nil && trade.RMultiple != nil && r.Equal(*trade.RMultiple):
+		return nil
+	}
+
+	var value interface{} = gorm.Expr("NULL")
+	if r != nil {
+		value = *r
+	}
+	if err := tx.Model(trade).UpdateColumn("r_multiple", value).Error; err != nil {
+		return err
+	}
+	trade.RMultiple = r
+	return nil
+}
+
+// tradeQuantity - จำนวนเหรียญทั้งไม้ (ถ้าไม่ได้บันทึกไว้ คิดจาก PositionSize / EntryPrice)
+func tradeQuantity(trade Trade) decimal.Decimal {
+	if trade.Quantity.IsPositive() || !trade.EntryPrice.IsPositive() {
+		return trade.Quantity
+	}
+	return trade.PositionSize.Div(trade.EntryPrice)
+}
+
+// lookupExchangeFeeRate - หา Taker Fee ของ Exchange ตามชื่อแบบไม่สนตัวพิมพ์ (seeds เก็บ Binance, Bitkub, OKX)
+// ตาราง exchanges เก็บเป็น % จึงต้องหาร 100
+func lookupExchangeFeeRate(name string) (decimal.Decimal, error) {
+	var exchange models.Exchange
+	if err := database.DB.Where("LOWER(name) = ?", strings.ToLower(strings.TrimSpace(name))).First(&exchange).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return exchange.TakerFee.Div(decimal.NewFromInt(100)), nil
+}
+
+// defaultBreakEvenTolerance - อ่านช่วงเสมอตัวจาก ENV "BREAK_EVEN_TOLERANCE_PERCENT"
+// ถ้าไม่ตั้งไว้ ปล่อยให้ services ใช้ DefaultBreakEvenTolerancePercent
+func defaultBreakEvenTolerance() decimal.NullDecimal {
+	raw := strings.TrimSpace(os.Getenv("BREAK_EVEN_TOLERANCE_PERCENT"))
+	if raw == "" {
+		return decimal.NullDecimal{}
+	}
+	tolerance, err := decimal.NewFromString(raw)
+	if err != nil || tolerance.IsNegative() {
+		log.Printf("⚠️ BREAK_EVEN_TOLERANCE_PERCENT=%q ไม่ถูกต้อง ใช้ค่า Default", raw)
+		return decimal.NullDecimal{}
+	}
+	return decimal.NewNullDecimal(tolerance)
+}
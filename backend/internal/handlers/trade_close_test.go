@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"mmrrdikub/internal/services"
+)
+
+// TestTradeRMultipleUsesInitialRisk - เติมไม้แล้ว MaxLoss เปลี่ยน แต่ R ยังหารด้วย Risk ตอนเปิดไม้
+func TestTradeRMultipleUsesInitialRisk(t *testing.T) {
+	initial := decimal.NewFromInt(100)
+	trade := Trade{
+		Status:      services.StatusClosed,
+		EntryPrice:  decimal.NewFromInt(100),
+		StopLoss:    decimal.NewFromInt(90),
+		Quantity:    decimal.NewFromInt(20),
+		MaxLoss:     decimal.NewFromInt(200), // syncTradeEntries คำนวณใหม่หลังเติมไม้
+		PnL:         decimal.NewFromInt(300),
+		InitialRisk: &initial,
+	}
+	if r := tradeRMultiple(trade); r == nil || !r.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("R = %v, want 3 (หารด้วย initial_risk)", r)
+	}
+
+	// ข้อมูลเก่าที่ยังไม่มี initial_risk ใช้ MaxLoss ปัจจุบัน
+	trade.InitialRisk = nil
+	if r := tradeRMultiple(trade); r == nil || !r.Equal(decimal.RequireFromString("1.5")) {
+		t.Errorf("R = %v, want 1.5 (fallback max_loss)", r)
+	}
+
+	// เปิดโดยไม่มี SL (initial_risk = 0) แล้วตั้ง SL ทีหลัง ใช้ Risk จากค่าปัจจุบัน
+	zero := decimal.Zero
+	trade.InitialRisk, trade.MaxLoss = &zero, decimal.Zero
+	if r := tradeRMultiple(trade); r == nil || !r.Equal(decimal.RequireFromString("1.5")) {
+		t.Errorf("R = %v, want 1.5 (|entry − SL| × quantity)", r)
+	}
+
+	trade.Status = services.StatusOpen
+	if r := tradeRMultiple(trade); r != nil {
+		t.Errorf("ไม้ที่ยังไม่ปิด R = %v, want nil", r)
+	}
+}
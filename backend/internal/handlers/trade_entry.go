@@ -0,0 +1,271 @@
+// Package handlers - Entry Fills (ทยอยเข้าไม้ / DCA)
+// เก็บการเข้าไม้แต่ละครั้งเป็นตารางลูก แล้วคำนวณราคาเฉลี่ย/จำนวน/มูลค่า/Risk ของ Trade ใหม่ทุกครั้ง
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// TradeEntry - การเข้าไม้หนึ่งครั้งของ Trade (ตารางลูก)
+type TradeEntry struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	TradeID   uint            `gorm:"index;not null" json:"trade_id"`
+	UserID    uint            `gorm:"index;not null" json:"user_id"`
+	Price     decimal.Decimal `gorm:"type:decimal(24,8);not null" json:"price"`     // ราคาที่ได้จริง
+	Quantity  decimal.Decimal `gorm:"type:decimal(24,12);not null" json:"quantity"` // จำนวนเหรียญ
+	Fee       decimal.Decimal `gorm:"type:decimal(18,4)" json:"fee"`                // Fee ของ Fill นี้ (USD)
+	FilledAt  time.Time       `json:"filled_at"`                                    // เวลาที่ได้ของ
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AddEntryRequest - ข้อมูลการเติมไม้
+type AddEntryRequest struct {
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Fee      decimal.Decimal `json:"fee"`
+	FilledAt *time.Time      `json:"filled_at"` // ไม่ส่ง = ตอนนี้
+}
+
+// GetTradeEntries - ดู Fill ฝั่ง Entry ทั้งหมดพร้อมสรุป
+// GET /api/trades/:id/entries
+func GetTradeEntries(c *fiber.Ctx) error {
+	trade, err := findUserTrade(c)
+	if err != nil {
+		return tradeNotFound(c)
+	}
+
+	var entries []TradeEntry
+	database.DB.Where("trade_id = ?", trade.ID).Order("filled_at ASC").Find(&entries)
+
+	// ไม้เก่าที่ยังไม่เคยเติม ไม่มี Fill ในตาราง ให้ถือว่า Entry เดิมคือ Fill แรก
+	if len(entries) == 0 {
+		entries = []TradeEntry{initialTradeEntry(trade)}
+	}
+
+	return c.JSON(fiber.Map{
+		"entries": entries,
+		"summary": services.AggregateEntries(entryFills(entries)),
+	})
+}
+
+// AddTradeEntry - เติมไม้ แล้วคำนวณราคาเฉลี่ยและ Risk ใหม่
+// POST /api/trades/:id/entries
+func AddTradeEntry(c *fiber.Ctx) error {
+	var req AddEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"code":    "invalid_body",
+			"message": err.Error(),
+		})
+	}
+
+	var entry TradeEntry
+	var trade Trade
+	var summary services.EntryAggregate
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		if err := ensureEntriesEditable(tx, trade); err != nil {
+			return err
+		}
+		before := trade
+
+		if !req.Price.IsPositive() {
+			return &services.ValidationError{Field: "price", Code: "must_be_positive", Message: "ราคาต้องมากกว่า 0"}
+		}
+		if !req.Quantity.IsPositive() {
+			return &services.ValidationError{Field: "quantity", Code: "must_be_positive", Message: "จำนวนเหรียญต้องมากกว่า 0"}
+		}
+		if req.Fee.IsNegative() {
+			return &services.ValidationError{Field: "fee", Code: "must_not_be_negative", Message: "Fee ต้องไม่เป็นลบ"}
+		}
+
+		// เติมครั้งแรก: บันทึก Entry เดิมเป็น Fill แรกก่อน
+		// (ไม้ PLANNED ยังไม่ได้เข้าจริง Fill นี้คือ Fill แรก)
+		var count int64
+		tx.Model(&TradeEntry{}).Where("trade_id = ?", trade.ID).Count(&count)
+		if count == 0 && trade.Status == services.StatusOpen {
+			initial := initialTradeEntry(trade)
+			if err := tx.Create(&initial).Error; err != nil {
+				return err
+			}
+		}
+
+		filledAt := time.Now()
+		if req.FilledAt != nil {
+			filledAt = *req.FilledAt
+		}
+		entry = TradeEntry{
+			TradeID:  trade.ID,
+			UserID:   trade.UserID,
+			Price:    req.Price,
+			Quantity: req.Quantity,
+			Fee:      req.Fee,
+			FilledAt: filledAt,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		// ไม้ที่วางแผนไว้ได้ Fill แรกแล้ว = เปิดไม้ (PLANNED → OPEN)
+		opened := trade.Status == services.StatusPlanned
+		if opened {
+			if err := tx.Model(&trade).Updates(transitionUpdates(services.StatusOpen, filledAt)).Error; err != nil {
+				return err
+			}
+		}
+
+		summary, err = syncTradeEntries(tx, &trade, req.Fee)
+		if err != nil {
+			return err
+		}
+		// Risk ตอนเปิดไม้ คิดจาก Fill แรกที่เพิ่งรวมเสร็จ (ตัวหารของ R-Multiple)
+		if opened {
+			if err := recordInitialRisk(tx, &trade); err != nil {
+				return err
+			}
+		}
+		_, err = recordTradeRevision(tx, c, RevisionUpdate, &before, trade)
+		return err
+	})
+	if err != nil {
+		return respondTradeTxError(c, "AddTradeEntry", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "เติมไม้สำเร็จ! ➕",
+		"entry":   entry,
+		"summary": summary,
+		"trade":   trade,
+	})
+}
+
+// DeleteTradeEntry - ลบ Fill ที่บันทึกผิด แล้วคำนวณใหม่
+// DELETE /api/trades/:id/entries/:entryId
+func DeleteTradeEntry(c *fiber.Ctx) error {
+	var trade Trade
+	var summary services.EntryAggregate
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		if err := ensureEntriesEditable(tx, trade); err != nil {
+			return err
+		}
+
+		var entry TradeEntry
+		if err := tx.Where("id = ? AND trade_id = ?", c.Params("entryId"), trade.ID).First(&entry).Error; err != nil {
+			return err
+		}
+		var count int64
+		tx.Model(&TradeEntry{}).Where("trade_id = ?", trade.ID).Count(&count)
+		if count <= 1 {
+			return &errTradeConflict{Code: "last_entry", Message: "ลบ Fill สุดท้ายไม่ได้ (ถ้าไม่ได้เข้าไม้ให้ลบทั้ง Trade)"}
+		}
+
+		if err := tx.Delete(&entry).Error; err != nil {
+			return err
+		}
+		before := trade
+		summary, err = syncTradeEntries(tx, &trade, entry.Fee.Neg())
+		if err != nil {
+			return err
+		}
+		_, err = recordTradeRevision(tx, c, RevisionUpdate, &before, trade)
+		return err
+	})
+	if err != nil {
+		return respondTradeTxError(c, "DeleteTradeEntry", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "ลบ Fill สำเร็จ! 🗑️",
+		"summary": summary,
+		"trade":   trade,
+	})
+}
+
+// ensureEntriesEditable - เติม/ลบ Fill ได้เฉพาะไม้ PLANNED/OPEN ที่ยังไม่ได้ทยอยขาย
+// (ถ้าขายไปแล้วบางส่วน กำไรที่รับรู้ไปแล้วคิดจากราคาเฉลี่ยเดิม จะไม่ตรงกัน)
+func ensureEntriesEditable(tx *gorm.DB, trade Trade) error {
+	if trade.Status != services.StatusOpen && trade.Status != services.StatusPlanned {
+		return &errTradeConflict{Code: "trade_not_open", Message: "เติมไม้ได้เฉพาะไม้ที่ PLANNED หรือ OPEN"}
+	}
+	var executed int64
+	tx.Model(&TradeExit{}).Where("trade_id = ? AND status = ?", trade.ID, ExitStatusExecuted).Count(&executed)
+	if executed > 0 {
+		return &errTradeConflict{Code: "has_partial_exits", Message: "ไม้นี้ทยอยขายไปแล้ว เติมไม้เพิ่มไม่ได้"}
+	}
+	return nil
+}
+
+// initialTradeEntry - แปลง Entry เดิมของ Trade เป็น Fill แรก
+func initialTradeEntry(trade Trade) TradeEntry {
+	filledAt := trade.CreatedAt
+	if trade.EntryTime != nil {
+		filledAt = *trade.EntryTime
+	}
+	return TradeEntry{
+		TradeID:  trade.ID,
+		UserID:   trade.UserID,
+		Price:    trade.EntryPrice,
+		Quantity: tradeQuantity(trade),
+		FilledAt: filledAt,
+	}
+}
+
+// entryFills - แปลงเป็น Input ของ services
+func entryFills(entries []TradeEntry) []services.EntryFill {
+	fills := make([]services.EntryFill, len(entries))
+	for i, entry := range entries {
+		fills[i] = services.EntryFill{Price: entry.Price, Quantity: entry.Quantity, Fee: entry.Fee}
+	}
+	return fills
+}
+
+// syncTradeEntries - รวม Fill ทั้งหมดแล้วอัพเดท Trade
+// feeDelta = Fee ที่เพิ่ม/ลดจาก Fill นี้ (Trade.Fee เดิมเป็นค่าประมาณไป-กลับ จึงปรับตามส่วนต่างแทนการเขียนทับ)
+func syncTradeEntries(tx *gorm.DB, trade *Trade, feeDelta decimal.Decimal) (services.EntryAggregate, error) {
+	var entries []TradeEntry
+	if err := tx.Where("trade_id = ?", trade.ID).Find(&entries).Error; err != nil {
+		return services.EntryAggregate{}, err
+	}
+	agg := services.AggregateEntries(entryFills(entries))
+
+	updates := map[string]interface{}{
+		"entry_price":        agg.AvgEntryPrice,
+		"quantity":           agg.TotalQuantity,
+		"position_size":      agg.PositionSize,
+		"remaining_quantity": agg.TotalQuantity,
+		"fee":                decimal.Max(trade.Fee.Add(feeDelta), decimal.Zero),
+	}
+
+	// Risk ใหม่ = ขาดทุนถ้าโดน SL ที่ราคาเฉลี่ยใหม่ + Fee ฝั่งเข้าที่จ่ายไปแล้ว
+	if trade.StopLoss.IsPositive() {
+		maxLoss := services.PositionRisk(agg.AvgEntryPrice, agg.TotalQuantity, trade.StopLoss, decimal.Zero).Add(agg.TotalFee)
+		updates["max_loss"] = maxLoss.Round(4)
+
+		var user User
+		if err := tx.Select("portfolio_balance").First(&user, trade.UserID).Error; err == nil && user.PortfolioBalance.IsPositive() {
+			updates["risk_percent"] = maxLoss.Div(user.PortfolioBalance).Mul(decimal.NewFromInt(100)).Round(4)
+		}
+	}
+
+	if err := tx.Model(trade).Updates(updates).Error; err != nil {
+		return agg, err
+	}
+	return agg, tx.First(trade, trade.ID).Error
+}
@@ -0,0 +1,433 @@
+// Package handlers - Partial Exits (ทยอยปิดไม้)
+// วางแผนขาย, ขายจริง, ยกเลิก แล้วอัพเดทเหรียญที่เหลือ/กำไรที่รับรู้/ราคาออกเฉลี่ยของ Trade ให้อัตโนมัติ
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// สถานะของแต่ละ Exit
+const (
+	ExitStatusPlanned   = "PLANNED"
+	ExitStatusExecuted  = "EXECUTED"
+	ExitStatusCancelled = "CANCELLED"
+)
+
+// TradeExit - จุดขายออกบางส่วนของ Trade (ตารางลูก)
+type TradeExit struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	TradeID uint   `gorm:"index;not null" json:"trade_id"`
+	UserID  uint   `gorm:"index;not null" json:"user_id"`
+	Type    string `gorm:"size:10;default:'MANUAL'" json:"type"`    // TP, SL, MANUAL
+	Status  string `gorm:"size:20;default:'PLANNED'" json:"status"` // PLANNED, EXECUTED, CANCELLED
+
+	// === แผน ===
+	Price    decimal.Decimal `gorm:"type:decimal(24,8)" json:"price"`              // ราคาที่วางแผนไว้
+	Percent  decimal.Decimal `gorm:"type:decimal(10,4)" json:"percent"`            // % ของไม้
+	Quantity decimal.Decimal `gorm:"type:decimal(24,12);not null" json:"quantity"` // จำนวนเหรียญ
+
+	// === ผลตอนขายจริง ===
+	ExecutedPrice decimal.Decimal `gorm:"type:decimal(24,8)" json:"executed_price"`
+	Fee           decimal.Decimal `gorm:"type:decimal(18,4)" json:"fee"`
+	GrossPnL      decimal.Decimal `gorm:"column:gross_pnl;type:decimal(18,4)" json:"gross_pnl"`
+	RealizedPnL   decimal.Decimal `gorm:"column:realized_pnl;type:decimal(18,4)" json:"realized_pnl"`
+
+	ExecutedAt  *time.Time `json:"executed_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// PlanExitRequest - วางแผนขายออก ระบุเป็น % ของไม้ หรือจำนวนเหรียญก็ได้
+type PlanExitRequest struct {
+	Type     string          `json:"type"`     // TP, SL, MANUAL (Default MANUAL)
+	Price    decimal.Decimal `json:"price"`    // ราคาที่วางแผนไว้
+	Percent  decimal.Decimal `json:"percent"`  // % ของไม้ (เช่น 50)
+	Quantity decimal.Decimal `json:"quantity"` // จำนวนเหรียญ (ใช้แทน Percent)
+}
+
+// ExecuteExitRequest - บันทึกว่าขายจริงแล้ว
+type ExecuteExitRequest struct {
+	Price      decimal.Decimal     `json:"price"`       // ราคาที่ขายได้จริง (ไม่ส่ง = ใช้ราคาที่วางแผนไว้)
+	Fee        decimal.NullDecimal `json:"fee"`         // Fee จริง (USD)
+	FeeRate    decimal.Decimal     `json:"fee_rate"`    // หรือ Fee ต่อครั้ง ให้คำนวณให้
+	ExecutedAt *time.Time          `json:"executed_at"` // เวลาขาย (ไม่ส่ง = ตอนนี้)
+}
+
+// errTradeConflict - ทำรายการกับ Trade/Exit/Entry ที่สถานะไม่ถูกต้อง (ตอบ 409)
+type errTradeConflict struct {
+	Code    string
+	Message string
+}
+
+func (e *errTradeConflict) Error() string {
+	return e.Message
+}
+
+// GetTradeExits - ดูจุดขายออกทั้งหมดพร้อมสรุป
+// GET /api/trades/:id/exits
+func GetTradeExits(c *fiber.Ctx) error {
+	trade, err := findUserTrade(c)
+	if err != nil {
+		return tradeNotFound(c)
+	}
+
+	var exits []TradeExit
+	database.DB.Where("trade_id = ?", trade.ID).Order("created_at ASC").Find(&exits)
+
+	return c.JSON(fiber.Map{
+		"exits":   exits,
+		"summary": summarizeTradeExits(trade, exits),
+	})
+}
+
+// PlanTradeExit - วางแผนขายออกบางส่วน
+// POST /api/trades/:id/exits
+func PlanTradeExit(c *fiber.Ctx) error {
+	var req PlanExitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"code":    "invalid_body",
+			"message": err.Error(),
+		})
+	}
+
+	var exit TradeExit
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		trade, err := lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		if services.IsTerminalStatus(trade.Status) {
+			return &errTradeConflict{Code: "trade_not_open", Message: "ไม้นี้ปิดไปแล้ว วางแผนขายเพิ่มไม่ได้"}
+		}
+
+		exitType := strings.ToUpper(req.Type)
+		if exitType == "" {
+			exitType = "MANUAL"
+		}
+		if exitType != "TP" && exitType != "SL" && exitType != "MANUAL" {
+			return &services.ValidationError{Field: "type", Code: "invalid_type", Message: "Type ต้องเป็น TP, SL หรือ MANUAL"}
+		}
+		if req.Price.IsNegative() {
+			return &services.ValidationError{Field: "price", Code: "must_not_be_negative", Message: "ราคาต้องไม่เป็นลบ"}
+		}
+		if req.Percent.IsNegative() || req.Percent.GreaterThan(decimal.NewFromInt(100)) {
+			return &services.ValidationError{Field: "percent", Code: "out_of_range", Message: "Percent ต้องอยู่ระหว่าง 0-100"}
+		}
+
+		total := tradeQuantity(trade)
+		qty := req.Quantity
+		if !qty.IsPositive() {
+			qty = services.ExitQuantityFromPercent(total, req.Percent)
+		}
+		if err := services.ValidateExitAllocation(total, allocatedExitQuantity(tx, trade.ID), qty); err != nil {
+			return err
+		}
+
+		exit = TradeExit{
+			TradeID:  trade.ID,
+			UserID:   trade.UserID,
+			Type:     exitType,
+			Status:   ExitStatusPlanned,
+			Price:    req.Price,
+			Percent:  qty.Div(total).Mul(decimal.NewFromInt(100)).Round(4),
+			Quantity: qty,
+		}
+		return tx.Create(&exit).Error
+	})
+	if err != nil {
+		return respondTradeTxError(c, "PlanTradeExit", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "วางแผนขายออกสำเร็จ! 🎯",
+		"exit":    exit,
+	})
+}
+
+// ExecuteTradeExit - บันทึกการขายจริง แล้วอัพเดท Trade (ถ้าขายครบ 100% จะปิดไม้ให้)
+// POST /api/trades/:id/exits/:exitId/execute
+func ExecuteTradeExit(c *fiber.Ctx) error {
+	var req ExecuteExitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "ข้อมูลไม่ถูกต้อง",
+			"code":    "invalid_body",
+			"message": err.Error(),
+		})
+	}
+
+	var exit TradeExit
+	var trade Trade
+	var summary services.ExitSummary
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		if trade.Status != services.StatusOpen && trade.Status != services.StatusPartiallyClosed {
+			return &errTradeConflict{Code: "trade_not_open", Message: "ขายได้เฉพาะไม้ที่เปิดอยู่ (OPEN / PARTIALLY_CLOSED)"}
+		}
+		if err := tx.Where("id = ? AND trade_id = ?", c.Params("exitId"), trade.ID).First(&exit).Error; err != nil {
+			return err
+		}
+		if exit.Status != ExitStatusPlanned {
+			return &errTradeConflict{Code: "exit_not_planned", Message: "Exit นี้ขายไปแล้วหรือถูกยกเลิกแล้ว"}
+		}
+
+		price := req.Price
+		if !price.IsPositive() {
+			price = exit.Price
+		}
+		if !price.IsPositive() {
+			return &services.ValidationError{Field: "price", Code: "must_be_positive", Message: "ต้องระบุราคาที่ขายได้จริง"}
+		}
+		if req.Fee.Valid && req.Fee.Decimal.IsNegative() {
+			return &services.ValidationError{Field: "fee", Code: "must_not_be_negative", Message: "Fee ต้องไม่เป็นลบ"}
+		}
+		if req.FeeRate.IsNegative() {
+			return &services.ValidationError{Field: "fee_rate", Code: "must_not_be_negative", Message: "FeeRate ต้องไม่เป็นลบ"}
+		}
+
+		fill := services.ExitFill{Price: price, Quantity: exit.Quantity, Fee: exitFee(trade, exit.Quantity, price, req)}
+		gross, net := services.PartialExitPnL(trade.Side, trade.EntryPrice, fill)
+
+		executedAt := time.Now()
+		if req.ExecutedAt != nil {
+			executedAt = *req.ExecutedAt
+		}
+		exit.Status = ExitStatusExecuted
+		exit.ExecutedPrice = price
+		exit.Fee = fill.Fee.Round(4)
+		exit.GrossPnL = gross.Round(4)
+		exit.RealizedPnL = net.Round(4)
+		exit.ExecutedAt = &executedAt
+		if err := tx.Save(&exit).Error; err != nil {
+			return err
+		}
+
+		before := trade
+		summary, err = syncTradeExits(tx, &trade, executedAt)
+		if err != nil {
+			return err
+		}
+		_, err = recordTradeRevision(tx, c, RevisionUpdate, &before, trade)
+		return err
+	})
+	if err != nil {
+		return respondTradeTxError(c, "ExecuteTradeExit", err)
+	}
+
+	message := "บันทึกการขายสำเร็จ! 💰"
+	if summary.FullyExited {
+		message = "ขายครบ 100% ปิดไม้เรียบร้อย! ✅"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+		"exit":    exit,
+		"summary": summary,
+		"trade":   trade,
+	})
+}
+
+// CancelTradeExit - ยกเลิกแผนขายที่ยังไม่ได้ขาย
+// POST /api/trades/:id/exits/:exitId/cancel
+func CancelTradeExit(c *fiber.Ctx) error {
+	var exit TradeExit
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		trade, err := lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND trade_id = ?", c.Params("exitId"), trade.ID).First(&exit).Error; err != nil {
+			return err
+		}
+		if exit.Status != ExitStatusPlanned {
+			return &errTradeConflict{Code: "exit_not_planned", Message: "ยกเลิกได้เฉพาะ Exit ที่ยังไม่ได้ขาย"}
+		}
+
+		now := time.Now()
+		exit.Status = ExitStatusCancelled
+		exit.CancelledAt = &now
+		return tx.Save(&exit).Error
+	})
+	if err != nil {
+		return respondTradeTxError(c, "CancelTradeExit", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "ยกเลิกแผนขายสำเร็จ! 🗑️",
+		"exit":    exit,
+	})
+}
+
+// findUserTrade - หา Trade ของ User จาก :id
+func findUserTrade(c *fiber.Ctx) (Trade, error) {
+	var trade Trade
+	err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), GetCurrentUserID(c)).First(&trade).Error
+	return trade, err
+}
+
+// lockUserTrade - หา Trade ของ User แล้ว Lock แถวไว้ (กันขายซ้อนกันจนเกิน 100%)
+func lockUserTrade(tx *gorm.DB, c *fiber.Ctx) (Trade, error) {
+	var trade Trade
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", c.Params("id"), GetCurrentUserID(c)).
+		First(&trade).Error
+	return trade, err
+}
+
+// allocatedExitQuantity - จำนวนเหรียญที่วางแผน/ขายไปแล้ว (ไม่นับที่ยกเลิก)
+func allocatedExitQuantity(tx *gorm.DB, tradeID uint) decimal.Decimal {
+	var exits []TradeExit
+	tx.Where("trade_id = ? AND status <> ?", tradeID, ExitStatusCancelled).Find(&exits)
+
+	total := decimal.Zero
+	for _, exit := range exits {
+		total = total.Add(exit.Quantity)
+	}
+	return total
+}
+
+// exitFee - Fee ของการขายครั้งนี้
+// ลำดับ: Fee ที่ส่งมา → คิดจาก FeeRate → แบ่ง Fee ของทั้งไม้ตามสัดส่วนเหรียญ
+func exitFee(trade Trade, qty, price decimal.Decimal, req ExecuteExitRequest) decimal.Decimal {
+	if req.Fee.Valid {
+		return req.Fee.Decimal
+	}
+	if req.FeeRate.IsPositive() {
+		return qty.Mul(trade.EntryPrice.Add(price)).Mul(req.FeeRate)
+	}
+	total := tradeQuantity(trade)
+	if !total.IsPositive() {
+		return decimal.Zero
+	}
+	return trade.Fee.Mul(qty).Div(total)
+}
+
+// summarizeTradeExits - สรุปเฉพาะ Exit ที่ขายจริงแล้ว
+func summarizeTradeExits(trade Trade, exits []TradeExit) services.ExitSummary {
+	fills := make([]services.ExitFill, 0, len(exits))
+	for _, exit := range exits {
+		if exit.Status != ExitStatusExecuted {
+			continue
+		}
+		fills = append(fills, services.ExitFill{Price: exit.ExecutedPrice, Quantity: exit.Quantity, Fee: exit.Fee})
+	}
+	return services.SummarizeExits(trade.Side, trade.EntryPrice, tradeQuantity(trade), fills)
+}
+
+// syncTradeExits - คำนวณสรุปใหม่จาก Exit ทั้งหมด แล้วอัพเดท Trade
+// ถ้าขายครบ 100% จะปิดไม้: ExitPrice = ราคาออกเฉลี่ย, PnL/Fee รวม Fee ฝั่งเข้า และตัดสิน WIN/LOSS/BREAK_EVEN
+func syncTradeExits(tx *gorm.DB, trade *Trade, executedAt time.Time) (services.ExitSummary, error) {
+	var exits []TradeExit
+	if err := tx.Where("trade_id = ?", trade.ID).Find(&exits).Error; err != nil {
+		return services.ExitSummary{}, err
+	}
+	summary := summarizeTradeExits(*trade, exits)
+
+	updates := map[string]interface{}{
+		"remaining_quantity": summary.RemainingQuantity,
+		"realized_pnl":       summary.RealizedPnL,
+		"avg_exit_price":     summary.AvgExitPrice,
+	}
+
+	if !summary.FullyExited && summary.ExitedQuantity.IsPositive() && trade.Status == services.StatusOpen {
+		for column, value := range transitionUpdates(services.StatusPartiallyClosed, executedAt) {
+			updates[column] = value
+		}
+	}
+
+	if summary.FullyExited {
+		// Fee ฝั่งเข้า (สะสมไว้ใน trades.fee ตอนเติมไม้) ต้องหักจาก PnL ด้วย ไม่งั้นกำไรสูงเกินจริง
+		var entries []TradeEntry
+		if err := tx.Where("trade_id = ?", trade.ID).Find(&entries).Error; err != nil {
+			return summary, err
+		}
+		entryFees := services.AggregateEntries(entryFills(entries)).TotalFee
+
+		input := closingInputForTrade(*trade, summary.AvgExitPrice)
+		input.Fee = summary.Fees.Add(entryFees)
+		input.FeeRate = decimal.Zero
+		result, err := services.CalculateClosingPnL(input)
+		if err != nil {
+			return summary, err
+		}
+		settled := services.SettleExits(summary, entryFees, trade.EntryPrice.Mul(result.Quantity), input.BreakEvenTolerancePercent)
+
+		updates["exit_price"] = summary.AvgExitPrice
+		updates["pnl"] = settled.PnL
+		updates["pnl_percent"] = decimal.Zero
+		if result.Margin.IsPositive() {
+			updates["pnl_percent"] = settled.PnL.Div(result.Margin).Mul(decimal.NewFromInt(100)).Round(4)
+		}
+		updates["fee"] = settled.Fees
+		updates["outcome"] = settled.Outcome
+		for column, value := range transitionUpdates(services.StatusClosed, executedAt) {
+			updates[column] = value
+		}
+	}
+
+	if err := tx.Model(trade).Updates(updates).Error; err != nil {
+		return summary, err
+	}
+	if err := tx.First(trade, trade.ID).Error; err != nil {
+		return summary, err
+	}
+	return summary, syncTradeRMultiple(tx, trade)
+}
+
+// respondTradeTxError - แปลง Error จาก Transaction เป็น Response
+func respondTradeTxError(c *fiber.Ctx, action string, err error) error {
+	if handled, respErr := respondValidationError(c, err); handled {
+		return respErr
+	}
+
+	var transition *services.TransitionError
+	if errors.As(err, &transition) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": transition.Message,
+			"code":  transition.Code,
+			"from":  transition.From,
+			"to":    transition.To,
+		})
+	}
+
+	var conflict *errTradeConflict
+	if errors.As(err, &conflict) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": conflict.Message,
+			"code":  conflict.Code,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tradeNotFound(c)
+	}
+
+	log.Printf("❌ %s error: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "ไม่สามารถบันทึกได้",
+		"message": err.Error(),
+	})
+}
+
+// tradeNotFound - ตอบ 404 แบบเดียวกับ Handler อื่นๆ ของ Trade
+func tradeNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "ไม่พบข้อมูลการเทรดนี้",
+	})
+}
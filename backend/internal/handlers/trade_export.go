@@ -0,0 +1,210 @@
+// Package handlers - Export Trade Journal (CSV)
+// ส่งออกไม้ทั้งหมดตาม TradeFilter แบบ Stream ทีละแถว ไม่โหลดทั้ง Journal เข้า Memory
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// exportFlushEvery - Flush ออกไปหา Client ทุกกี่แถว
+const exportFlushEvery = 500
+
+// exportErrorMarker - คอลัมน์แรกของแถวสุดท้ายเมื่อ Export ไม่ครบ (ไฟล์ที่ครบไม่มีแถวนี้)
+// Status 200 ส่งไปแล้วตอนเริ่ม Stream จึงบอก Client ได้ทางเนื้อไฟล์เท่านั้น
+const exportErrorMarker = "#export_error"
+
+// errExportAborted - Client ตัดการเชื่อมต่อระหว่าง Export
+var errExportAborted = errors.New("client disconnected")
+
+// tradeExportColumn - หนึ่งคอลัมน์ของไฟล์ Export
+type tradeExportColumn struct {
+	Header string
+	Value  func(t Trade, loc *time.Location) string
+}
+
+// tradeExportColumns - ลำดับคอลัมน์คงที่ (เพิ่มคอลัมน์ใหม่ต่อท้ายเท่านั้น ไม่งั้นไฟล์ที่ผู้ใช้ Map ไว้จะเพี้ยน)
+var tradeExportColumns = []tradeExportColumn{
+	{"id", func(t Trade, _ *time.Location) string { return strconv.FormatUint(uint64(t.ID), 10) }},
+	{"pair", func(t Trade, _ *time.Location) string { return csvText(t.Pair) }},
+	{"side", func(t Trade, _ *time.Location) string { return t.Side }},
+	{"status", func(t Trade, _ *time.Location) string { return t.Status }},
+	{"outcome", func(t Trade, _ *time.Location) string { return t.Outcome }},
+	{"entry_price", func(t Trade, _ *time.Location) string { return t.EntryPrice.String() }},
+	{"exit_price", func(t Trade, _ *time.Location) string { return t.ExitPrice.String() }},
+	{"avg_exit_price", func(t Trade, _ *time.Location) string { return t.AvgExitPrice.String() }},
+	{"stop_loss", func(t Trade, _ *time.Location) string { return t.StopLoss.String() }},
+	{"take_profit", func(t Trade, _ *time.Location) string { return t.TakeProfit.String() }},
+	{"quantity", func(t Trade, _ *time.Location) string { return t.Quantity.String() }},
+	{"position_size", func(t Trade, _ *time.Location) string { return t.PositionSize.String() }},
+	{"leverage", func(t Trade, _ *time.Location) string { return strconv.Itoa(t.Leverage) }},
+	{"risk_percent", func(t Trade, _ *time.Location) string { return t.RiskPercent.String() }},
+	{"max_loss", func(t Trade, _ *time.Location) string { return t.MaxLoss.String() }},
+	{"max_win", func(t Trade, _ *time.Location) string { return t.MaxWin.String() }},
+	{"risk_reward_ratio", func(t Trade, _ *time.Location) string { return t.RiskRewardRatio.String() }},
+	{"fee", func(t Trade, _ *time.Location) string { return t.Fee.String() }},
+	{"pnl", func(t Trade, _ *time.Location) string { return t.PnL.String() }},
+	{"pnl_percent", func(t Trade, _ *time.Location) string { return t.PnLPercent.String() }},
+	{"realized_pnl", func(t Trade, _ *time.Location) string { return t.RealizedPnL.String() }},
+	{"r_multiple", func(t Trade, _ *time.Location) string { return csvDecimalPtr(t.RMultiple) }},
+	{"setup_score", func(t Trade, _ *time.Location) string { return strconv.Itoa(t.SetupScore) }},
+	{"tags", func(t Trade, _ *time.Location) string { return csvText(t.Tags) }},
+	{"entry_reason", func(t Trade, _ *time.Location) string { return csvText(t.EntryReason) }},
+	{"notes", func(t Trade, _ *time.Location) string { return csvText(t.Notes) }},
+	{"entry_time", func(t Trade, loc *time.Location) string { return csvTime(t.EntryTime, loc) }},
+	{"exit_time", func(t Trade, loc *time.Location) string { return csvTime(t.ExitTime, loc) }},
+	{"planned_at", func(t Trade, loc *time.Location) string { return csvTime(t.PlannedAt, loc) }},
+	{"opened_at", func(t Trade, loc *time.Location) string { return csvTime(t.OpenedAt, loc) }},
+	{"closed_at", func(t Trade, loc *time.Location) string { return csvTime(t.ClosedAt, loc) }},
+	{"cancelled_at", func(t Trade, loc *time.Location) string { return csvTime(t.CancelledAt, loc) }},
+	{"created_at", func(t Trade, loc *time.Location) string { return csvTime(&t.CreatedAt, loc) }},
+	{"updated_at", func(t Trade, loc *time.Location) string { return csvTime(&t.UpdatedAt, loc) }},
+}
+
+// ExportTrades - ส่งออก Journal เป็น CSV (กรองแบบเดียวกับ GET /api/trades แต่ไม่แบ่งหน้า)
+// GET /api/trades/export?format=csv&tz=Asia/Bangkok&status=&pair=&side=&date_from=&date_to=
+// tz = Timezone ของเวลาในไฟล์ (ไม่ส่ง = Timezone ที่ตั้งไว้ในบัญชี)
+// อ่านแถวพังระหว่าง Stream = แถวสุดท้ายของไฟล์เป็น exportErrorMarker
+func ExportTrades(c *fiber.Ctx) error {
+	filter, err := parseTradeFilter(c)
+	if err != nil {
+		return respondTradeTxError(c, "ExportTrades", err)
+	}
+	if format := strings.ToLower(c.Query("format", "csv")); format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "รองรับเฉพาะ format=csv",
+			"field": "format",
+			"code":  "invalid_format",
+		})
+	}
+	loc := filter.location()
+	if tz := c.Query("tz"); tz != "" {
+		if loc, err = services.LoadTimezone(tz); err != nil {
+			_, respErr := respondValidationError(c, err)
+			return respErr
+		}
+	}
+
+	userID := GetCurrentUserID(c)
+	query, ok := applyTradeStatusFilter(database.DB.Model(&Trade{}).Where("user_id = ?", userID), filter.Status)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ไม่รู้จักสถานะนี้",
+			"field": "status",
+			"code":  "invalid_status",
+		})
+	}
+	if query, ok = applyTradeOutcomeFilter(query, filter.Outcome); !ok {
+		return respondInvalidOutcome(c)
+	}
+
+	// เปิด Cursor ก่อนเริ่มส่ง ถ้า Query พังยังตอบ 500 ได้ (หลังเริ่ม Stream แล้วเปลี่ยน Status ไม่ได้)
+	rows, err := applyTradeFilters(query, filter, tradeListDateColumn).Order("created_at ASC, id ASC").Rows()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	filename := fmt.Sprintf("trades-%s.csv", time.Now().In(loc).Format("20060102-150405"))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer rows.Close()
+
+		count, err := writeTradeCSV(w, loc, func(trade *Trade) (bool, error) {
+			if !rows.Next() {
+				return false, rows.Err()
+			}
+			return true, database.DB.ScanRows(rows, trade)
+		})
+		switch {
+		case errors.Is(err, errExportAborted):
+			log.Printf("⚠️ ExportTrades aborted after %d rows (user %d)", count, userID)
+		case err != nil:
+			log.Printf("❌ ExportTrades error after %d rows (user %d): %v", count, userID, err)
+		default:
+			log.Printf("📤 ExportTrades: user %d, %d rows", userID, count)
+		}
+	})
+	return nil
+}
+
+// writeTradeCSV - เขียน Header แล้วตามด้วยทุกแถวที่ next ให้มา (next คืน false = หมดแล้ว)
+// next Error = ปิดท้ายด้วยแถว exportErrorMarker แทนที่จะจบเหมือนไฟล์ที่ครบ
+func writeTradeCSV(w *bufio.Writer, loc *time.Location, next func(*Trade) (bool, error)) (int, error) {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(tradeExportColumns))
+	for i, column := range tradeExportColumns {
+		header[i] = column.Header
+	}
+	writer.Write(header)
+
+	record := make([]string, len(tradeExportColumns))
+	count := 0
+	for {
+		var trade Trade
+		more, err := next(&trade)
+		if err != nil {
+			writer.Write([]string{exportErrorMarker, fmt.Sprintf("ไฟล์ไม่ครบ: Export หยุดหลัง %d แถว กรุณา Export ใหม่", count)})
+			writer.Flush()
+			return count, err
+		}
+		if !more {
+			break
+		}
+		for i, column := range tradeExportColumns {
+			record[i] = column.Value(trade, loc)
+		}
+		writer.Write(record)
+
+		count++
+		if count%exportFlushEvery == 0 {
+			writer.Flush()
+			// Client ตัดการเชื่อมต่อไปแล้ว หยุดอ่านต่อ
+			if writer.Error() != nil || w.Flush() != nil {
+				return count, errExportAborted
+			}
+		}
+	}
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// csvTime - เวลาแบบ ISO 8601 (RFC 3339) ตาม Timezone ที่เลือก ("" = ไม่มีค่า)
+func csvTime(t *time.Time, loc *time.Location) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// csvDecimalPtr - ตัวเลขที่อาจไม่มีค่า ("" = ไม่มีค่า)
+func csvDecimalPtr(value *decimal.Decimal) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+// csvText - กัน CSV/Formula Injection: ข้อความที่ขึ้นต้นด้วย = + - @ จะถูก Excel ตีความเป็นสูตร
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestTradeExportColumnsOrder - ลำดับคอลัมน์ห้ามเปลี่ยน (เพิ่มใหม่ต่อท้ายเท่านั้น)
+func TestTradeExportColumnsOrder(t *testing.T) {
+	want := []string{
+		"id", "pair", "side", "status", "outcome",
+		"entry_price", "exit_price", "avg_exit_price", "stop_loss", "take_profit",
+		"quantity", "position_size", "leverage", "risk_percent", "max_loss", "max_win",
+		"risk_reward_ratio", "fee", "pnl", "pnl_percent", "realized_pnl", "r_multiple",
+		"setup_score", "tags", "entry_reason", "notes",
+		"entry_time", "exit_time", "planned_at", "opened_at", "closed_at", "cancelled_at",
+		"created_at", "updated_at",
+	}
+	got := make([]string, len(tradeExportColumns))
+	for i, column := range tradeExportColumns {
+		got[i] = column.Header
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("header = %v\nwant %v", got, want)
+	}
+}
+
+// TestCSVText - ข้อความที่ Excel ตีความเป็นสูตรต้องขึ้นต้นด้วย '
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"BTCUSDT", "BTCUSDT"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+2", "'+1+2"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+		{"ซื้อตามเทรนด์", "ซื้อตามเทรนด์"},
+	}
+	for _, tc := range tests {
+		if got := csvText(tc.value); got != tc.want {
+			t.Errorf("csvText(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+// TestCSVTime - RFC 3339 ตาม Timezone ที่เลือก และไม่มีค่า = ""
+func TestCSVTime(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	at := time.Date(2025, 1, 31, 20, 30, 0, 0, time.UTC)
+	if got := csvTime(&at, bangkok); got != "2025-02-01T03:30:00+07:00" {
+		t.Errorf("csvTime(bangkok) = %q", got)
+	}
+	if got := csvTime(&at, time.UTC); got != "2025-01-31T20:30:00Z" {
+		t.Errorf("csvTime(utc) = %q", got)
+	}
+	if got := csvTime(nil, time.UTC); got != "" {
+		t.Errorf("csvTime(nil) = %q", got)
+	}
+	if got := csvTime(&time.Time{}, time.UTC); got != "" {
+		t.Errorf("csvTime(zero) = %q", got)
+	}
+}
+
+// TestCSVDecimalPtr - nil = "" ส่วน 0 ยังเป็น "0" (แยกไม่มีค่ากับศูนย์)
+func TestCSVDecimalPtr(t *testing.T) {
+	if got := csvDecimalPtr(nil); got != "" {
+		t.Errorf("csvDecimalPtr(nil) = %q", got)
+	}
+	zero := decimal.Zero
+	if got := csvDecimalPtr(&zero); got != "0" {
+		t.Errorf("csvDecimalPtr(0) = %q", got)
+	}
+	r := decimal.RequireFromString("-1.25")
+	if got := csvDecimalPtr(&r); got != "-1.25" {
+		t.Errorf("csvDecimalPtr(-1.25) = %q", got)
+	}
+}
+
+// exportRecords - เขียน CSV จากรายการ Trade (failAfter >= 0 = next Error หลังส่งไปเท่านั้นแถว) แล้วอ่านกลับ
+func exportRecords(t *testing.T, trades []Trade, failAfter int) ([][]string, int, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	i := 0
+	count, err := writeTradeCSV(w, time.UTC, func(trade *Trade) (bool, error) {
+		if i == failAfter {
+			return false, errors.New("scan failed")
+		}
+		if i >= len(trades) {
+			return false, nil
+		}
+		*trade = trades[i]
+		i++
+		return true, nil
+	})
+	if flushErr := w.Flush(); flushErr != nil {
+		t.Fatal(flushErr)
+	}
+	reader := csv.NewReader(&buf)
+	reader.FieldsPerRecord = -1
+	records, readErr := reader.ReadAll()
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return records, count, err
+}
+
+// TestWriteTradeCSV - ไฟล์ที่ครบไม่มีแถว Error ส่วนไฟล์ที่อ่านพังกลางทางปิดท้ายด้วย exportErrorMarker
+func TestWriteTradeCSV(t *testing.T) {
+	trades := []Trade{{Pair: "BTCUSDT", Side: "LONG"}, {Pair: "=cmd", Side: "SHORT"}}
+
+	records, count, err := exportRecords(t, trades, -1)
+	if err != nil || count != 2 || len(records) != 3 {
+		t.Fatalf("complete export: count=%d err=%v records=%v", count, err, records)
+	}
+	if records[2][1] != "'=cmd" {
+		t.Errorf("pair = %q, want escaped", records[2][1])
+	}
+	for _, record := range records {
+		if record[0] == exportErrorMarker {
+			t.Errorf("complete export มีแถว Error: %v", record)
+		}
+	}
+
+	records, count, err = exportRecords(t, trades, 1)
+	if err == nil || count != 1 {
+		t.Fatalf("failed export: count=%d err=%v", count, err)
+	}
+	if len(records) != 3 || records[2][0] != exportErrorMarker {
+		t.Errorf("failed export ต้องปิดท้ายด้วย %s: %v", exportErrorMarker, records)
+	}
+}
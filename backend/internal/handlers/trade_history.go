@@ -0,0 +1,340 @@
+// Package handlers - Trade History (Audit Trail)
+// ทุกการสร้าง/แก้ไข/ลบ Trade ถูกบันทึกเป็น Revision ที่แก้ไม่ได้ พร้อมฟิลด์ที่เปลี่ยน ผู้แก้ เวลา และ IP
+// ย้อนกลับไป Revision เก่าได้ (ตัวการย้อนก็ถูกบันทึกเป็น Revision ใหม่)
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// ประเภทของ Revision
+const (
+	RevisionCreate  = "CREATE"
+	RevisionUpdate  = "UPDATE"
+	RevisionDelete  = "DELETE"
+	RevisionRevert  = "REVERT"
+	RevisionRestore = "RESTORE" // กู้คืนจากถังขยะ
+	RevisionPurge   = "PURGE"   // ลบถาวร (ประวัติยังอยู่)
+)
+
+// TradeRevision - ประวัติการแก้ไข Trade หนึ่งครั้ง (Append-only ห้ามแก้/ลบ)
+type TradeRevision struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	TradeID      uint          `gorm:"not null;uniqueIndex:idx_trade_revisions_trade_revision" json:"trade_id"`
+	Revision     int           `gorm:"not null;uniqueIndex:idx_trade_revisions_trade_revision" json:"revision"` // ลำดับของ Trade นี้ (1, 2, 3, ...)
+	UserID       uint          `gorm:"index;not null" json:"user_id"`                                           // เจ้าของ Trade
+	ActorUserID  uint          `gorm:"not null" json:"actor_user_id"`                                           // คนที่แก้ (0 = ระบบ)
+	Action       string        `gorm:"size:20;not null" json:"action"`                                          // CREATE, UPDATE, DELETE, REVERT, RESTORE, PURGE
+	Changes      ChangeSet     `gorm:"type:jsonb" json:"changes"`                                               // ฟิลด์ที่เปลี่ยน {field, old, new}
+	Snapshot     TradeSnapshot `gorm:"type:jsonb" json:"snapshot"`                                              // Trade ทั้งก้อนหลังแก้ (ใช้ย้อนกลับ)
+	RevertedFrom *uint         `json:"reverted_from,omitempty"`                                                 // ย้อนกลับมาจาก Revision ไหน
+	IP           string        `gorm:"size:64" json:"ip"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// errRevisionImmutable - กันโค้ดส่วนอื่นแก้/ลบประวัติ
+var errRevisionImmutable = errors.New("trade revision แก้ไขหรือลบไม่ได้")
+
+// errRevisionNotFound - ไม่พบ Revision ที่จะย้อนกลับ
+var errRevisionNotFound = errors.New("ไม่พบ Revision นี้")
+
+// BeforeUpdate - Revision แก้ไม่ได้
+func (r *TradeRevision) BeforeUpdate(tx *gorm.DB) error {
+	return errRevisionImmutable
+}
+
+// BeforeDelete - Revision ลบไม่ได้
+func (r *TradeRevision) BeforeDelete(tx *gorm.DB) error {
+	return errRevisionImmutable
+}
+
+// ChangeSet - รายการฟิลด์ที่เปลี่ยน เก็บเป็น JSON
+type ChangeSet []services.FieldChange
+
+// Value - แปลงเป็น JSON ก่อนเขียนลง DB
+func (c ChangeSet) Value() (driver.Value, error) {
+	if c == nil {
+		c = ChangeSet{}
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Scan - อ่าน JSON จาก DB
+func (c *ChangeSet) Scan(value interface{}) error {
+	return scanJSONColumn(value, c)
+}
+
+// TradeSnapshot - ค่าทุกฟิลด์ของ Trade ณ เวลาหนึ่ง (key = ชื่อฟิลด์ตาม JSON)
+type TradeSnapshot map[string]interface{}
+
+// Value - แปลงเป็น JSON ก่อนเขียนลง DB
+func (s TradeSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+// Scan - อ่าน JSON จาก DB
+func (s *TradeSnapshot) Scan(value interface{}) error {
+	return scanJSONColumn(value, s)
+}
+
+// scanJSONColumn - อ่านคอลัมน์ jsonb (ตัวเลขเก็บเป็น json.Number ไม่ให้ทศนิยมเพี้ยน)
+func scanJSONColumn(value interface{}, dest interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("อ่าน JSON column ไม่ได้: %T", value)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(dest)
+}
+
+// snapshotTrade - แปลง Trade เป็น Snapshot (ไม่รวมตารางลูก Exits/Entries)
+func snapshotTrade(trade Trade) (TradeSnapshot, error) {
+	trade.Exits = nil
+	trade.Entries = nil
+	data, err := json.Marshal(trade)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot TradeSnapshot
+	return snapshot, scanJSONColumn(data, &snapshot)
+}
+
+// recordTradeRevision - บันทึก Revision ใน Transaction เดียวกับการแก้ Trade
+// before = nil ตอนสร้าง, ถ้าแก้แล้วไม่มีฟิลด์ไหนเปลี่ยนจะไม่บันทึก (คืน nil)
+func recordTradeRevision(tx *gorm.DB, c *fiber.Ctx, action string, before *Trade, after Trade) (*TradeRevision, error) {
+	revision, err := buildTradeRevision(GetCurrentUserID(c), clientIP(c), action, before, after)
+	if err != nil || revision == nil {
+		return nil, err
+	}
+	return revision, saveTradeRevision(tx, revision)
+}
+
+// buildTradeRevision - เตรียม Revision (ยังไม่บันทึก) คืน nil ถ้าไม่มีอะไรเปลี่ยน
+// actorID = 0 และ ip = "" สำหรับงานเบื้องหลัง (เช่น ลบถาวรตามระยะเวลา)
+func buildTradeRevision(actorID uint, ip string, action string, before *Trade, after Trade) (*TradeRevision, error) {
+	snapshot, err := snapshotTrade(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []services.FieldChange
+	switch {
+	case action == RevisionDelete:
+		changes = []services.FieldChange{{Field: "deleted_at", Old: nil, New: time.Now()}}
+	case action == RevisionRestore:
+		changes = []services.FieldChange{{Field: "deleted_at", Old: before.DeletedAt.Time, New: nil}}
+	case action == RevisionPurge:
+		changes = []services.FieldChange{{Field: "purged_at", Old: nil, New: time.Now()}}
+	case before == nil:
+		changes = services.DiffSnapshots(nil, snapshot, "updated_at")
+	default:
+		previous, err := snapshotTrade(*before)
+		if err != nil {
+			return nil, err
+		}
+		changes = services.DiffSnapshots(previous, snapshot, "updated_at")
+		if len(changes) == 0 {
+			return nil, nil
+		}
+	}
+
+	return &TradeRevision{
+		TradeID:     after.ID,
+		UserID:      after.UserID,
+		ActorUserID: actorID,
+		Action:      action,
+		Changes:     changes,
+		Snapshot:    snapshot,
+		IP:          ip,
+	}, nil
+}
+
+// saveTradeRevision - ใส่ลำดับ Revision ถัดไปแล้วบันทึก
+// (Unique index trade_id + revision กันลำดับซ้ำถ้ามีการแก้พร้อมกัน)
+func saveTradeRevision(tx *gorm.DB, revision *TradeRevision) error {
+	var last int
+	if err := tx.Model(&TradeRevision{}).Where("trade_id = ?", revision.TradeID).
+		Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+	revision.Revision = last + 1
+	return tx.Create(revision).Error
+}
+
+// GetTradeHistory - ดูประวัติการแก้ไขทั้งหมดของ Trade (ล่าสุดก่อน)
+// GET /api/trades/:id/history
+func GetTradeHistory(c *fiber.Ctx) error {
+	// Trade ที่ลบไปแล้วก็ยังดูประวัติได้
+	var trade Trade
+	if err := database.DB.Unscoped().Where("id = ? AND user_id = ?", c.Params("id"), GetCurrentUserID(c)).First(&trade).Error; err != nil {
+		return tradeNotFound(c)
+	}
+
+	var revisions []TradeRevision
+	if err := database.DB.Where("trade_id = ?", trade.ID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงประวัติได้",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"trade_id":  trade.ID,
+		"revisions": revisions,
+		"total":     len(revisions),
+	})
+}
+
+// revertableColumns - คอลัมน์ที่ย้อนกลับได้ (ไม่รวม id, user_id, created_at, updated_at, deleted_at)
+var revertableColumns = []string{
+	"pair", "side", "stop_loss", "take_profit", "leverage",
+	"risk_percent", "max_win", "max_loss", "risk_reward_ratio", "fee",
+	"entry_reason", "setup_score", "notes", "tags",
+	"exit_price", "pnl", "pnl_percent", "status", "outcome",
+	"entry_time", "exit_time", "planned_at", "opened_at", "partially_closed_at", "closed_at", "cancelled_at",
+	"entry_price", "quantity", "position_size", "remaining_quantity", "realized_pnl", "avg_exit_price",
+}
+
+// fillDerivedColumns - คอลัมน์ที่คำนวณจาก Entry/Exit Fills ถ้ามี Fill แล้วห้ามย้อน (ตัวเลขจะไม่ตรงกับตารางลูก)
+var fillDerivedColumns = map[string]bool{
+	"entry_price": true, "quantity": true, "position_size": true,
+	"remaining_quantity": true, "realized_pnl": true, "avg_exit_price": true,
+}
+
+// RevertTrade - ย้อน Trade กลับไปเป็นค่าของ Revision ที่เลือก
+// POST /api/trades/:id/history/:revisionId/revert
+func RevertTrade(c *fiber.Ctx) error {
+	var trade Trade
+	var revision *TradeRevision
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockUserTrade(tx, c)
+		if err != nil {
+			return err
+		}
+
+		var target TradeRevision
+		if err := tx.Where("id = ? AND trade_id = ?", c.Params("revisionId"), trade.ID).First(&target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRevisionNotFound
+			}
+			return err
+		}
+
+		restored, err := tradeFromSnapshot(target.Snapshot)
+		if err != nil {
+			return err
+		}
+		restored.ID = trade.ID
+		restored.UserID = trade.UserID
+
+		// ย้อนได้ตามเส้นทาง Lifecycle เท่านั้น (เช่น ไม้ CLOSED ย้อนกลับเป็น OPEN ไม่ได้)
+		if err := services.ValidateTransition(trade.Status, restored.Status); err != nil {
+			return err
+		}
+		if err := ensureRevertKeepsFills(tx, trade, restored); err != nil {
+			return err
+		}
+
+		before := trade
+		if err := tx.Model(&trade).Select(revertableColumns).Updates(&restored).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&trade, trade.ID).Error; err != nil {
+			return err
+		}
+		if err := syncTradeRMultiple(tx, &trade); err != nil {
+			return err
+		}
+
+		revision, err = buildTradeRevision(GetCurrentUserID(c), clientIP(c), RevisionRevert, &before, trade)
+		if err != nil || revision == nil {
+			return err
+		}
+		revision.RevertedFrom = &target.ID
+		return saveTradeRevision(tx, revision)
+	})
+	if errors.Is(err, errRevisionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": errRevisionNotFound.Error(),
+		})
+	}
+	if err != nil {
+		return respondTradeTxError(c, "RevertTrade", err)
+	}
+
+	message := "ย้อนกลับสำเร็จ! ⏪"
+	if revision == nil {
+		message = "ค่าปัจจุบันตรงกับ Revision นี้อยู่แล้ว"
+	}
+	return c.JSON(fiber.Map{
+		"message":  message,
+		"trade":    trade,
+		"revision": revision,
+	})
+}
+
+// tradeFromSnapshot - แปลง Snapshot กลับเป็น Trade
+func tradeFromSnapshot(snapshot TradeSnapshot) (Trade, error) {
+	var trade Trade
+	if snapshot == nil {
+		return trade, &errTradeConflict{Code: "empty_snapshot", Message: "Revision นี้ไม่มีข้อมูลให้ย้อนกลับ"}
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return trade, err
+	}
+	return trade, json.Unmarshal(data, &trade)
+}
+
+// ensureRevertKeepsFills - ไม้ที่มี Entry Fill หรือขายไปแล้ว ห้ามย้อนตัวเลขที่คำนวณจาก Fill
+func ensureRevertKeepsFills(tx *gorm.DB, current, restored Trade) error {
+	var fills, executed int64
+	tx.Model(&TradeEntry{}).Where("trade_id = ?", current.ID).Count(&fills)
+	tx.Model(&TradeExit{}).Where("trade_id = ? AND status = ?", current.ID, ExitStatusExecuted).Count(&executed)
+	if fills == 0 && executed == 0 {
+		return nil
+	}
+
+	currentSnapshot, err := snapshotTrade(current)
+	if err != nil {
+		return err
+	}
+	restoredSnapshot, err := snapshotTrade(restored)
+	if err != nil {
+		return err
+	}
+	for _, change := range services.DiffSnapshots(currentSnapshot, restoredSnapshot) {
+		if fillDerivedColumns[change.Field] {
+			return &errTradeConflict{
+				Code:    "has_fills",
+				Message: fmt.Sprintf("ไม้นี้มี Fill แล้ว ย้อน %s ไม่ได้ (แก้ที่ Entry/Exit แทน)", change.Field),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+// Package handlers - Import Trades จากไฟล์ของ Exchange/Broker
+// อ่านไฟล์ด้วย Importer ของ services → รวม Fill เป็นไม้ไป-กลับ → แสดงตัวอย่าง (Dry-run) → บันทึกจริงพร้อม Entry/Exit Fills
+// กันนำเข้าซ้ำด้วย Trade ID ของ Exchange ที่เก็บไว้ใน imported_fills
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// สถานะของแต่ละไม้ในผลตัวอย่าง
+const (
+	ImportStatusNew              = "new"               // ยังไม่เคยนำเข้า จะถูกบันทึก
+	ImportStatusDuplicate        = "duplicate"         // นำเข้าไปแล้วครบทุก Fill ข้าม
+	ImportStatusPartialDuplicate = "partial_duplicate" // เคยนำเข้าบาง Fill (ไฟล์ทับช่วงกัน) ข้าม ต้องแก้เอง
+)
+
+// importLockNamespace - Advisory Lock ต่อ User กันนำเข้าไฟล์เดียวกันพร้อมกันจนได้ไม้ซ้ำ
+const importLockNamespace = 4201
+
+// TradeImport - ประวัติการนำเข้าแต่ละครั้ง
+type TradeImport struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"index;not null" json:"user_id"`
+	Source        string    `gorm:"size:30;not null" json:"source"` // ชื่อ Importer เช่น binance_futures, okx, mt5
+	Filename      string    `gorm:"size:255" json:"filename"`
+	Fills         int       `json:"fills"`
+	TradesCreated int       `json:"trades_created"`
+	Skipped       int       `json:"skipped"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ImportedFill - Trade ID ของ Exchange ที่นำเข้าแล้ว (ห้ามซ้ำต่อ User + แหล่งข้อมูล)
+type ImportedFill struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_imported_fills_external" json:"user_id"`
+	Source     string    `gorm:"size:30;not null;uniqueIndex:idx_imported_fills_external" json:"source"`
+	ExternalID string    `gorm:"size:100;not null;uniqueIndex:idx_imported_fills_external" json:"external_id"`
+	TradeID    uint      `gorm:"index;not null" json:"trade_id"`
+	ImportID   uint      `gorm:"index;not null" json:"import_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ImportPreviewTrade - ไม้หนึ่งไม้ในผลตัวอย่าง
+type ImportPreviewTrade struct {
+	services.ImportedTrade
+	ImportStatus string `json:"import_status"`
+	TradeID      *uint  `json:"trade_id,omitempty"` // ID ที่บันทึกแล้ว (เฉพาะตอน Commit)
+}
+
+// ImportTrades - นำเข้าไม้จากไฟล์ของ Exchange/Broker
+// POST /api/trades/import (multipart/form-data)
+//
+//	file        = ไฟล์ CSV หรือ Statement (HTML)
+//	source      = ชื่อ Importer (GET /api/trades/importers) | auto (Default: ตรวจจากเนื้อไฟล์)
+//	timezone    = Timezone ของเวลาในไฟล์ที่ไม่ได้ระบุ Timezone (Default: ตามแต่ละ Importer ส่วนใหญ่ UTC)
+//	columns     = generic_csv: JSON {"time":"หัวคอลัมน์", "symbol":..., "side":..., "price":..., "quantity":..., "fee":...}
+//	time_layout = generic_csv: รูปแบบเวลาแบบ Go เช่น 02/01/2006 15:04
+//	dry_run     = true (Default: แสดงตัวอย่างอย่างเดียว) | false (บันทึกจริง)
+func ImportTrades(c *fiber.Ctx) error {
+	userID := GetCurrentUserID(c)
+	dryRun, err := strconv.ParseBool(c.FormValue("dry_run", "true"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "dry_run ต้องเป็น true หรือ false",
+			"field": "dry_run",
+			"code":  "invalid_dry_run",
+		})
+	}
+	opts := services.ImportOptions{TimeLayout: c.FormValue("time_layout")}
+	if tz := c.FormValue("timezone"); tz != "" {
+		if opts.Location, err = services.LoadTimezone(tz); err != nil {
+			_, respErr := respondValidationError(c, err)
+			return respErr
+		}
+	}
+	if columns := c.FormValue("columns"); columns != "" {
+		if err := json.Unmarshal([]byte(columns), &opts.Columns); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "columns ต้องเป็น JSON Object ของ ฟิลด์ → หัวคอลัมน์",
+				"field":   "columns",
+				"code":    "invalid_columns",
+				"message": err.Error(),
+			})
+		}
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ต้องแนบไฟล์ในช่อง file",
+			"field": "file",
+			"code":  "file_required",
+		})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "เปิดไฟล์ไม่ได้",
+			"message": err.Error(),
+		})
+	}
+	defer file.Close()
+
+	result, err := services.Import(c.FormValue("source"), file, opts)
+	if err != nil {
+		return respondTradeTxError(c, "ImportTrades", err)
+	}
+	source := result.Source
+
+	var preview []ImportPreviewTrade
+	var batch *TradeImport
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", importLockNamespace, userID).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		if preview, err = classifyImportedTrades(tx, userID, source, result.Trades); err != nil || dryRun {
+			return err
+		}
+
+		batch = &TradeImport{UserID: userID, Source: source, Filename: header.Filename, Fills: result.Fills}
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range preview {
+			if preview[i].ImportStatus != ImportStatusNew {
+				batch.Skipped++
+				continue
+			}
+			trade, err := createImportedTrade(tx, c, batch, preview[i].ImportedTrade)
+			if err != nil {
+				return err
+			}
+			preview[i].TradeID = &trade.ID
+			batch.TradesCreated++
+		}
+		return tx.Model(batch).Updates(map[string]interface{}{"trades_created": batch.TradesCreated, "skipped": batch.Skipped}).Error
+	})
+	if err != nil {
+		return respondTradeTxError(c, "ImportTrades", err)
+	}
+
+	counts := map[string]int{ImportStatusNew: 0, ImportStatusDuplicate: 0, ImportStatusPartialDuplicate: 0}
+	for _, trade := range preview {
+		counts[trade.ImportStatus]++
+	}
+	if batch != nil {
+		log.Printf("📥 ImportTrades: user %d, %s, %d trades created, %d skipped", userID, source, batch.TradesCreated, batch.Skipped)
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run":  dryRun,
+		"source":   source,
+		"fills":    result.Fills,
+		"counts":   counts,
+		"trades":   preview,
+		"warnings": result.Warnings,
+		"import":   batch,
+	})
+}
+
+// classifyImportedTrades - เทียบ Trade ID ของแต่ละไม้กับที่เคยนำเข้าแล้ว
+func classifyImportedTrades(tx *gorm.DB, userID uint, source string, trades []services.ImportedTrade) ([]ImportPreviewTrade, error) {
+	var ids []string
+	for _, trade := range trades {
+		ids = append(ids, trade.ExternalIDs...)
+	}
+
+	existing := make(map[string]bool)
+	const chunk = 1000 // จำกัดจำนวน Parameter ต่อ Query
+	for start := 0; start < len(ids); start += chunk {
+		end := start + chunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var found []string
+		if err := tx.Model(&ImportedFill{}).
+			Where("user_id = ? AND source = ? AND external_id IN ?", userID, source, ids[start:end]).
+			Pluck("external_id", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			existing[id] = true
+		}
+	}
+
+	preview := make([]ImportPreviewTrade, len(trades))
+	for i, trade := range trades {
+		imported := 0
+		for _, id := range trade.ExternalIDs {
+			if existing[id] {
+				imported++
+			}
+		}
+		status := ImportStatusNew
+		switch {
+		case imported == len(trade.ExternalIDs):
+			status = ImportStatusDuplicate
+		case imported > 0:
+			status = ImportStatusPartialDuplicate
+		}
+		preview[i] = ImportPreviewTrade{ImportedTrade: trade, ImportStatus: status}
+	}
+	return preview, nil
+}
+
+// createImportedTrade - บันทึกไม้ที่นำเข้า: Trade + Entry Fills + Exit Fills (EXECUTED)
+// แล้วให้ syncTradeExits คำนวณ PnL/สถานะด้วยสูตรเดียวกับการทยอยปิดไม้ปกติ
+// (PnL ที่บันทึก = กำไรจาก Exchange หัก Fee ทั้งฝั่งเข้าและออก ไม่ใช่ RealizedPnL ของ Exit อย่างเดียว)
+func createImportedTrade(tx *gorm.DB, c *fiber.Ctx, batch *TradeImport, imported services.ImportedTrade) (Trade, error) {
+	openedAt := imported.OpenedAt
+	trade := Trade{
+		UserID:            batch.UserID,
+		Pair:              imported.Pair,
+		Side:              imported.Side,
+		EntryPrice:        imported.Entry.AvgEntryPrice,
+		Quantity:          imported.Entry.TotalQuantity,
+		PositionSize:      imported.Entry.PositionSize,
+		RemainingQuantity: imported.Entry.TotalQuantity,
+		Leverage:          1,
+		Fee:               imported.Entry.TotalFee,
+		Notes:             fmt.Sprintf("นำเข้าจาก %s (%s)", batch.Source, batch.Filename),
+		Status:            services.StatusOpen,
+		EntryTime:         &openedAt,
+		OpenedAt:          &openedAt,
+		CreatedAt:         openedAt, // ให้ตัวกรองวันที่/การเรียงลำดับใช้วันที่เทรดจริง ไม่ใช่วันที่นำเข้า
+	}
+	if err := tx.Create(&trade).Error; err != nil {
+		return trade, err
+	}
+
+	for _, fill := range imported.Entries {
+		entry := TradeEntry{TradeID: trade.ID, UserID: trade.UserID, Price: fill.Price, Quantity: fill.Quantity, Fee: fill.Fee, FilledAt: fill.Time}
+		if err := tx.Create(&entry).Error; err != nil {
+			return trade, err
+		}
+	}
+	for _, fill := range imported.Exits {
+		executedAt := fill.Time
+		gross, net := services.PartialExitPnL(trade.Side, trade.EntryPrice, services.ExitFill{Price: fill.Price, Quantity: fill.Quantity, Fee: fill.Fee})
+		exit := TradeExit{
+			TradeID:       trade.ID,
+			UserID:        trade.UserID,
+			Type:          "MANUAL",
+			Status:        ExitStatusExecuted,
+			Price:         fill.Price,
+			Quantity:      fill.Quantity,
+			ExecutedPrice: fill.Price,
+			Fee:           fill.Fee,
+			GrossPnL:      gross.Round(4),
+			RealizedPnL:   net.Round(4),
+			ExecutedAt:    &executedAt,
+		}
+		if trade.Quantity.IsPositive() {
+			exit.Percent = fill.Quantity.Div(trade.Quantity).Mul(decimal.NewFromInt(100)).Round(4)
+		}
+		if err := tx.Create(&exit).Error; err != nil {
+			return trade, err
+		}
+	}
+	if len(imported.Exits) > 0 {
+		executedAt := imported.Exits[0].Time
+		if imported.ClosedAt != nil {
+			executedAt = *imported.ClosedAt
+		}
+		if _, err := syncTradeExits(tx, &trade, executedAt); err != nil {
+			return trade, err
+		}
+	}
+
+	fills := make([]ImportedFill, len(imported.ExternalIDs))
+	for i, id := range imported.ExternalIDs {
+		fills[i] = ImportedFill{UserID: trade.UserID, Source: batch.Source, ExternalID: id, TradeID: trade.ID, ImportID: batch.ID}
+	}
+	if err := tx.Create(&fills).Error; err != nil {
+		return trade, err
+	}
+
+	_, err := recordTradeRevision(tx, c, RevisionCreate, nil, trade)
+	return trade, err
+}
+
+// GetTradeImports - ประวัติการนำเข้า
+// GET /api/trades/imports
+func GetTradeImports(c *fiber.Ctx) error {
+	var imports []TradeImport
+	if err := database.DB.Where("user_id = ?", GetCurrentUserID(c)).Order("created_at DESC").Limit(100).Find(&imports).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{"imports": imports})
+}
+
+// GetTradeImporters - รายชื่อ Importer ที่ใช้เป็น source ได้
+// GET /api/trades/importers
+func GetTradeImporters(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"importers": services.ImporterNames()})
+}
@@ -0,0 +1,79 @@
+// Package handlers - Trade Lifecycle
+// แปลงสถานะที่ Client ขอเป็นการเปลี่ยนสถานะตาม services.ValidateTransition และบันทึกเวลาของแต่ละขั้น
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"mmrrdikub/internal/services"
+)
+
+// parseRequestedStatus - แยกสถานะปลายทางและผลลัพธ์จาก status ที่ส่งมา
+// "WIN"/"LOSS"/"BREAK_EVEN" (Frontend เดิม) = ขอ CLOSED พร้อมผลนั้น, "" = ไม่เปลี่ยนสถานะ
+func parseRequestedStatus(raw string) (string, string, bool) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	if raw == "" {
+		return "", "", true
+	}
+	if services.IsOutcome(raw) {
+		return services.StatusClosed, raw, true
+	}
+	status, ok := services.NormalizeTradeStatus(raw)
+	return status, "", ok
+}
+
+// validateTradeTransition - เช็คการเปลี่ยนสถานะของ Trade
+// PARTIALLY_CLOSED เปลี่ยนเองไม่ได้ ต้องเกิดจากการขายผ่าน /exits เท่านั้น
+func validateTradeTransition(trade Trade, target string) error {
+	if target == services.StatusPartiallyClosed && trade.Status != target {
+		return &services.TransitionError{
+			From:    trade.Status,
+			To:      target,
+			Code:    "status_managed_by_exits",
+			Message: "PARTIALLY_CLOSED จะเปลี่ยนให้อัตโนมัติเมื่อขายผ่าน /api/trades/:id/exits",
+		}
+	}
+	return services.ValidateTransition(trade.Status, target)
+}
+
+// transitionUpdates - คอลัมน์ที่ต้องอัพเดทเมื่อเปลี่ยนเป็นสถานะ to
+func transitionUpdates(to string, at time.Time) map[string]interface{} {
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case services.StatusPlanned:
+		updates["planned_at"] = &at
+	case services.StatusOpen:
+		updates["opened_at"] = &at
+		updates["entry_time"] = &at
+	case services.StatusPartiallyClosed:
+		updates["partially_closed_at"] = &at
+	case services.StatusClosed:
+		updates["closed_at"] = &at
+		updates["exit_time"] = &at
+	case services.StatusCancelled:
+		updates["cancelled_at"] = &at
+	}
+	return updates
+}
+
+// outcomeFromPnL - ตัดสินผลจากเครื่องหมายของ PnL (ใช้ตอน Override ที่ไม่ได้ส่งผลมา)
+func outcomeFromPnL(pnl decimal.Decimal) string {
+	switch {
+	case pnl.IsPositive():
+		return services.OutcomeWin
+	case pnl.IsNegative():
+		return services.OutcomeLoss
+	}
+	return services.OutcomeBreakEven
+}
+
+// ResultLabel - ป้ายสถานะสำหรับแสดงผล (ไม้ที่ปิดแล้วแสดงเป็น WIN/LOSS/BREAK_EVEN)
+func (t Trade) ResultLabel() string {
+	if t.Status == services.StatusClosed && t.Outcome != "" {
+		return t.Outcome
+	}
+	return t.Status
+}
@@ -0,0 +1,251 @@
+// Package handlers - Trash Bin (ถังขยะ)
+// DeleteTrade เป็น Soft Delete อยู่แล้ว ไฟล์นี้เพิ่มการดู/กู้คืน/ลบถาวร
+// และงานเบื้องหลังที่ลบถาวรเมื่ออยู่ในถังขยะเกินระยะเวลาที่ตั้งไว้
+package handlers
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// trashPurgeBatchSize - จำนวน Trade ที่ลบถาวรต่อรอบของการดึง
+const trashPurgeBatchSize = 100
+
+// TrashedTrade - Trade ในถังขยะ พร้อมเวลาที่ลบและเวลาที่จะถูกลบถาวร
+type TrashedTrade struct {
+	Trade
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"` // nil = ไม่ลบอัตโนมัติ
+}
+
+// GetTrashedTrades - ดู Trade ที่ลบไปแล้ว (ล่าสุดก่อน)
+// GET /api/trades/trash
+func GetTrashedTrades(c *fiber.Ctx) error {
+	userID := GetCurrentUserID(c)
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	var trades []Trade
+	query := database.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	if err := query.Order("deleted_at DESC").Limit(limit).Offset(offset).Find(&trades).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "ไม่สามารถดึงข้อมูลได้",
+			"message": err.Error(),
+		})
+	}
+
+	var total int64
+	database.DB.Unscoped().Model(&Trade{}).Where("user_id = ? AND deleted_at IS NOT NULL", userID).Count(&total)
+
+	retention := trashRetention()
+	items := make([]TrashedTrade, len(trades))
+	for i, trade := range trades {
+		items[i] = TrashedTrade{
+			Trade:     trade,
+			DeletedAt: trade.DeletedAt.Time,
+			PurgeAt:   services.TrashPurgeAt(trade.DeletedAt.Time, retention),
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"trades":         items,
+		"total":          total,
+		"retention_days": int(retention.Hours() / 24),
+	})
+}
+
+// RestoreTrade - กู้คืน Trade จากถังขยะ
+// POST /api/trades/:id/restore
+func RestoreTrade(c *fiber.Ctx) error {
+	var trade Trade
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockTrashedTrade(tx, c)
+		if err != nil {
+			return err
+		}
+
+		before := trade
+		if err := tx.Unscoped().Model(&trade).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&trade, trade.ID).Error; err != nil {
+			return err
+		}
+		_, err = recordTradeRevision(tx, c, RevisionRestore, &before, trade)
+		return err
+	})
+	if err != nil {
+		return respondTradeTxError(c, "RestoreTrade", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "กู้คืนสำเร็จ! ♻️",
+		"trade":   trade,
+	})
+}
+
+// PurgeTrade - ลบถาวร (ต้องอยู่ในถังขยะก่อน กันลบพลาด)
+// DELETE /api/trades/:id/permanent
+func PurgeTrade(c *fiber.Ctx) error {
+	var trade Trade
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		trade, err = lockTrashedTrade(tx, c)
+		if err != nil {
+			return err
+		}
+		return purgeTrade(tx, trade, GetCurrentUserID(c), clientIP(c))
+	})
+	if err != nil {
+		return respondTradeTxError(c, "PurgeTrade", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "ลบถาวรสำเร็จ! 🔥",
+		"id":      trade.ID,
+	})
+}
+
+// lockTrashedTrade - หา Trade ของ User แล้ว Lock แถวไว้ (ต้องอยู่ในถังขยะ ไม่งั้นตอบ 409 not_in_trash)
+func lockTrashedTrade(tx *gorm.DB, c *fiber.Ctx) (Trade, error) {
+	var trade Trade
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", c.Params("id"), GetCurrentUserID(c)).
+		First(&trade).Error
+	if err != nil {
+		return trade, err
+	}
+	if !services.InTrash(trashedAt(trade)) {
+		return trade, &errTradeConflict{Code: "not_in_trash", Message: "Trade นี้ไม่ได้อยู่ในถังขยะ (ต้องลบก่อนถึงจะกู้คืน/ลบถาวรได้)"}
+	}
+	return trade, nil
+}
+
+// trashedAt - เวลาที่ Trade ถูกลบ (nil = ยังไม่ลบ)
+func trashedAt(trade Trade) *time.Time {
+	if !trade.DeletedAt.Valid {
+		return nil
+	}
+	return &trade.DeletedAt.Time
+}
+
+// purgeTrade - ลบ Trade และตารางลูกถาวร (ประวัติใน trade_revisions ยังเก็บไว้ พร้อม Revision PURGE)
+func purgeTrade(tx *gorm.DB, trade Trade, actorID uint, ip string) error {
+	if err := tx.Where("trade_id = ?", trade.ID).Delete(&TradeEntry{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trade_id = ?", trade.ID).Delete(&TradeExit{}).Error; err != nil {
+		return err
+	}
+	// ลบถาวรแล้วให้นำเข้า Fill ชุดเดิมใหม่ได้
+	if err := tx.Where("trade_id = ?", trade.ID).Delete(&ImportedFill{}).Error; err != nil {
+		return err
+	}
+
+	revision, err := buildTradeRevision(actorID, ip, RevisionPurge, &trade, trade)
+	if err != nil {
+		return err
+	}
+	if err := saveTradeRevision(tx, revision); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&trade).Error
+}
+
+// StartTrashPurger - เริ่มงานเบื้องหลังลบถาวร Trade ที่อยู่ในถังขยะเกิน TRASH_RETENTION_DAYS
+// ตรวจทุก TRASH_PURGE_INTERVAL (Default 1h), ตั้ง TRASH_RETENTION_DAYS=0 เพื่อปิด
+func StartTrashPurger() {
+	retention := trashRetention()
+	if _, ok := services.TrashPurgeCutoff(time.Now(), retention); !ok {
+		log.Println("🗑️ Trash purger ปิดอยู่ (TRASH_RETENTION_DAYS=0)")
+		return
+	}
+	interval := trashPurgeInterval()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			cutoff, _ := services.TrashPurgeCutoff(time.Now(), retention)
+			if purged, err := purgeExpiredTrades(cutoff); err != nil {
+				log.Printf("❌ Trash purge error: %v", err)
+			} else if purged > 0 {
+				log.Printf("🗑️ Trash purge: ลบถาวร %d รายการ", purged)
+			}
+			<-ticker.C
+		}
+	}()
+	log.Printf("🗑️ Trash purger: เก็บ %s, ตรวจทุก %s", retention, interval)
+}
+
+// purgeExpiredTrades - ลบถาวร Trade ที่ถูกลบก่อน cutoff (ทีละชุด ชุดละ Transaction ต่อรายการ)
+func purgeExpiredTrades(cutoff time.Time) (int, error) {
+	purged := 0
+	for {
+		var ids []uint
+		if err := database.DB.Unscoped().Model(&Trade{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("deleted_at ASC").Limit(trashPurgeBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		for _, id := range ids {
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				var trade Trade
+				if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&trade, id).Error; err != nil {
+					return err
+				}
+				// เช็คซ้ำหลัง Lock เผื่อผู้ใช้กู้คืนไประหว่างนี้
+				if !services.TrashExpired(trashedAt(trade), cutoff) {
+					return gorm.ErrRecordNotFound
+				}
+				return purgeTrade(tx, trade, 0, "")
+			})
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return purged, err
+			}
+			if err == nil {
+				purged++
+			}
+		}
+		if len(ids) < trashPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// trashRetention - อ่านระยะเวลาเก็บในถังขยะจาก ENV "TRASH_RETENTION_DAYS" (Default 30 วัน, 0 = ไม่ลบอัตโนมัติ)
+func trashRetention() time.Duration {
+	raw := os.Getenv("TRASH_RETENTION_DAYS")
+	retention, ok := services.ParseTrashRetention(raw)
+	if !ok {
+		log.Printf("⚠️ TRASH_RETENTION_DAYS=%q ไม่ถูกต้อง ใช้ค่า Default", raw)
+	}
+	return retention
+}
+
+// trashPurgeInterval - อ่านความถี่ในการตรวจจาก ENV "TRASH_PURGE_INTERVAL" (เช่น 30m, 6h)
+func trashPurgeInterval() time.Duration {
+	return durationEnv("TRASH_PURGE_INTERVAL", services.DefaultTrashPurgeInterval)
+}
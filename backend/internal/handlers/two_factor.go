@@ -0,0 +1,480 @@
+// Package handlers - Two-Factor Authentication (TOTP)
+// เปิด 2FA ด้วยแอป Authenticator, Recovery Code ใช้ครั้งเดียว และ Login สองขั้นด้วย Challenge Token
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mmrrdikub/internal/services"
+	"mmrrdikub/pkg/database"
+)
+
+// ค่าของ 2FA
+const (
+	twoFactorIssuer       = "MMRRDiKub"
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// UserRecoveryCode - Recovery Code ของ 2FA (เก็บเฉพาะ Hash ใช้ได้ครั้งเดียว)
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"-"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorChallengeClaims - Token ชั่วคราวระหว่าง Login ขั้นแรก (รหัสผ่าน) กับขั้นที่สอง (รหัส 2FA)
+// เซ็นด้วย Key แยกจาก Access Token จึงใช้แทน Access Token ไม่ได้
+type TwoFactorChallengeClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// TwoFactorChallengeResponse - Response ของ Login เมื่อเปิด 2FA ไว้
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresAt         int64  `json:"expires_at"`
+}
+
+// TwoFactorCodeRequest - Body ของการยืนยันตอนเปิด 2FA
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorPasswordRequest - Body ของการปิด 2FA / ขอ Recovery Code ชุดใหม่ (ต้องใช้ทั้งรหัสผ่านและรหัส 2FA)
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"` // รหัส TOTP หรือ Recovery Code
+}
+
+// TwoFactorVerifyRequest - Body ของ Login ขั้นที่สอง
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // รหัส TOTP หรือ Recovery Code
+}
+
+// ============================================
+// Handler Functions
+// ============================================
+
+// GetTwoFactorStatus - สถานะ 2FA และจำนวน Recovery Code ที่เหลือ
+// GET /api/auth/2fa
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	var user User
+	if err := database.DB.First(&user, GetCurrentUserID(c)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบข้อมูล User",
+		})
+	}
+
+	var remaining int64
+	if user.TOTPEnabled {
+		if err := database.DB.Model(&UserRecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Count(&remaining).Error; err != nil {
+			return twoFactorError(c, "ไม่สามารถดึงสถานะ 2FA ได้", err)
+		}
+	}
+	return c.JSON(fiber.Map{
+		"enabled":                  user.TOTPEnabled,
+		"pending":                  !user.TOTPEnabled && user.TOTPPendingSecret != nil,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor - เริ่มเปิด 2FA: สร้าง Secret ใหม่ให้สแกน (ยังไม่มีผลจนกว่าจะยืนยันด้วยรหัสแรก)
+// POST /api/auth/2fa/setup
+func SetupTwoFactor(c *fiber.Ctx) error {
+	var user User
+	if err := database.DB.First(&user, GetCurrentUserID(c)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบข้อมูล User",
+		})
+	}
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "เปิด 2FA ไว้แล้ว",
+			"code":  "two_factor_enabled",
+		})
+	}
+
+	secret, err := services.NewTOTPSecret()
+	if err != nil {
+		return twoFactorError(c, "ไม่สามารถสร้าง Secret ได้", err)
+	}
+	if err := database.DB.Model(&user).Update("totp_pending_secret", secret).Error; err != nil {
+		return twoFactorError(c, "ไม่สามารถบันทึก Secret ได้", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": services.TOTPURI(twoFactorIssuer, user.Username, secret),
+		"digits":      services.TOTPDigits,
+		"period":      int(services.TOTPPeriod / time.Second),
+	})
+}
+
+// ConfirmTwoFactor - ยืนยันรหัสแรกจากแอปแล้วเปิด 2FA พร้อมออก Recovery Code (แสดงครั้งเดียว)
+// อุปกรณ์อื่นที่ Login อยู่ถูกออกจากระบบ ต้อง Login ใหม่ด้วย 2FA
+// POST /api/auth/2fa/confirm
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง code จากแอป Authenticator",
+		})
+	}
+
+	userID := GetCurrentUserID(c)
+	now := time.Now()
+	attempts := []attemptKey{twoFactorKey(userID), loginIPKey(c)}
+	if retry := lockedFor(now, attempts...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+
+	var codes []string
+	var codeErr error // รหัสไม่ถูกต้อง / ยังไม่ได้เริ่ม Setup
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			codeErr = errors.New("เปิด 2FA ไว้แล้ว")
+			return nil
+		}
+		if user.TOTPPendingSecret == nil {
+			codeErr = errors.New("กรุณาเริ่มที่ POST /api/auth/2fa/setup ก่อน")
+			return nil
+		}
+		step, ok := services.VerifyTOTP(*user.TOTPPendingSecret, req.Code, now, 0)
+		if !ok {
+			codeErr = errInvalidTwoFactorCode
+			return nil
+		}
+
+		err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_secret":         *user.TOTPPendingSecret,
+			"totp_pending_secret": nil,
+			"totp_enabled":        true,
+			"totp_last_step":      step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if codes, err = replaceRecoveryCodes(tx, user.ID); err != nil {
+			return err
+		}
+		_, err = revokeUserSessions(tx, user.ID, GetCurrentSessionID(c))
+		return err
+	})
+	if err != nil {
+		return twoFactorError(c, "ไม่สามารถเปิด 2FA ได้", err)
+	}
+	if codeErr != nil {
+		if errors.Is(codeErr, errInvalidTwoFactorCode) {
+			recordFailure(now, attempts...)
+		}
+		return respondTwoFactorCodeError(c, codeErr)
+	}
+	resetAttempts(attempts[0])
+
+	log.Printf("🔐 2FA enabled: user=%d", userID)
+	return c.JSON(fiber.Map{
+		"message":        "เปิด 2FA แล้ว เก็บ Recovery Code ไว้ในที่ปลอดภัย (แสดงครั้งเดียว)",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor - ปิด 2FA (ต้องใช้รหัสผ่าน + รหัส TOTP หรือ Recovery Code)
+// POST /api/auth/2fa/disable
+func DisableTwoFactor(c *fiber.Ctx) error {
+	return withPasswordAndCode(c, func(tx *gorm.DB, user User) (fiber.Map, error) {
+		err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_secret":         nil,
+			"totp_pending_secret": nil,
+			"totp_enabled":        false,
+			"totp_last_step":      0,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&UserRecoveryCode{}).Error; err != nil {
+			return nil, err
+		}
+		log.Printf("🔓 2FA disabled: user=%d", user.ID)
+		return fiber.Map{"message": "ปิด 2FA แล้ว"}, nil
+	})
+}
+
+// RegenerateRecoveryCodes - ออก Recovery Code ชุดใหม่ (ชุดเดิมใช้ไม่ได้ทันที)
+// POST /api/auth/2fa/recovery-codes
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	return withPasswordAndCode(c, func(tx *gorm.DB, user User) (fiber.Map, error) {
+		codes, err := replaceRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"message":        "สร้าง Recovery Code ชุดใหม่แล้ว (แสดงครั้งเดียว)",
+			"recovery_codes": codes,
+		}, nil
+	})
+}
+
+// VerifyTwoFactorLogin - Login ขั้นที่สอง: แลก Challenge Token + รหัส 2FA เป็น Access/Refresh Token
+// POST /api/auth/2fa/verify
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var req TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || strings.TrimSpace(req.Code) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง challenge_token และ code",
+		})
+	}
+
+	claims, err := parseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Challenge หมดอายุหรือไม่ถูกต้อง กรุณา Login ใหม่",
+			"code":  "invalid_challenge",
+		})
+	}
+
+	now := time.Now()
+	attempts := []attemptKey{twoFactorKey(claims.UserID), loginIPKey(c)}
+	if retry := lockedFor(now, attempts...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+
+	var user User
+	var codeErr error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, claims.UserID).Error; err != nil {
+			return err
+		}
+		if err := checkAccountUsable(user); err != nil {
+			codeErr = err
+			return nil
+		}
+		if !user.TOTPEnabled {
+			codeErr = errors.New("บัญชีนี้ไม่ได้เปิด 2FA กรุณา Login ใหม่")
+			return nil
+		}
+		ok, err := verifySecondFactor(tx, user, req.Code, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			codeErr = errInvalidTwoFactorCode
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "ไม่พบบัญชีผู้ใช้ กรุณา Login ใหม่",
+		})
+	}
+	if err != nil {
+		return twoFactorError(c, "ไม่สามารถตรวจสอบรหัส 2FA ได้", err)
+	}
+	var vErr *services.ValidationError
+	if errors.As(codeErr, &vErr) {
+		return respondAccountBlocked(c, codeErr)
+	}
+	if codeErr != nil {
+		if errors.Is(codeErr, errInvalidTwoFactorCode) {
+			recordFailure(now, attempts...)
+		}
+		return respondTwoFactorCodeError(c, codeErr)
+	}
+	resetAttempts(attempts[0], loginAccountKey(user.Username))
+
+	response, err := startSession(c, user)
+	if err != nil {
+		return twoFactorError(c, "ไม่สามารถสร้าง Token ได้", err)
+	}
+	return c.JSON(response)
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// errInvalidTwoFactorCode - รหัส 2FA ผิด/ใช้ไปแล้ว
+var errInvalidTwoFactorCode = errors.New("รหัส 2FA ไม่ถูกต้อง")
+
+// issueTwoFactorChallenge - ออก Challenge Token อายุสั้นหลังรหัสผ่านถูกต้อง
+func issueTwoFactorChallenge(user User) (TwoFactorChallengeResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(twoFactorChallengeTTL)
+	claims := TwoFactorChallengeClaims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "mmrrdikub",
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(twoFactorChallengeKey())
+	if err != nil {
+		return TwoFactorChallengeResponse{}, err
+	}
+	return TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         expiresAt.Unix(),
+	}, nil
+}
+
+// parseTwoFactorChallenge - ตรวจ Challenge Token
+func parseTwoFactorChallenge(tokenString string) (*TwoFactorChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TwoFactorChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return twoFactorChallengeKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(*TwoFactorChallengeClaims); ok && token.Valid && claims.UserID != 0 {
+		return claims, nil
+	}
+	return nil, jwt.ErrSignatureInvalid
+}
+
+// twoFactorChallengeKey - Key ของ Challenge Token (แยกจาก Access Token)
+func twoFactorChallengeKey() []byte {
+	return []byte(getJWTSecret() + ":2fa-challenge")
+}
+
+// verifySecondFactor - ตรวจรหัส TOTP (กันใช้รหัสซ้ำด้วย totp_last_step) หรือใช้ Recovery Code ทิ้ง 1 ตัว
+// ต้องเรียกใน Transaction ที่ Lock แถว User ไว้แล้ว
+func verifySecondFactor(tx *gorm.DB, user User, code string, now time.Time) (bool, error) {
+	if services.IsTOTPCode(code) {
+		if user.TOTPSecret == nil {
+			return false, nil
+		}
+		step, ok := services.VerifyTOTP(*user.TOTPSecret, code, now, user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		return true, tx.Model(&user).Update("totp_last_step", step).Error
+	}
+
+	result := tx.Model(&UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, services.HashRecoveryCode(code)).
+		Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("🔑 Recovery code used: user=%d", user.ID)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// replaceRecoveryCodes - ลบ Recovery Code เดิมทั้งหมดแล้วสร้างชุดใหม่ (คืนรหัสจริงให้แสดงครั้งเดียว)
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := services.NewRecoveryCodes(services.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]UserRecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = UserRecoveryCode{UserID: userID, CodeHash: services.HashRecoveryCode(code)}
+	}
+	return codes, tx.Create(&rows).Error
+}
+
+// withPasswordAndCode - ตรวจรหัสผ่าน + รหัส 2FA ของ User ปัจจุบัน แล้วทำ fn ใน Transaction เดียวกัน
+func withPasswordAndCode(c *fiber.Ctx, fn func(tx *gorm.DB, user User) (fiber.Map, error)) error {
+	var req TwoFactorPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || strings.TrimSpace(req.Code) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาส่ง password และ code",
+		})
+	}
+
+	userID := GetCurrentUserID(c)
+	now := time.Now()
+	attempts := []attemptKey{twoFactorKey(userID), loginIPKey(c)}
+	if retry := lockedFor(now, attempts...); retry > 0 {
+		return respondTooManyAttempts(c, retry)
+	}
+
+	var body fiber.Map
+	var codeErr error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			codeErr = errors.New("ยังไม่ได้เปิด 2FA")
+			return nil
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+			codeErr = errInvalidTwoFactorCode
+			return nil
+		}
+		ok, err := verifySecondFactor(tx, user, req.Code, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			codeErr = errInvalidTwoFactorCode
+			return nil
+		}
+		body, err = fn(tx, user)
+		return err
+	})
+	if err != nil {
+		return twoFactorError(c, "ไม่สามารถบันทึกได้", err)
+	}
+	if codeErr != nil {
+		if errors.Is(codeErr, errInvalidTwoFactorCode) {
+			recordFailure(now, attempts...)
+			// ไม่บอกว่าผิดที่รหัสผ่านหรือรหัส 2FA
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "รหัสผ่านหรือรหัส 2FA ไม่ถูกต้อง",
+				"code":  "invalid_credentials",
+			})
+		}
+		return respondTwoFactorCodeError(c, codeErr)
+	}
+	resetAttempts(attempts[0])
+	return c.JSON(body)
+}
+
+// respondTwoFactorCodeError - รหัสผิด = 401, สถานะ 2FA ไม่ถูกต้อง = 409
+func respondTwoFactorCodeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errInvalidTwoFactorCode) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "invalid_code",
+		})
+	}
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// twoFactorError - ตอบ 500 พร้อม Log
+func twoFactorError(c *fiber.Ctx, message string, err error) error {
+	log.Printf("❌ 2FA error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
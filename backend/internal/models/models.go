@@ -5,6 +5,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // User - โครงสร้างข้อมูลผู้ใช้งาน
@@ -18,20 +20,22 @@ type User struct {
 
 // Asset - โครงสร้างข้อมูลคู่เทรด
 type Asset struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Symbol    string    `gorm:"uniqueIndex;not null;size:20" json:"symbol"`
-	Type      string    `gorm:"not null;size:20" json:"type"` // Crypto, Forex
-	TickSize  float64   `gorm:"not null" json:"tick_size"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	Symbol       string          `gorm:"uniqueIndex;not null;size:20" json:"symbol"`
+	Type         string          `gorm:"not null;size:20" json:"type"` // Crypto, Forex
+	TickSize     decimal.Decimal `gorm:"type:decimal(24,12);not null" json:"tick_size"`
+	QuantityStep decimal.Decimal `gorm:"type:decimal(24,12);default:0" json:"quantity_step"` // Lot Step ของจำนวนเหรียญ (0 = ไม่ปัด)
+	MinNotional  decimal.Decimal `gorm:"type:decimal(18,4);default:0" json:"min_notional"`   // มูลค่าไม้ขั้นต่ำ (USD)
+	CreatedAt    time.Time       `gorm:"autoCreateTime" json:"created_at"`
 }
 
 // Exchange - โครงสร้างข้อมูลกระดานเทรด
 type Exchange struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"uniqueIndex;not null;size:50" json:"name"`
-	MakerFee  float64   `gorm:"default:0" json:"maker_fee"`
-	TakerFee  float64   `gorm:"default:0" json:"taker_fee"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	Name      string          `gorm:"uniqueIndex;not null;size:50" json:"name"`
+	MakerFee  decimal.Decimal `gorm:"type:decimal(6,4);default:0" json:"maker_fee"` // หน่วยเป็น % (0.0200 = 0.02%)
+	TakerFee  decimal.Decimal `gorm:"type:decimal(6,4);default:0" json:"taker_fee"` // หน่วยเป็น %
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
 }
 
 // TradeJournal - โครงสร้างข้อมูลบันทึกการเทรด (ตารางแม่)
@@ -0,0 +1,104 @@
+// Package services - Performance Analytics
+// รับตัวเลขรวมจาก SQL (นับ/รวม/สูงสุด/Streak) แล้วคำนวณค่าสถิติที่ต้องหารกัน
+package services
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// PerformanceAggregate - ตัวเลขดิบจาก SQL (ไม้ที่ปิดแล้วเท่านั้น)
+type PerformanceAggregate struct {
+	TotalTrades       int64
+	Wins              int64
+	Losses            int64
+	BreakEvens        int64
+	NetPnL            decimal.Decimal `gorm:"column:net_pnl"` // รวม PnL ทุกไม้ (รวมไม้เสมอตัว)
+	GrossProfit       decimal.Decimal // รวม PnL ของไม้ที่ชนะ
+	GrossLoss         decimal.Decimal // รวม |PnL| ของไม้ที่แพ้ (เป็นบวก)
+	LargestWin        decimal.Decimal
+	LargestLoss       decimal.Decimal // ไม้ที่ขาดทุนมากที่สุด (เป็นลบ)
+	SumR              decimal.Decimal // รวม R-Multiple ของไม้ที่มี Risk
+	RCount            int64           // จำนวนไม้ที่คำนวณ R ได้
+	StdDevR           decimal.Decimal `gorm:"column:std_dev_r"` // ส่วนเบี่ยงเบนมาตรฐานของ R (Sample)
+	LongestWinStreak  int64
+	LongestLossStreak int64
+}
+
+// PerformanceSummary - สถิติผลการเทรด
+// ค่าที่หารด้วยศูนย์ไม่ได้ (เช่น ProfitFactor ตอนยังไม่เคยแพ้) เป็น nil
+type PerformanceSummary struct {
+	TotalTrades       int64            `json:"total_trades"`
+	Wins              int64            `json:"wins"`
+	Losses            int64            `json:"losses"`
+	BreakEvens        int64            `json:"break_evens"`
+	WinRate           decimal.Decimal  `json:"win_rate"` // % ของไม้ที่ปิดแล้วทั้งหมด
+	NetPnL            decimal.Decimal  `json:"net_pnl"`
+	GrossProfit       decimal.Decimal  `json:"gross_profit"`
+	GrossLoss         decimal.Decimal  `json:"gross_loss"`
+	AverageWin        decimal.Decimal  `json:"average_win"`
+	AverageLoss       decimal.Decimal  `json:"average_loss"` // เป็นบวก
+	LargestWin        decimal.Decimal  `json:"largest_win"`
+	LargestLoss       decimal.Decimal  `json:"largest_loss"`
+	Expectancy        decimal.Decimal  `json:"expectancy"` // กำไรคาดหวังต่อไม้ (USD)
+	ProfitFactor      *decimal.Decimal `json:"profit_factor"`
+	PayoffRatio       *decimal.Decimal `json:"payoff_ratio"` // AverageWin / AverageLoss
+	AverageR          *decimal.Decimal `json:"average_r"`    // Expectancy ในหน่วย R
+	SQN               *decimal.Decimal `json:"sqn"`          // System Quality Number
+	LongestWinStreak  int64            `json:"longest_win_streak"`
+	LongestLossStreak int64            `json:"longest_loss_streak"`
+}
+
+// SummarizePerformance - คำนวณสถิติจากตัวเลขรวม
+//
+//	WinRate      = Wins / Total × 100
+//	Expectancy   = WinRate × AvgWin − LossRate × AvgLoss
+//	ProfitFactor = GrossProfit / GrossLoss
+//	PayoffRatio  = AvgWin / AvgLoss
+//	SQN          = √min(N, 100) × AvgR / StdDev(R)
+func SummarizePerformance(agg PerformanceAggregate) PerformanceSummary {
+	summary := PerformanceSummary{
+		TotalTrades:       agg.TotalTrades,
+		Wins:              agg.Wins,
+		Losses:            agg.Losses,
+		BreakEvens:        agg.BreakEvens,
+		NetPnL:            agg.NetPnL.Round(4),
+		GrossProfit:       agg.GrossProfit.Round(4),
+		GrossLoss:         agg.GrossLoss.Round(4),
+		LargestWin:        agg.LargestWin.Round(4),
+		LargestLoss:       agg.LargestLoss.Round(4),
+		LongestWinStreak:  agg.LongestWinStreak,
+		LongestLossStreak: agg.LongestLossStreak,
+	}
+	if agg.TotalTrades == 0 {
+		return summary
+	}
+
+	total := decimal.NewFromInt(agg.TotalTrades)
+	winRate := decimal.NewFromInt(agg.Wins).Div(total)
+	lossRate := decimal.NewFromInt(agg.Losses).Div(total)
+	summary.WinRate = winRate.Mul(decimalHundred).Round(2)
+
+	if agg.Wins > 0 {
+		summary.AverageWin = agg.GrossProfit.Div(decimal.NewFromInt(agg.Wins)).Round(4)
+	}
+	if agg.Losses > 0 {
+		summary.AverageLoss = agg.GrossLoss.Div(decimal.NewFromInt(agg.Losses)).Round(4)
+	}
+	summary.Expectancy = winRate.Mul(summary.AverageWin).Sub(lossRate.Mul(summary.AverageLoss)).Round(4)
+
+	if agg.GrossLoss.IsPositive() {
+		profitFactor := agg.GrossProfit.Div(agg.GrossLoss).Round(4)
+		summary.ProfitFactor = &profitFactor
+	}
+	if summary.AverageLoss.IsPositive() && agg.Wins > 0 {
+		payoff := summary.AverageWin.Div(summary.AverageLoss).Round(4)
+		summary.PayoffRatio = &payoff
+	}
+	if agg.RCount > 0 {
+		meanR := agg.SumR.Div(decimal.NewFromInt(agg.RCount))
+		averageR := meanR.Round(4)
+		summary.AverageR = &averageR
+		summary.SQN = systemQualityNumber(agg.RCount, meanR, agg.StdDevR)
+	}
+	return summary
+}
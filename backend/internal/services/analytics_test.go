@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestSummarizePerformance - 10 ไม้: ชนะ 6 (รวม 600, สูงสุด 200), แพ้ 3 (รวม 150, หนักสุด -80), เสมอ 1
+func TestSummarizePerformance(t *testing.T) {
+	summary := SummarizePerformance(PerformanceAggregate{
+		TotalTrades: 10, Wins: 6, Losses: 3, BreakEvens: 1,
+		NetPnL: d(450.5), GrossProfit: d(600), GrossLoss: d(150),
+		LargestWin: d(200), LargestLoss: d(-80),
+		SumR: d(9), RCount: 9, StdDevR: d(1.5),
+		LongestWinStreak: 4, LongestLossStreak: 2,
+	})
+
+	// WinRate = 60%, AvgWin = 100, AvgLoss = 50
+	// Expectancy = 0.6 x 100 - 0.3 x 50 = 45, PF = 600 / 150 = 4, Payoff = 100 / 50 = 2, AvgR = 1
+	assertDecimal(t, "WinRate", summary.WinRate, d(60), 0.0001)
+	assertDecimal(t, "AverageWin", summary.AverageWin, d(100), 0.0001)
+	assertDecimal(t, "AverageLoss", summary.AverageLoss, d(50), 0.0001)
+	assertDecimal(t, "Expectancy", summary.Expectancy, d(45), 0.0001)
+	if summary.ProfitFactor == nil || summary.PayoffRatio == nil || summary.AverageR == nil {
+		t.Fatalf("ProfitFactor/PayoffRatio/AverageR ต้องมีค่า: %+v", summary)
+	}
+	assertDecimal(t, "ProfitFactor", *summary.ProfitFactor, d(4), 0.0001)
+	assertDecimal(t, "PayoffRatio", *summary.PayoffRatio, d(2), 0.0001)
+	assertDecimal(t, "AverageR", *summary.AverageR, d(1), 0.0001)
+	// SQN = √9 x 1 / 1.5 = 2
+	if summary.SQN == nil {
+		t.Fatalf("SQN ต้องมีค่า")
+	}
+	assertDecimal(t, "SQN", *summary.SQN, d(2), 0.0001)
+	if summary.LongestWinStreak != 4 || summary.LongestLossStreak != 2 {
+		t.Errorf("Streak ผิด: %d / %d", summary.LongestWinStreak, summary.LongestLossStreak)
+	}
+}
+
+// TestSummarizePerformanceNoLosses - ยังไม่เคยแพ้ ProfitFactor/Payoff หารไม่ได้ ต้องเป็น nil
+func TestSummarizePerformanceNoLosses(t *testing.T) {
+	summary := SummarizePerformance(PerformanceAggregate{
+		TotalTrades: 2, Wins: 2, NetPnL: d(30), GrossProfit: d(30), LargestWin: d(20),
+	})
+	assertDecimal(t, "WinRate", summary.WinRate, d(100), 0.0001)
+	assertDecimal(t, "Expectancy", summary.Expectancy, d(15), 0.0001)
+	if summary.ProfitFactor != nil || summary.PayoffRatio != nil || summary.AverageR != nil {
+		t.Errorf("ค่าที่หารด้วยศูนย์ต้องเป็น nil: %+v", summary)
+	}
+
+	empty := SummarizePerformance(PerformanceAggregate{})
+	if !empty.WinRate.IsZero() || !empty.Expectancy.IsZero() {
+		t.Errorf("ไม่มีไม้ ค่าต้องเป็น 0: %+v", empty)
+	}
+}
@@ -0,0 +1,80 @@
+// Package services - Account Archive (Backup/Restore ข้อมูลทั้งบัญชีเป็น JSON)
+// กติกาของไฟล์: รูปแบบ/Version, การหาไม้ที่ซ้ำกับของเดิม และรายการ Tag
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// รูปแบบไฟล์ Backup
+// เพิ่ม Version เมื่อเปลี่ยนความหมายของฟิลด์เดิม (เพิ่มฟิลด์ใหม่ไม่ต้องเพิ่ม Version) และให้ Restore อ่าน Version เก่าได้เสมอ
+const (
+	AccountArchiveFormat  = "mmrrdikub.account"
+	AccountArchiveVersion = 1
+)
+
+// CheckAccountArchive - ไฟล์เป็น Backup ของระบบนี้ และ Version ไม่ใหม่กว่าที่ Server รู้จัก
+func CheckAccountArchive(format string, version int) error {
+	if format != AccountArchiveFormat {
+		return newValidationError("format", "invalid_archive", fmt.Sprintf("ไม่ใช่ไฟล์ Backup ของระบบ (format ต้องเป็น %q)", AccountArchiveFormat))
+	}
+	if version < 1 || version > AccountArchiveVersion {
+		return newValidationError("version", "unsupported_version", fmt.Sprintf("ไม่รองรับ Backup version %d (รองรับ 1-%d)", version, AccountArchiveVersion))
+	}
+	return nil
+}
+
+// CheckArchiveImportRefs - Fill ที่นำเข้าทุกตัวต้องอ้างถึงประวัติการนำเข้าที่อยู่ในไฟล์
+// (Fill ต้องมีชุดการนำเข้าเสมอ ถ้าไม่มีในไฟล์ถือว่าไฟล์ไม่สมบูรณ์ ไม่สร้าง Fill ที่ชี้ไปยังชุดที่ไม่มีอยู่)
+func CheckArchiveImportRefs(importIDs, fillImportIDs []uint) error {
+	known := make(map[uint]bool, len(importIDs))
+	for _, id := range importIDs {
+		known[id] = true
+	}
+	missing := 0
+	for _, id := range fillImportIDs {
+		if id == 0 || !known[id] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return newValidationError("imported_fills", "missing_import", fmt.Sprintf("ไฟล์ไม่สมบูรณ์: Fill %d รายการอ้างถึงประวัติการนำเข้าที่ไม่มีในไฟล์", missing))
+	}
+	return nil
+}
+
+// TradeFingerprint - ค่าที่ใช้ตัดสินว่าไม้ใน Backup คือไม้เดียวกับที่มีอยู่แล้ว (Restore ซ้ำต้องไม่ได้ไม้ซ้ำ)
+// เวลาตัดเหลือ Microsecond ตามความละเอียดของ PostgreSQL
+func TradeFingerprint(pair, side string, entryPrice, quantity decimal.Decimal, createdAt time.Time) string {
+	return strings.Join([]string{
+		strings.ToUpper(strings.TrimSpace(pair)),
+		strings.ToUpper(side),
+		entryPrice.String(),
+		quantity.String(),
+		createdAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}, "|")
+}
+
+// ArchiveTags - Tag ทั้งหมดที่ใช้ (แยกจาก "breakout,trend" ของแต่ละไม้) ไม่ซ้ำ เรียงตามตัวอักษร
+func ArchiveTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, raw := range tags {
+		for _, tag := range strings.Split(raw, ",") {
+			tag = strings.TrimSpace(tag)
+			key := strings.ToLower(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return strings.ToLower(result[i]) < strings.ToLower(result[j]) })
+	return result
+}
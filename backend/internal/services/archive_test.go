@@ -0,0 +1,89 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckAccountArchive - รูปแบบ/Version ของไฟล์ Backup
+func TestCheckAccountArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		version int
+		code    string
+	}{
+		{"Version ปัจจุบัน", AccountArchiveFormat, AccountArchiveVersion, ""},
+		{"ไม่ใช่ไฟล์ Backup", "something.else", 1, "invalid_archive"},
+		{"ไม่มี Version", AccountArchiveFormat, 0, "unsupported_version"},
+		{"Version ใหม่กว่า Server", AccountArchiveFormat, AccountArchiveVersion + 1, "unsupported_version"},
+	}
+	for _, tc := range tests {
+		err := CheckAccountArchive(tc.format, tc.version)
+		if tc.code == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		verr, ok := err.(*ValidationError)
+		if !ok || verr.Code != tc.code {
+			t.Errorf("%s: Expected %s but got %v", tc.name, tc.code, err)
+		}
+	}
+}
+
+// TestCheckArchiveImportRefs - Fill ที่ไม่มีชุดการนำเข้าในไฟล์ทำให้ทั้งไฟล์ไม่ผ่าน
+func TestCheckArchiveImportRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports []uint
+		fills   []uint
+		wantErr bool
+	}{
+		{"ไม่มี Fill", nil, nil, false},
+		{"ทุก Fill มีชุดการนำเข้า", []uint{3, 7}, []uint{3, 3, 7}, false},
+		{"ชุดการนำเข้าหายไป", []uint{3}, []uint{3, 7}, true},
+		{"Fill ไม่มี import_id", []uint{3}, []uint{0}, true},
+	}
+	for _, tc := range tests {
+		err := CheckArchiveImportRefs(tc.imports, tc.fills)
+		if !tc.wantErr {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		verr, ok := err.(*ValidationError)
+		if !ok || verr.Code != "missing_import" || verr.Field != "imported_fills" {
+			t.Errorf("%s: Expected missing_import but got %v", tc.name, err)
+		}
+	}
+}
+
+// TestTradeFingerprint - ค่าจาก DB (มีศูนย์ต่อท้าย/Timezone อื่น) ต้องได้ Fingerprint เดียวกับใน Backup
+func TestTradeFingerprint(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 123456789, time.UTC)
+	bangkok := time.FixedZone("ICT", 7*3600)
+
+	a := TradeFingerprint("BTC/USDT", "LONG", d(60000), d(0.01), at)
+	b := TradeFingerprint("btc/usdt ", "LONG", d(60000.00000000), d(0.010), at.In(bangkok).Truncate(time.Microsecond))
+	if a != b {
+		t.Errorf("Fingerprint ต้องตรงกัน: %s / %s", a, b)
+	}
+	if c := TradeFingerprint("BTC/USDT", "SHORT", d(60000), d(0.01), at); c == a {
+		t.Errorf("ฝั่งต่างกันต้องได้ Fingerprint ต่างกัน")
+	}
+}
+
+// TestArchiveTags - แยก Tag ไม่ซ้ำ (ไม่สนตัวพิมพ์) เรียงตามตัวอักษร
+func TestArchiveTags(t *testing.T) {
+	got := ArchiveTags([]string{"breakout, trend", "Trend,scalp", "", " ,news"})
+	if strings.Join(got, ",") != "breakout,news,scalp,trend" {
+		t.Errorf("ArchiveTags = %v", got)
+	}
+	if empty := ArchiveTags(nil); empty == nil || len(empty) != 0 {
+		t.Errorf("ไม่มี Tag ต้องได้ [] (ไม่ใช่ null ใน JSON)")
+	}
+}
@@ -0,0 +1,58 @@
+// Package services - Audit Trail
+// เทียบ Snapshot ก่อน/หลังแก้ไข แล้วคืนรายการฟิลด์ที่เปลี่ยน (ใช้เก็บประวัติการแก้ไข Trade)
+package services
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FieldChange - ฟิลด์ที่เปลี่ยนหนึ่งฟิลด์
+type FieldChange struct {
+	Field string      `json:"field"` // ชื่อฟิลด์ตาม JSON
+	Old   interface{} `json:"old"`   // ค่าเดิม (nil = ไม่มีค่า/เพิ่งสร้าง)
+	New   interface{} `json:"new"`   // ค่าใหม่ (nil = ถูกลบ)
+}
+
+// DiffSnapshots - เทียบ Snapshot สองชุด คืนฟิลด์ที่เปลี่ยนเรียงตามชื่อ
+// before = nil คือเพิ่งสร้าง, after = nil คือถูกลบ, ignore = ฟิลด์ที่ไม่ต้องสนใจ (เช่น updated_at)
+// เทียบค่าแบบข้อความ เพราะตัวเลขเงินอาจมาเป็น Number หรือ String ขึ้นกับ MONEY_JSON_FORMAT
+func DiffSnapshots(before, after map[string]interface{}, ignore ...string) []FieldChange {
+	skip := make(map[string]bool, len(ignore))
+	for _, field := range ignore {
+		skip[field] = true
+	}
+
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := make([]FieldChange, 0)
+	for field := range fields {
+		if skip[field] {
+			continue
+		}
+		oldValue, newValue := before[field], after[field]
+		if sameValue(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// sameValue - nil กับ nil เท่ากัน, นอกนั้นเทียบข้อความ ("100" == 100)
+func sameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
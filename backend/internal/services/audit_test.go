@@ -0,0 +1,50 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestDiffSnapshots - คืนเฉพาะฟิลด์ที่เปลี่ยน เรียงตามชื่อ และข้ามฟิลด์ที่สั่งให้ข้าม
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]interface{}{
+		"entry_price": json.Number("100"),
+		"notes":       "เข้าตาม Breakout",
+		"status":      "OPEN",
+		"exit_price":  nil,
+		"updated_at":  "2026-01-01T00:00:00Z",
+	}
+	after := map[string]interface{}{
+		"entry_price": "100", // ค่าเดิมแต่มาเป็น String ต้องไม่นับว่าเปลี่ยน
+		"notes":       "เข้าตาม Breakout",
+		"status":      "CLOSED",
+		"exit_price":  json.Number("110.5"),
+		"updated_at":  "2026-01-02T00:00:00Z",
+	}
+
+	changes := DiffSnapshots(before, after, "updated_at")
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes but got %d: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "exit_price" || changes[0].Old != nil || changes[0].New != json.Number("110.5") {
+		t.Errorf("exit_price change ผิด: %+v", changes[0])
+	}
+	if changes[1].Field != "status" || changes[1].Old != "OPEN" || changes[1].New != "CLOSED" {
+		t.Errorf("status change ผิด: %+v", changes[1])
+	}
+}
+
+// TestDiffSnapshotsCreateDelete - ตอนสร้าง (before = nil) ทุกฟิลด์ที่มีค่าเป็นการเปลี่ยน, ตอนลบก็เช่นกัน
+func TestDiffSnapshotsCreateDelete(t *testing.T) {
+	snapshot := map[string]interface{}{"pair": "BTC/USDT", "exit_price": nil}
+
+	created := DiffSnapshots(nil, snapshot)
+	if len(created) != 1 || created[0].Field != "pair" || created[0].Old != nil {
+		t.Errorf("Create diff ผิด: %+v", created)
+	}
+
+	deleted := DiffSnapshots(snapshot, nil)
+	if len(deleted) != 1 || deleted[0].Field != "pair" || deleted[0].New != nil {
+		t.Errorf("Delete diff ผิด: %+v", deleted)
+	}
+}
@@ -0,0 +1,141 @@
+// Package services - Breakdown Analytics
+// สรุปผลการเทรดแยกกลุ่ม (คู่เหรียญ, ฝั่ง, Tag, คะแนน Setup, วัน, ชั่วโมง, ระยะเวลาถือ)
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// มิติที่ใช้แบ่งกลุ่ม
+const (
+	DimensionPair        = "pair"
+	DimensionSide        = "side"
+	DimensionTag         = "tag"
+	DimensionSetupScore  = "setup_score"
+	DimensionWeekday     = "weekday"      // วันที่เข้าไม้ (จันทร์-อาทิตย์)
+	DimensionHour        = "hour"         // ชั่วโมงที่เข้าไม้ (00-23)
+	DimensionHoldingTime = "holding_time" // ระยะเวลาถือ (เข้า → ปิด)
+)
+
+// HoldingTimeBuckets - ช่วงระยะเวลาถือ (ขอบบน) ลำดับตรงกับเลขกลุ่ม 0, 1, 2, ...
+var HoldingTimeBuckets = []struct {
+	Label string
+	Upper time.Duration // 0 = ไม่มีขอบบน
+}{
+	{"< 1h", time.Hour},
+	{"1h - 4h", 4 * time.Hour},
+	{"4h - 1d", 24 * time.Hour},
+	{"1d - 1w", 7 * 24 * time.Hour},
+	{"> 1w", 0},
+}
+
+// weekdayLabels - ISO weekday (1 = จันทร์ ... 7 = อาทิตย์)
+var weekdayLabels = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// BreakdownAggregate - ตัวเลขดิบของหนึ่งกลุ่มจาก SQL
+type BreakdownAggregate struct {
+	GroupKey string `gorm:"column:group_key"`
+	PerformanceAggregate
+}
+
+// BreakdownRow - สรุปผลของหนึ่งกลุ่ม
+type BreakdownRow struct {
+	Group        string           `json:"group"`
+	Key          string           `json:"key"` // ค่าดิบ (เช่น weekday = "1")
+	TotalTrades  int64            `json:"total_trades"`
+	Wins         int64            `json:"wins"`
+	Losses       int64            `json:"losses"`
+	WinRate      decimal.Decimal  `json:"win_rate"`
+	NetPnL       decimal.Decimal  `json:"net_pnl"`
+	Expectancy   decimal.Decimal  `json:"expectancy"`
+	ProfitFactor *decimal.Decimal `json:"profit_factor"`
+	AverageR     *decimal.Decimal `json:"average_r"`
+	SQN          *decimal.Decimal `json:"sqn"`
+}
+
+// IsBreakdownDimension - เป็นมิติที่รองรับหรือไม่
+func IsBreakdownDimension(dimension string) bool {
+	switch dimension {
+	case DimensionPair, DimensionSide, DimensionTag, DimensionSetupScore,
+		DimensionWeekday, DimensionHour, DimensionHoldingTime:
+		return true
+	}
+	return false
+}
+
+// isOrderedDimension - มิติที่มีลำดับในตัว (เรียงตามค่า) ที่เหลือเรียงตาม Net PnL มากไปน้อย
+func isOrderedDimension(dimension string) bool {
+	switch dimension {
+	case DimensionSetupScore, DimensionWeekday, DimensionHour, DimensionHoldingTime:
+		return true
+	}
+	return false
+}
+
+// SummarizeBreakdown - คำนวณสถิติของแต่ละกลุ่ม ตั้งชื่อกลุ่ม และเรียงลำดับ
+func SummarizeBreakdown(dimension string, groups []BreakdownAggregate) []BreakdownRow {
+	rows := make([]BreakdownRow, 0, len(groups))
+	for _, group := range groups {
+		summary := SummarizePerformance(group.PerformanceAggregate)
+		rows = append(rows, BreakdownRow{
+			Group:        BreakdownLabel(dimension, group.GroupKey),
+			Key:          group.GroupKey,
+			TotalTrades:  summary.TotalTrades,
+			Wins:         summary.Wins,
+			Losses:       summary.Losses,
+			WinRate:      summary.WinRate,
+			NetPnL:       summary.NetPnL,
+			Expectancy:   summary.Expectancy,
+			ProfitFactor: summary.ProfitFactor,
+			AverageR:     summary.AverageR,
+			SQN:          summary.SQN,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if isOrderedDimension(dimension) {
+			a, errA := strconv.Atoi(rows[i].Key)
+			b, errB := strconv.Atoi(rows[j].Key)
+			if errA == nil && errB == nil {
+				return a < b
+			}
+		}
+		return rows[i].NetPnL.GreaterThan(rows[j].NetPnL)
+	})
+	return rows
+}
+
+// BreakdownLabel - แปลงค่าดิบเป็นชื่อกลุ่มที่อ่านง่าย
+func BreakdownLabel(dimension, key string) string {
+	key = strings.TrimSpace(key)
+	switch dimension {
+	case DimensionTag:
+		if key == "" {
+			return "(untagged)"
+		}
+	case DimensionSetupScore:
+		if key == "0" {
+			return "(unscored)"
+		}
+		return key + "★"
+	case DimensionWeekday:
+		if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= 7 {
+			return weekdayLabels[n]
+		}
+	case DimensionHour:
+		if n, err := strconv.Atoi(key); err == nil && n >= 0 && n <= 23 {
+			return fmt.Sprintf("%02d:00", n)
+		}
+	case DimensionHoldingTime:
+		if n, err := strconv.Atoi(key); err == nil && n >= 0 && n < len(HoldingTimeBuckets) {
+			return HoldingTimeBuckets[n].Label
+		}
+	}
+	return key
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestSummarizeBreakdown - มิติที่มีลำดับเรียงตามค่า, มิติอื่นเรียงตาม Net PnL
+func TestSummarizeBreakdown(t *testing.T) {
+	groups := []BreakdownAggregate{
+		{GroupKey: "10", PerformanceAggregate: PerformanceAggregate{TotalTrades: 2, Wins: 1, Losses: 1, NetPnL: d(20), GrossProfit: d(50), GrossLoss: d(30)}},
+		{GroupKey: "9", PerformanceAggregate: PerformanceAggregate{TotalTrades: 1, Wins: 1, NetPnL: d(5), GrossProfit: d(5)}},
+	}
+
+	hours := SummarizeBreakdown(DimensionHour, groups)
+	if hours[0].Group != "09:00" || hours[1].Group != "10:00" {
+		t.Fatalf("ชั่วโมงต้องเรียงตามค่า: %q, %q", hours[0].Group, hours[1].Group)
+	}
+	assertDecimal(t, "WinRate[10:00]", hours[1].WinRate, d(50), 0.0001)
+	assertDecimal(t, "Expectancy[10:00]", hours[1].Expectancy, d(10), 0.0001)
+
+	pairs := SummarizeBreakdown(DimensionPair, []BreakdownAggregate{
+		{GroupKey: "ETH/USDT", PerformanceAggregate: PerformanceAggregate{TotalTrades: 1, NetPnL: d(-5)}},
+		{GroupKey: "BTC/USDT", PerformanceAggregate: PerformanceAggregate{TotalTrades: 1, NetPnL: d(15)}},
+	})
+	if pairs[0].Group != "BTC/USDT" {
+		t.Errorf("คู่เหรียญต้องเรียงตาม Net PnL มากไปน้อย: %q", pairs[0].Group)
+	}
+}
+
+// TestBreakdownLabel - ตั้งชื่อกลุ่ม
+func TestBreakdownLabel(t *testing.T) {
+	tests := []struct {
+		dimension string
+		key       string
+		want      string
+	}{
+		{DimensionWeekday, "1", "Monday"},
+		{DimensionWeekday, "7", "Sunday"},
+		{DimensionHour, "0", "00:00"},
+		{DimensionHoldingTime, "2", "4h - 1d"},
+		{DimensionSetupScore, "5", "5★"},
+		{DimensionSetupScore, "0", "(unscored)"},
+		{DimensionTag, "", "(untagged)"},
+		{DimensionPair, "BTC/USDT", "BTC/USDT"},
+	}
+	for _, tc := range tests {
+		if got := BreakdownLabel(tc.dimension, tc.key); got != tc.want {
+			t.Errorf("BreakdownLabel(%s, %q) = %q, want %q", tc.dimension, tc.key, got, tc.want)
+		}
+	}
+	if IsBreakdownDimension("exchange") || !IsBreakdownDimension(DimensionTag) {
+		t.Errorf("IsBreakdownDimension ผิด")
+	}
+}
@@ -1,39 +1,107 @@
 // Package services - Position Size Calculator
 // ตัวคำนวณขนาดไม้ที่รวม Fee และ Weighted SL ไว้แล้ว
+// 🔥 ใช้ decimal.Decimal ทั้งหมด กันปัญหา float เพี้ยน (โดยเฉพาะเหรียญราคาเล็กๆ อย่าง SHIB/PEPE)
 package services
 
 import (
-	"errors"
-	"math"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// ค่าคงที่ที่ใช้บ่อย
+var (
+	decimalOne     = decimal.NewFromInt(1)
+	decimalTwo     = decimal.NewFromInt(2)
+	decimalHundred = decimal.NewFromInt(100)
 )
 
 // StopLoss - แต่ละจุด SL ที่ตั้งไว้ พร้อมน้ำหนัก
 type StopLoss struct {
-	Price  float64 // ราคา SL ที่ตั้งไว้
-	Weight float64 // น้ำหนัก (เช่น 0.5 = 50% ของไม้ จะโดนตัดที่จุดนี้)
+	Price  decimal.Decimal `json:"price"`  // ราคา SL ที่ตั้งไว้
+	Weight decimal.Decimal `json:"weight"` // น้ำหนัก (เช่น 0.5 = 50% ของไม้ จะโดนตัดที่จุดนี้)
 }
 
 // CalculationInput - ข้อมูลที่ต้องใส่มาคำนวณ
+// JSON รับได้ทั้งตัวเลข (1000.5) และ String ("1000.5")
 type CalculationInput struct {
-	Balance     float64    // เงินทุนทั้งหมด (USD)
-	RiskPercent float64    // % ที่ยอมขาดทุนต่อไม้ (เช่น 1.0 = 1%)
-	EntryPrice  float64    // ราคาเข้า Entry
-	StopLosses  []StopLoss // ลิสต์ของจุด SL หลายๆ จุด พร้อมน้ำหนัก
-	Leverage    float64    // ตัวคูณ Leverage (ถ้าไม่ใช้ใส่ 1)
-	FeeRate     float64    // ค่า Fee ต่อครั้ง (เช่น 0.0004 = 0.04%)
+	Balance     decimal.Decimal `json:"balance"`      // เงินทุนทั้งหมด (USD)
+	RiskPercent decimal.Decimal `json:"risk_percent"` // % ที่ยอมขาดทุนต่อไม้ (เช่น 1.0 = 1%)
+	EntryPrice  decimal.Decimal `json:"entry_price"`  // ราคาเข้า Entry
+	StopLosses  []StopLoss      `json:"stop_losses"`  // ลิสต์ของจุด SL หลายๆ จุด พร้อมน้ำหนัก
+	TakeProfits []TakeProfit    `json:"take_profits"` // ลิสต์ของจุด TP หลายๆ จุด พร้อมน้ำหนัก (ไม่บังคับ)
+	Leverage    decimal.Decimal `json:"leverage"`     // ตัวคูณ Leverage (ถ้าไม่ใช้ใส่ 1)
+	FeeRate     decimal.Decimal `json:"fee_rate"`     // ค่า Fee ต่อครั้ง (เช่น 0.0004 = 0.04%)
+
+	// อัตรา Maintenance Margin (เช่น 0.005 = 0.5%) ถ้าไม่ใส่ใช้ DefaultMaintenanceMarginRate
+	MaintenanceMarginRate decimal.Decimal `json:"maintenance_margin_rate"`
+
+	// คู่เทรด (เช่น BTCUSDT) - Handler จะไปหากฎ Tick/Step จาก Asset catalog มาใส่ใน Asset
+	Symbol string     `json:"symbol"`
+	Asset  *AssetSpec `json:"-"`
 }
 
 // CalculationResult - ผลลัพธ์ที่ได้จากการคำนวณ
 type CalculationResult struct {
-	PositionSizeUSD  float64 // มูลค่ารวมของไม้ (USD)
-	PositionQuantity float64 // จำนวนเหรียญที่จะซื้อ/ขาย
-	WeightedAvgSL    float64 // ราคา SL เฉลี่ย (ถ่วงน้ำหนัก)
-	TotalFeeEstimate float64 // ค่า Fee รวมโดยประมาณ (Entry + Exit)
+	PositionSizeUSD   decimal.Decimal     `json:"position_size_usd"`   // มูลค่ารวมของไม้ (USD)
+	PositionQuantity  decimal.Decimal     `json:"position_quantity"`   // จำนวนเหรียญที่จะซื้อ/ขาย
+	WeightedAvgSL     decimal.Decimal     `json:"weighted_avg_sl"`     // ราคา SL เฉลี่ย (ถ่วงน้ำหนัก)
+	TotalFeeEstimate  decimal.Decimal     `json:"total_fee_estimate"`  // ค่า Fee รวมโดยประมาณ (Entry + Exit)
+	RiskAmount        decimal.Decimal     `json:"risk_amount"`         // จำนวนเงินที่ยอมเสีย (USD)
+	Distance          decimal.Decimal     `json:"distance"`            // ระยะห่าง Entry ถึง SL เฉลี่ย
+	StopLossBreakdown []StopLossBreakdown `json:"stop_loss_breakdown"` // รายละเอียดแยกตามจุด SL
+
+	// === ฝั่ง Take Profit (มีค่าเมื่อส่ง TakeProfits มา) ===
+	WeightedAvgTP       decimal.Decimal       `json:"weighted_avg_tp"`       // ราคา TP เฉลี่ย (ถ่วงน้ำหนัก)
+	ExpectedReward      decimal.Decimal       `json:"expected_reward"`       // กำไรสุทธิรวมถ้าชน TP ครบทุกจุด (USD)
+	RiskRewardRatio     decimal.Decimal       `json:"risk_reward_ratio"`     // R:R แบบรวม Fee (ExpectedReward / RiskAmount)
+	TakeProfitBreakdown []TakeProfitBreakdown `json:"take_profit_breakdown"` // รายละเอียดแยกตามจุด TP
+
+	// === ฝั่ง Margin (Isolated) ===
+	InitialMargin     decimal.Decimal `json:"initial_margin"`     // เงินที่ต้องวางเป็น Margin (USD)
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"` // Margin ขั้นต่ำก่อนโดน Liquidate (USD)
+	LiquidationPrice  decimal.Decimal `json:"liquidation_price"`  // ราคา Liquidation โดยประมาณ
+
+	// ผลการปัดตาม Tick/Step ของ Exchange (มีเมื่อระบุ Asset)
+	Rounding *RoundingReport `json:"rounding,omitempty"`
+}
+
+// StopLossBreakdown - ผลคำนวณแยกของแต่ละจุด SL
+// ใช้ดูว่าแต่ละจุดตัดเหรียญกี่ตัว และเสียเงินเท่าไหร่ถ้าโดน
+type StopLossBreakdown struct {
+	Price    decimal.Decimal `json:"price"`    // ราคา SL
+	Weight   decimal.Decimal `json:"weight"`   // น้ำหนักที่ Normalize แล้ว (รวมกันได้ 1)
+	Quantity decimal.Decimal `json:"quantity"` // จำนวนเหรียญที่โดนตัดที่จุดนี้
+	Distance decimal.Decimal `json:"distance"` // ระยะห่างจาก Entry
+	Loss     decimal.Decimal `json:"loss"`     // ขาดทุนรวม Fee ถ้าโดนจุดนี้ (USD)
+}
+
+// ValidationError - Error จากการ Validate ข้อมูล
+// มี Field กับ Code ให้ Handler เอาไปตอบ 400 แบบมีโครงสร้างได้
+type ValidationError struct {
+	Field   string // ชื่อฟิลด์ (ตาม JSON) ที่มีปัญหา
+	Code    string // รหัส Error สำหรับ Frontend เช่น "must_be_positive"
+	Message string // ข้อความอธิบาย
+}
+
+// Error - ทำให้ ValidationError เป็น error ได้
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// newValidationError - Helper สร้าง ValidationError สั้นๆ
+func newValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Field: field, Code: code, Message: message}
 }
 
 // CalculatePositionSize - ฟังก์ชันหลักสำหรับคำนวณขนาดไม้
 // รับ input แล้วคืน result พร้อม error ถ้ามีปัญหา
 func CalculatePositionSize(input CalculationInput) (CalculationResult, error) {
+	// ถ้ารู้ว่าเป็นคู่ไหน ปัดราคาทุกจุดตาม TickSize ก่อน (Exchange รับแค่ราคาที่ลง Tick)
+	if input.Asset != nil {
+		input = applyTickSize(input)
+	}
+
 	// เช็คก่อนว่าข้อมูลที่ใส่มาถูกต้องมั้ย
 	if err := validateInput(input); err != nil {
 		return CalculationResult{}, err
@@ -45,124 +113,211 @@ func CalculatePositionSize(input CalculationInput) (CalculationResult, error) {
 
 	// --- คำนวณค่าพื้นฐาน ---
 	// หาจำนวนเงินที่ยอม Risk (เช่น Balance 1000, Risk 1% = 10 USD)
-	riskAmount := input.Balance * (input.RiskPercent / 100)
+	riskAmount := input.Balance.Mul(input.RiskPercent).Div(decimalHundred)
 
 	// หา Distance (ระยะห่างจาก Entry ถึง SL เฉลี่ย)
-	// ใช้ math.Abs เพราะอาจเป็น Long หรือ Short ก็ได้
-	distance := math.Abs(input.EntryPrice - weightedAvgSL)
+	// ใช้ Abs เพราะอาจเป็น Long หรือ Short ก็ได้
+	distance := input.EntryPrice.Sub(weightedAvgSL).Abs()
 
 	// --- Logic 2: Fee Adjustment (The Killer Feature) ---
 	// ปกติ: Size = RiskAmount / Distance
 	// แต่! ถ้าโดน SL จริง เราต้องเสีย Fee ด้วย
 	// เลยเอา Fee มาบวกเป็นตัวหาร กันงบหลุด!
 	// สูตร: Size = RiskAmount / (Distance + Fee_Entry + Fee_SL)
-	feeAtEntry := input.EntryPrice * input.FeeRate     // ค่า Fee ตอนเปิดไม้
-	feeAtSL := weightedAvgSL * input.FeeRate           // ค่า Fee ตอนโดน SL
-	adjustedDivisor := distance + feeAtEntry + feeAtSL // ตัวหารที่รวม Fee แล้ว
+	feeAtEntry := input.EntryPrice.Mul(input.FeeRate)        // ค่า Fee ตอนเปิดไม้
+	feeAtSL := weightedAvgSL.Mul(input.FeeRate)              // ค่า Fee ตอนโดน SL
+	adjustedDivisor := distance.Add(feeAtEntry).Add(feeAtSL) // ตัวหารที่รวม Fee แล้ว
 
 	// กัน Divide by Zero (ถ้า Entry กับ SL เท่ากันพอดี)
-	if adjustedDivisor == 0 {
-		return CalculationResult{}, errors.New("ระยะห่าง Entry-SL เท่ากับ 0 คำนวณไม่ได้")
+	if adjustedDivisor.IsZero() {
+		return CalculationResult{}, newValidationError("stop_losses", "zero_distance", "ระยะห่าง Entry-SL เท่ากับ 0 คำนวณไม่ได้")
 	}
 
 	// --- คำนวณ Position Size ---
 	// Size = จำนวน Risk หารด้วยตัวหารที่ปรับแล้ว
-	positionQuantity := riskAmount / adjustedDivisor
+	positionQuantity := riskAmount.Div(adjustedDivisor)
+	quantityDecimals := int32(6) // เหรียญเอาเยอะหน่อย 6 ตำแหน่ง
+
+	// --- Logic 2.5: ปัดจำนวนเหรียญลงตาม Lot Step ---
+	// Risk จริงจะน้อยกว่าที่ตั้งไว้นิดหน่อย รายงานส่วนต่างให้ผู้ใช้เห็น
+	var rounding *RoundingReport
+	if input.Asset != nil && input.Asset.QuantityStep.IsPositive() {
+		rawQuantity := positionQuantity
+		positionQuantity = floorToStep(rawQuantity, input.Asset.QuantityStep)
+		quantityDecimals = stepDecimals(input.Asset.QuantityStep)
+
+		if !positionQuantity.IsPositive() {
+			return CalculationResult{}, newValidationError("risk_percent", "below_quantity_step", "Risk น้อยเกินไป ขนาดไม้ปัดแล้วต่ำกว่า Lot Step ขั้นต่ำ")
+		}
+
+		actualRisk := positionQuantity.Mul(adjustedDivisor)
+		riskDiff := actualRisk.Sub(riskAmount)
+		rounding = &RoundingReport{
+			Symbol:                input.Asset.Symbol,
+			TickSize:              input.Asset.TickSize,
+			QuantityStep:          input.Asset.QuantityStep,
+			MinNotional:           input.Asset.MinNotional,
+			RawQuantity:           rawQuantity.Round(8),
+			RoundedQuantity:       positionQuantity,
+			ActualRisk:            actualRisk.Round(4),
+			RiskDifference:        riskDiff.Round(4),
+			RiskDifferencePercent: riskDiff.Div(riskAmount).Mul(decimalHundred).Round(4),
+		}
+	}
 
 	// --- Logic 3: แปลง Quantity เป็น USD ---
 	// เอาจำนวนเหรียญ คูณราคา Entry = มูลค่าไม้เป็น USD
-	positionSizeUSD := positionQuantity * input.EntryPrice
+	positionSizeUSD := positionQuantity.Mul(input.EntryPrice)
+
+	// ไม้ต้องไม่เล็กกว่า Min Notional ของ Exchange
+	if input.Asset != nil && input.Asset.MinNotional.IsPositive() && positionSizeUSD.LessThan(input.Asset.MinNotional) {
+		return CalculationResult{}, newValidationError("risk_percent", "below_min_notional", "มูลค่าไม้ต่ำกว่า Min Notional ของ Exchange")
+	}
 
 	// --- คำนวณ Fee รวมโดยประมาณ ---
 	// Fee = มูลค่าไม้ x FeeRate x 2 (เปิด 1 ครั้ง + ปิด 1 ครั้ง)
-	totalFeeEstimate := positionSizeUSD * input.FeeRate * 2
+	totalFeeEstimate := positionSizeUSD.Mul(input.FeeRate).Mul(decimalTwo)
 
 	// คืนผลลัพธ์ครบทุกค่า
-	return CalculationResult{
-		PositionSizeUSD:  round(positionSizeUSD, 2),  // ปัดทศนิยม 2 ตำแหน่ง
-		PositionQuantity: round(positionQuantity, 6), // เหรียญเอาเยอะหน่อย 6 ตำแหน่ง
-		WeightedAvgSL:    round(weightedAvgSL, 2),    // ปัดทศนิยม 2 ตำแหน่ง
-		TotalFeeEstimate: round(totalFeeEstimate, 4), // ปัดทศนิยม 4 ตำแหน่ง
-	}, nil
+	result := CalculationResult{
+		PositionSizeUSD:   positionSizeUSD.Round(2), // ปัดทศนิยม 2 ตำแหน่ง
+		PositionQuantity:  positionQuantity.Round(quantityDecimals),
+		WeightedAvgSL:     weightedAvgSL.Round(8),    // ราคาเก็บ 8 ตำแหน่งตาม DB (เหรียญราคาเล็กไม่เพี้ยน)
+		TotalFeeEstimate:  totalFeeEstimate.Round(4), // ปัดทศนิยม 4 ตำแหน่ง
+		RiskAmount:        riskAmount.Round(2),
+		Distance:          distance.Round(8),
+		StopLossBreakdown: buildStopLossBreakdown(input, positionQuantity),
+		Rounding:          rounding,
+	}
+
+	// --- Logic 4: Margin + Liquidation ตาม Leverage ---
+	margin := estimateMargin(input, positionSizeUSD, isLongSetup(input.EntryPrice, weightedAvgSL))
+	result.InitialMargin = margin.InitialMargin.Round(2)
+	result.MaintenanceMargin = margin.MaintenanceMargin.Round(4)
+	result.LiquidationPrice = margin.LiquidationPrice.Round(8)
+
+	// --- Logic 5: ฝั่ง TP หลายจุด ---
+	// ขาดทุนจริงถ้าโดน SL = Qty x ตัวหารที่รวม Fee (= riskAmount พอดีถ้าไม่ได้ปัด Lot)
+	// R:R เลยเป็น กำไรสุทธิรวม / ขาดทุนจริง
+	if len(input.TakeProfits) > 0 {
+		lossAtSL := positionQuantity.Mul(adjustedDivisor)
+		plan := buildTakeProfitPlan(input, positionQuantity, lossAtSL)
+		result.WeightedAvgTP = plan.WeightedAvgTP.Round(8)
+		result.ExpectedReward = plan.ExpectedReward.Round(4)
+		result.RiskRewardRatio = plan.ExpectedReward.Div(lossAtSL).Round(4)
+		result.TakeProfitBreakdown = plan.Breakdown
+	}
+
+	return result, nil
+}
+
+// buildStopLossBreakdown - แตกผลลัพธ์ออกเป็นราย SL
+// แต่ละจุดได้เหรียญตามสัดส่วนน้ำหนัก และขาดทุน = Qty x (ระยะ + Fee เข้า + Fee ออก)
+func buildStopLossBreakdown(input CalculationInput, positionQuantity decimal.Decimal) []StopLossBreakdown {
+	totalWeight := decimal.Zero
+	for _, sl := range input.StopLosses {
+		totalWeight = totalWeight.Add(sl.Weight)
+	}
+	if totalWeight.IsZero() {
+		return nil
+	}
+
+	breakdown := make([]StopLossBreakdown, 0, len(input.StopLosses))
+	for _, sl := range input.StopLosses {
+		weight := sl.Weight.Div(totalWeight)
+		qty := positionQuantity.Mul(weight)
+		distance := input.EntryPrice.Sub(sl.Price).Abs()
+		fees := input.EntryPrice.Add(sl.Price).Mul(input.FeeRate)
+		breakdown = append(breakdown, StopLossBreakdown{
+			Price:    sl.Price,
+			Weight:   weight.Round(6),
+			Quantity: qty.Round(6),
+			Distance: distance.Round(8),
+			Loss:     qty.Mul(distance.Add(fees)).Round(4),
+		})
+	}
+	return breakdown
 }
 
 // calculateWeightedSL - คำนวณ SL เฉลี่ยแบบถ่วงน้ำหนัก
 // ตัวอย่าง: SL1=90 (น้ำหนัก 0.6), SL2=85 (น้ำหนัก 0.4)
 // ผลลัพธ์: (90*0.6 + 85*0.4) / (0.6+0.4) = 88
-func calculateWeightedSL(stopLosses []StopLoss) float64 {
-	var totalWeight float64 // น้ำหนักรวม
-	var weightedSum float64 // ผลรวมแบบถ่วงน้ำหนัก
+func calculateWeightedSL(stopLosses []StopLoss) decimal.Decimal {
+	totalWeight := decimal.Zero // น้ำหนักรวม
+	weightedSum := decimal.Zero // ผลรวมแบบถ่วงน้ำหนัก
 
 	// วนลูปรวมค่าทุกจุด SL
 	for _, sl := range stopLosses {
-		weightedSum += sl.Price * sl.Weight // เอาราคา x น้ำหนัก
-		totalWeight += sl.Weight            // รวมน้ำหนักไว้
+		weightedSum = weightedSum.Add(sl.Price.Mul(sl.Weight)) // เอาราคา x น้ำหนัก
+		totalWeight = totalWeight.Add(sl.Weight)               // รวมน้ำหนักไว้
 	}
 
 	// กัน Divide by Zero
-	if totalWeight == 0 {
-		return 0
+	if totalWeight.IsZero() {
+		return decimal.Zero
 	}
 
 	// คืนค่าเฉลี่ยถ่วงน้ำหนัก
-	return weightedSum / totalWeight
+	return weightedSum.Div(totalWeight)
 }
 
 // validateInput - เช็คว่าข้อมูลที่ใส่มาถูกต้องมั้ย
 // ถ้าเจอปัญหาจะคืน error กลับไป
 func validateInput(input CalculationInput) error {
 	// Balance ต้องมากกว่า 0
-	if input.Balance <= 0 {
-		return errors.New("Balance ต้องมากกว่า 0")
+	if !input.Balance.IsPositive() {
+		return newValidationError("balance", "must_be_positive", "Balance ต้องมากกว่า 0")
 	}
 
 	// Risk% ต้องอยู่ระหว่าง 0-100
-	if input.RiskPercent <= 0 || input.RiskPercent > 100 {
-		return errors.New("RiskPercent ต้องอยู่ระหว่าง 0-100")
+	if !input.RiskPercent.IsPositive() || input.RiskPercent.GreaterThan(decimalHundred) {
+		return newValidationError("risk_percent", "out_of_range", "RiskPercent ต้องอยู่ระหว่าง 0-100")
 	}
 
 	// Entry ต้องมากกว่า 0
-	if input.EntryPrice <= 0 {
-		return errors.New("EntryPrice ต้องมากกว่า 0")
+	if !input.EntryPrice.IsPositive() {
+		return newValidationError("entry_price", "must_be_positive", "EntryPrice ต้องมากกว่า 0")
 	}
 
 	// ต้องมี SL อย่างน้อย 1 จุด
 	if len(input.StopLosses) == 0 {
-		return errors.New("ต้องระบุ StopLoss อย่างน้อย 1 จุด")
+		return newValidationError("stop_losses", "required", "ต้องระบุ StopLoss อย่างน้อย 1 จุด")
 	}
 
 	// เช็คแต่ละ SL ว่าถูกต้องมั้ย
 	for i, sl := range input.StopLosses {
-		if sl.Price <= 0 {
-			return errors.New("StopLoss ราคาต้องมากกว่า 0")
-		}
-		if sl.Weight <= 0 {
-			return errors.New("StopLoss น้ำหนักต้องมากกว่า 0")
+		if !sl.Price.IsPositive() {
+			return newValidationError(fmt.Sprintf("stop_losses[%d].price", i), "must_be_positive", "StopLoss ราคาต้องมากกว่า 0")
 		}
-		// เตือนถ้า SL เท่ากับ Entry (แต่ปล่อยผ่าน เผื่อเป็น case พิเศษ)
-		if sl.Price == input.EntryPrice {
-			_ = i // suppress unused variable warning
+		if !sl.Weight.IsPositive() {
+			return newValidationError(fmt.Sprintf("stop_losses[%d].weight", i), "must_be_positive", "StopLoss น้ำหนักต้องมากกว่า 0")
 		}
 	}
 
+	// เช็คฝั่ง TP (ถ้ามี)
+	if err := validateTakeProfits(input); err != nil {
+		return err
+	}
+
 	// Leverage ถ้าใส่มาต้องมากกว่า 0
-	if input.Leverage < 0 {
-		return errors.New("Leverage ต้องมากกว่าหรือเท่ากับ 0")
+	if input.Leverage.IsNegative() {
+		return newValidationError("leverage", "must_not_be_negative", "Leverage ต้องมากกว่าหรือเท่ากับ 0")
 	}
 
 	// FeeRate ต้องไม่เป็นลบ
-	if input.FeeRate < 0 {
-		return errors.New("FeeRate ต้องไม่เป็นลบ")
+	if input.FeeRate.IsNegative() {
+		return newValidationError("fee_rate", "must_not_be_negative", "FeeRate ต้องไม่เป็นลบ")
 	}
 
-	return nil
-}
+	// Maintenance Margin Rate ต้องอยู่ระหว่าง 0-1
+	if input.MaintenanceMarginRate.IsNegative() || input.MaintenanceMarginRate.GreaterThanOrEqual(decimalOne) {
+		return newValidationError("maintenance_margin_rate", "out_of_range", "MaintenanceMarginRate ต้องอยู่ระหว่าง 0-1")
+	}
 
-// round - ปัดทศนิยมให้ได้ตำแหน่งที่ต้องการ
-// ใช้ math.Round ช่วย เพราะ Go ไม่มี built-in
-func round(val float64, precision int) float64 {
-	// สร้างตัวคูณ (เช่น precision=2 จะได้ 100)
-	p := math.Pow(10, float64(precision))
-	// คูณ ปัด แล้วหาร กลับมา
-	return math.Round(val*p) / p
+	// SL ต้องโดนก่อน Liquidation
+	if err := validateLiquidation(input); err != nil {
+		return err
+	}
+
+	return nil
 }
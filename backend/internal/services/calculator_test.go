@@ -1,10 +1,24 @@
 package services
 
 import (
-	"math"
 	"testing"
+
+	"github.com/shopspring/decimal"
 )
 
+// d - Helper สร้าง decimal จาก float สั้นๆ ในเคสทดสอบ
+func d(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+// assertDecimal - เทียบค่า decimal โดยยอมให้คลาดเคลื่อนได้ไม่เกิน tolerance
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want decimal.Decimal, tolerance float64) {
+	t.Helper()
+	if got.Sub(want).Abs().GreaterThan(d(tolerance)) {
+		t.Errorf("Expected %s %s but got %s", name, want, got)
+	}
+}
+
 // TestCalculatePositionSize - ฟังก์ชันทดสอบหลักสำหรับคำนวณ Position Size
 // ใช้ Table Driven Test เพื่อทดสอบหลายๆ กรณีในฟังก์ชันเดียว
 func TestCalculatePositionSize(t *testing.T) {
@@ -21,28 +35,28 @@ func TestCalculatePositionSize(t *testing.T) {
 			// Expected: Size = Risk / Distance = 10 / 1 = 10 units
 			// Value = 10 * 100 = 1000 USD
 			input: CalculationInput{
-				Balance:     1000,
-				RiskPercent: 1.0,
-				EntryPrice:  100,
+				Balance:     d(1000),
+				RiskPercent: d(1.0),
+				EntryPrice:  d(100),
 				StopLosses: []StopLoss{
-					{Price: 99, Weight: 1.0},
+					{Price: d(99), Weight: d(1.0)},
 				},
-				Leverage: 1,
-				FeeRate:  0,
+				Leverage: d(1),
+				FeeRate:  d(0),
 			},
 			verifyFn: func(t *testing.T, res CalculationResult, err error) {
 				if err != nil {
 					t.Errorf("ไม่ควรมี error แต่ได้: %v", err)
 				}
 				// ตรวจสอบ Position Size (USD)
-				expectedSizeUSD := 1000.0
-				if res.PositionSizeUSD != expectedSizeUSD {
-					t.Errorf("Expected SizeUSD %.2f but got %.2f", expectedSizeUSD, res.PositionSizeUSD)
+				expectedSizeUSD := d(1000)
+				if !res.PositionSizeUSD.Equal(expectedSizeUSD) {
+					t.Errorf("Expected SizeUSD %s but got %s", expectedSizeUSD, res.PositionSizeUSD)
 				}
 				// ตรวจสอบ Quantity
-				expectedQty := 10.0
-				if res.PositionQuantity != expectedQty {
-					t.Errorf("Expected Quantity %.2f but got %.2f", expectedQty, res.PositionQuantity)
+				expectedQty := d(10)
+				if !res.PositionQuantity.Equal(expectedQty) {
+					t.Errorf("Expected Quantity %s but got %s", expectedQty, res.PositionQuantity)
 				}
 			},
 		},
@@ -56,32 +70,30 @@ func TestCalculatePositionSize(t *testing.T) {
 			// Size (Qty) = 10 / 1.199 ≈ 8.340283...
 			// Size (USD) = 8.34... * 100 ≈ 834.02...
 			input: CalculationInput{
-				Balance:     1000,
-				RiskPercent: 1.0,
-				EntryPrice:  100,
+				Balance:     d(1000),
+				RiskPercent: d(1.0),
+				EntryPrice:  d(100),
 				StopLosses: []StopLoss{
-					{Price: 99, Weight: 1.0},
+					{Price: d(99), Weight: d(1.0)},
 				},
-				Leverage: 1,
-				FeeRate:  0.001, // 0.1%
+				Leverage: d(1),
+				FeeRate:  d(0.001), // 0.1%
 			},
 			verifyFn: func(t *testing.T, res CalculationResult, err error) {
 				if err != nil {
 					t.Errorf("ไม่ควรมี error แต่ได้: %v", err)
 				}
 				// Size ต้องน้อยกว่า 1000 แน่นอน เพราะต้องเผื่อค่า Fee
-				if res.PositionSizeUSD >= 1000 {
-					t.Errorf("Size รวม Fee แล้วควรน้อยกว่า 1000 แต่ได้ %.2f", res.PositionSizeUSD)
+				if res.PositionSizeUSD.GreaterThanOrEqual(d(1000)) {
+					t.Errorf("Size รวม Fee แล้วควรน้อยกว่า 1000 แต่ได้ %s", res.PositionSizeUSD)
 				}
 
 				// คำนวณค่าคาดหวังแบบละเอียด
-				expectedDivisor := 1.0 + (100 * 0.001) + (99 * 0.001) // 1.199
-				expectedQty := 10.0 / expectedDivisor
+				expectedDivisor := d(1.199) // 1 + (100 * 0.001) + (99 * 0.001)
+				expectedQty := d(10).Div(expectedDivisor)
 
-				// ตรวจสอบ Quantity (ยอมรับความคลาดเคลื่อนทศนิยมได้นิดหน่อย)
-				if math.Abs(res.PositionQuantity-expectedQty) > 0.0001 {
-					t.Errorf("Expected Quantity %.4f but got %.4f", expectedQty, res.PositionQuantity)
-				}
+				// ตรวจสอบ Quantity (ยอมรับความคลาดเคลื่อนจากการปัด 6 ตำแหน่ง)
+				assertDecimal(t, "Quantity", res.PositionQuantity, expectedQty, 0.0001)
 			},
 		},
 		{
@@ -92,15 +104,15 @@ func TestCalculatePositionSize(t *testing.T) {
 			// Distance = 100 - 98.5 = 1.5
 			// Size (Qty) = 10 / 1.5 ≈ 6.666...
 			input: CalculationInput{
-				Balance:     1000,
-				RiskPercent: 1.0,
-				EntryPrice:  100,
+				Balance:     d(1000),
+				RiskPercent: d(1.0),
+				EntryPrice:  d(100),
 				StopLosses: []StopLoss{
-					{Price: 99, Weight: 0.5},
-					{Price: 98, Weight: 0.5},
+					{Price: d(99), Weight: d(0.5)},
+					{Price: d(98), Weight: d(0.5)},
 				},
-				Leverage: 1,
-				FeeRate:  0,
+				Leverage: d(1),
+				FeeRate:  d(0),
 			},
 			verifyFn: func(t *testing.T, res CalculationResult, err error) {
 				if err != nil {
@@ -108,18 +120,16 @@ func TestCalculatePositionSize(t *testing.T) {
 				}
 
 				// ตรวจสอบ Weighted Avg SL
-				expectedAvgSL := 98.5
-				if res.WeightedAvgSL != expectedAvgSL {
-					t.Errorf("Expected AvgSL %.2f but got %.2f", expectedAvgSL, res.WeightedAvgSL)
+				expectedAvgSL := d(98.5)
+				if !res.WeightedAvgSL.Equal(expectedAvgSL) {
+					t.Errorf("Expected AvgSL %s but got %s", expectedAvgSL, res.WeightedAvgSL)
 				}
 
 				// ตรวจสอบ Quantity
-				expectedQty := 10.0 / 1.5 // 6.666...
+				expectedQty := d(10).Div(d(1.5)) // 6.666...
 
 				// ยอมรับความคลาดเคลื่อนจากการปัดเศษใน Code (ปัด 6 ตำแหน่ง)
-				if math.Abs(res.PositionQuantity-expectedQty) > 0.00001 {
-					t.Errorf("Expected Quantity %.4f but got %.4f", expectedQty, res.PositionQuantity)
-				}
+				assertDecimal(t, "Quantity", res.PositionQuantity, expectedQty, 0.00001)
 			},
 		},
 	}
@@ -134,3 +144,87 @@ func TestCalculatePositionSize(t *testing.T) {
 		})
 	}
 }
+
+// TestCalculatePositionSizeBreakdown - ทดสอบผลแยกราย SL และค่า Risk/Distance ที่คืนออกมา
+func TestCalculatePositionSizeBreakdown(t *testing.T) {
+	// ทุน 1000, Risk 1% (10$), Entry 100, SL1: 99 (น้ำหนัก 3), SL2: 98 (น้ำหนัก 1)
+	// Weighted SL = (99*3 + 98*1) / 4 = 98.75, Distance = 1.25
+	// Qty = 10 / 1.25 = 8 → SL1 ได้ 6 ตัว (ขาดทุน 6$), SL2 ได้ 2 ตัว (ขาดทุน 4$)
+	res, err := CalculatePositionSize(CalculationInput{
+		Balance:     d(1000),
+		RiskPercent: d(1.0),
+		EntryPrice:  d(100),
+		StopLosses: []StopLoss{
+			{Price: d(99), Weight: d(3)},
+			{Price: d(98), Weight: d(1)},
+		},
+		Leverage: d(1),
+	})
+	if err != nil {
+		t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+	}
+
+	assertDecimal(t, "RiskAmount", res.RiskAmount, d(10), 0)
+	assertDecimal(t, "Distance", res.Distance, d(1.25), 0)
+	if len(res.StopLossBreakdown) != 2 {
+		t.Fatalf("Expected 2 breakdown rows but got %d", len(res.StopLossBreakdown))
+	}
+
+	expected := []StopLossBreakdown{
+		{Price: d(99), Weight: d(0.75), Quantity: d(6), Distance: d(1), Loss: d(6)},
+		{Price: d(98), Weight: d(0.25), Quantity: d(2), Distance: d(2), Loss: d(4)},
+	}
+	for i, want := range expected {
+		got := res.StopLossBreakdown[i]
+		if !got.Price.Equal(want.Price) || !got.Weight.Equal(want.Weight) || !got.Quantity.Equal(want.Quantity) ||
+			!got.Distance.Equal(want.Distance) || !got.Loss.Equal(want.Loss) {
+			t.Errorf("breakdown[%d]: expected %+v but got %+v", i, want, got)
+		}
+	}
+}
+
+// TestCalculatePositionSizeValidationError - Error ต้องเป็น ValidationError พร้อม Field/Code
+func TestCalculatePositionSizeValidationError(t *testing.T) {
+	_, err := CalculatePositionSize(CalculationInput{
+		Balance:     d(1000),
+		RiskPercent: d(1.0),
+		EntryPrice:  d(100),
+		StopLosses: []StopLoss{
+			{Price: d(99), Weight: d(1)},
+			{Price: d(98), Weight: d(0)},
+		},
+	})
+
+	vErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected *ValidationError but got %T (%v)", err, err)
+	}
+	if vErr.Field != "stop_losses[1].weight" || vErr.Code != "must_be_positive" {
+		t.Errorf("Unexpected field/code: %s / %s", vErr.Field, vErr.Code)
+	}
+}
+
+// TestCalculatePositionSizeSmallCap - เหรียญราคาเล็ก (SHIB/PEPE) ต้องไม่เพี้ยนจาก float
+func TestCalculatePositionSizeSmallCap(t *testing.T) {
+	// Entry 0.00001234, SL 0.00001200 → Distance 0.00000034
+	// Qty = 10 / 0.00000034 = 29,411,764.705882...
+	res, err := CalculatePositionSize(CalculationInput{
+		Balance:     decimal.RequireFromString("1000"),
+		RiskPercent: decimal.RequireFromString("1"),
+		EntryPrice:  decimal.RequireFromString("0.00001234"),
+		StopLosses:  []StopLoss{{Price: decimal.RequireFromString("0.00001200"), Weight: decimal.RequireFromString("1")}},
+	})
+	if err != nil {
+		t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+	}
+
+	if got := res.Distance.String(); got != "0.00000034" {
+		t.Errorf("Expected Distance 0.00000034 but got %s", got)
+	}
+	if got := res.WeightedAvgSL.String(); got != "0.000012" {
+		t.Errorf("Expected AvgSL 0.000012 but got %s", got)
+	}
+	if got := res.PositionQuantity.String(); got != "29411764.705882" {
+		t.Errorf("Expected Quantity 29411764.705882 but got %s", got)
+	}
+}
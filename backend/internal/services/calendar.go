@@ -0,0 +1,133 @@
+// Package services - Calendar & Timezone
+// แบ่งวันตาม Timezone ของผู้ใช้ (เช่น Asia/Bangkok) ไม่ใช่ UTC แล้วสรุป PnL รายวันของเดือน
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTimezone - Timezone ตั้งต้นของผู้ใช้ที่ยังไม่ได้ตั้งค่า
+const DefaultTimezone = "UTC"
+
+// LoadTimezone - แปลงชื่อ IANA (เช่น "Asia/Bangkok") เป็น Location ("" = UTC)
+func LoadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	// time.LoadLocation รับ "Local" ด้วย ซึ่งขึ้นกับเครื่อง Server ไม่ใช่ของผู้ใช้
+	if name == "Local" {
+		return nil, newValidationError("timezone", "invalid_timezone", "ต้องระบุชื่อ Timezone แบบ IANA เช่น Asia/Bangkok")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, newValidationError("timezone", "invalid_timezone", fmt.Sprintf("ไม่รู้จัก Timezone %q", name))
+	}
+	return loc, nil
+}
+
+// ParseLocalDate - แปลง "2006-01-02" เป็นเวลาเริ่มวัน (00:00) ตาม Timezone loc
+func ParseLocalDate(value string, loc *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(value), loc)
+	if err != nil {
+		return time.Time{}, newValidationError("date", "invalid_date", fmt.Sprintf("วันที่ %q ต้องเป็นรูปแบบ YYYY-MM-DD", value))
+	}
+	return date, nil
+}
+
+// ParseMonth - แปลง "2006-01" เป็นช่วง [ต้นเดือน, ต้นเดือนถัดไป) ตาม Timezone loc
+func ParseMonth(value string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", strings.TrimSpace(value), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, newValidationError("month", "invalid_month", fmt.Sprintf("เดือน %q ต้องเป็นรูปแบบ YYYY-MM", value))
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// CalendarTrade - ไม้ที่ปิดแล้วหนึ่งไม้
+type CalendarTrade struct {
+	ClosedAt time.Time       `gorm:"column:closed_time"`
+	PnL      decimal.Decimal `gorm:"column:pnl"`
+	Outcome  string          `gorm:"column:outcome"`
+}
+
+// CalendarDay - สรุปหนึ่งวัน
+type CalendarDay struct {
+	Date    string          `json:"date"` // YYYY-MM-DD ตาม Timezone ของผู้ใช้
+	Trades  int             `json:"trades"`
+	Wins    int             `json:"wins"`
+	Losses  int             `json:"losses"`
+	NetPnL  decimal.Decimal `json:"net_pnl"`
+	WinRate decimal.Decimal `json:"win_rate"`
+}
+
+// CalendarMonth - สรุปทั้งเดือน (มีครบทุกวัน วันที่ไม่ได้เทรดเป็น 0)
+type CalendarMonth struct {
+	Month       string          `json:"month"`
+	Timezone    string          `json:"timezone"`
+	Days        []CalendarDay   `json:"days"`
+	Trades      int             `json:"trades"`
+	NetPnL      decimal.Decimal `json:"net_pnl"`
+	WinRate     decimal.Decimal `json:"win_rate"`
+	TradingDays int             `json:"trading_days"`
+	GreenDays   int             `json:"green_days"` // วันที่ Net PnL > 0
+	RedDays     int             `json:"red_days"`   // วันที่ Net PnL < 0
+}
+
+// BuildCalendar - สรุป PnL รายวันของเดือนที่เริ่มที่ start (ต้นเดือนตาม Timezone ที่ต้องการ)
+// ไม้ที่ปิดนอกเดือนนี้จะถูกข้าม
+func BuildCalendar(start time.Time, trades []CalendarTrade) CalendarMonth {
+	loc := start.Location()
+	end := start.AddDate(0, 1, 0)
+	month := CalendarMonth{Month: start.Format("2006-01"), Timezone: loc.String(), Days: []CalendarDay{}}
+
+	index := make(map[string]int)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		index[date] = len(month.Days)
+		month.Days = append(month.Days, CalendarDay{Date: date})
+	}
+
+	wins := 0
+	for _, trade := range trades {
+		i, ok := index[trade.ClosedAt.In(loc).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day := &month.Days[i]
+		day.Trades++
+		day.NetPnL = day.NetPnL.Add(trade.PnL)
+		switch trade.Outcome {
+		case OutcomeWin:
+			day.Wins++
+			wins++
+		case OutcomeLoss:
+			day.Losses++
+		}
+		month.Trades++
+		month.NetPnL = month.NetPnL.Add(trade.PnL)
+	}
+
+	for i := range month.Days {
+		day := &month.Days[i]
+		if day.Trades == 0 {
+			continue
+		}
+		month.TradingDays++
+		day.WinRate = decimal.NewFromInt(int64(day.Wins)).Div(decimal.NewFromInt(int64(day.Trades))).Mul(decimalHundred).Round(2)
+		switch {
+		case day.NetPnL.IsPositive():
+			month.GreenDays++
+		case day.NetPnL.IsNegative():
+			month.RedDays++
+		}
+	}
+	if month.Trades > 0 {
+		month.WinRate = decimal.NewFromInt(int64(wins)).Div(decimal.NewFromInt(int64(month.Trades))).Mul(decimalHundred).Round(2)
+	}
+	return month
+}
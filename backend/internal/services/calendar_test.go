@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBuildCalendarBangkok - ไม้ที่ปิด 20:00 UTC ของวันที่ 1 = 03:00 วันที่ 2 เวลากรุงเทพ ต้องนับเป็นวันที่ 2
+func TestBuildCalendarBangkok(t *testing.T) {
+	bangkok, err := LoadTimezone("Asia/Bangkok")
+	if err != nil {
+		t.Fatalf("LoadTimezone: %v", err)
+	}
+	start, end, err := ParseMonth("2026-03", bangkok)
+	if err != nil {
+		t.Fatalf("ParseMonth: %v", err)
+	}
+	if !end.Equal(time.Date(2026, time.April, 1, 0, 0, 0, 0, bangkok)) {
+		t.Fatalf("ปลายช่วงผิด: %v", end)
+	}
+
+	trades := []CalendarTrade{
+		{ClosedAt: time.Date(2026, time.March, 1, 20, 0, 0, 0, time.UTC), PnL: d(50), Outcome: OutcomeWin},
+		{ClosedAt: time.Date(2026, time.March, 2, 4, 0, 0, 0, time.UTC), PnL: d(-20), Outcome: OutcomeLoss},
+		{ClosedAt: time.Date(2026, time.March, 5, 1, 0, 0, 0, time.UTC), PnL: d(-10), Outcome: OutcomeLoss},
+		{ClosedAt: time.Date(2026, time.February, 28, 16, 0, 0, 0, time.UTC), PnL: d(99), Outcome: OutcomeWin}, // 23:00 ก.พ. ไม่อยู่ในเดือนนี้
+	}
+	month := BuildCalendar(start, trades)
+
+	if len(month.Days) != 31 || month.Month != "2026-03" || month.Timezone != "Asia/Bangkok" {
+		t.Fatalf("เดือนผิด: %d วัน %s %s", len(month.Days), month.Month, month.Timezone)
+	}
+	if month.Days[0].Trades != 0 {
+		t.Errorf("วันที่ 1 (เวลาไทย) ต้องไม่มีไม้ แต่ได้ %d", month.Days[0].Trades)
+	}
+	day2 := month.Days[1]
+	if day2.Date != "2026-03-02" || day2.Trades != 2 || day2.Wins != 1 {
+		t.Fatalf("วันที่ 2 ผิด: %+v", day2)
+	}
+	assertDecimal(t, "Day2.NetPnL", day2.NetPnL, d(30), 0.0001)
+	assertDecimal(t, "Day2.WinRate", day2.WinRate, d(50), 0.0001)
+
+	if month.Trades != 3 || month.TradingDays != 2 || month.GreenDays != 1 || month.RedDays != 1 {
+		t.Errorf("สรุปเดือนผิด: %+v", month)
+	}
+	assertDecimal(t, "Month.NetPnL", month.NetPnL, d(20), 0.0001)
+}
+
+// TestParseLocalDate - วันที่ตาม Timezone ของผู้ใช้ และไม่รับรูปแบบผิด/Timezone ที่ไม่รู้จัก
+func TestParseLocalDate(t *testing.T) {
+	bangkok, _ := LoadTimezone("Asia/Bangkok")
+	date, err := ParseLocalDate("2026-03-02", bangkok)
+	if err != nil {
+		t.Fatalf("ParseLocalDate: %v", err)
+	}
+	// 00:00 เวลาไทย = 17:00 UTC ของวันก่อนหน้า
+	if want := time.Date(2026, time.March, 1, 17, 0, 0, 0, time.UTC); !date.Equal(want) {
+		t.Errorf("Expected %v but got %v", want, date.UTC())
+	}
+
+	if _, err := ParseLocalDate("02/03/2026", bangkok); err == nil {
+		t.Errorf("รูปแบบวันที่ผิดต้อง Error")
+	}
+	if _, err := LoadTimezone("Mars/Olympus"); err == nil {
+		t.Errorf("Timezone ที่ไม่รู้จักต้อง Error")
+	}
+	if _, err := LoadTimezone("Local"); err == nil {
+		t.Errorf("ไม่ควรรับ Local")
+	}
+	if loc, err := LoadTimezone(""); err != nil || loc != time.UTC {
+		t.Errorf("ค่าว่างต้องเป็น UTC")
+	}
+}
@@ -0,0 +1,97 @@
+// Package services - ตรวจรูปแบบอีเมล และนโยบายฟีเจอร์ที่ต้องยืนยันอีเมลก่อนใช้
+package services
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// ความยาวสูงสุดของอีเมล (RFC 5321) และของคอลัมน์ users.email
+const (
+	maxEmailLength      = 100
+	maxEmailLocalLength = 64
+)
+
+// ฟีเจอร์ที่ตั้งให้ต้องยืนยันอีเมลก่อนใช้ได้ (ENV "EMAIL_VERIFICATION_REQUIRED_FOR")
+const (
+	FeatureAI            = "ai"             // /api/ai/* (เรียก API ภายนอกมีค่าใช้จ่าย)
+	FeatureImport        = "import"         // นำเข้า Trade จากไฟล์
+	FeatureExport        = "export"         // ส่งออก Journal เป็น CSV
+	FeatureBackup        = "backup"         // Backup/Restore ทั้งบัญชี
+	FeaturePasswordReset = "password_reset" // ลืมรหัสผ่าน (ส่ง OTP ไปอีเมลที่ยังไม่ยืนยัน)
+)
+
+// DefaultVerificationFeatures - ค่า Default เมื่อไม่ได้ตั้ง ENV
+var DefaultVerificationFeatures = []string{FeatureAI, FeatureImport, FeatureBackup}
+
+// knownFeatures - ชื่อฟีเจอร์ที่รู้จัก
+var knownFeatures = map[string]bool{
+	FeatureAI: true, FeatureImport: true, FeatureExport: true, FeatureBackup: true, FeaturePasswordReset: true,
+}
+
+// NormalizeEmail - ตรวจอีเมลตาม RFC 5322 (เฉพาะรูปแบบ local@domain ไม่รับชื่อแสดงผล) คืนอีเมลที่ตัดช่องว่าง
+// และแปลง Domain เป็นตัวเล็ก (Local part คงตัวพิมพ์เดิมตาม RFC)
+func NormalizeEmail(raw string) (string, error) {
+	email := strings.TrimSpace(raw)
+	if email == "" {
+		return "", newValidationError("email", "required", "กรุณากรอกอีเมล")
+	}
+	invalid := newValidationError("email", "invalid_email", "รูปแบบอีเมลไม่ถูกต้อง")
+	if len(email) > maxEmailLength {
+		return "", newValidationError("email", "too_long", "อีเมลยาวเกินไป")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", invalid
+	}
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if len(local) > maxEmailLocalLength || !validEmailDomain(domain) {
+		return "", invalid
+	}
+	return local + "@" + domain, nil
+}
+
+// validEmailDomain - Domain ต้องเป็นชื่อโฮสต์ที่มีจุด (ไม่รับ IP literal / localhost)
+func validEmailDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseVerificationFeatures - อ่านรายการฟีเจอร์ (คั่นด้วย ,) "" = Default, "none" = ไม่บังคับเลย
+// คืนชื่อที่ไม่รู้จักแยกไว้ให้ผู้เรียก Log เตือน
+func ParseVerificationFeatures(raw string) (features map[string]bool, unknown []string) {
+	features = make(map[string]bool)
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		for _, f := range DefaultVerificationFeatures {
+			features[f] = true
+		}
+		return features, nil
+	}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "" || item == "none":
+		case knownFeatures[item]:
+			features[item] = true
+		default:
+			unknown = append(unknown, item)
+		}
+	}
+	return features, unknown
+}
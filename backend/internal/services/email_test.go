@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeEmail - รูปแบบอีเมลที่รับ/ไม่รับ และการแปลง Domain เป็นตัวเล็ก
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		raw      string
+		want     string
+		wantCode string
+	}{
+		{"bob@example.com", "bob@example.com", ""},
+		{"  Bob.Smith+journal@Example.CO.th ", "Bob.Smith+journal@example.co.th", ""},
+		{"", "", "required"},
+		{"bob", "", "invalid_email"},
+		{"bob@localhost", "", "invalid_email"},
+		{"Bob <bob@example.com>", "", "invalid_email"},
+		{"bob@@example.com", "", "invalid_email"},
+		{"bob@-example.com", "", "invalid_email"},
+		{"bob@example..com", "", "invalid_email"},
+		{"bob@[127.0.0.1]", "", "invalid_email"},
+		{strings.Repeat("a", 65) + "@example.com", "", "invalid_email"},
+		{strings.Repeat("a", 60) + "@" + strings.Repeat("b", 40) + ".com", "", "too_long"},
+	}
+	for _, tc := range tests {
+		got, err := NormalizeEmail(tc.raw)
+		if tc.wantCode == "" {
+			if err != nil || got != tc.want {
+				t.Errorf("NormalizeEmail(%q) = (%q, %v), want %q", tc.raw, got, err, tc.want)
+			}
+			continue
+		}
+		vErr, ok := err.(*ValidationError)
+		if !ok || vErr.Code != tc.wantCode {
+			t.Errorf("NormalizeEmail(%q) error = %v, want code %s", tc.raw, err, tc.wantCode)
+		}
+	}
+}
+
+// TestParseVerificationFeatures - Default, none และชื่อที่ไม่รู้จัก
+func TestParseVerificationFeatures(t *testing.T) {
+	features, unknown := ParseVerificationFeatures("")
+	if len(features) != len(DefaultVerificationFeatures) || len(unknown) != 0 {
+		t.Errorf("default: got %v %v", features, unknown)
+	}
+
+	features, _ = ParseVerificationFeatures("none")
+	if len(features) != 0 {
+		t.Errorf("none: got %v", features)
+	}
+
+	features, unknown = ParseVerificationFeatures(" AI, export ,chat")
+	if !features[FeatureAI] || !features[FeatureExport] || features[FeatureImport] {
+		t.Errorf("list: got %v", features)
+	}
+	if len(unknown) != 1 || unknown[0] != "chat" {
+		t.Errorf("unknown: got %v", unknown)
+	}
+}
@@ -0,0 +1,188 @@
+// Package services - Equity Curve & Drawdown
+// สร้างกราฟเงินทุนสะสมจากไม้ที่ปิดแล้ว (เรียงตามเวลาปิด) เริ่มจากยอดพอร์ต แล้วหา Drawdown
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ช่วงเวลาของจุดบนกราฟ
+const (
+	EquityBucketTrade = "trade" // จุดละไม้ (Default)
+	EquityBucketDay   = "day"
+	EquityBucketWeek  = "week" // เริ่มวันจันทร์
+	EquityBucketMonth = "month"
+)
+
+// EquityTrade - ไม้ที่ปิดแล้วหนึ่งไม้ (ต้องเรียงตามเวลาปิดมาก่อน)
+type EquityTrade struct {
+	ClosedAt time.Time       `gorm:"column:closed_time"`
+	PnL      decimal.Decimal `gorm:"column:pnl"`
+}
+
+// EquityPoint - จุดบนกราฟ (ค่า ณ ไม้สุดท้ายของช่วงนั้น)
+type EquityPoint struct {
+	Time            time.Time       `json:"time"`             // เวลาปิดไม้ หรือเวลาเริ่มของช่วง
+	Trades          int             `json:"trades"`           // จำนวนไม้ในช่วงนี้
+	PnL             decimal.Decimal `json:"pnl"`              // PnL รวมของช่วงนี้
+	Equity          decimal.Decimal `json:"equity"`           // เงินทุนสะสม
+	Peak            decimal.Decimal `json:"peak"`             // จุดสูงสุดที่เคยทำได้
+	Drawdown        decimal.Decimal `json:"drawdown"`         // Equity − Peak (≤ 0)
+	DrawdownPercent decimal.Decimal `json:"drawdown_percent"` // % จาก Peak (≤ 0)
+}
+
+// DrawdownStats - สรุป Drawdown
+type DrawdownStats struct {
+	MaxDrawdown              decimal.Decimal  `json:"max_drawdown"`         // USD (เป็นบวก)
+	MaxDrawdownPercent       decimal.Decimal  `json:"max_drawdown_percent"` // % จาก Peak (เป็นบวก อาจคนละช่วงกับ MaxDrawdown)
+	PeakAt                   *time.Time       `json:"peak_at"`              // จุดสูงสุดก่อน Max Drawdown
+	TroughAt                 *time.Time       `json:"trough_at"`            // จุดต่ำสุดของ Max Drawdown
+	RecoveredAt              *time.Time       `json:"recovered_at"`         // กลับมาเท่า Peak เดิม (nil = ยังไม่กลับ)
+	DrawdownDurationDays     decimal.Decimal  `json:"drawdown_duration_days"`
+	RecoveryTimeDays         *decimal.Decimal `json:"recovery_time_days"` // Trough → Recovered
+	CurrentUnderwater        decimal.Decimal  `json:"current_underwater"` // Equity ปัจจุบัน − Peak (≤ 0)
+	CurrentUnderwaterPercent decimal.Decimal  `json:"current_underwater_percent"`
+}
+
+// EquityCurve - กราฟเงินทุนพร้อมสรุป Drawdown
+type EquityCurve struct {
+	StartingBalance decimal.Decimal `json:"starting_balance"`
+	EndingBalance   decimal.Decimal `json:"ending_balance"`
+	Bucket          string          `json:"bucket"`
+	Points          []EquityPoint   `json:"points"`
+	Drawdown        DrawdownStats   `json:"drawdown"`
+}
+
+// BuildEquityCurve - สร้างกราฟจากยอดเริ่มต้นและไม้ที่ปิดแล้ว
+// Drawdown คิดจากทีละไม้เสมอ (ไม่ใช่จากจุดที่รวมเป็นช่วงแล้ว) เพื่อไม่ให้ Drawdown กลางช่วงหายไป
+// now = เวลาปัจจุบัน ใช้นับระยะเวลาของ Drawdown ที่ยังไม่ฟื้น, loc = Timezone ที่ใช้แบ่งวัน/สัปดาห์/เดือน
+func BuildEquityCurve(startingBalance decimal.Decimal, trades []EquityTrade, bucket string, loc *time.Location, now time.Time) (EquityCurve, error) {
+	if bucket == "" {
+		bucket = EquityBucketTrade
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch bucket {
+	case EquityBucketTrade, EquityBucketDay, EquityBucketWeek, EquityBucketMonth:
+	default:
+		return EquityCurve{}, newValidationError("bucket", "invalid_bucket", fmt.Sprintf("ไม่รู้จัก bucket %q (trade, day, week, month)", bucket))
+	}
+
+	curve := EquityCurve{StartingBalance: startingBalance, EndingBalance: startingBalance, Bucket: bucket, Points: []EquityPoint{}}
+	equity, peak := startingBalance, startingBalance
+	peakAt := time.Time{}
+	var maxDrawdown, maxDrawdownPercent decimal.Decimal
+	var maxPeakAt, troughAt, recoveredAt *time.Time
+	maxRecovered := true
+
+	for _, trade := range trades {
+		equity = equity.Add(trade.PnL)
+		if equity.GreaterThanOrEqual(peak) {
+			// กลับมาถึง Peak เดิม = ฟื้นจาก Drawdown ที่ใหญ่ที่สุด (ถ้ายังไม่เคยฟื้น)
+			if !maxRecovered {
+				at := trade.ClosedAt
+				recoveredAt = &at
+				maxRecovered = true
+			}
+			peak, peakAt = equity, trade.ClosedAt
+		}
+
+		drawdown := equity.Sub(peak)
+		drawdownPercent := percentOfPeak(drawdown, peak)
+		if drawdown.Neg().GreaterThan(maxDrawdown) {
+			maxDrawdown = drawdown.Neg()
+			start, trough := peakAt, trade.ClosedAt
+			maxPeakAt, troughAt, recoveredAt = &start, &trough, nil
+			maxRecovered = false
+		}
+		if drawdownPercent.Neg().GreaterThan(maxDrawdownPercent) {
+			maxDrawdownPercent = drawdownPercent.Neg()
+		}
+
+		point := EquityPoint{
+			Time:            trade.ClosedAt,
+			Trades:          1,
+			PnL:             trade.PnL,
+			Equity:          equity,
+			Peak:            peak,
+			Drawdown:        drawdown,
+			DrawdownPercent: drawdownPercent.Round(4),
+		}
+		curve.Points = appendEquityPoint(curve.Points, point, bucket, loc)
+	}
+
+	curve.EndingBalance = equity
+	stats := DrawdownStats{
+		MaxDrawdown:              maxDrawdown,
+		MaxDrawdownPercent:       maxDrawdownPercent.Round(4),
+		PeakAt:                   maxPeakAt,
+		TroughAt:                 troughAt,
+		RecoveredAt:              recoveredAt,
+		CurrentUnderwater:        equity.Sub(peak),
+		CurrentUnderwaterPercent: percentOfPeak(equity.Sub(peak), peak).Round(4),
+	}
+	if maxPeakAt != nil {
+		// ไม่มีเวลาของ Peak (Peak = ยอดเริ่มต้น) ให้นับจากไม้แรก
+		start := *maxPeakAt
+		if start.IsZero() {
+			start = trades[0].ClosedAt
+		}
+		end := now
+		if recoveredAt != nil {
+			end = *recoveredAt
+			recovery := durationDays(recoveredAt.Sub(*troughAt))
+			stats.RecoveryTimeDays = &recovery
+		}
+		stats.DrawdownDurationDays = durationDays(end.Sub(start))
+		stats.PeakAt = &start
+	}
+	curve.Drawdown = stats
+	return curve, nil
+}
+
+// appendEquityPoint - เพิ่มจุด หรือรวมกับจุดสุดท้ายถ้าอยู่ในช่วงเดียวกัน
+func appendEquityPoint(points []EquityPoint, point EquityPoint, bucket string, loc *time.Location) []EquityPoint {
+	if bucket == EquityBucketTrade {
+		return append(points, point)
+	}
+	point.Time = bucketStart(point.Time, bucket, loc)
+	if n := len(points); n > 0 && points[n-1].Time.Equal(point.Time) {
+		last := points[n-1]
+		point.Trades += last.Trades
+		point.PnL = point.PnL.Add(last.PnL)
+		points[n-1] = point
+		return points
+	}
+	return append(points, point)
+}
+
+// bucketStart - เวลาเริ่มของช่วงที่ t อยู่ (ตาม Timezone loc)
+func bucketStart(t time.Time, bucket string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch bucket {
+	case EquityBucketWeek:
+		offset := (int(day.Weekday()) + 6) % 7 // จันทร์ = 0
+		return day.AddDate(0, 0, -offset)
+	case EquityBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return day
+}
+
+// percentOfPeak - value เป็น % ของ Peak (Peak ≤ 0 คิด % ไม่ได้ คืน 0)
+func percentOfPeak(value, peak decimal.Decimal) decimal.Decimal {
+	if !peak.IsPositive() {
+		return decimal.Zero
+	}
+	return value.Div(peak).Mul(decimalHundred)
+}
+
+// durationDays - แปลงระยะเวลาเป็นจำนวนวัน (ทศนิยม 2 ตำแหน่ง)
+func durationDays(d time.Duration) decimal.Decimal {
+	return decimal.NewFromFloat(d.Hours()).Div(decimal.NewFromInt(24)).Round(2)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// day - เวลาเที่ยงวัน UTC ของวันที่ n ใน ม.ค. 2026 (1 ม.ค. 2026 = วันพฤหัส)
+func day(n int) time.Time {
+	return time.Date(2026, time.January, n, 12, 0, 0, 0, time.UTC)
+}
+
+// TestBuildEquityCurve - พอร์ต 1,000 → 1,100 (Peak) → 880 (ต่ำสุด -20%) → 1,150 (ฟื้น)
+func TestBuildEquityCurve(t *testing.T) {
+	trades := []EquityTrade{
+		{ClosedAt: day(1), PnL: d(100)},  // 1,100 Peak
+		{ClosedAt: day(3), PnL: d(-120)}, // 980
+		{ClosedAt: day(5), PnL: d(-100)}, // 880 Trough: -220 (-20%)
+		{ClosedAt: day(8), PnL: d(270)},  // 1,150 ฟื้นแล้ว
+	}
+
+	curve, err := BuildEquityCurve(d(1000), trades, "", time.UTC, day(20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(curve.Points) != 4 {
+		t.Fatalf("Expected 4 points but got %d", len(curve.Points))
+	}
+	assertDecimal(t, "EndingBalance", curve.EndingBalance, d(1150), 0.0001)
+	assertDecimal(t, "Point[2].Drawdown", curve.Points[2].Drawdown, d(-220), 0.0001)
+
+	dd := curve.Drawdown
+	assertDecimal(t, "MaxDrawdown", dd.MaxDrawdown, d(220), 0.0001)
+	assertDecimal(t, "MaxDrawdownPercent", dd.MaxDrawdownPercent, d(20), 0.0001)
+	assertDecimal(t, "CurrentUnderwater", dd.CurrentUnderwater, d(0), 0.0001)
+	if dd.PeakAt == nil || !dd.PeakAt.Equal(day(1)) || dd.TroughAt == nil || !dd.TroughAt.Equal(day(5)) {
+		t.Fatalf("Peak/Trough ผิด: %v / %v", dd.PeakAt, dd.TroughAt)
+	}
+	if dd.RecoveredAt == nil || !dd.RecoveredAt.Equal(day(8)) {
+		t.Fatalf("RecoveredAt ผิด: %v", dd.RecoveredAt)
+	}
+	// Peak วันที่ 1 → ฟื้นวันที่ 8 = 7 วัน, Trough วันที่ 5 → ฟื้นวันที่ 8 = 3 วัน
+	assertDecimal(t, "DrawdownDurationDays", dd.DrawdownDurationDays, d(7), 0.0001)
+	if dd.RecoveryTimeDays == nil {
+		t.Fatalf("RecoveryTimeDays ต้องมีค่า")
+	}
+	assertDecimal(t, "RecoveryTimeDays", *dd.RecoveryTimeDays, d(3), 0.0001)
+}
+
+// TestBuildEquityCurveStillUnderwater - ยังไม่ฟื้น นับระยะเวลาถึงตอนนี้ และ RecoveryTime เป็น nil
+func TestBuildEquityCurveStillUnderwater(t *testing.T) {
+	trades := []EquityTrade{
+		{ClosedAt: day(2), PnL: d(-50)},
+		{ClosedAt: day(4), PnL: d(20)},
+	}
+
+	curve, err := BuildEquityCurve(d(500), trades, EquityBucketTrade, time.UTC, day(12))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dd := curve.Drawdown
+	assertDecimal(t, "MaxDrawdown", dd.MaxDrawdown, d(50), 0.0001)
+	assertDecimal(t, "MaxDrawdownPercent", dd.MaxDrawdownPercent, d(10), 0.0001)
+	assertDecimal(t, "CurrentUnderwater", dd.CurrentUnderwater, d(-30), 0.0001)
+	assertDecimal(t, "CurrentUnderwaterPercent", dd.CurrentUnderwaterPercent, d(-6), 0.0001)
+	if dd.RecoveredAt != nil || dd.RecoveryTimeDays != nil {
+		t.Errorf("ยังไม่ฟื้น RecoveredAt/RecoveryTimeDays ต้องเป็น nil")
+	}
+	// Peak คือยอดเริ่มต้น นับจากไม้แรก (วันที่ 2) ถึงตอนนี้ (วันที่ 12) = 10 วัน
+	assertDecimal(t, "DrawdownDurationDays", dd.DrawdownDurationDays, d(10), 0.0001)
+}
+
+// TestBuildEquityCurveBuckets - รวมจุดเป็นรายวัน/สัปดาห์/เดือน โดยใช้ค่าของไม้สุดท้ายในช่วง
+func TestBuildEquityCurveBuckets(t *testing.T) {
+	trades := []EquityTrade{
+		{ClosedAt: day(5), PnL: d(10)}, // จันทร์
+		{ClosedAt: day(5).Add(time.Hour), PnL: d(-4)},
+		{ClosedAt: day(7), PnL: d(6)},  // พุธ สัปดาห์เดียวกัน
+		{ClosedAt: day(12), PnL: d(1)}, // จันทร์ถัดไป
+	}
+
+	tests := []struct {
+		bucket     string
+		wantPoints int
+		wantFirst  float64 // PnL ของจุดแรก
+	}{
+		{EquityBucketDay, 3, 6},
+		{EquityBucketWeek, 2, 12},
+		{EquityBucketMonth, 1, 13},
+	}
+	for _, tc := range tests {
+		t.Run(tc.bucket, func(t *testing.T) {
+			curve, err := BuildEquityCurve(d(100), trades, tc.bucket, time.UTC, day(20))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(curve.Points) != tc.wantPoints {
+				t.Fatalf("Expected %d points but got %d", tc.wantPoints, len(curve.Points))
+			}
+			assertDecimal(t, "Points[0].PnL", curve.Points[0].PnL, d(tc.wantFirst), 0.0001)
+			assertDecimal(t, "Last.Equity", curve.Points[len(curve.Points)-1].Equity, d(113), 0.0001)
+		})
+	}
+
+	if _, err := BuildEquityCurve(d(100), trades, "hour", time.UTC, day(20)); err == nil {
+		t.Errorf("bucket ที่ไม่รู้จักต้อง Error")
+	}
+}
@@ -0,0 +1,101 @@
+// Package services - Partial Exit (ทยอยปิดไม้)
+// รวมผลการขายทีละส่วน: เหลือเหรียญเท่าไหร่ กำไรที่รับรู้แล้ว และราคาออกเฉลี่ย
+package services
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExitFill - การขายออกหนึ่งครั้ง
+type ExitFill struct {
+	Price    decimal.Decimal // ราคาที่ขายได้จริง
+	Quantity decimal.Decimal // จำนวนเหรียญที่ขาย
+	Fee      decimal.Decimal // Fee ของการขายครั้งนี้ (USD)
+}
+
+// ExitSummary - สรุปการทยอยปิดไม้ทั้งหมด
+type ExitSummary struct {
+	ExitedQuantity    decimal.Decimal `json:"exited_quantity"`    // ขายไปแล้วกี่เหรียญ
+	RemainingQuantity decimal.Decimal `json:"remaining_quantity"` // เหลือถืออยู่กี่เหรียญ
+	ExitedPercent     decimal.Decimal `json:"exited_percent"`     // ขายไปแล้วกี่ % ของไม้
+	AvgExitPrice      decimal.Decimal `json:"avg_exit_price"`     // ราคาออกเฉลี่ย (ถ่วงตามจำนวน)
+	GrossPnL          decimal.Decimal `json:"gross_pnl"`          // กำไรก่อนหัก Fee ของส่วนที่ขายแล้ว
+	Fees              decimal.Decimal `json:"fees"`               // Fee รวมของส่วนที่ขายแล้ว
+	RealizedPnL       decimal.Decimal `json:"realized_pnl"`       // กำไรสุทธิที่รับรู้แล้ว (USD)
+	FullyExited       bool            `json:"fully_exited"`       // ขายครบ 100% แล้ว
+}
+
+// PartialExitPnL - กำไร/ขาดทุนของการขายหนึ่งครั้ง คืน (Gross, Net)
+func PartialExitPnL(side string, entryPrice decimal.Decimal, fill ExitFill) (decimal.Decimal, decimal.Decimal) {
+	move := fill.Price.Sub(entryPrice)
+	if !strings.EqualFold(side, "LONG") {
+		move = move.Neg()
+	}
+	gross := move.Mul(fill.Quantity)
+	return gross, gross.Sub(fill.Fee)
+}
+
+// SummarizeExits - รวมทุก Fill ที่ขายไปแล้ว เทียบกับจำนวนเหรียญทั้งไม้
+func SummarizeExits(side string, entryPrice, totalQuantity decimal.Decimal, fills []ExitFill) ExitSummary {
+	summary := ExitSummary{}
+	notional := decimal.Zero
+
+	for _, fill := range fills {
+		gross, net := PartialExitPnL(side, entryPrice, fill)
+		summary.ExitedQuantity = summary.ExitedQuantity.Add(fill.Quantity)
+		summary.GrossPnL = summary.GrossPnL.Add(gross)
+		summary.Fees = summary.Fees.Add(fill.Fee)
+		summary.RealizedPnL = summary.RealizedPnL.Add(net)
+		notional = notional.Add(fill.Price.Mul(fill.Quantity))
+	}
+
+	if summary.ExitedQuantity.IsPositive() {
+		summary.AvgExitPrice = notional.Div(summary.ExitedQuantity).Round(8)
+	}
+	summary.RemainingQuantity = decimal.Max(totalQuantity.Sub(summary.ExitedQuantity), decimal.Zero)
+	if totalQuantity.IsPositive() {
+		summary.ExitedPercent = summary.ExitedQuantity.Div(totalQuantity).Mul(decimalHundred).Round(4)
+	}
+	summary.FullyExited = totalQuantity.IsPositive() && summary.RemainingQuantity.IsZero()
+
+	summary.GrossPnL = summary.GrossPnL.Round(4)
+	summary.Fees = summary.Fees.Round(4)
+	summary.RealizedPnL = summary.RealizedPnL.Round(4)
+	return summary
+}
+
+// ExitSettlement - ผลของไม้ที่ขายครบแล้ว (รวม Fee ฝั่งเข้าด้วย)
+type ExitSettlement struct {
+	PnL     decimal.Decimal // กำไรสุทธิหลังหัก Fee ทั้งเข้าและออก (USD)
+	Fees    decimal.Decimal // Fee เข้า + ออก (USD)
+	Outcome string          // WIN, LOSS, BREAK_EVEN ตัดสินจาก PnL สุทธิ
+}
+
+// SettleExits - ปิดไม้ที่ขายครบ: RealizedPnL ของ Exit ยังไม่หัก Fee ฝั่งเข้า จึงหักที่นี่ก่อนตัดสินผล
+// notional = มูลค่าไม้ตอนเข้า ใช้หาช่วงเสมอตัว
+func SettleExits(summary ExitSummary, entryFees, notional decimal.Decimal, tolerancePercent decimal.NullDecimal) ExitSettlement {
+	pnl := summary.RealizedPnL.Sub(entryFees).Round(4)
+	return ExitSettlement{
+		PnL:     pnl,
+		Fees:    summary.Fees.Add(entryFees).Round(4),
+		Outcome: closingOutcome(pnl, notional, tolerancePercent),
+	}
+}
+
+// ExitQuantityFromPercent - แปลง % ของไม้เป็นจำนวนเหรียญ
+func ExitQuantityFromPercent(totalQuantity, percent decimal.Decimal) decimal.Decimal {
+	return totalQuantity.Mul(percent).Div(decimalHundred)
+}
+
+// ValidateExitAllocation - เช็คว่าจำนวนที่จะขายไม่เกินที่เหลือ (รวมที่วางแผนไว้แล้ว)
+func ValidateExitAllocation(totalQuantity, allocatedQuantity, quantity decimal.Decimal) error {
+	if !quantity.IsPositive() {
+		return newValidationError("quantity", "must_be_positive", "จำนวนที่จะขายต้องมากกว่า 0")
+	}
+	if allocatedQuantity.Add(quantity).GreaterThan(totalQuantity) {
+		return newValidationError("quantity", "exceeds_remaining", "จำนวนที่จะขายเกินกว่าเหรียญที่เหลือในไม้")
+	}
+	return nil
+}
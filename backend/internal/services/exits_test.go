@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestSummarizeExits - ทยอยขาย LONG 2 ครั้ง แล้วเช็คราคาเฉลี่ย/กำไรที่รับรู้/จำนวนที่เหลือ
+func TestSummarizeExits(t *testing.T) {
+	// ไม้ 10 เหรียญ เข้า 100
+	// ขาย 4 ที่ 110 (Fee 0.4) → Gross 40, Net 39.6
+	// ขาย 6 ที่ 120 (Fee 0.6) → Gross 120, Net 119.4
+	fills := []ExitFill{
+		{Price: d(110), Quantity: d(4), Fee: d(0.4)},
+		{Price: d(120), Quantity: d(6), Fee: d(0.6)},
+	}
+
+	partial := SummarizeExits("LONG", d(100), d(10), fills[:1])
+	assertDecimal(t, "Remaining", partial.RemainingQuantity, d(6), 0)
+	assertDecimal(t, "ExitedPercent", partial.ExitedPercent, d(40), 0)
+	assertDecimal(t, "RealizedPnL", partial.RealizedPnL, d(39.6), 0)
+	if partial.FullyExited {
+		t.Errorf("ขายไป 40%% ยังไม่ควรปิดไม้")
+	}
+
+	full := SummarizeExits("LONG", d(100), d(10), fills)
+	// ราคาเฉลี่ย = (4 x 110 + 6 x 120) / 10 = 116
+	assertDecimal(t, "AvgExitPrice", full.AvgExitPrice, d(116), 0)
+	assertDecimal(t, "GrossPnL", full.GrossPnL, d(160), 0)
+	assertDecimal(t, "Fees", full.Fees, d(1), 0)
+	assertDecimal(t, "RealizedPnL", full.RealizedPnL, d(159), 0)
+	assertDecimal(t, "Remaining", full.RemainingQuantity, d(0), 0)
+	if !full.FullyExited {
+		t.Errorf("ขายครบ 100%% แล้วควร FullyExited")
+	}
+}
+
+// TestSettleExits - ปิดไม้ที่ขายครบ: PnL หัก Fee ฝั่งเข้า และ Fee รวมทั้งเข้าและออก
+func TestSettleExits(t *testing.T) {
+	// ไม้ 10 เหรียญ เข้า 100 (Fee ฝั่งเข้า 0.5) ขายครบที่ 116 เฉลี่ย (Fee ฝั่งออก 1) → Net = 160 - 1.5 = 158.5
+	full := SummarizeExits("LONG", d(100), d(10), []ExitFill{
+		{Price: d(110), Quantity: d(4), Fee: d(0.4)},
+		{Price: d(120), Quantity: d(6), Fee: d(0.6)},
+	})
+	settled := SettleExits(full, d(0.5), d(1000), decimal.NullDecimal{})
+	assertDecimal(t, "PnL", settled.PnL, d(158.5), 0)
+	assertDecimal(t, "Fees", settled.Fees, d(1.5), 0)
+	if settled.Outcome != OutcomeWin {
+		t.Errorf("Outcome = %s, want WIN", settled.Outcome)
+	}
+
+	// ขายออกเท่าทุน: Exit ได้ +0.5 แต่ Fee ฝั่งเข้า 2 ทำให้ขาดทุนสุทธิ 1.5 (เกินช่วงเสมอตัว 0.05% ของ 1,000 = 0.5)
+	flat := SummarizeExits("LONG", d(100), d(10), []ExitFill{{Price: d(100.1), Quantity: d(10), Fee: d(0.5)}})
+	settled = SettleExits(flat, d(2), d(1000), decimal.NullDecimal{})
+	assertDecimal(t, "PnL", settled.PnL, d(-1.5), 0)
+	assertDecimal(t, "Fees", settled.Fees, d(2.5), 0)
+	if settled.Outcome != OutcomeLoss {
+		t.Errorf("Outcome = %s, want LOSS (ตัดสินจาก PnL สุทธิหลังหัก Fee ฝั่งเข้า)", settled.Outcome)
+	}
+}
+
+// TestPartialExitPnLShort - SHORT ขายออกที่ราคาต่ำกว่า Entry ต้องได้กำไร
+func TestPartialExitPnLShort(t *testing.T) {
+	gross, net := PartialExitPnL("SHORT", d(100), ExitFill{Price: d(90), Quantity: d(2), Fee: d(0.5)})
+	assertDecimal(t, "Gross", gross, d(20), 0)
+	assertDecimal(t, "Net", net, d(19.5), 0)
+}
+
+// TestValidateExitAllocation - ห้ามวางแผนขายเกินจำนวนเหรียญในไม้
+func TestValidateExitAllocation(t *testing.T) {
+	if err := ValidateExitAllocation(d(10), d(6), d(4)); err != nil {
+		t.Fatalf("ขายพอดี 100%% ต้องผ่าน แต่ได้: %v", err)
+	}
+
+	err := ValidateExitAllocation(d(10), d(6), d(5))
+	vErr, ok := err.(*ValidationError)
+	if !ok || vErr.Code != "exceeds_remaining" {
+		t.Fatalf("Expected code exceeds_remaining but got %v", err)
+	}
+
+	assertDecimal(t, "QuantityFromPercent", ExitQuantityFromPercent(d(0.5), d(25)), d(0.125), 0)
+}
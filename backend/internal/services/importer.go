@@ -0,0 +1,445 @@
+// Package services - Import Trades จากไฟล์ของ Exchange/Broker
+// Importer แต่ละตัว (เลือกด้วยชื่อ หรือให้ตรวจจากเนื้อไฟล์) อ่านไฟล์เป็น Fill (การซื้อ/ขายแต่ละครั้ง)
+// แล้วรวมเป็นไม้ไป-กลับ (Round Trip): เปิด Position → ปิดจนเหลือ 0
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ชื่อ Importer ที่มีในระบบ (ใช้เป็น source ตอนนำเข้า และเก็บคู่กับ Trade ID กันนำเข้าซ้ำ)
+const (
+	ImportSourceBinanceFutures = "binance_futures"
+	ImportSourceBybit          = "bybit"
+	ImportSourceOKX            = "okx"
+	ImportSourceBitkub         = "bitkub"
+	ImportSourceMT4            = "mt4"
+	ImportSourceMT5            = "mt5"
+	ImportSourceGenericCSV     = "generic_csv"
+)
+
+// ฝั่งของ Fill
+const (
+	FillBuy  = "BUY"
+	FillSell = "SELL"
+)
+
+// importSniffBytes - จำนวน Byte ต้นไฟล์ที่ส่งให้ Detect
+const importSniffBytes = 64 * 1024
+
+// quoteAssets - เหรียญ/สกุลเงินที่ใช้ตั้งราคา เรียงยาวไปสั้น (FDUSD ต้องเช็คก่อน USD)
+var quoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USD", "THB", "BTC", "ETH", "BNB", "EUR", "JPY", "GBP", "CHF", "AUD", "CAD", "NZD"}
+
+// Importer - ตัวอ่านไฟล์ของ Exchange/Broker หนึ่งรูปแบบ
+// เพิ่มรูปแบบใหม่ได้โดยเขียน Importer แล้ว RegisterImporter ใน init() ไม่ต้องแก้ Handler
+type Importer interface {
+	// Name - ชื่อที่ใช้เลือก Importer (เช่น "binance_futures")
+	Name() string
+	// Detect - ไฟล์นี้เป็นรูปแบบของ Importer นี้หรือไม่ (ดูจากต้นไฟล์ไม่เกิน 64KB)
+	Detect(sample []byte) bool
+	// Parse - อ่านไฟล์เป็น Fill (ExternalID ว่างได้ ระบบจะสร้างจากเนื้อหาของแถวให้)
+	Parse(r io.Reader, opts ImportOptions) ([]ImportFill, []string, error)
+	// Normalize - รวม Fill เป็นไม้ (Futures ใช้ GroupRoundTrips, Spot ใช้ GroupSpotRoundTrips)
+	Normalize(fills []ImportFill) ([]ImportedTrade, []string)
+}
+
+// ImportOptions - ตัวเลือกเพิ่มเติมของการนำเข้า
+type ImportOptions struct {
+	Location   *time.Location    // Timezone ของเวลาในไฟล์ที่ไม่ได้ระบุ Timezone (nil = ค่าตั้งต้นของ Importer)
+	Columns    map[string]string // generic_csv: ฟิลด์ (time, symbol, side, ...) → หัวคอลัมน์ในไฟล์
+	TimeLayout string            // generic_csv: รูปแบบเวลาแบบ Go (เช่น "02/01/2006 15:04") ถ้าไม่ใช่รูปแบบมาตรฐาน
+}
+
+// location - Timezone ของเวลาในไฟล์ (ไม่ระบุ = fallback)
+func (o ImportOptions) location(fallback *time.Location) *time.Location {
+	if o.Location != nil {
+		return o.Location
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return time.UTC
+}
+
+// ImportFill - การซื้อ/ขายหนึ่งครั้งจากไฟล์
+type ImportFill struct {
+	ExternalID   string          `json:"external_id"`           // Trade ID ของ Exchange (ใช้กันนำเข้าซ้ำ)
+	PositionID   string          `json:"position_id,omitempty"` // Ticket ของ Position (MT4/MT5) Fill ต่าง Position ไม่รวมกัน
+	Symbol       string          `json:"symbol"`                // ชื่อตาม Exchange เช่น BTCUSDT
+	Pair         string          `json:"pair"`                  // ชื่อในระบบ เช่น BTC/USDT
+	Side         string          `json:"side"`                  // BUY, SELL
+	PositionSide string          `json:"position_side"`         // LONG/SHORT (Hedge Mode) หรือ "" (One-way)
+	Price        decimal.Decimal `json:"price"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	Fee          decimal.Decimal `json:"fee"`          // เป็นสกุล Quote
+	RealizedPnL  decimal.Decimal `json:"realized_pnl"` // กำไรที่ Exchange รายงาน (ถ้ามี)
+	Time         time.Time       `json:"time"`
+	Row          int             `json:"row"` // บรรทัดในไฟล์ (สำหรับแจ้ง Error)
+}
+
+// ImportedTrade - ไม้ไป-กลับหนึ่งไม้ที่รวมจาก Fill
+type ImportedTrade struct {
+	Pair         string          `json:"pair"`
+	Side         string          `json:"side"`   // LONG, SHORT
+	Status       string          `json:"status"` // OPEN, PARTIALLY_CLOSED, CLOSED
+	Entries      []ImportFill    `json:"entries"`
+	Exits        []ImportFill    `json:"exits"`
+	OpenedAt     time.Time       `json:"opened_at"`
+	ClosedAt     *time.Time      `json:"closed_at"`
+	ExternalIDs  []string        `json:"external_ids"`
+	Warnings     []string        `json:"warnings,omitempty"`
+	Entry        EntryAggregate  `json:"entry_summary"`
+	Exit         ExitSummary     `json:"exit_summary"`
+	ExchangePnL  decimal.Decimal `json:"exchange_pnl"` // รวม Realized PnL ที่ Exchange รายงาน (ไว้เทียบ)
+	scaledInLate bool
+}
+
+// ImportResult - ผลการอ่านไฟล์
+type ImportResult struct {
+	Source   string          `json:"source"`
+	Fills    int             `json:"fills"`
+	Trades   []ImportedTrade `json:"trades"`
+	Warnings []string        `json:"warnings"`
+}
+
+// importers - Importer ที่ลงทะเบียนไว้ (ตามลำดับ ใช้ตอนตรวจรูปแบบไฟล์)
+var importers []Importer
+
+// RegisterImporter - ลงทะเบียน Importer (ชื่อซ้ำ = Bug ของโปรแกรม)
+func RegisterImporter(importer Importer) {
+	if _, exists := LookupImporter(importer.Name()); exists {
+		panic(fmt.Sprintf("importer %q registered twice", importer.Name()))
+	}
+	importers = append(importers, importer)
+}
+
+// LookupImporter - หา Importer จากชื่อ
+func LookupImporter(name string) (Importer, bool) {
+	for _, importer := range importers {
+		if importer.Name() == name {
+			return importer, true
+		}
+	}
+	return nil, false
+}
+
+// ImporterNames - ชื่อ Importer ทั้งหมด (เรียงตามตัวอักษร)
+func ImporterNames() []string {
+	names := make([]string, len(importers))
+	for i, importer := range importers {
+		names[i] = importer.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectImporter - หา Importer ที่อ่านไฟล์นี้ได้จากต้นไฟล์
+func DetectImporter(sample []byte) (Importer, bool) {
+	for _, importer := range importers {
+		if importer.Detect(sample) {
+			return importer, true
+		}
+	}
+	return nil, false
+}
+
+// Import - อ่านไฟล์ด้วย Importer ที่เลือก ("" หรือ "auto" = ตรวจจากเนื้อไฟล์) แล้วรวมเป็นไม้
+func Import(name string, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	reader := bufio.NewReaderSize(r, importSniffBytes)
+
+	var importer Importer
+	var ok bool
+	if name == "" || name == "auto" {
+		sample, _ := reader.Peek(importSniffBytes) // ไฟล์เล็กกว่า 64KB ได้ io.EOF พร้อมข้อมูลทั้งไฟล์
+		if importer, ok = DetectImporter(sample); !ok {
+			return ImportResult{}, newValidationError("source", "unknown_format",
+				fmt.Sprintf("ไม่รู้จักรูปแบบไฟล์ ให้ระบุ source (%s)", strings.Join(ImporterNames(), ", ")))
+		}
+	} else if importer, ok = LookupImporter(name); !ok {
+		return ImportResult{}, newValidationError("source", "unknown_source",
+			fmt.Sprintf("ไม่รองรับ %q (%s)", name, strings.Join(ImporterNames(), ", ")))
+	}
+
+	fills, warnings, err := importer.Parse(reader, opts)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	fills, duplicateWarnings := assignExternalIDs(fills)
+	warnings = append(warnings, duplicateWarnings...)
+	if len(fills) == 0 {
+		return ImportResult{}, newValidationError("file", "no_fills", "ไม่พบรายการเทรดในไฟล์")
+	}
+
+	trades, groupWarnings := importer.Normalize(fills)
+	return ImportResult{
+		Source:   importer.Name(),
+		Fills:    len(fills),
+		Trades:   trades,
+		Warnings: append(warnings, groupWarnings...),
+	}, nil
+}
+
+// assignExternalIDs - ข้าม Fill ที่ Trade ID ซ้ำในไฟล์ และสร้าง ID ให้ Fill ที่ไม่มี
+// ID ที่สร้างมาจากเนื้อหาของแถว นำเข้าไฟล์เดิมซ้ำจะได้ ID เดิม (แถวที่เหมือนกันทุกอย่างนับลำดับต่อท้าย)
+func assignExternalIDs(fills []ImportFill) ([]ImportFill, []string) {
+	var warnings []string
+	seen := make(map[string]int)
+	result := make([]ImportFill, 0, len(fills))
+	for _, fill := range fills {
+		if fill.ExternalID == "" {
+			key := strings.Join([]string{fill.Time.UTC().Format(time.RFC3339Nano), fill.Symbol, fill.Side, fill.Price.String(), fill.Quantity.String(), fill.Fee.String()}, "|")
+			seen[key]++
+			sum := sha1.Sum([]byte(key + "|" + strconv.Itoa(seen[key])))
+			fill.ExternalID = "row:" + hex.EncodeToString(sum[:8])
+		} else if seen[fill.ExternalID] > 0 {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: Trade ID %s ซ้ำในไฟล์ ข้ามแถวนี้", fill.Row, fill.ExternalID))
+			continue
+		} else {
+			seen[fill.ExternalID]++
+		}
+		result = append(result, fill)
+	}
+	return result, warnings
+}
+
+// SymbolToPair - แปลงชื่อ Symbol ของ Exchange เป็นรูปแบบ Pair ของระบบ (กลับด้านกับ NormalizeSymbol)
+// BTCUSDT → BTC/USDT, BTC-USDT-SWAP → BTC/USDT, ETH_USDC → ETH/USDC, EURUSD.m → EUR/USD
+func SymbolToPair(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if strings.Contains(symbol, "/") {
+		return symbol
+	}
+	if parts := strings.FieldsFunc(symbol, func(r rune) bool { return r == '-' || r == '_' }); len(parts) >= 2 {
+		return parts[0] + "/" + parts[1]
+	}
+	if i := strings.IndexAny(symbol, ".#"); i > 0 {
+		symbol = symbol[:i] // Suffix ของ Broker เช่น EURUSD.m, XAUUSD#
+	}
+	for _, quote := range quoteAssets {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return strings.TrimSuffix(symbol, quote) + "/" + quote
+		}
+	}
+	return symbol
+}
+
+// quoteAsset - สกุล Quote ของ Pair (BTC/USDT → USDT)
+func quoteAsset(pair string) string {
+	if i := strings.LastIndex(pair, "/"); i >= 0 {
+		return pair[i+1:]
+	}
+	return ""
+}
+
+// parseFillSide - แปลงฝั่งเป็น BUY/SELL (แบบ "Open Long" บอก Position Side มาด้วย)
+func parseFillSide(raw string) (string, string) {
+	switch strings.ToUpper(strings.Join(strings.Fields(raw), " ")) {
+	case "BUY", "B":
+		return FillBuy, ""
+	case "SELL", "S":
+		return FillSell, ""
+	case "OPEN LONG":
+		return FillBuy, "LONG"
+	case "CLOSE LONG":
+		return FillSell, "LONG"
+	case "OPEN SHORT":
+		return FillSell, "SHORT"
+	case "CLOSE SHORT":
+		return FillBuy, "SHORT"
+	}
+	return "", ""
+}
+
+// importTimeLayouts - รูปแบบเวลาที่พบในไฟล์
+var importTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006.01.02 15:04:05", // MT4/MT5
+	"2006.01.02 15:04",
+}
+
+// parseImportTime - เวลาในไฟล์ (ข้อความหรือ Unix milliseconds) ที่ไม่มี Timezone ถือเป็นเวลาของ loc
+// layouts = รูปแบบเพิ่มเติมที่ลองก่อนรูปแบบมาตรฐาน
+func parseImportTime(raw string, loc *time.Location, layouts ...string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil && ms > 1e11 {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	for _, layout := range append(layouts, importTimeLayouts...) {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", raw)
+}
+
+// parseImportAmount - ตัวเลขที่อาจมีหน่วยต่อท้าย เช่น "0.0123 USDT", "1,234.5" หรือ "1 234.5" คืน (ค่า, หน่วย)
+func parseImportAmount(raw string) (decimal.Decimal, string) {
+	raw = strings.NewReplacer(",", "", " ", "", "\u00a0", "").Replace(strings.TrimSpace(raw))
+	end := 0
+	for end < len(raw) && strings.ContainsRune("0123456789.-+eE", rune(raw[end])) {
+		end++
+	}
+	// ถอยจนอ่านเป็นตัวเลขได้: "0.5ETH" ต้องไม่อ่าน E เป็นเลขยกกำลัง
+	for ; end > 0; end-- {
+		if value, err := decimal.NewFromString(raw[:end]); err == nil {
+			return value, strings.ToUpper(raw[end:])
+		}
+	}
+	return decimal.Zero, ""
+}
+
+// GroupRoundTrips - รวม Fill ของ Futures/Margin เป็นไม้ไป-กลับ แยกตาม Pair, Position Side และ Position ID
+// Fill ที่เพิ่ม Position = Entry, ที่ลด Position = Exit, กลับเป็น 0 = ปิดไม้
+// Fill ที่ลดจนเลย 0 (กลับฝั่ง) จะถูกแบ่ง: ส่วนแรกปิดไม้เดิม ส่วนที่เหลือเปิดไม้ใหม่ (ID ต่อท้าย "#open")
+func GroupRoundTrips(fills []ImportFill) ([]ImportedTrade, []string) {
+	return groupRoundTrips(fills, false)
+}
+
+// GroupSpotRoundTrips - เหมือน GroupRoundTrips แต่ Spot เปิด Short ไม่ได้
+// ขายโดยไม่มีรายการซื้อในไฟล์ (ซื้อไว้ก่อนช่วงของไฟล์) จะข้าม ขายเกินจำนวนที่ซื้อจะปิดไม้โดยไม่กลับฝั่ง
+func GroupSpotRoundTrips(fills []ImportFill) ([]ImportedTrade, []string) {
+	return groupRoundTrips(fills, true)
+}
+
+func groupRoundTrips(fills []ImportFill, spot bool) ([]ImportedTrade, []string) {
+	ordered := append([]ImportFill(nil), fills...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Time.Before(ordered[j].Time) })
+
+	var trades []ImportedTrade
+	var warnings []string
+	open := make(map[string]*ImportedTrade)
+	position := make(map[string]decimal.Decimal) // จำนวนที่ยังถืออยู่ของไม้ที่เปิด
+
+	for _, fill := range ordered {
+		key := fill.Pair + "|" + fill.PositionSide + "|" + fill.PositionID
+		trip := open[key]
+		if trip == nil {
+			if spot && fill.Side == FillSell {
+				warnings = append(warnings, fmt.Sprintf("แถว %d: ขาย %s โดยไม่มีรายการซื้อในไฟล์ ข้ามแถวนี้", fill.Row, fill.Pair))
+				continue
+			}
+			open[key] = newImportedTrade(fill)
+			position[key] = fill.Quantity
+			continue
+		}
+
+		increases := (trip.Side == "LONG") == (fill.Side == FillBuy)
+		if increases {
+			if len(trip.Exits) > 0 && !trip.scaledInLate {
+				trip.scaledInLate = true
+				trip.Warnings = append(trip.Warnings, "เติมไม้หลังจากทยอยปิดไปแล้ว PnL คิดจากราคาเข้าเฉลี่ยของทุก Entry อาจไม่ตรงกับ Exchange")
+			}
+			trip.Entries = append(trip.Entries, fill)
+			position[key] = position[key].Add(fill.Quantity)
+			continue
+		}
+
+		remaining := position[key]
+		if fill.Quantity.LessThanOrEqual(remaining) {
+			trip.Exits = append(trip.Exits, fill)
+			position[key] = remaining.Sub(fill.Quantity)
+			if position[key].IsZero() {
+				trades = append(trades, finishImportedTrade(*trip, &fill.Time))
+				delete(open, key)
+			}
+			continue
+		}
+
+		// เกินจำนวนที่ถือ: ปิดไม้เดิมด้วยจำนวนที่เหลือ แล้ว (ยกเว้น Spot) เปิดไม้ใหม่ด้วยส่วนเกิน
+		closing, opening := splitFill(fill, remaining)
+		trip.Exits = append(trip.Exits, closing)
+		trades = append(trades, finishImportedTrade(*trip, &fill.Time))
+		delete(open, key)
+		if spot {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: ขาย %s เกินจำนวนที่ซื้อในไฟล์ ส่วนเกินไม่นับ", fill.Row, fill.Pair))
+			continue
+		}
+		if fill.PositionSide != "" {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: ปิด %s %s เกินจำนวนที่ถืออยู่ ส่วนเกินนับเป็นไม้ใหม่", fill.Row, fill.Pair, fill.PositionSide))
+		}
+		open[key] = newImportedTrade(opening)
+		position[key] = opening.Quantity
+	}
+
+	// ไม้ที่ยังไม่ปิด เรียงตามเวลาเปิดเพื่อให้ผลลัพธ์คงที่
+	keys := make([]string, 0, len(open))
+	for key := range open {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := open[keys[i]].OpenedAt, open[keys[j]].OpenedAt
+		return a.Before(b) || (a.Equal(b) && keys[i] < keys[j])
+	})
+	for _, key := range keys {
+		trades = append(trades, finishImportedTrade(*open[key], nil))
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].OpenedAt.Before(trades[j].OpenedAt) })
+	return trades, warnings
+}
+
+// newImportedTrade - เปิดไม้ใหม่จาก Fill แรก
+func newImportedTrade(fill ImportFill) *ImportedTrade {
+	side := "LONG"
+	if fill.Side == FillSell {
+		side = "SHORT"
+	}
+	return &ImportedTrade{Pair: fill.Pair, Side: side, Entries: []ImportFill{fill}, OpenedAt: fill.Time}
+}
+
+// splitFill - แบ่ง Fill เป็นส่วนที่ปิดไม้เดิม (จำนวน closeQty) และส่วนที่เปิดไม้ใหม่ Fee/PnL แบ่งตามสัดส่วน
+func splitFill(fill ImportFill, closeQty decimal.Decimal) (ImportFill, ImportFill) {
+	ratio := closeQty.Div(fill.Quantity)
+	closing, opening := fill, fill
+	closing.Quantity = closeQty
+	closing.Fee = fill.Fee.Mul(ratio).Round(8)
+	opening.Quantity = fill.Quantity.Sub(closeQty)
+	opening.Fee = fill.Fee.Sub(closing.Fee)
+	opening.RealizedPnL = decimal.Zero
+	opening.ExternalID = fill.ExternalID + "#open"
+	return closing, opening
+}
+
+// finishImportedTrade - สรุปราคาเฉลี่ย/PnL ด้วยสูตรเดียวกับการเติมไม้และทยอยปิดไม้ในระบบ
+func finishImportedTrade(trip ImportedTrade, closedAt *time.Time) ImportedTrade {
+	entries := make([]EntryFill, len(trip.Entries))
+	for i, fill := range trip.Entries {
+		entries[i] = EntryFill{Price: fill.Price, Quantity: fill.Quantity, Fee: fill.Fee}
+		trip.ExternalIDs = append(trip.ExternalIDs, fill.ExternalID)
+	}
+	trip.Entry = AggregateEntries(entries)
+
+	exits := make([]ExitFill, len(trip.Exits))
+	for i, fill := range trip.Exits {
+		exits[i] = ExitFill{Price: fill.Price, Quantity: fill.Quantity, Fee: fill.Fee}
+		trip.ExternalIDs = append(trip.ExternalIDs, fill.ExternalID)
+		trip.ExchangePnL = trip.ExchangePnL.Add(fill.RealizedPnL)
+	}
+	trip.Exit = SummarizeExits(trip.Side, trip.Entry.AvgEntryPrice, trip.Entry.TotalQuantity, exits)
+
+	switch {
+	case closedAt != nil:
+		trip.Status = StatusClosed
+		trip.ClosedAt = closedAt
+	case len(trip.Exits) > 0:
+		trip.Status = StatusPartiallyClosed
+	default:
+		trip.Status = StatusOpen
+	}
+	return trip
+}
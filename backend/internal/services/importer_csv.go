@@ -0,0 +1,364 @@
+// Package services - Importer สำหรับไฟล์ CSV
+// Exchange แต่ละเจ้าต่างกันแค่ชื่อหัวคอลัมน์/รูปแบบ Pair จึงใช้ csvImporter ตัวเดียวกันคนละ importColumns
+// generic_csv ให้ผู้ใช้ Map หัวคอลัมน์เองสำหรับไฟล์ที่ไม่มี Importer เฉพาะ
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// importColumns - ชื่อหัวคอลัมน์ที่เป็นไปได้ของแต่ละค่า (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+type importColumns struct {
+	ID           []string
+	Time         []string
+	Symbol       []string
+	Side         []string
+	PositionSide []string
+	Price        []string
+	Quantity     []string
+	Fee          []string
+	FeeAsset     []string
+	RealizedPnL  []string
+	Type         []string // ประเภทรายการ (ข้ามแถวที่ไม่ใช่การเทรด เช่น Funding)
+	Unit         []string // หน่วยของจำนวน (ข้ามแถวที่เป็นจำนวนสัญญา เพราะไม่รู้ขนาดสัญญา)
+}
+
+// csvImporter - Importer ของไฟล์ CSV ที่รู้หัวคอลัมน์ล่วงหน้า
+type csvImporter struct {
+	name       string
+	columns    importColumns
+	signatures [][]string          // หัวคอลัมน์ (ตัวเล็ก) ที่ต้องมีครบอย่างน้อยหนึ่งชุด ถึงจะถือว่าเป็นไฟล์ของ Exchange นี้
+	pair       func(string) string // แปลง Symbol เป็น Pair (nil = SymbolToPair)
+	spot       bool                // Spot เปิด Short ไม่ได้
+	zone       string              // Timezone ของเวลาในไฟล์ ถ้าผู้ใช้ไม่ระบุ ("" = UTC)
+}
+
+func init() {
+	RegisterImporter(&csvImporter{
+		name: ImportSourceBinanceFutures,
+		columns: importColumns{
+			ID:           []string{"Trade ID", "TradeId", "ID"},
+			Time:         []string{"Date(UTC)", "Time(UTC)", "Date", "Time"},
+			Symbol:       []string{"Symbol"},
+			Side:         []string{"Side"},
+			PositionSide: []string{"Position Side", "PositionSide"},
+			Price:        []string{"Price"},
+			Quantity:     []string{"Quantity", "Qty"},
+			Fee:          []string{"Fee", "Commission"},
+			FeeAsset:     []string{"Fee Coin", "Fee Asset", "Commission Asset"},
+			RealizedPnL:  []string{"Realized Profit", "Realized PnL"},
+		},
+		signatures: [][]string{{"symbol", "side", "price", "realized profit"}, {"date(utc)", "symbol", "side", "price"}},
+	})
+	RegisterImporter(&csvImporter{
+		name: ImportSourceBybit,
+		columns: importColumns{
+			ID:          []string{"Exec ID", "Trade ID", "Transaction ID", "Fill ID"},
+			Time:        []string{"Trade Time(UTC+0)", "Transaction Time(UTC+0)", "Trade Time", "Transaction Time", "Time"},
+			Symbol:      []string{"Contracts", "Symbol"},
+			Side:        []string{"Side", "Direction"},
+			Price:       []string{"Filled Price", "Exec Price", "Trade Price", "Price"},
+			Quantity:    []string{"Filled Qty", "Exec Qty", "Filled", "Qty", "Quantity"},
+			Fee:         []string{"Trading Fee", "Exec Fee", "Fee"},
+			FeeAsset:    []string{"Fee Currency", "Fee Coin"},
+			RealizedPnL: []string{"Closed P&L", "Realized P&L", "Realized PnL"},
+			Type:        []string{"Exec Type", "Type"},
+		},
+		signatures: [][]string{{"contracts", "direction"}, {"exec id", "exec type"}},
+	})
+	RegisterImporter(&csvImporter{
+		name: ImportSourceOKX,
+		columns: importColumns{
+			ID:           []string{"Trade ID", "Bill ID", "id"},
+			Time:         []string{"Time", "Filled time", "Fill time"},
+			Symbol:       []string{"Instrument", "Symbol"},
+			Side:         []string{"Action", "Side"},
+			PositionSide: []string{"Position side", "Pos side"},
+			Price:        []string{"Fill price", "Filled price", "Price"},
+			Quantity:     []string{"Fill size", "Filled size", "Amount"},
+			Fee:          []string{"Fee"},
+			FeeAsset:     []string{"Fee unit", "Fee currency"},
+			RealizedPnL:  []string{"PnL", "Realized PnL"},
+			Unit:         []string{"Trading unit", "Unit"},
+		},
+		signatures: [][]string{{"instrument", "action", "fill price"}},
+	})
+	RegisterImporter(&csvImporter{
+		name: ImportSourceBitkub,
+		columns: importColumns{
+			ID:       []string{"Txn ID", "Order ID", "Transaction ID", "ID"},
+			Time:     []string{"Date", "Date/Time", "Time"},
+			Symbol:   []string{"Pair", "Symbol", "Coin"},
+			Side:     []string{"Type", "Side"},
+			Price:    []string{"Rate", "Price"},
+			Quantity: []string{"Amount", "Volume"},
+			Fee:      []string{"Fee"},
+		},
+		signatures: [][]string{{"rate", "pair"}, {"rate", "symbol"}},
+		pair:       bitkubPair,
+		spot:       true,
+		zone:       "Asia/Bangkok",
+	})
+	RegisterImporter(genericCSVImporter{})
+}
+
+func (i *csvImporter) Name() string { return i.name }
+
+// Detect - หัวคอลัมน์บรรทัดแรกมีครบตาม signatures ชุดใดชุดหนึ่ง
+func (i *csvImporter) Detect(sample []byte) bool {
+	header := csvSampleHeader(sample)
+	for _, signature := range i.signatures {
+		matched := true
+		for _, name := range signature {
+			if !header[name] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *csvImporter) Parse(r io.Reader, opts ImportOptions) ([]ImportFill, []string, error) {
+	fallback := time.UTC
+	if i.zone != "" {
+		if loc, err := LoadTimezone(i.zone); err == nil {
+			fallback = loc
+		}
+	}
+	pair := i.pair
+	if pair == nil {
+		pair = SymbolToPair
+	}
+	return parseFillsCSV(r, i.columns, csvParseOptions{loc: opts.location(fallback), pair: pair})
+}
+
+func (i *csvImporter) Normalize(fills []ImportFill) ([]ImportedTrade, []string) {
+	if i.spot {
+		return GroupSpotRoundTrips(fills)
+	}
+	return GroupRoundTrips(fills)
+}
+
+// bitkubPair - Bitkub ใช้ THB_BTC (Quote ขึ้นก่อน) และ BTC_THB ในไฟล์คนละรุ่น → BTC/THB
+func bitkubPair(symbol string) string {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(symbol)), "_")
+	if len(parts) == 2 && parts[0] == "THB" {
+		return parts[1] + "/THB"
+	}
+	if len(parts) == 1 && parts[0] != "" && !strings.Contains(parts[0], "/") {
+		return parts[0] + "/THB" // ไฟล์ที่ระบุแค่เหรียญ
+	}
+	return SymbolToPair(symbol)
+}
+
+// genericCSVImporter - CSV ใดก็ได้ ผู้ใช้ระบุเองว่าคอลัมน์ไหนคือค่าอะไร (ImportOptions.Columns)
+// ไม่ตรวจจากเนื้อไฟล์ ต้องเลือก source=generic_csv เท่านั้น
+type genericCSVImporter struct{}
+
+// genericCSVFields - ฟิลด์ที่ Map ได้ (ชื่อใน ImportOptions.Columns) และฟิลด์ที่ต้องมี
+var (
+	genericCSVFields   = []string{"id", "time", "symbol", "side", "position_side", "price", "quantity", "fee", "fee_asset", "realized_pnl", "type"}
+	genericCSVRequired = []string{"time", "symbol", "side", "price", "quantity"}
+)
+
+func (genericCSVImporter) Name() string { return ImportSourceGenericCSV }
+
+func (genericCSVImporter) Detect([]byte) bool { return false }
+
+func (genericCSVImporter) Parse(r io.Reader, opts ImportOptions) ([]ImportFill, []string, error) {
+	mapped := make(map[string][]string, len(opts.Columns))
+	for field, header := range opts.Columns {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !containsString(genericCSVFields, field) {
+			return nil, nil, newValidationError("columns", "unknown_field",
+				fmt.Sprintf("ไม่รู้จักฟิลด์ %q (%s)", field, strings.Join(genericCSVFields, ", ")))
+		}
+		if header = strings.TrimSpace(header); header != "" {
+			mapped[field] = []string{header}
+		}
+	}
+	var missing []string
+	for _, field := range genericCSVRequired {
+		if mapped[field] == nil {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, newValidationError("columns", "missing_mapping",
+			fmt.Sprintf("ต้องระบุคอลัมน์ของ %s", strings.Join(missing, ", ")))
+	}
+
+	columns := importColumns{
+		ID: mapped["id"], Time: mapped["time"], Symbol: mapped["symbol"], Side: mapped["side"],
+		PositionSide: mapped["position_side"], Price: mapped["price"], Quantity: mapped["quantity"],
+		Fee: mapped["fee"], FeeAsset: mapped["fee_asset"], RealizedPnL: mapped["realized_pnl"], Type: mapped["type"],
+	}
+	var layouts []string
+	if opts.TimeLayout != "" {
+		layouts = []string{opts.TimeLayout}
+	}
+	return parseFillsCSV(r, columns, csvParseOptions{loc: opts.location(nil), pair: SymbolToPair, timeLayouts: layouts})
+}
+
+func (genericCSVImporter) Normalize(fills []ImportFill) ([]ImportedTrade, []string) {
+	return GroupRoundTrips(fills)
+}
+
+// csvParseOptions - ค่าที่ parseFillsCSV ต้องใช้นอกจากหัวคอลัมน์
+type csvParseOptions struct {
+	loc         *time.Location
+	pair        func(string) string
+	timeLayouts []string
+}
+
+// parseFillsCSV - อ่านทุกแถวเป็น Fill แถวที่อ่านไม่ได้จะข้ามพร้อมคำเตือน
+func parseFillsCSV(r io.Reader, columns importColumns, opts csvParseOptions) ([]ImportFill, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, newValidationError("file", "invalid_csv", "อ่านหัวตารางของไฟล์ CSV ไม่ได้")
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff") // BOM จาก Excel
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(aliases []string) int {
+		for _, alias := range aliases {
+			if i, ok := index[strings.ToLower(alias)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	col := struct{ id, time, symbol, side, positionSide, price, qty, fee, feeAsset, pnl, kind, unit int }{
+		find(columns.ID), find(columns.Time), find(columns.Symbol), find(columns.Side), find(columns.PositionSide),
+		find(columns.Price), find(columns.Quantity), find(columns.Fee), find(columns.FeeAsset), find(columns.RealizedPnL),
+		find(columns.Type), find(columns.Unit),
+	}
+	required := []struct {
+		name  string
+		index int
+	}{{"time", col.time}, {"symbol", col.symbol}, {"side", col.side}, {"price", col.price}, {"quantity", col.qty}}
+	for _, column := range required {
+		if column.index < 0 {
+			return nil, nil, newValidationError("file", "missing_column", fmt.Sprintf("ไม่พบคอลัมน์ %s ในไฟล์ (ไฟล์ไม่ตรงกับ Exchange ที่เลือก?)", column.name))
+		}
+	}
+
+	var fills []ImportFill
+	var warnings []string
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, newValidationError("file", "invalid_csv", fmt.Sprintf("แถว %d: %v", row, err))
+		}
+		get := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		if kind := strings.ToUpper(get(col.kind)); col.kind != col.side && kind != "" && kind != "TRADE" {
+			continue
+		}
+		if unit := strings.ToUpper(get(col.unit)); strings.HasPrefix(unit, "CONT") {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: จำนวนเป็นหน่วยสัญญา (Contracts) ให้ Export เป็นหน่วยเหรียญ ข้ามแถวนี้", row))
+			continue
+		}
+
+		fill := ImportFill{Row: row, Symbol: get(col.symbol), ExternalID: get(col.id)}
+		fill.Pair = opts.pair(fill.Symbol)
+		fill.Side, fill.PositionSide = parseFillSide(get(col.side))
+		if ps := strings.ToUpper(get(col.positionSide)); ps == "LONG" || ps == "SHORT" {
+			fill.PositionSide = ps
+		}
+
+		var parseErr error
+		if fill.Time, parseErr = parseImportTime(get(col.time), opts.loc, opts.timeLayouts...); parseErr != nil {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: เวลา %q ไม่ถูกต้อง ข้ามแถวนี้", row, get(col.time)))
+			continue
+		}
+		fill.Price, _ = parseImportAmount(get(col.price))
+		fill.Quantity, _ = parseImportAmount(get(col.qty))
+		fill.Quantity = fill.Quantity.Abs()
+		if fill.Side == "" || fill.Pair == "" || !fill.Price.IsPositive() || !fill.Quantity.IsPositive() {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: ข้อมูลไม่ครบ (symbol/side/price/quantity) ข้ามแถวนี้", row))
+			continue
+		}
+
+		fee, feeAsset := parseImportAmount(get(col.fee))
+		if asset := strings.ToUpper(get(col.feeAsset)); asset != "" {
+			feeAsset = asset
+		}
+		if feeAsset != "" && feeAsset != quoteAsset(fill.Pair) && !fee.IsZero() {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: Fee จ่ายเป็น %s แปลงเป็น %s ไม่ได้ นับ Fee เป็น 0", row, feeAsset, quoteAsset(fill.Pair)))
+			fee = decimal.Zero
+		}
+		fill.Fee = fee.Abs()
+		fill.RealizedPnL, _ = parseImportAmount(get(col.pnl))
+		fills = append(fills, fill)
+	}
+	return fills, warnings, nil
+}
+
+// csvSampleHeader - หัวคอลัมน์ (ตัวเล็ก) ของบรรทัดแรกในตัวอย่างไฟล์
+func csvSampleHeader(sample []byte) map[string]bool {
+	sample = bytes.TrimPrefix(sample, []byte("\ufeff"))
+	if i := bytes.IndexByte(sample, '\n'); i >= 0 {
+		sample = sample[:i]
+	}
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.LazyQuotes = true
+	record, err := reader.Read()
+	if err != nil {
+		return nil
+	}
+	header := make(map[string]bool, len(record))
+	for _, name := range record {
+		header[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return header
+}
+
+// isBlankRecord - แถวว่าง (ท้ายไฟล์บาง Exchange)
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString - มีค่านี้ใน slice หรือไม่
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+// Package services - Importer สำหรับ Statement (HTML) ของ MetaTrader 4/5
+// นำเข้าเฉพาะ Position ที่ปิดแล้ว: แต่ละ Ticket เป็นไม้หนึ่งไม้ (Fill เข้า + Fill ออก)
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/shopspring/decimal"
+)
+
+// mtImporter - Statement ของ MT4 (Closed Transactions) หรือ MT5 (Trade History Report → Positions)
+type mtImporter struct {
+	name   string
+	marker string // ข้อความ (ตัวเล็ก) ที่มีเฉพาะใน Statement ของรุ่นนี้
+	mt5    bool
+}
+
+func init() {
+	RegisterImporter(&mtImporter{name: ImportSourceMT4, marker: "closed transactions"})
+	RegisterImporter(&mtImporter{name: ImportSourceMT5, marker: "trade history report", mt5: true})
+}
+
+// mtPosition - หนึ่งแถวของ Position ที่ปิดแล้วใน Statement
+type mtPosition struct {
+	ticket     string
+	symbol     string
+	kind       string // buy, sell
+	lots       string
+	openTime   string
+	openPrice  string
+	closeTime  string
+	closePrice string
+	fees       []string // Commission, Taxes, Swap (ติดลบ = เสียเงิน)
+	profit     string
+}
+
+func (i *mtImporter) Name() string { return i.name }
+
+func (i *mtImporter) Detect(sample []byte) bool {
+	text := strings.ToLower(decodeStatement(sample))
+	return strings.Contains(text, "<table") && strings.Contains(text, i.marker)
+}
+
+func (i *mtImporter) Parse(r io.Reader, opts ImportOptions) ([]ImportFill, []string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, newValidationError("file", "invalid_file", "อ่านไฟล์ไม่ได้")
+	}
+	loc := opts.location(nil) // เวลาใน Statement เป็นเวลาของ Server ของ Broker
+
+	var fills []ImportFill
+	var warnings []string
+	for row, cells := range htmlTableRows(decodeStatement(data)) {
+		position, ok := i.position(cells)
+		if !ok {
+			continue
+		}
+		row++ // ลำดับแถว <tr> ในไฟล์ (เริ่มที่ 1)
+
+		openTime, err := parseImportTime(position.openTime, loc)
+		if err != nil {
+			continue
+		}
+		closeTime, err := parseImportTime(position.closeTime, loc)
+		if err != nil {
+			continue // Position ที่ยังเปิดอยู่ (ไม่มีเวลาปิด)
+		}
+		openPrice, _ := parseImportAmount(position.openPrice)
+		closePrice, _ := parseImportAmount(position.closePrice)
+		lots, _ := parseImportAmount(position.lots)
+		profit, _ := parseImportAmount(position.profit)
+		if !openPrice.IsPositive() || !closePrice.IsPositive() || !lots.IsPositive() {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: Ticket %s ข้อมูลไม่ครบ ข้ามแถวนี้", row, position.ticket))
+			continue
+		}
+
+		// Statement บอกกำไรเป็นสกุลเงินของบัญชี แต่ไม่บอกขนาดสัญญาต่อ Lot (Forex 100,000 / ทอง 100 / ...)
+		// จึงคิดจำนวนย้อนจากกำไร ÷ ระยะราคา ให้ PnL ในระบบตรงกับ Statement
+		quantity := lots
+		if move := closePrice.Sub(openPrice).Abs(); move.IsPositive() && !profit.IsZero() {
+			quantity = profit.Div(move).Abs().Round(8)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("แถว %d: Ticket %s ราคาไม่เปลี่ยน ใช้จำนวน Lot เป็นจำนวน", row, position.ticket))
+		}
+
+		// ค่าธรรมเนียมรวมไว้ที่ขาออก: Commission/Taxes/Swap ติดลบ = Fee บวก (Swap ที่ได้รับ = Fee ติดลบ)
+		fee := decimal.Zero
+		for _, raw := range position.fees {
+			value, _ := parseImportAmount(raw)
+			fee = fee.Sub(value)
+		}
+
+		entrySide, exitSide := FillBuy, FillSell
+		if position.kind == "sell" {
+			entrySide, exitSide = FillSell, FillBuy
+		}
+		fill := ImportFill{
+			PositionID: position.ticket,
+			Symbol:     position.symbol,
+			Pair:       SymbolToPair(position.symbol),
+			Quantity:   quantity,
+			Row:        row,
+		}
+		entry, exit := fill, fill
+		entry.ExternalID, entry.Side, entry.Price, entry.Time = position.ticket+":open", entrySide, openPrice, openTime
+		exit.ExternalID, exit.Side, exit.Price, exit.Time = position.ticket+":close", exitSide, closePrice, closeTime
+		exit.Fee, exit.RealizedPnL = fee, profit
+		fills = append(fills, entry, exit)
+	}
+	return fills, warnings, nil
+}
+
+func (i *mtImporter) Normalize(fills []ImportFill) ([]ImportedTrade, []string) {
+	return GroupRoundTrips(fills) // PositionID แยกแต่ละ Ticket เป็นคนละไม้ แม้จะ Hedge คู่เดียวกัน
+}
+
+// position - แปลงแถวของตารางเป็น Position (แถวอื่น เช่น หัวตาราง, Pending Order, ยอดรวม คืน false)
+//
+//	MT4 (14 ช่อง): Ticket, Open Time, Type, Size, Item, Price, S/L, T/P, Close Time, Price, Commission, Taxes, Swap, Profit
+//	MT5 (13 ช่อง): Time, Position, Symbol, Type, Volume, Price, S/L, T/P, Time, Price, Commission, Swap, Profit
+func (i *mtImporter) position(cells []string) (mtPosition, bool) {
+	var p mtPosition
+	switch {
+	case !i.mt5 && len(cells) == 14 && isDigits(cells[0]):
+		p = mtPosition{ticket: cells[0], openTime: cells[1], kind: cells[2], lots: cells[3], symbol: cells[4], openPrice: cells[5],
+			closeTime: cells[8], closePrice: cells[9], fees: cells[10:13], profit: cells[13]}
+	case i.mt5 && len(cells) == 13 && isDigits(cells[1]):
+		p = mtPosition{openTime: cells[0], ticket: cells[1], symbol: cells[2], kind: cells[3], lots: cells[4], openPrice: cells[5],
+			closeTime: cells[8], closePrice: cells[9], fees: cells[10:12], profit: cells[12]}
+	default:
+		return p, false
+	}
+	p.kind = strings.ToLower(p.kind)
+	return p, p.kind == "buy" || p.kind == "sell"
+}
+
+// decodeStatement - Statement ของ MT5 บันทึกเป็น UTF-16 (มี BOM) ส่วน MT4 เป็น UTF-8/ANSI
+func decodeStatement(data []byte) string {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		return string(data)
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// htmlTableRows - ข้อความในแต่ละช่อง <td> ของทุกแถว <tr> (ข้ามช่องที่ซ่อนไว้ class="hidden" ของ MT5)
+// Statement ของ MT เป็น HTML ตารางล้วนที่ไม่ซ้อนกัน จึงไม่ต้องใช้ HTML Parser เต็มรูปแบบ
+func htmlTableRows(doc string) [][]string {
+	lower := asciiLower(doc) // ตำแหน่งตรงกับ doc ทุกตัวอักษร
+	var rows [][]string
+	for pos := 0; ; {
+		start := strings.Index(lower[pos:], "<tr")
+		if start < 0 {
+			break
+		}
+		start += pos
+		end := strings.Index(lower[start+3:], "<tr")
+		if end < 0 {
+			end = len(lower)
+		} else {
+			end += start + 3
+		}
+
+		var cells []string
+		for cell := start; ; {
+			open := strings.Index(lower[cell:end], "<td")
+			if open < 0 {
+				break
+			}
+			open += cell
+			tagEnd := strings.IndexByte(lower[open:end], '>')
+			if tagEnd < 0 {
+				break
+			}
+			tagEnd += open
+			closeTag := strings.Index(lower[tagEnd:end], "</td")
+			if closeTag < 0 {
+				closeTag = end
+			} else {
+				closeTag += tagEnd
+			}
+			if !strings.Contains(lower[open:tagEnd], "hidden") {
+				cells = append(cells, htmlText(doc[tagEnd+1:closeTag]))
+			}
+			cell = closeTag
+			if cell >= end {
+				break
+			}
+			cell++
+		}
+		rows = append(rows, cells)
+		pos = end
+	}
+	return rows
+}
+
+// htmlText - ตัด Tag ข้างในออกแล้วแปลง Entity (&nbsp; &amp; ...) เป็นข้อความ
+func htmlText(fragment string) string {
+	var text strings.Builder
+	inTag := false
+	for _, r := range fragment {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			text.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(strings.ReplaceAll(html.UnescapeString(text.String()), "\u00a0", " "))
+}
+
+// asciiLower - ตัวพิมพ์เล็กเฉพาะ A-Z (ความยาวเท่าเดิม ใช้ตำแหน่งร่วมกับข้อความต้นฉบับได้)
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// isDigits - ข้อความเป็นตัวเลขล้วน (Ticket/Position ID)
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// updateGolden - go test ./internal/services -run TestImportGolden -update เขียนไฟล์ .golden.json ใหม่
+var updateGolden = flag.Bool("update", false, "rewrite importer golden files")
+
+// TestSymbolToPair - แปลง Symbol ของ Exchange เป็น Pair
+func TestSymbolToPair(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"BTCUSDT", "BTC/USDT"},
+		{"1000PEPEUSDT", "1000PEPE/USDT"},
+		{"ethfdusd", "ETH/FDUSD"},
+		{"BTC-USDT-SWAP", "BTC/USDT"},
+		{"ETH_USDC", "ETH/USDC"},
+		{"SOL/USDT", "SOL/USDT"},
+		{"eurusd.m", "EUR/USD"},
+		{"XAUUSD#", "XAU/USD"},
+	}
+	for _, tc := range tests {
+		if got := SymbolToPair(tc.symbol); got != tc.want {
+			t.Errorf("SymbolToPair(%q) = %q, want %q", tc.symbol, got, tc.want)
+		}
+	}
+}
+
+// TestParseBinanceFuturesCSV - Long 2 Fill ปิด 2 ครั้ง แล้ว Short ที่ยังไม่ปิด
+func TestParseBinanceFuturesCSV(t *testing.T) {
+	file := "\ufeffDate(UTC),Trade ID,Symbol,Side,Price,Quantity,Amount,Fee,Fee Coin,Realized Profit\n" +
+		"2026-03-01 10:00:00,1001,BTCUSDT,BUY,60000,0.01,600,0.24,USDT,0\n" +
+		"2026-03-01 11:00:00,1002,BTCUSDT,BUY,59000,0.01,590,0.236,USDT,0\n" +
+		"2026-03-01 12:00:00,1003,BTCUSDT,SELL,61000,0.01,610,0.244,USDT,15\n" +
+		"2026-03-01 13:00:00,1004,BTCUSDT,SELL,62000,0.01,620,0.248,USDT,25\n" +
+		"2026-03-02 09:00:00,1005,ETHUSDT,SELL,3000,0.5,1500,0.6,USDT,0\n" +
+		"2026-03-02 09:05:00,1005,ETHUSDT,SELL,3000,0.5,1500,0.6,USDT,0\n"
+
+	result, err := Import(ImportSourceBinanceFutures, strings.NewReader(file), ImportOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Fills != 5 || len(result.Trades) != 2 {
+		t.Fatalf("Expected 5 fills / 2 trades but got %d / %d", result.Fills, len(result.Trades))
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("Trade ID ซ้ำต้องมีคำเตือน: %v", result.Warnings)
+	}
+
+	// Long: เข้าเฉลี่ย 59,500 x 0.02, ออก 61,000 + 62,000 → Gross = 15 + 25 = 40, Fee ขาออก 0.492
+	btc := result.Trades[0]
+	if btc.Pair != "BTC/USDT" || btc.Side != "LONG" || btc.Status != StatusClosed || btc.ClosedAt == nil {
+		t.Fatalf("BTC trade ผิด: %+v", btc)
+	}
+	assertDecimal(t, "AvgEntryPrice", btc.Entry.AvgEntryPrice, d(59500), 0.0001)
+	assertDecimal(t, "EntryFee", btc.Entry.TotalFee, d(0.476), 0.0001)
+	assertDecimal(t, "GrossPnL", btc.Exit.GrossPnL, d(40), 0.0001)
+	assertDecimal(t, "RealizedPnL", btc.Exit.RealizedPnL, d(39.508), 0.0001)
+	assertDecimal(t, "ExchangePnL", btc.ExchangePnL, d(40), 0.0001)
+	// ค่าที่บันทึกลง Trade (syncTradeExits) = กำไรจาก Exchange หัก Fee ทั้งหมด 40 - 0.476 - 0.492 = 39.032
+	settled := SettleExits(btc.Exit, btc.Entry.TotalFee, btc.Entry.PositionSize, decimal.NullDecimal{})
+	assertDecimal(t, "StoredPnL", settled.PnL, btc.ExchangePnL.Sub(btc.Entry.TotalFee).Sub(btc.Exit.Fees), 0)
+	assertDecimal(t, "StoredPnL", settled.PnL, d(39.032), 0.0001)
+	assertDecimal(t, "StoredFee", settled.Fees, d(0.968), 0.0001)
+	if settled.Outcome != OutcomeWin {
+		t.Errorf("Outcome = %s, want WIN", settled.Outcome)
+	}
+	if strings.Join(btc.ExternalIDs, ",") != "1001,1002,1003,1004" {
+		t.Errorf("ExternalIDs ผิด: %v", btc.ExternalIDs)
+	}
+
+	eth := result.Trades[1]
+	if eth.Side != "SHORT" || eth.Status != StatusOpen || eth.ClosedAt != nil {
+		t.Errorf("ETH trade ต้องเป็น SHORT ที่ยังเปิดอยู่: %+v", eth)
+	}
+}
+
+// TestParseBybitCSV - Direction บอก Position Side, ข้ามแถว Funding, Fee แบบมีหน่วย
+func TestParseBybitCSV(t *testing.T) {
+	file := "Contracts,Direction,Filled Qty,Filled Price,Trading Fee,Exec Type,Exec ID,Trade Time(UTC+0)\n" +
+		"SOLUSDT,Open Short,10,150,0.825 USDT,Trade,a-1,2026-03-03 08:00:00\n" +
+		"SOLUSDT,Close Short,10,140,0.77 USDT,Trade,a-2,2026-03-03 09:30:00\n" +
+		"SOLUSDT,Close Short,0,0,0.01,Funding,a-3,2026-03-03 10:00:00\n"
+
+	result, err := Import(ImportSourceBybit, strings.NewReader(file), ImportOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Trades) != 1 || len(result.Warnings) != 0 {
+		t.Fatalf("Expected 1 trade, no warnings but got %d / %v", len(result.Trades), result.Warnings)
+	}
+	sol := result.Trades[0]
+	if sol.Side != "SHORT" || sol.Status != StatusClosed || sol.Entries[0].PositionSide != "SHORT" {
+		t.Fatalf("SOL trade ผิด: %+v", sol)
+	}
+	// (150 - 140) x 10 = 100, Fee ขาออก 0.77
+	assertDecimal(t, "RealizedPnL", sol.Exit.RealizedPnL, d(99.23), 0.0001)
+}
+
+// TestGroupRoundTripsFlip - ขายเกินจำนวนที่ถือ (One-way): ปิด Long แล้วเปิด Short ด้วยส่วนเกิน
+func TestGroupRoundTripsFlip(t *testing.T) {
+	file := "Date(UTC),Trade ID,Symbol,Side,Price,Quantity,Fee\n" +
+		"2026-03-01 10:00:00,1,BTCUSDT,BUY,100,1,0.1\n" +
+		"2026-03-01 11:00:00,2,BTCUSDT,SELL,110,3,0.3\n"
+
+	result, err := Import(ImportSourceBinanceFutures, strings.NewReader(file), ImportOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Trades) != 2 {
+		t.Fatalf("Expected 2 trades but got %d", len(result.Trades))
+	}
+	long, short := result.Trades[0], result.Trades[1]
+	if long.Status != StatusClosed || short.Side != "SHORT" || short.Status != StatusOpen {
+		t.Fatalf("ผลการแบ่ง Fill ผิด: %s %s / %s %s", long.Side, long.Status, short.Side, short.Status)
+	}
+	assertDecimal(t, "Long.ExitFee", long.Exits[0].Fee, d(0.1), 0.0001)
+	assertDecimal(t, "Short.Quantity", short.Entry.TotalQuantity, d(2), 0.0001)
+	assertDecimal(t, "Short.EntryFee", short.Entry.TotalFee, d(0.2), 0.0001)
+	if short.ExternalIDs[0] != "2#open" {
+		t.Errorf("ส่วนที่เปิดไม้ใหม่ต้องได้ ID แยก: %v", short.ExternalIDs)
+	}
+}
+
+// TestImportErrors - ไฟล์ผิดรูปแบบ และไฟล์ไม่มี Trade ID ต้องได้ ID เดิมทุกครั้ง
+func TestImportErrors(t *testing.T) {
+	if _, err := Import(ImportSourceBinanceFutures, strings.NewReader("foo,bar\n1,2\n"), ImportOptions{}); err == nil {
+		t.Errorf("ไฟล์ที่ไม่มีคอลัมน์ที่ต้องใช้ต้อง Error")
+	}
+	if _, err := Import("kraken", strings.NewReader(""), ImportOptions{}); err == nil {
+		t.Errorf("แหล่งข้อมูลที่ไม่รองรับต้อง Error")
+	}
+
+	file := "Date(UTC),Symbol,Side,Price,Quantity\n2026-03-01 10:00:00,BTCUSDT,BUY,100,1\n2026-03-01 10:00:00,BTCUSDT,BUY,100,1\n"
+	first, _ := Import(ImportSourceBinanceFutures, strings.NewReader(file), ImportOptions{})
+	second, _ := Import(ImportSourceBinanceFutures, strings.NewReader(file), ImportOptions{})
+	a, b := first.Trades[0].ExternalIDs, second.Trades[0].ExternalIDs
+	if len(a) != 2 || a[0] == a[1] || a[0] != b[0] || a[1] != b[1] {
+		t.Errorf("ID ที่สร้างจากเนื้อหาต้องไม่ซ้ำกันในไฟล์ และคงที่ระหว่างการนำเข้า: %v / %v", a, b)
+	}
+}
+
+// TestImportGolden - ไฟล์ตัวอย่างจริงของแต่ละ Importer (testdata/import) ต้องได้ผลตรงกับ .golden.json
+// และตรวจรูปแบบไฟล์อัตโนมัติได้ถูก Importer (ยกเว้น generic_csv ที่ต้องเลือกเอง)
+func TestImportGolden(t *testing.T) {
+	tests := []struct {
+		file   string
+		source string
+		opts   ImportOptions
+	}{
+		{"binance_futures.csv", ImportSourceBinanceFutures, ImportOptions{}},
+		{"bybit.csv", ImportSourceBybit, ImportOptions{}},
+		{"okx.csv", ImportSourceOKX, ImportOptions{}},
+		{"bitkub.csv", ImportSourceBitkub, ImportOptions{}},
+		{"mt4.htm", ImportSourceMT4, ImportOptions{}},
+		{"mt5.htm", ImportSourceMT5, ImportOptions{}},
+		{"generic.csv", ImportSourceGenericCSV, ImportOptions{
+			Columns:    map[string]string{"time": "When", "symbol": "Market", "side": "Buy/Sell", "price": "Px", "quantity": "Size", "fee": "Commission"},
+			TimeLayout: "02/01/2006 15:04",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.source, func(t *testing.T) {
+			path := filepath.Join("testdata", "import", tc.file)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Import(tc.source, bytes.NewReader(data), tc.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden.json"
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("ไม่มี golden file (รันด้วย -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ผลไม่ตรงกับ %s (ตรวจแล้วรันด้วย -update ถ้าตั้งใจเปลี่ยน)\n%s", golden, got)
+			}
+
+			detected, ok := DetectImporter(data)
+			switch {
+			case tc.source == ImportSourceGenericCSV && ok:
+				t.Errorf("generic_csv ต้องไม่ถูกตรวจเจออัตโนมัติ แต่ได้ %s", detected.Name())
+			case tc.source != ImportSourceGenericCSV && (!ok || detected.Name() != tc.source):
+				t.Errorf("ตรวจรูปแบบไฟล์ผิด: Expected %s but got %v", tc.source, detected)
+			}
+		})
+	}
+}
+
+// TestImporterRegistry - ชื่อ Importer ครบ และ generic_csv ต้องระบุคอลัมน์ที่จำเป็น
+func TestImporterRegistry(t *testing.T) {
+	want := "binance_futures,bitkub,bybit,generic_csv,mt4,mt5,okx"
+	if got := strings.Join(ImporterNames(), ","); got != want {
+		t.Errorf("ImporterNames = %s, want %s", got, want)
+	}
+	if _, err := Import("auto", strings.NewReader("foo,bar\n1,2\n"), ImportOptions{}); err == nil {
+		t.Errorf("ไฟล์ที่ไม่รู้จักรูปแบบต้อง Error")
+	}
+	opts := ImportOptions{Columns: map[string]string{"time": "When", "symbol": "Market"}}
+	if _, err := Import(ImportSourceGenericCSV, strings.NewReader("When,Market\n"), opts); err == nil || !strings.Contains(err.Error(), "side") {
+		t.Errorf("ไม่ได้ Map คอลัมน์ที่ต้องใช้ต้อง Error: %v", err)
+	}
+}
+
+// TestBitkubPair - Bitkub ใช้ Quote ขึ้นก่อน
+func TestBitkubPair(t *testing.T) {
+	for symbol, want := range map[string]string{"THB_BTC": "BTC/THB", "BTC_THB": "BTC/THB", "kub": "KUB/THB"} {
+		if got := bitkubPair(symbol); got != want {
+			t.Errorf("bitkubPair(%q) = %q, want %q", symbol, got, want)
+		}
+	}
+}
+
+// TestParseImportAmount - ตัวเลขที่มีหน่วย/ตัวคั่นหลักพัน
+func TestParseImportAmount(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float64
+		unit string
+	}{
+		{"0.0123 USDT", 0.0123, "USDT"},
+		{"0.5 ETH", 0.5, "ETH"},
+		{"2,100,000", 2100000, ""},
+		{"1 200.00", 1200, ""},
+		{"-7.00", -7, ""},
+		{"abc", 0, ""},
+	}
+	for _, tc := range tests {
+		got, unit := parseImportAmount(tc.raw)
+		assertDecimal(t, tc.raw, got, d(tc.want), 0.000001)
+		if unit != tc.unit {
+			t.Errorf("parseImportAmount(%q) unit = %q, want %q", tc.raw, unit, tc.unit)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package services - Trade Lifecycle
+// สถานะของไม้เปลี่ยนได้ตามเส้นทางที่กำหนดเท่านั้น กันไม้ที่ปิดแล้วถูกแก้กลับเป็น OPEN
+//
+//	PLANNED → OPEN → PARTIALLY_CLOSED → CLOSED (ผล WIN / LOSS / BREAK_EVEN)
+//	   ↓        ↓
+//	CANCELLED CANCELLED
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// สถานะของไม้
+const (
+	StatusPlanned         = "PLANNED"
+	StatusOpen            = "OPEN"
+	StatusPartiallyClosed = "PARTIALLY_CLOSED"
+	StatusClosed          = "CLOSED"
+	StatusCancelled       = "CANCELLED"
+)
+
+// tradeTransitions - จากสถานะไหนไปสถานะไหนได้บ้าง
+var tradeTransitions = map[string][]string{
+	StatusPlanned:         {StatusOpen, StatusCancelled},
+	StatusOpen:            {StatusPartiallyClosed, StatusClosed, StatusCancelled},
+	StatusPartiallyClosed: {StatusClosed},
+	StatusClosed:          {},
+	StatusCancelled:       {},
+}
+
+// TransitionError - เปลี่ยนสถานะไม่ได้ (Handler ตอบ 409)
+type TransitionError struct {
+	From    string // สถานะปัจจุบัน
+	To      string // สถานะที่ขอเปลี่ยน
+	Code    string // รหัส Error สำหรับ Frontend เช่น "illegal_transition"
+	Message string
+}
+
+// Error - ทำให้ TransitionError เป็น error ได้
+func (e *TransitionError) Error() string {
+	return e.Message
+}
+
+// NormalizeTradeStatus - แปลงเป็นตัวพิมพ์ใหญ่ แล้วเช็คว่าเป็นสถานะที่รู้จัก
+func NormalizeTradeStatus(status string) (string, bool) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	_, ok := tradeTransitions[status]
+	return status, ok
+}
+
+// IsOutcome - เป็นผลของไม้ที่ปิดแล้วหรือไม่ (WIN, LOSS, BREAK_EVEN)
+func IsOutcome(value string) bool {
+	switch strings.ToUpper(value) {
+	case OutcomeWin, OutcomeLoss, OutcomeBreakEven:
+		return true
+	}
+	return false
+}
+
+// IsTerminalStatus - สถานะสุดท้าย เปลี่ยนต่อไม่ได้แล้ว
+func IsTerminalStatus(status string) bool {
+	return len(tradeTransitions[status]) == 0
+}
+
+// ValidateTransition - เช็คว่าเปลี่ยนจาก from ไป to ได้หรือไม่
+// สถานะเดิม → สถานะเดิม ไม่นับเป็นการเปลี่ยน (ผ่านเสมอ)
+func ValidateTransition(from, to string) error {
+	if _, ok := tradeTransitions[to]; !ok {
+		return &TransitionError{From: from, To: to, Code: "invalid_status", Message: fmt.Sprintf("ไม่รู้จักสถานะ %q", to)}
+	}
+	if from == to {
+		return nil
+	}
+	for _, allowed := range tradeTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &TransitionError{
+		From:    from,
+		To:      to,
+		Code:    "illegal_transition",
+		Message: fmt.Sprintf("เปลี่ยนสถานะจาก %s เป็น %s ไม่ได้", from, to),
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestValidateTransition - เช็คเส้นทางเปลี่ยนสถานะที่อนุญาต/ไม่อนุญาต
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		wantCode string
+	}{
+		{StatusPlanned, StatusOpen, ""},
+		{StatusPlanned, StatusCancelled, ""},
+		{StatusOpen, StatusPartiallyClosed, ""},
+		{StatusOpen, StatusClosed, ""},
+		{StatusPartiallyClosed, StatusClosed, ""},
+		{StatusClosed, StatusClosed, ""},
+		{StatusClosed, StatusOpen, "illegal_transition"},
+		{StatusCancelled, StatusOpen, "illegal_transition"},
+		{StatusPartiallyClosed, StatusOpen, "illegal_transition"},
+		{StatusPlanned, StatusClosed, "illegal_transition"},
+		{StatusOpen, "WIN", "invalid_status"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.from+"→"+tc.to, func(t *testing.T) {
+			err := ValidateTransition(tc.from, tc.to)
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ควรเปลี่ยนได้ แต่ได้: %v", err)
+				}
+				return
+			}
+			tErr, ok := err.(*TransitionError)
+			if !ok || tErr.Code != tc.wantCode {
+				t.Fatalf("Expected code %s but got %v", tc.wantCode, err)
+			}
+		})
+	}
+}
+
+// TestNormalizeTradeStatus - รับตัวพิมพ์เล็กได้ และไม่รับผลลัพธ์ (WIN) เป็นสถานะ
+func TestNormalizeTradeStatus(t *testing.T) {
+	if status, ok := NormalizeTradeStatus(" partially_closed "); !ok || status != StatusPartiallyClosed {
+		t.Errorf("Expected PARTIALLY_CLOSED but got %q (%v)", status, ok)
+	}
+	if _, ok := NormalizeTradeStatus("WIN"); ok {
+		t.Errorf("WIN เป็น Outcome ไม่ใช่ Status")
+	}
+	if !IsOutcome("break_even") || IsOutcome("CLOSED") {
+		t.Errorf("IsOutcome ผิด")
+	}
+	if !IsTerminalStatus(StatusCancelled) || IsTerminalStatus(StatusOpen) {
+		t.Errorf("IsTerminalStatus ผิด")
+	}
+}
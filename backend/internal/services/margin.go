@@ -0,0 +1,88 @@
+// Package services - Leverage, Margin และ Liquidation
+// ประมาณการ Margin ที่ต้องใช้และราคา Liquidation แบบ Isolated Margin
+package services
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultMaintenanceMarginRate - อัตรา Maintenance Margin ค่าเริ่มต้น (0.5%)
+// ใกล้เคียงกับ Tier แรกของ Binance/Bybit Futures สำหรับ BTCUSDT
+var DefaultMaintenanceMarginRate = decimal.RequireFromString("0.005")
+
+// marginEstimate - ผลคำนวณฝั่ง Margin
+type marginEstimate struct {
+	InitialMargin     decimal.Decimal
+	MaintenanceMargin decimal.Decimal
+	LiquidationPrice  decimal.Decimal
+}
+
+// effectiveLeverage - Leverage 0 ถือว่าไม่ใช้ Leverage (= 1)
+func effectiveLeverage(leverage decimal.Decimal) decimal.Decimal {
+	if !leverage.IsPositive() {
+		return decimalOne
+	}
+	return leverage
+}
+
+// effectiveMaintenanceRate - ถ้าไม่ได้ระบุ ใช้ค่า Default
+func effectiveMaintenanceRate(rate decimal.Decimal) decimal.Decimal {
+	if !rate.IsPositive() {
+		return DefaultMaintenanceMarginRate
+	}
+	return rate
+}
+
+// estimateLiquidationPrice - ราคา Liquidation โดยประมาณ (Isolated Margin)
+// LONG:  Liq = Entry x (1 - 1/Leverage + MMR)
+// SHORT: Liq = Entry x (1 + 1/Leverage - MMR)
+// ไม่รวม Fee และ Funding จึงเป็นค่าประมาณ (ของจริงจะโดนเร็วกว่านิดหน่อย)
+func estimateLiquidationPrice(entryPrice, leverage, maintenanceRate decimal.Decimal, isLong bool) decimal.Decimal {
+	inverseLeverage := decimalOne.Div(effectiveLeverage(leverage))
+	maintenanceRate = effectiveMaintenanceRate(maintenanceRate)
+
+	if isLong {
+		liq := entryPrice.Mul(decimalOne.Sub(inverseLeverage).Add(maintenanceRate))
+		if liq.IsNegative() {
+			return decimal.Zero
+		}
+		return liq
+	}
+	return entryPrice.Mul(decimalOne.Add(inverseLeverage).Sub(maintenanceRate))
+}
+
+// estimateMargin - คำนวณ Initial Margin, Maintenance Margin และราคา Liquidation
+func estimateMargin(input CalculationInput, positionSizeUSD decimal.Decimal, isLong bool) marginEstimate {
+	leverage := effectiveLeverage(input.Leverage)
+	maintenanceRate := effectiveMaintenanceRate(input.MaintenanceMarginRate)
+
+	return marginEstimate{
+		InitialMargin:     positionSizeUSD.Div(leverage),
+		MaintenanceMargin: positionSizeUSD.Mul(maintenanceRate),
+		LiquidationPrice:  estimateLiquidationPrice(input.EntryPrice, leverage, maintenanceRate, isLong),
+	}
+}
+
+// validateLiquidation - SL ทุกจุดต้องอยู่ก่อนราคา Liquidation
+// ถ้า SL ไกลกว่าจุด Liq แปลว่าพอร์ตโดนล้างก่อนจะถึง SL (เสียทั้ง Margin แทนที่จะเสียแค่ Risk)
+// ตรวจทุกจุดไม่ใช่แค่ Weighted SL: Weighted SL เป็นแค่ค่าเฉลี่ยไว้คิด Risk ไม่ใช่ราคาที่มีคำสั่งจริง
+// SL แต่ละจุดปิดไม้ทีละส่วน ส่วนที่เหลือยังใช้ราคา Liq เดิม (Isolated Margin คืน Margin ตามสัดส่วนที่ปิด)
+// ถ้าจุดที่ไกลสุดเลย Liq ไม้ส่วนนั้นโดนล้างก่อนถึง SL แม้ Weighted SL จะยังอยู่ก่อน Liq ก็ตาม
+func validateLiquidation(input CalculationInput) error {
+	isLong := isLongSetup(input.EntryPrice, calculateWeightedSL(input.StopLosses))
+	liq := estimateLiquidationPrice(input.EntryPrice, input.Leverage, input.MaintenanceMarginRate, isLong)
+
+	for i, sl := range input.StopLosses {
+		if (isLong && sl.Price.LessThanOrEqual(liq)) || (!isLong && sl.Price.GreaterThanOrEqual(liq)) {
+			return newValidationError(
+				fmt.Sprintf("stop_losses[%d].price", i),
+				"beyond_liquidation",
+				fmt.Sprintf("StopLoss %s อยู่เลยราคา Liquidation (~%s) ลด Leverage หรือขยับ SL เข้ามา", sl.Price, liq.Round(8)),
+			)
+		}
+	}
+
+	return nil
+}
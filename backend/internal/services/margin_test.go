@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestCalculatePositionSizeMargin - ทดสอบ Margin และราคา Liquidation ตาม Leverage
+func TestCalculatePositionSizeMargin(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    CalculationInput
+		wantLiq  float64
+		wantIM   float64
+		wantMM   float64
+		wantCode string
+	}{
+		{
+			// Qty = 10, Size = 1000 USD, Leverage 10 → IM = 100
+			// MM = 1000 x 0.005 = 5, Liq = 100 x (1 - 0.1 + 0.005) = 90.5
+			name: "LONG Leverage 10x",
+			input: CalculationInput{
+				Balance: d(1000), RiskPercent: d(1), EntryPrice: d(100),
+				StopLosses: []StopLoss{{Price: d(99), Weight: d(1)}},
+				Leverage:   d(10),
+			},
+			wantLiq: 90.5,
+			wantIM:  100,
+			wantMM:  5,
+		},
+		{
+			// Liq = 100 x (1 + 0.05 - 0.01) = 104
+			name: "SHORT Leverage 20x กำหนด MMR 1%",
+			input: CalculationInput{
+				Balance: d(1000), RiskPercent: d(1), EntryPrice: d(100),
+				StopLosses:            []StopLoss{{Price: d(101), Weight: d(1)}},
+				Leverage:              d(20),
+				MaintenanceMarginRate: d(0.01),
+			},
+			wantLiq: 104,
+			wantIM:  50,
+			wantMM:  10,
+		},
+		{
+			// Leverage 50x → Liq = 100 x (1 - 0.02 + 0.005) = 98.5 แต่ SL2 อยู่ที่ 97
+			name: "SL เลยจุด Liquidation ต้องโดน Reject",
+			input: CalculationInput{
+				Balance: d(1000), RiskPercent: d(1), EntryPrice: d(100),
+				StopLosses: []StopLoss{{Price: d(99), Weight: d(0.5)}, {Price: d(97), Weight: d(0.5)}},
+				Leverage:   d(50),
+			},
+			wantCode: "beyond_liquidation",
+		},
+		{
+			// Weighted SL = 99.5 x 0.8 + 98 x 0.2 = 99.2 อยู่ก่อน Liq 98.5 แต่ SL2 ที่ 98 เลย Liq
+			// ไม้ 20% สุดท้ายโดนล้างก่อนถึง SL จึงต้อง Reject
+			name: "Weighted SL ผ่านแต่ SL จุดไกลสุดเลย Liquidation",
+			input: CalculationInput{
+				Balance: d(1000), RiskPercent: d(1), EntryPrice: d(100),
+				StopLosses: []StopLoss{{Price: d(99.5), Weight: d(0.8)}, {Price: d(98), Weight: d(0.2)}},
+				Leverage:   d(50),
+			},
+			wantCode: "beyond_liquidation",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := CalculatePositionSize(tc.input)
+			if tc.wantCode != "" {
+				vErr, ok := err.(*ValidationError)
+				if !ok || vErr.Code != tc.wantCode {
+					t.Fatalf("Expected code %s but got %v", tc.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+			}
+			assertDecimal(t, "Liq", res.LiquidationPrice, d(tc.wantLiq), 0)
+			assertDecimal(t, "IM", res.InitialMargin, d(tc.wantIM), 0)
+			assertDecimal(t, "MM", res.MaintenanceMargin, d(tc.wantMM), 0)
+		})
+	}
+}
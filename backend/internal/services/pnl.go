@@ -0,0 +1,165 @@
+// Package services - PnL ตอนปิดไม้
+// คำนวณกำไร/ขาดทุนฝั่ง Server จากราคาออกจริง แทนการเชื่อตัวเลขที่ Client ส่งมา
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ผลลัพธ์ของไม้ที่ปิดแล้ว (ตรงกับ Trade.Outcome)
+const (
+	OutcomeWin       = "WIN"
+	OutcomeLoss      = "LOSS"
+	OutcomeBreakEven = "BREAK_EVEN"
+)
+
+// DefaultBreakEvenTolerancePercent - ถ้า Net PnL ห่างจาก 0 ไม่เกินกี่ % ของมูลค่าไม้ ให้นับเป็น BREAK_EVEN
+// 0.05% = ขยับไม่ถึงค่า Fee ไป-กลับหนึ่งรอบ ถือว่าเสมอตัว
+var DefaultBreakEvenTolerancePercent = decimal.RequireFromString("0.05")
+
+// ClosingInput - ข้อมูลไม้ที่จะปิด
+type ClosingInput struct {
+	Side         string          // LONG หรือ SHORT
+	EntryPrice   decimal.Decimal // ราคาเข้า
+	ExitPrice    decimal.Decimal // ราคาออกจริง
+	Quantity     decimal.Decimal // จำนวนเหรียญ (ถ้าเป็น 0 จะคิดจาก PositionSize / EntryPrice)
+	PositionSize decimal.Decimal // มูลค่าไม้ตอนเข้า (USD)
+	Leverage     decimal.Decimal // ใช้หา Margin (0 = ไม่ใช้ Leverage)
+
+	Fee     decimal.Decimal // Fee รวมที่รู้ค่าแล้ว (USD) ถ้ามีจะใช้ค่านี้ก่อน
+	FeeRate decimal.Decimal // Fee ต่อครั้ง (เช่น 0.0005) ใช้เมื่อไม่มี Fee
+
+	AccountBalance decimal.Decimal // เงินในพอร์ต ใช้หา PnL % ของบัญชี (0 = ไม่คำนวณ)
+	RiskAmount     decimal.Decimal // เงินที่ยอมเสียถ้าโดน SL (USD) ใช้หา R-Multiple
+	StopLoss       decimal.Decimal // ถ้าไม่มี RiskAmount จะคิด Risk จากระยะ Entry-SL แทน
+
+	// ช่วงที่นับว่าเสมอตัว (% ของมูลค่าไม้) ถ้าไม่ใส่ใช้ DefaultBreakEvenTolerancePercent
+	BreakEvenTolerancePercent decimal.NullDecimal
+}
+
+// ClosingResult - ผล PnL ของไม้ที่ปิด
+type ClosingResult struct {
+	Quantity          decimal.Decimal  `json:"quantity"`            // จำนวนเหรียญที่ใช้คิด
+	GrossPnL          decimal.Decimal  `json:"gross_pnl"`           // กำไร/ขาดทุนก่อนหัก Fee (USD)
+	Fees              decimal.Decimal  `json:"fees"`                // Fee เข้า + ออก (USD)
+	NetPnL            decimal.Decimal  `json:"net_pnl"`             // กำไร/ขาดทุนสุทธิ (USD)
+	Margin            decimal.Decimal  `json:"margin"`              // Margin ที่วางไว้ (USD)
+	PnLPercentMargin  decimal.Decimal  `json:"pnl_percent_margin"`  // Net PnL เทียบกับ Margin (%)
+	PnLPercentAccount decimal.Decimal  `json:"pnl_percent_account"` // Net PnL เทียบกับเงินในพอร์ต (%)
+	RMultiple         *decimal.Decimal `json:"r_multiple"`          // Net PnL / Risk (nil = ไม่รู้ Risk)
+	Outcome           string           `json:"outcome"`             // WIN, LOSS, BREAK_EVEN
+}
+
+// CalculateClosingPnL - คำนวณ PnL ตอนปิดไม้ และตัดสินผล WIN/LOSS/BREAK_EVEN
+func CalculateClosingPnL(input ClosingInput) (ClosingResult, error) {
+	if err := validateClosingInput(input); err != nil {
+		return ClosingResult{}, err
+	}
+
+	qty := input.Quantity
+	if !qty.IsPositive() {
+		qty = input.PositionSize.Div(input.EntryPrice)
+	}
+	isLong := strings.EqualFold(input.Side, "LONG")
+
+	// LONG กำไรเมื่อราคาขึ้น, SHORT กำไรเมื่อราคาลง
+	move := input.ExitPrice.Sub(input.EntryPrice)
+	if !isLong {
+		move = move.Neg()
+	}
+	gross := move.Mul(qty)
+
+	fees := input.Fee
+	if !fees.IsPositive() {
+		fees = qty.Mul(input.EntryPrice.Add(input.ExitPrice)).Mul(input.FeeRate)
+	}
+	net := gross.Sub(fees)
+
+	notional := input.EntryPrice.Mul(qty)
+	margin := notional.Div(effectiveLeverage(input.Leverage))
+
+	result := ClosingResult{
+		Quantity: qty.Round(12),
+		GrossPnL: gross.Round(4),
+		Fees:     fees.Round(4),
+		NetPnL:   net.Round(4),
+		Margin:   margin.Round(4),
+		Outcome:  closingOutcome(net, notional, input.BreakEvenTolerancePercent),
+	}
+	if margin.IsPositive() {
+		result.PnLPercentMargin = net.Div(margin).Mul(decimalHundred).Round(4)
+	}
+	if input.AccountBalance.IsPositive() {
+		result.PnLPercentAccount = net.Div(input.AccountBalance).Mul(decimalHundred).Round(4)
+	}
+
+	result.RMultiple = RMultiple(net, InitialRisk(input.RiskAmount, input.EntryPrice, input.StopLoss, qty))
+
+	return result, nil
+}
+
+// ValidateManualPnL - pnl / pnl_percent / outcome ที่ส่งมาเองต้องมากับ override_pnl = true
+// ไม่งั้น Server คิด PnL เองจาก exit_price และค่าที่ส่งมาจะถูกทิ้งไปเงียบๆ
+func ValidateManualPnL(override bool, pnl, pnlPercent decimal.Decimal, outcome string) error {
+	if override {
+		return nil
+	}
+	const message = "ส่ง override_pnl: true ถ้าจะใส่ %s เอง (ไม่งั้น Server คำนวณจาก exit_price)"
+	switch {
+	case !pnl.IsZero():
+		return newValidationError("pnl", "override_required", fmt.Sprintf(message, "pnl"))
+	case !pnlPercent.IsZero():
+		return newValidationError("pnl_percent", "override_required", fmt.Sprintf(message, "pnl_percent"))
+	case outcome != "":
+		return newValidationError("outcome", "override_required", fmt.Sprintf(message, "outcome"))
+	}
+	return nil
+}
+
+// closingOutcome - ตัดสินผลจาก Net PnL โดยเผื่อช่วง Break-even ไว้
+func closingOutcome(net, notional decimal.Decimal, tolerancePercent decimal.NullDecimal) string {
+	tolerance := DefaultBreakEvenTolerancePercent
+	if tolerancePercent.Valid {
+		tolerance = tolerancePercent.Decimal
+	}
+
+	if net.Abs().LessThanOrEqual(notional.Mul(tolerance).Div(decimalHundred)) {
+		return OutcomeBreakEven
+	}
+	if net.IsPositive() {
+		return OutcomeWin
+	}
+	return OutcomeLoss
+}
+
+// validateClosingInput - เช็คข้อมูลก่อนคิด PnL
+func validateClosingInput(input ClosingInput) error {
+	if !strings.EqualFold(input.Side, "LONG") && !strings.EqualFold(input.Side, "SHORT") {
+		return newValidationError("side", "invalid_side", "Side ต้องเป็น LONG หรือ SHORT")
+	}
+	if !input.EntryPrice.IsPositive() {
+		return newValidationError("entry_price", "must_be_positive", "EntryPrice ต้องมากกว่า 0")
+	}
+	if !input.ExitPrice.IsPositive() {
+		return newValidationError("exit_price", "must_be_positive", "ExitPrice ต้องมากกว่า 0")
+	}
+	if !input.Quantity.IsPositive() && !input.PositionSize.IsPositive() {
+		return newValidationError("quantity", "must_be_positive", "ต้องรู้ Quantity หรือ PositionSize อย่างใดอย่างหนึ่ง")
+	}
+	if input.Leverage.IsNegative() {
+		return newValidationError("leverage", "must_not_be_negative", "Leverage ต้องมากกว่าหรือเท่ากับ 0")
+	}
+	if input.Fee.IsNegative() {
+		return newValidationError("fee", "must_not_be_negative", "Fee ต้องไม่เป็นลบ")
+	}
+	if input.FeeRate.IsNegative() {
+		return newValidationError("fee_rate", "must_not_be_negative", "FeeRate ต้องไม่เป็นลบ")
+	}
+	if input.BreakEvenTolerancePercent.Valid && input.BreakEvenTolerancePercent.Decimal.IsNegative() {
+		return newValidationError("break_even_tolerance_percent", "must_not_be_negative", "ช่วง Break-even ต้องไม่เป็นลบ")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestCalculateClosingPnL - ทดสอบ PnL ตอนปิดไม้ ทั้ง LONG/SHORT และการตัดสินผล
+func TestCalculateClosingPnL(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       ClosingInput
+		wantGross   float64
+		wantFees    float64
+		wantNet     float64
+		wantMargin  float64
+		wantAccount float64
+		wantR       *float64
+		wantOutcome string
+	}{
+		{
+			// Qty = 10, กำไร 10 x 10 = 100, Fee ที่รู้แล้ว 1 → Net 99
+			// Margin = 1000 / 10 = 100 → 99%, พอร์ต 10,000 → 0.99%, Risk 50 → 1.98R
+			name: "LONG ชนะ ใช้ Fee ที่บันทึกไว้",
+			input: ClosingInput{
+				Side: "LONG", EntryPrice: d(100), ExitPrice: d(110), PositionSize: d(1000),
+				Leverage: d(10), Fee: d(1), AccountBalance: d(10000), RiskAmount: d(50),
+			},
+			wantGross: 100, wantFees: 1, wantNet: 99,
+			wantMargin: 99, wantAccount: 0.99, wantR: ptr(1.98),
+			wantOutcome: OutcomeWin,
+		},
+		{
+			// Qty = 2, SHORT ราคาขึ้น 5 → -10, Fee = 2 x (100 + 105) x 0.001 = 0.41 → Net -10.41
+			// ไม่มี RiskAmount → Risk = |100 - 104| x 2 = 8 → -1.30125R
+			name: "SHORT แพ้ คิด Fee จาก FeeRate และ Risk จาก SL",
+			input: ClosingInput{
+				Side: "SHORT", EntryPrice: d(100), ExitPrice: d(105), Quantity: d(2),
+				FeeRate: d(0.001), StopLoss: d(104),
+			},
+			wantGross: -10, wantFees: 0.41, wantNet: -10.41,
+			wantMargin: -5.205, wantR: ptr(-1.3013),
+			wantOutcome: OutcomeLoss,
+		},
+		{
+			// Net = 0.5 - 0 = 0.5, มูลค่าไม้ 1000 → ช่วงเสมอตัว 0.05% = 0.5 พอดี
+			name: "กำไรนิดเดียว นับเป็น BREAK_EVEN",
+			input: ClosingInput{
+				Side: "LONG", EntryPrice: d(100), ExitPrice: d(100.05), Quantity: d(10),
+			},
+			wantGross: 0.5, wantNet: 0.5, wantMargin: 0.05,
+			wantOutcome: OutcomeBreakEven,
+		},
+		{
+			name: "ตั้ง Tolerance เป็น 0 กำไรนิดเดียวก็นับ WIN",
+			input: ClosingInput{
+				Side: "LONG", EntryPrice: d(100), ExitPrice: d(100.05), Quantity: d(10),
+				BreakEvenTolerancePercent: decimal.NewNullDecimal(decimal.Zero),
+			},
+			wantGross: 0.5, wantNet: 0.5, wantMargin: 0.05,
+			wantOutcome: OutcomeWin,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := CalculateClosingPnL(tc.input)
+			if err != nil {
+				t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+			}
+			assertDecimal(t, "GrossPnL", res.GrossPnL, d(tc.wantGross), 0)
+			assertDecimal(t, "Fees", res.Fees, d(tc.wantFees), 0)
+			assertDecimal(t, "NetPnL", res.NetPnL, d(tc.wantNet), 0)
+			assertDecimal(t, "PnLPercentMargin", res.PnLPercentMargin, d(tc.wantMargin), 0)
+			assertDecimal(t, "PnLPercentAccount", res.PnLPercentAccount, d(tc.wantAccount), 0)
+			if tc.wantR == nil {
+				if res.RMultiple != nil {
+					t.Errorf("RMultiple: ไม่ควรมีค่า แต่ได้ %s", res.RMultiple)
+				}
+			} else {
+				if res.RMultiple == nil {
+					t.Fatalf("RMultiple: ควรมีค่า %v แต่ได้ nil", *tc.wantR)
+				}
+				assertDecimal(t, "RMultiple", *res.RMultiple, d(*tc.wantR), 0)
+			}
+			if res.Outcome != tc.wantOutcome {
+				t.Errorf("Outcome: Expected %s but got %s", tc.wantOutcome, res.Outcome)
+			}
+		})
+	}
+}
+
+// TestCalculateClosingPnLValidation - ข้อมูลไม่ครบต้องได้ ValidationError
+func TestCalculateClosingPnLValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     ClosingInput
+		wantField string
+	}{
+		{"Side ผิด", ClosingInput{Side: "BUY", EntryPrice: d(1), ExitPrice: d(1), Quantity: d(1)}, "side"},
+		{"ไม่มีราคาออก", ClosingInput{Side: "LONG", EntryPrice: d(1), Quantity: d(1)}, "exit_price"},
+		{"ไม่รู้ขนาดไม้", ClosingInput{Side: "LONG", EntryPrice: d(1), ExitPrice: d(1)}, "quantity"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CalculateClosingPnL(tc.input)
+			vErr, ok := err.(*ValidationError)
+			if !ok || vErr.Field != tc.wantField {
+				t.Fatalf("Expected field %s but got %v", tc.wantField, err)
+			}
+		})
+	}
+}
+
+// TestValidateManualPnL - pnl/pnl_percent/outcome ที่ส่งมาโดยไม่มี override_pnl ต้องโดนปฏิเสธ ไม่ใช่ถูกทิ้งเงียบๆ
+func TestValidateManualPnL(t *testing.T) {
+	tests := []struct {
+		name       string
+		override   bool
+		pnl        decimal.Decimal
+		pnlPercent decimal.Decimal
+		outcome    string
+		wantField  string
+	}{
+		{"ไม่ส่งอะไรมา", false, decimal.Zero, decimal.Zero, "", ""},
+		{"override พร้อม pnl", true, d(12.5), d(3), "WIN", ""},
+		{"pnl ไม่มี override", false, d(-4), decimal.Zero, "", "pnl"},
+		{"pnl_percent ไม่มี override", false, decimal.Zero, d(1), "", "pnl_percent"},
+		{"outcome ไม่มี override", false, decimal.Zero, decimal.Zero, "LOSS", "outcome"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateManualPnL(tc.override, tc.pnl, tc.pnlPercent, tc.outcome)
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatalf("Expected no error but got %v", err)
+				}
+				return
+			}
+			vErr, ok := err.(*ValidationError)
+			if !ok || vErr.Field != tc.wantField || vErr.Code != "override_required" {
+				t.Fatalf("Expected field %s but got %v", tc.wantField, err)
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
@@ -0,0 +1,147 @@
+// Package services - R-Multiple
+// วัดผลแต่ละไม้เป็นจำนวนเท่าของ Risk ที่วางไว้ตอนเข้า (1R = เงินที่ยอมเสียถ้าโดน SL)
+// ทำให้เทียบผลงานข้ามขนาดไม้/ขนาดพอร์ตได้ ซึ่ง PnL เป็น USD ทำไม่ได้
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// ค่าตั้งต้นของ Histogram
+var (
+	DefaultRBucketSize = decimal.RequireFromString("0.5")
+	minRBucketSize     = decimal.RequireFromString("0.1")
+	maxRBucketSize     = decimal.NewFromInt(5)
+	rHistogramLimit    = decimal.NewFromInt(10) // ไม้ที่เกิน ±10R รวมไว้ในช่องริมสุด
+)
+
+// sqnMaxTrades - SQN จำกัดจำนวนไม้ไว้ที่ 100 (ตามนิยามของ Van Tharp) ไม่ให้ค่าพองตามจำนวนไม้
+const sqnMaxTrades = 100
+
+// InitialRisk - เงินที่เสี่ยงไว้ตอนเข้าไม้ (USD)
+// ใช้ MaxLoss ที่บันทึกไว้ก่อน ถ้าไม่มีคิดจาก |Entry − SL| × Quantity (0 = ไม่รู้ Risk)
+func InitialRisk(maxLoss, entryPrice, stopLoss, quantity decimal.Decimal) decimal.Decimal {
+	if maxLoss.IsPositive() {
+		return maxLoss
+	}
+	if stopLoss.IsPositive() && quantity.IsPositive() {
+		return entryPrice.Sub(stopLoss).Abs().Mul(quantity)
+	}
+	return decimal.Zero
+}
+
+// RMultiple - Net PnL / Risk (nil = ไม่รู้ Risk)
+func RMultiple(pnl, risk decimal.Decimal) *decimal.Decimal {
+	if !risk.IsPositive() {
+		return nil
+	}
+	r := pnl.Div(risk).Round(4)
+	return &r
+}
+
+// systemQualityNumber - SQN = √N × ค่าเฉลี่ย R / ส่วนเบี่ยงเบนมาตรฐานของ R (N สูงสุด 100)
+func systemQualityNumber(count int64, meanR, stdDevR decimal.Decimal) *decimal.Decimal {
+	if count < 2 || !stdDevR.IsPositive() {
+		return nil
+	}
+	if count > sqnMaxTrades {
+		count = sqnMaxTrades
+	}
+	sqn := decimal.NewFromFloat(math.Sqrt(float64(count))).Mul(meanR).Div(stdDevR).Round(4)
+	return &sqn
+}
+
+// RBucket - หนึ่งช่องของ Histogram [From, To)
+type RBucket struct {
+	From  decimal.Decimal `json:"from"`
+	To    decimal.Decimal `json:"to"`
+	Label string          `json:"label"`
+	Count int             `json:"count"`
+}
+
+// RDistribution - การกระจายของ R-Multiple
+type RDistribution struct {
+	Trades      int              `json:"trades"`       // จำนวนไม้ที่คำนวณ R ได้
+	ExpectancyR *decimal.Decimal `json:"expectancy_r"` // R เฉลี่ยต่อไม้
+	StdDevR     *decimal.Decimal `json:"std_dev_r"`
+	SQN         *decimal.Decimal `json:"sqn"`
+	BucketSize  decimal.Decimal  `json:"bucket_size"`
+	Buckets     []RBucket        `json:"buckets"`
+}
+
+// BuildRDistribution - สร้าง Histogram ของ R (ช่องละ bucketSize R, 0 = ค่าตั้งต้น) พร้อม Expectancy และ SQN
+// ช่องครอบคลุมตั้งแต่ R ต่ำสุดถึงสูงสุดที่มี (รวมช่องว่างระหว่างกลาง) แต่ไม่เกิน ±10R
+func BuildRDistribution(rs []decimal.Decimal, bucketSize decimal.Decimal) (RDistribution, error) {
+	if bucketSize.IsZero() {
+		bucketSize = DefaultRBucketSize
+	}
+	if bucketSize.LessThan(minRBucketSize) || bucketSize.GreaterThan(maxRBucketSize) {
+		return RDistribution{}, newValidationError("bucket_size", "invalid_bucket_size",
+			fmt.Sprintf("bucket_size ต้องอยู่ระหว่าง %s ถึง %s", minRBucketSize, maxRBucketSize))
+	}
+
+	dist := RDistribution{Trades: len(rs), BucketSize: bucketSize, Buckets: []RBucket{}}
+	if len(rs) == 0 {
+		return dist, nil
+	}
+
+	// สถิติ: ค่าเฉลี่ย และส่วนเบี่ยงเบนมาตรฐานแบบ Sample (N − 1)
+	count := decimal.NewFromInt(int64(len(rs)))
+	sum := decimal.Zero
+	for _, r := range rs {
+		sum = sum.Add(r)
+	}
+	mean := sum.Div(count)
+	expectancy := mean.Round(4)
+	dist.ExpectancyR = &expectancy
+	if len(rs) > 1 {
+		squares := decimal.Zero
+		for _, r := range rs {
+			diff := r.Sub(mean)
+			squares = squares.Add(diff.Mul(diff))
+		}
+		variance := squares.Div(count.Sub(decimal.NewFromInt(1)))
+		stdDev := decimal.NewFromFloat(math.Sqrt(variance.InexactFloat64())).Round(4)
+		dist.StdDevR = &stdDev
+		dist.SQN = systemQualityNumber(int64(len(rs)), mean, stdDev)
+	}
+
+	// Histogram: เลขช่อง = floor(R / size) จำกัดไว้ในช่วง ±10R
+	lowest := rHistogramLimit.Neg().Div(bucketSize).Floor().IntPart()
+	highest := rHistogramLimit.Div(bucketSize).Ceil().IntPart() - 1
+	counts := make(map[int64]int)
+	clampedLow, clampedHigh := false, false
+	for _, r := range rs {
+		i := r.Div(bucketSize).Floor().IntPart()
+		if i < lowest {
+			i, clampedLow = lowest, true
+		}
+		if i > highest {
+			i, clampedHigh = highest, true
+		}
+		counts[i]++
+	}
+	indexes := make([]int64, 0, len(counts))
+	for i := range counts {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+
+	for i := indexes[0]; i <= indexes[len(indexes)-1]; i++ {
+		from := bucketSize.Mul(decimal.NewFromInt(i))
+		to := from.Add(bucketSize)
+		label := fmt.Sprintf("%sR to %sR", from, to)
+		switch {
+		case i == lowest && clampedLow:
+			label = fmt.Sprintf("< %sR", to)
+		case i == highest && clampedHigh:
+			label = fmt.Sprintf("≥ %sR", from)
+		}
+		dist.Buckets = append(dist.Buckets, RBucket{From: from, To: to, Label: label, Count: counts[i]})
+	}
+	return dist, nil
+}
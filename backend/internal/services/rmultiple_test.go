@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestInitialRisk - ใช้ MaxLoss ก่อน ถ้าไม่มีคิดจากระยะ SL
+func TestInitialRisk(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxLoss  float64
+		entry    float64
+		stopLoss float64
+		quantity float64
+		want     float64
+	}{
+		{"ใช้ MaxLoss", 50, 100, 90, 2, 50},
+		{"LONG: (100 - 90) x 2", 0, 100, 90, 2, 20},
+		{"SHORT: |100 - 110| x 3", 0, 100, 110, 3, 30},
+		{"ไม่มี SL", 0, 100, 0, 2, 0},
+	}
+	for _, tc := range tests {
+		got := InitialRisk(d(tc.maxLoss), d(tc.entry), d(tc.stopLoss), d(tc.quantity))
+		assertDecimal(t, tc.name, got, d(tc.want), 0.0001)
+	}
+
+	if r := RMultiple(d(30), d(20)); r == nil {
+		t.Fatalf("RMultiple ต้องมีค่า")
+	} else {
+		assertDecimal(t, "RMultiple", *r, d(1.5), 0.0001)
+	}
+	if RMultiple(d(30), decimal.Zero) != nil {
+		t.Errorf("ไม่รู้ Risk ต้องเป็น nil")
+	}
+}
+
+// TestBuildRDistribution - R = -1, -1, 0.2, 2, 3 (Mean 0.64) ช่องละ 1R
+func TestBuildRDistribution(t *testing.T) {
+	rs := []decimal.Decimal{d(-1), d(-1), d(0.2), d(2), d(3)}
+	dist, err := BuildRDistribution(rs, d(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dist.ExpectancyR == nil || dist.StdDevR == nil || dist.SQN == nil {
+		t.Fatalf("Expectancy/StdDev/SQN ต้องมีค่า: %+v", dist)
+	}
+	// Variance = (2.6896 x 2 + 0.1936 + 1.8496 + 5.5696) / 4 = 3.248, StdDev ≈ 1.8022
+	// SQN = √5 x 0.64 / 1.8022 ≈ 0.7941
+	assertDecimal(t, "ExpectancyR", *dist.ExpectancyR, d(0.64), 0.0001)
+	assertDecimal(t, "StdDevR", *dist.StdDevR, d(1.8022), 0.0001)
+	assertDecimal(t, "SQN", *dist.SQN, d(0.7941), 0.001)
+
+	// ช่อง -1R ถึง 4R (ช่อง 1R-2R ว่างแต่ต้องมี)
+	wantCounts := []int{2, 1, 0, 1, 1}
+	if len(dist.Buckets) != len(wantCounts) {
+		t.Fatalf("Expected %d buckets but got %d", len(wantCounts), len(dist.Buckets))
+	}
+	for i, want := range wantCounts {
+		if dist.Buckets[i].Count != want {
+			t.Errorf("Bucket[%d] %s: Expected %d but got %d", i, dist.Buckets[i].Label, want, dist.Buckets[i].Count)
+		}
+	}
+	if dist.Buckets[0].Label != "-1R to 0R" {
+		t.Errorf("Label ผิด: %q", dist.Buckets[0].Label)
+	}
+}
+
+// TestBuildRDistributionOutliers - ไม้ที่เกิน ±10R รวมไว้ในช่องริมสุด
+func TestBuildRDistributionOutliers(t *testing.T) {
+	dist, err := BuildRDistribution([]decimal.Decimal{d(-25), d(9.6), d(40)}, decimal.Zero)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, last := dist.Buckets[0], dist.Buckets[len(dist.Buckets)-1]
+	if first.Label != "< -9.5R" || first.Count != 1 {
+		t.Errorf("ช่องแรกผิด: %+v", first)
+	}
+	if last.Label != "≥ 9.5R" || last.Count != 2 {
+		t.Errorf("ช่องสุดท้ายผิด: %+v", last)
+	}
+	if dist.SQN == nil {
+		t.Errorf("SQN ต้องมีค่า")
+	}
+
+	if _, err := BuildRDistribution(nil, d(0.01)); err == nil {
+		t.Errorf("bucket_size เล็กเกินไปต้อง Error")
+	}
+	empty, _ := BuildRDistribution(nil, decimal.Zero)
+	if empty.Trades != 0 || len(empty.Buckets) != 0 || empty.ExpectancyR != nil {
+		t.Errorf("ไม่มีไม้ ต้องว่าง: %+v", empty)
+	}
+}
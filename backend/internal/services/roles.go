@@ -0,0 +1,53 @@
+// Package services - Role ของผู้ใช้ (สิทธิ์การเข้าถึง) สถานะบัญชี และเงื่อนไขค้นหาของหน้า Admin
+package services
+
+import "strings"
+
+// Role ของผู้ใช้ (ตรงกับ users.role ใน schema/seeds)
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// NormalizeRole - แปลง Role เป็นตัวเล็ก (ว่าง = user) คืน false ถ้าไม่รู้จัก
+func NormalizeRole(role string) (string, bool) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case "":
+		return RoleUser, true
+	case RoleUser, RoleAdmin:
+		return role, true
+	}
+	return role, false
+}
+
+// HasRole - Role ของผู้ใช้อยู่ในรายการที่อนุญาตหรือไม่ (Role ที่ไม่รู้จักไม่ผ่านเสมอ)
+func HasRole(role string, allowed ...string) bool {
+	role, ok := NormalizeRole(role)
+	if !ok {
+		return false
+	}
+	for _, a := range allowed {
+		if a == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAccountStatus - บัญชีใช้ Login/Refresh ได้หรือไม่ (ถูกระงับ หรือ Admin สั่งให้ตั้งรหัสผ่านใหม่)
+func CheckAccountStatus(active, mustResetPassword bool) error {
+	if !active {
+		return newValidationError("account", "account_disabled", "บัญชีนี้ถูกระงับการใช้งาน กรุณาติดต่อผู้ดูแลระบบ")
+	}
+	if mustResetPassword {
+		return newValidationError("account", "password_reset_required", "กรุณาตั้งรหัสผ่านใหม่ผ่าน \"ลืมรหัสผ่าน\" ก่อนเข้าสู่ระบบ")
+	}
+	return nil
+}
+
+// LikePattern - คำค้นสำหรับ ILIKE แบบ "มีคำนี้อยู่" (Escape % _ \ ที่ผู้ใช้พิมพ์มาให้เป็นตัวอักษรธรรมดา)
+func LikePattern(search string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(strings.TrimSpace(search)) + "%"
+}
@@ -0,0 +1,71 @@
+package services
+
+import "testing"
+
+// TestHasRole - สิทธิ์ตาม Role (ว่าง = user, ไม่สนตัวพิมพ์, Role แปลกไม่ผ่าน)
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		allowed []string
+		want    bool
+	}{
+		{"Admin เข้าหน้า Admin", RoleAdmin, []string{RoleAdmin}, true},
+		{"User เข้าหน้า Admin ไม่ได้", RoleUser, []string{RoleAdmin}, false},
+		{"Role ว่าง = user", "", []string{RoleUser}, true},
+		{"ไม่สนตัวพิมพ์", " ADMIN ", []string{RoleAdmin}, true},
+		{"Role ที่ไม่รู้จัก", "superuser", []string{RoleAdmin, RoleUser}, false},
+		{"ไม่ระบุ Role ที่อนุญาต", RoleAdmin, nil, false},
+	}
+	for _, tc := range tests {
+		if got := HasRole(tc.role, tc.allowed...); got != tc.want {
+			t.Errorf("%s: HasRole(%q) = %v, want %v", tc.name, tc.role, got, tc.want)
+		}
+	}
+}
+
+// TestCheckAccountStatus - บัญชีที่ถูกระงับ/ต้องรีเซ็ตรหัสผ่านใช้งานไม่ได้ (ระงับมาก่อน)
+func TestCheckAccountStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		active    bool
+		mustReset bool
+		wantCode  string
+	}{
+		{"ปกติ", true, false, ""},
+		{"ถูกระงับ", false, false, "account_disabled"},
+		{"ต้องรีเซ็ตรหัสผ่าน", true, true, "password_reset_required"},
+		{"ระงับและต้องรีเซ็ต", false, true, "account_disabled"},
+	}
+	for _, tc := range tests {
+		err := CheckAccountStatus(tc.active, tc.mustReset)
+		if tc.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		vErr, ok := err.(*ValidationError)
+		if !ok || vErr.Code != tc.wantCode {
+			t.Errorf("%s: got %v, want code %s", tc.name, err, tc.wantCode)
+		}
+	}
+}
+
+// TestLikePattern - อักขระพิเศษของ LIKE ที่ผู้ใช้พิมพ์ต้องไม่กลายเป็น Wildcard
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"bob", "%bob%"},
+		{" 50%_off ", `%50\%\_off%`},
+		{`a\b`, `%a\\b%`},
+		{"", "%%"},
+	}
+	for _, tc := range tests {
+		if got := LikePattern(tc.search); got != tc.want {
+			t.Errorf("LikePattern(%q) = %q, want %q", tc.search, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package services - Tick Size / Lot Step Rounding
+// ปัดราคาและจำนวนเหรียญแบบที่ Exchange ทำจริง แล้วรายงานว่า Risk เปลี่ยนไปเท่าไหร่
+package services
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// AssetSpec - กฎการปัดของคู่เทรด (ดึงมาจาก models.Asset)
+type AssetSpec struct {
+	Symbol       string          `json:"symbol"`
+	TickSize     decimal.Decimal `json:"tick_size"`     // ราคาขยับได้ทีละเท่าไหร่ (เช่น 0.01)
+	QuantityStep decimal.Decimal `json:"quantity_step"` // จำนวนเหรียญขยับได้ทีละเท่าไหร่ (เช่น 0.001)
+	MinNotional  decimal.Decimal `json:"min_notional"`  // มูลค่าไม้ขั้นต่ำ (USD)
+}
+
+// RoundingReport - สรุปผลการปัดตามกฎของ Exchange
+type RoundingReport struct {
+	Symbol                string          `json:"symbol"`
+	TickSize              decimal.Decimal `json:"tick_size"`
+	QuantityStep          decimal.Decimal `json:"quantity_step"`
+	MinNotional           decimal.Decimal `json:"min_notional"`
+	RawQuantity           decimal.Decimal `json:"raw_quantity"`            // จำนวนเหรียญก่อนปัด
+	RoundedQuantity       decimal.Decimal `json:"rounded_quantity"`        // จำนวนเหรียญหลังปัดลงตาม Step
+	ActualRisk            decimal.Decimal `json:"actual_risk"`             // Risk จริงหลังปัด (USD)
+	RiskDifference        decimal.Decimal `json:"risk_difference"`         // ActualRisk - RiskAmount (ติดลบ = เสี่ยงน้อยลง)
+	RiskDifferencePercent decimal.Decimal `json:"risk_difference_percent"` // ต่างกันกี่ % ของ RiskAmount
+}
+
+// NormalizeSymbol - แปลง "BTC/USDT" หรือ "btc-usdt" เป็น "BTCUSDT" ให้ตรงกับ Asset catalog
+func NormalizeSymbol(symbol string) string {
+	replacer := strings.NewReplacer("/", "", "-", "", "_", "", " ", "")
+	return strings.ToUpper(replacer.Replace(symbol))
+}
+
+// stepDecimals - นับจำนวนทศนิยมของ Step (เช่น 0.001 → 3) ใช้กำหนดทศนิยมของผลลัพธ์
+func stepDecimals(step decimal.Decimal) int32 {
+	if exp := step.Exponent(); exp < 0 {
+		return -exp
+	}
+	return 0
+}
+
+// roundToTick - ปัดราคาไปหา Tick ที่ใกล้ที่สุด
+func roundToTick(price, tick decimal.Decimal) decimal.Decimal {
+	if !tick.IsPositive() {
+		return price
+	}
+	return price.Div(tick).Round(0).Mul(tick)
+}
+
+// floorToStep - ปัดจำนวนเหรียญลงตาม Step (Exchange ไม่ยอมให้เกิน และปัดลงทำให้ Risk ไม่เกินงบ)
+func floorToStep(qty, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return qty
+	}
+	return qty.Div(step).Floor().Mul(step)
+}
+
+// applyTickSize - ปัดราคา Entry/SL/TP ทุกจุดตาม TickSize ก่อนเริ่มคำนวณ
+func applyTickSize(input CalculationInput) CalculationInput {
+	tick := input.Asset.TickSize
+	if !tick.IsPositive() {
+		return input
+	}
+
+	input.EntryPrice = roundToTick(input.EntryPrice, tick)
+
+	stopLosses := make([]StopLoss, len(input.StopLosses))
+	for i, sl := range input.StopLosses {
+		stopLosses[i] = StopLoss{Price: roundToTick(sl.Price, tick), Weight: sl.Weight}
+	}
+	input.StopLosses = stopLosses
+
+	takeProfits := make([]TakeProfit, len(input.TakeProfits))
+	for i, tp := range input.TakeProfits {
+		takeProfits[i] = TakeProfit{Price: roundToTick(tp.Price, tick), Weight: tp.Weight}
+	}
+	input.TakeProfits = takeProfits
+
+	return input
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestCalculatePositionSizeRounding - ปัดราคาตาม Tick และจำนวนเหรียญตาม Lot Step
+func TestCalculatePositionSizeRounding(t *testing.T) {
+	// Entry 100.04 → 100.0, SL 98.96 → 99.0 (Tick 0.1) → Distance 1
+	// Qty ดิบ = 10.5 / 1 = 10.5 → ปัดลงตาม Step 1 = 10
+	// Risk จริง = 10 x 1 = 10 (ต่างจากที่ตั้งไว้ -0.5 = -4.7619%)
+	res, err := CalculatePositionSize(CalculationInput{
+		Balance:     d(1050),
+		RiskPercent: d(1.0),
+		EntryPrice:  d(100.04),
+		StopLosses:  []StopLoss{{Price: d(98.96), Weight: d(1)}},
+		Leverage:    d(1),
+		Asset:       &AssetSpec{Symbol: "SOLUSDT", TickSize: d(0.1), QuantityStep: d(1), MinNotional: d(5)},
+	})
+	if err != nil {
+		t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+	}
+
+	assertDecimal(t, "Quantity", res.PositionQuantity, d(10), 0)
+	assertDecimal(t, "AvgSL", res.WeightedAvgSL, d(99), 0)
+	if res.Rounding == nil {
+		t.Fatalf("Expected rounding report")
+	}
+	assertDecimal(t, "RawQuantity", res.Rounding.RawQuantity, d(10.5), 0)
+	assertDecimal(t, "ActualRisk", res.Rounding.ActualRisk, d(10), 0)
+	assertDecimal(t, "RiskDifference", res.Rounding.RiskDifference, d(-0.5), 0)
+	assertDecimal(t, "RiskDifferencePercent", res.Rounding.RiskDifferencePercent, d(-4.7619), 0)
+}
+
+// TestCalculatePositionSizeMinNotional - ไม้เล็กกว่า Min Notional ต้องโดน Reject
+func TestCalculatePositionSizeMinNotional(t *testing.T) {
+	_, err := CalculatePositionSize(CalculationInput{
+		Balance:     d(100),
+		RiskPercent: d(1.0),
+		EntryPrice:  d(100),
+		StopLosses:  []StopLoss{{Price: d(90), Weight: d(1)}},
+		Asset:       &AssetSpec{Symbol: "BTCUSDT", TickSize: d(0.1), QuantityStep: d(0.001), MinNotional: d(100)},
+	})
+
+	vErr, ok := err.(*ValidationError)
+	if !ok || vErr.Code != "below_min_notional" {
+		t.Fatalf("Expected below_min_notional but got %v", err)
+	}
+}
+
+// TestRoundingHelpers - ทดสอบ Helper ปัดเศษ
+func TestRoundingHelpers(t *testing.T) {
+	assertDecimal(t, "floorToStep(0.3, 0.1)", floorToStep(d(0.3), d(0.1)), d(0.3), 0)
+	assertDecimal(t, "floorToStep(1.23456, 0.001)", floorToStep(d(1.23456), d(0.001)), d(1.234), 0)
+	assertDecimal(t, "roundToTick small price", roundToTick(d(0.0000123456), d(0.00000001)), d(0.00001235), 0)
+	if got := NormalizeSymbol("btc/usdt"); got != "BTCUSDT" {
+		t.Errorf("NormalizeSymbol = %v", got)
+	}
+}
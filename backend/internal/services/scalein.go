@@ -0,0 +1,207 @@
+// Package services - Scale-in (ทยอยเข้าไม้ / DCA)
+// รวม Fill ฝั่ง Entry เป็นราคาเฉลี่ย และคำนวณว่าเติมได้อีกเท่าไหร่โดย Risk รวมไม่เกินงบ
+package services
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// EntryFill - การเข้าไม้หนึ่งครั้ง
+type EntryFill struct {
+	Price    decimal.Decimal // ราคาที่ได้จริง
+	Quantity decimal.Decimal // จำนวนเหรียญ
+	Fee      decimal.Decimal // Fee ของ Fill นี้ (USD)
+}
+
+// EntryAggregate - ผลรวมของทุก Fill
+type EntryAggregate struct {
+	AvgEntryPrice decimal.Decimal `json:"avg_entry_price"` // ราคาเข้าเฉลี่ย (ถ่วงตามจำนวน)
+	TotalQuantity decimal.Decimal `json:"total_quantity"`  // จำนวนเหรียญรวม
+	PositionSize  decimal.Decimal `json:"position_size"`   // มูลค่ารวม (USD)
+	TotalFee      decimal.Decimal `json:"total_fee"`       // Fee ฝั่งเข้ารวม (USD)
+}
+
+// AggregateEntries - รวม Fill ฝั่ง Entry เป็นราคาเฉลี่ย/จำนวนรวม
+func AggregateEntries(fills []EntryFill) EntryAggregate {
+	agg := EntryAggregate{}
+	for _, fill := range fills {
+		agg.TotalQuantity = agg.TotalQuantity.Add(fill.Quantity)
+		agg.PositionSize = agg.PositionSize.Add(fill.Price.Mul(fill.Quantity))
+		agg.TotalFee = agg.TotalFee.Add(fill.Fee)
+	}
+	if agg.TotalQuantity.IsPositive() {
+		agg.AvgEntryPrice = agg.PositionSize.Div(agg.TotalQuantity).Round(8)
+	}
+	agg.PositionSize = agg.PositionSize.Round(4)
+	agg.TotalFee = agg.TotalFee.Round(4)
+	return agg
+}
+
+// PositionRisk - ขาดทุนรวม Fee ถ้าโดน SL (สูตรเดียวกับตัวหารใน CalculatePositionSize)
+// Risk = Qty x (|Entry - SL| + Entry x Fee + SL x Fee)
+func PositionRisk(entryPrice, quantity, stopLoss, feeRate decimal.Decimal) decimal.Decimal {
+	return quantity.Mul(riskPerUnit(entryPrice, stopLoss, feeRate))
+}
+
+// riskPerUnit - ขาดทุนต่อเหรียญถ้าโดน SL (รวม Fee เข้า + ออก)
+func riskPerUnit(entryPrice, stopLoss, feeRate decimal.Decimal) decimal.Decimal {
+	return entryPrice.Sub(stopLoss).Abs().Add(entryPrice.Add(stopLoss).Mul(feeRate))
+}
+
+// ScaleInInput - "ถ้าจะเติมไม้ที่ราคา X ได้อีกกี่เหรียญ ให้ Risk รวมยังเป็น N%"
+type ScaleInInput struct {
+	Balance         decimal.Decimal `json:"balance"`           // เงินทุนทั้งหมด (USD)
+	RiskPercent     decimal.Decimal `json:"risk_percent"`      // Risk รวมทั้งไม้ที่ยอมได้ (%)
+	CurrentQuantity decimal.Decimal `json:"current_quantity"`  // เหรียญที่ถืออยู่แล้ว
+	CurrentAvgEntry decimal.Decimal `json:"current_avg_entry"` // ราคาเข้าเฉลี่ยตอนนี้
+	StopLosses      []StopLoss      `json:"stop_losses"`       // SL ของทั้งไม้ (หลังเติมแล้ว)
+	AddPrice        decimal.Decimal `json:"add_price"`         // ราคาที่จะเติม
+	FeeRate         decimal.Decimal `json:"fee_rate"`          // ค่า Fee ต่อครั้ง
+
+	// ดึงไม้ที่เปิดอยู่มาเป็น Current* ให้ (Handler จัดการ)
+	TradeID uint `json:"trade_id"`
+
+	// คู่เทรด - Handler จะไปหากฎ Tick/Step จาก Asset catalog มาใส่ใน Asset
+	Symbol string     `json:"symbol"`
+	Asset  *AssetSpec `json:"-"`
+}
+
+// ScaleInResult - ผลการคำนวณเติมไม้
+type ScaleInResult struct {
+	WeightedAvgSL   decimal.Decimal `json:"weighted_avg_sl"`  // SL เฉลี่ยที่ใช้คิด
+	RiskBudget      decimal.Decimal `json:"risk_budget"`      // Risk รวมที่ยอมได้ (USD)
+	CurrentRisk     decimal.Decimal `json:"current_risk"`     // Risk ของเหรียญที่ถืออยู่ (USD)
+	RemainingBudget decimal.Decimal `json:"remaining_budget"` // งบ Risk ที่เหลือให้เติม (ติดลบ = เกินงบแล้ว)
+	AddQuantity     decimal.Decimal `json:"add_quantity"`     // เติมได้อีกกี่เหรียญ
+	AddSizeUSD      decimal.Decimal `json:"add_size_usd"`     // มูลค่าที่เติม (USD)
+	NewQuantity     decimal.Decimal `json:"new_quantity"`     // เหรียญรวมหลังเติม
+	NewAvgEntry     decimal.Decimal `json:"new_avg_entry"`    // ราคาเข้าเฉลี่ยใหม่
+	NewPositionSize decimal.Decimal `json:"new_position_size"`
+	NewRisk         decimal.Decimal `json:"new_risk"` // Risk รวมหลังเติม (USD)
+
+	// ผลการปัดตาม Tick/Step ของ Exchange (มีเมื่อระบุ Asset)
+	Rounding *RoundingReport `json:"rounding,omitempty"`
+}
+
+// CalculateScaleIn - หาจำนวนเหรียญที่เติมได้ที่ราคา AddPrice โดย Risk รวมไม่เกิน RiskPercent
+// AddQty = (งบ Risk - Risk ที่มีอยู่) / Risk ต่อเหรียญที่ราคาเติม
+func CalculateScaleIn(input ScaleInInput) (ScaleInResult, error) {
+	if input.Asset != nil && input.Asset.TickSize.IsPositive() {
+		input.AddPrice = roundToTick(input.AddPrice, input.Asset.TickSize)
+		stopLosses := make([]StopLoss, len(input.StopLosses))
+		for i, sl := range input.StopLosses {
+			stopLosses[i] = StopLoss{Price: roundToTick(sl.Price, input.Asset.TickSize), Weight: sl.Weight}
+		}
+		input.StopLosses = stopLosses
+	}
+
+	if err := validateScaleInInput(input); err != nil {
+		return ScaleInResult{}, err
+	}
+
+	weightedAvgSL := calculateWeightedSL(input.StopLosses)
+	budget := input.Balance.Mul(input.RiskPercent).Div(decimalHundred)
+	currentRisk := PositionRisk(input.CurrentAvgEntry, input.CurrentQuantity, weightedAvgSL, input.FeeRate)
+	remaining := budget.Sub(currentRisk)
+
+	addRiskPerUnit := riskPerUnit(input.AddPrice, weightedAvgSL, input.FeeRate)
+	if addRiskPerUnit.IsZero() {
+		return ScaleInResult{}, newValidationError("add_price", "zero_distance", "ราคาเติมเท่ากับ SL คำนวณไม่ได้")
+	}
+
+	addQty := decimal.Zero
+	if remaining.IsPositive() {
+		addQty = remaining.Div(addRiskPerUnit)
+	}
+	quantityDecimals := int32(6)
+
+	var rounding *RoundingReport
+	if input.Asset != nil && input.Asset.QuantityStep.IsPositive() {
+		rawQuantity := addQty
+		addQty = floorToStep(rawQuantity, input.Asset.QuantityStep)
+		quantityDecimals = stepDecimals(input.Asset.QuantityStep)
+
+		actualRisk := addQty.Mul(addRiskPerUnit)
+		riskDiff := actualRisk.Sub(decimal.Max(remaining, decimal.Zero))
+		rounding = &RoundingReport{
+			Symbol:          input.Asset.Symbol,
+			TickSize:        input.Asset.TickSize,
+			QuantityStep:    input.Asset.QuantityStep,
+			MinNotional:     input.Asset.MinNotional,
+			RawQuantity:     rawQuantity.Round(8),
+			RoundedQuantity: addQty,
+			ActualRisk:      actualRisk.Round(4),
+			RiskDifference:  riskDiff.Round(4),
+		}
+		if remaining.IsPositive() {
+			rounding.RiskDifferencePercent = riskDiff.Div(remaining).Mul(decimalHundred).Round(4)
+		}
+	}
+	addQty = addQty.Round(quantityDecimals)
+
+	agg := AggregateEntries([]EntryFill{
+		{Price: input.CurrentAvgEntry, Quantity: input.CurrentQuantity},
+		{Price: input.AddPrice, Quantity: addQty},
+	})
+
+	return ScaleInResult{
+		WeightedAvgSL:   weightedAvgSL.Round(8),
+		RiskBudget:      budget.Round(2),
+		CurrentRisk:     currentRisk.Round(4),
+		RemainingBudget: remaining.Round(4),
+		AddQuantity:     addQty,
+		AddSizeUSD:      addQty.Mul(input.AddPrice).Round(2),
+		NewQuantity:     agg.TotalQuantity,
+		NewAvgEntry:     agg.AvgEntryPrice,
+		NewPositionSize: agg.PositionSize.Round(2),
+		NewRisk:         PositionRisk(agg.AvgEntryPrice, agg.TotalQuantity, weightedAvgSL, input.FeeRate).Round(4),
+		Rounding:        rounding,
+	}, nil
+}
+
+// validateScaleInInput - เช็คข้อมูลก่อนคำนวณเติมไม้
+func validateScaleInInput(input ScaleInInput) error {
+	if !input.Balance.IsPositive() {
+		return newValidationError("balance", "must_be_positive", "Balance ต้องมากกว่า 0")
+	}
+	if !input.RiskPercent.IsPositive() || input.RiskPercent.GreaterThan(decimalHundred) {
+		return newValidationError("risk_percent", "out_of_range", "RiskPercent ต้องอยู่ระหว่าง 0-100")
+	}
+	if input.CurrentQuantity.IsNegative() {
+		return newValidationError("current_quantity", "must_not_be_negative", "CurrentQuantity ต้องไม่เป็นลบ")
+	}
+	if input.CurrentQuantity.IsPositive() && !input.CurrentAvgEntry.IsPositive() {
+		return newValidationError("current_avg_entry", "must_be_positive", "ถือเหรียญอยู่ต้องระบุราคาเข้าเฉลี่ย")
+	}
+	if !input.AddPrice.IsPositive() {
+		return newValidationError("add_price", "must_be_positive", "AddPrice ต้องมากกว่า 0")
+	}
+	if input.FeeRate.IsNegative() {
+		return newValidationError("fee_rate", "must_not_be_negative", "FeeRate ต้องไม่เป็นลบ")
+	}
+	if len(input.StopLosses) == 0 {
+		return newValidationError("stop_losses", "required", "ต้องระบุ StopLoss อย่างน้อย 1 จุด")
+	}
+	for i, sl := range input.StopLosses {
+		if !sl.Price.IsPositive() {
+			return newValidationError(fmt.Sprintf("stop_losses[%d].price", i), "must_be_positive", "StopLoss ราคาต้องมากกว่า 0")
+		}
+		if !sl.Weight.IsPositive() {
+			return newValidationError(fmt.Sprintf("stop_losses[%d].weight", i), "must_be_positive", "StopLoss น้ำหนักต้องมากกว่า 0")
+		}
+	}
+
+	// ทิศทางดูจากไม้ที่ถืออยู่ (ถ้ายังไม่มี ดูจากราคาที่จะเติม)
+	reference := input.AddPrice
+	if input.CurrentQuantity.IsPositive() {
+		reference = input.CurrentAvgEntry
+	}
+	weightedAvgSL := calculateWeightedSL(input.StopLosses)
+	isLong := isLongSetup(reference, weightedAvgSL)
+	if (isLong && input.AddPrice.LessThanOrEqual(weightedAvgSL)) || (!isLong && input.AddPrice.GreaterThanOrEqual(weightedAvgSL)) {
+		return newValidationError("add_price", "wrong_side", "ราคาที่จะเติมต้องอยู่ฝั่งเดียวกับ Entry (ยังไม่ชน SL)")
+	}
+	return nil
+}
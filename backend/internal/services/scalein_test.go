@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestAggregateEntries - รวม Fill ฝั่ง Entry เป็นราคาเฉลี่ย
+func TestAggregateEntries(t *testing.T) {
+	// (100 x 1 + 90 x 3) / 4 = 92.5
+	agg := AggregateEntries([]EntryFill{
+		{Price: d(100), Quantity: d(1), Fee: d(0.1)},
+		{Price: d(90), Quantity: d(3), Fee: d(0.2)},
+	})
+	assertDecimal(t, "AvgEntryPrice", agg.AvgEntryPrice, d(92.5), 0)
+	assertDecimal(t, "TotalQuantity", agg.TotalQuantity, d(4), 0)
+	assertDecimal(t, "PositionSize", agg.PositionSize, d(370), 0)
+	assertDecimal(t, "TotalFee", agg.TotalFee, d(0.3), 0)
+}
+
+// TestCalculateScaleIn - เติมไม้แล้ว Risk รวมต้องเท่ากับงบพอดี
+func TestCalculateScaleIn(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         ScaleInInput
+		wantAdd       float64
+		wantRemaining float64
+		wantNewRisk   float64
+	}{
+		{
+			// งบ 2% ของ 1000 = 20, ถืออยู่ 1 เหรียญ (100 → SL 95) Risk 5
+			// เหลือ 15, เติมที่ 98 เสียเหรียญละ 3 → เติมได้ 5 เหรียญ
+			name: "LONG เติมได้ตามงบที่เหลือ",
+			input: ScaleInInput{
+				Balance: d(1000), RiskPercent: d(2),
+				CurrentQuantity: d(1), CurrentAvgEntry: d(100),
+				StopLosses: []StopLoss{{Price: d(95), Weight: d(1)}},
+				AddPrice:   d(98),
+			},
+			wantAdd: 5, wantRemaining: 15, wantNewRisk: 20,
+		},
+		{
+			// ถืออยู่ 5 เหรียญ Risk 25 เกินงบ 20 แล้ว → เติมไม่ได้
+			name: "งบ Risk หมดแล้ว",
+			input: ScaleInInput{
+				Balance: d(1000), RiskPercent: d(2),
+				CurrentQuantity: d(5), CurrentAvgEntry: d(100),
+				StopLosses: []StopLoss{{Price: d(95), Weight: d(1)}},
+				AddPrice:   d(98),
+			},
+			wantAdd: 0, wantRemaining: -5, wantNewRisk: 25,
+		},
+		{
+			// เหลือ 15, เติมที่ 97 เสียเหรียญละ 2 → 7.5 ปัดลงตาม Step 1 = 7
+			// Risk ใหม่ = 5 + 7 x 2 = 19
+			name: "ปัดจำนวนเหรียญลงตาม Lot Step",
+			input: ScaleInInput{
+				Balance: d(1000), RiskPercent: d(2),
+				CurrentQuantity: d(1), CurrentAvgEntry: d(100),
+				StopLosses: []StopLoss{{Price: d(95), Weight: d(1)}},
+				AddPrice:   d(97),
+				Asset:      &AssetSpec{Symbol: "TESTUSDT", QuantityStep: d(1)},
+			},
+			wantAdd: 7, wantRemaining: 15, wantNewRisk: 19,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := CalculateScaleIn(tc.input)
+			if err != nil {
+				t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+			}
+			assertDecimal(t, "AddQuantity", res.AddQuantity, d(tc.wantAdd), 0)
+			assertDecimal(t, "RemainingBudget", res.RemainingBudget, d(tc.wantRemaining), 0)
+			assertDecimal(t, "NewRisk", res.NewRisk, d(tc.wantNewRisk), 0.0001)
+		})
+	}
+}
+
+// TestCalculateScaleInWrongSide - เติม LONG ที่ราคาต่ำกว่า SL ต้องโดน Reject
+func TestCalculateScaleInWrongSide(t *testing.T) {
+	_, err := CalculateScaleIn(ScaleInInput{
+		Balance: d(1000), RiskPercent: d(2),
+		CurrentQuantity: d(1), CurrentAvgEntry: d(100),
+		StopLosses: []StopLoss{{Price: d(95), Weight: d(1)}},
+		AddPrice:   d(94),
+	})
+	vErr, ok := err.(*ValidationError)
+	if !ok || vErr.Code != "wrong_side" {
+		t.Fatalf("Expected code wrong_side but got %v", err)
+	}
+}
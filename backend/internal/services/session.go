@@ -0,0 +1,75 @@
+// Package services - Session ของการ Login (Refresh Token แบบหมุนเวียน)
+// Access Token (JWT) อายุสั้น ส่วน Refresh Token เก็บที่ Server เป็น Hash และเปลี่ยนใหม่ทุกครั้งที่ใช้
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RefreshReuseGrace - ช่วงเวลาหลังหมุน Token ที่ยอมให้ Token ก่อนหน้าถูกส่งมาซ้ำโดยไม่ถือว่าถูกขโมย
+// (หลายแท็บของ Browser Refresh พร้อมกัน) แต่ก็ไม่ออก Token ใหม่ให้
+const RefreshReuseGrace = 30 * time.Second
+
+// RefreshSession - สถานะของ Session ที่ใช้ตัดสินว่า Refresh ได้หรือไม่
+type RefreshSession struct {
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	RotatedAt *time.Time // เวลาที่ออก Refresh Token ปัจจุบัน (Token ก่อนหน้าใช้ไม่ได้ตั้งแต่ตอนนั้น)
+}
+
+// NewRefreshToken - Refresh Token แบบสุ่ม 256 bit (ส่งให้ Client ครั้งเดียว Server เก็บแค่ Hash)
+func NewRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashRefreshToken - Hash ที่เก็บใน Database (Token สุ่มยาวพอแล้ว จึงไม่ต้องใช้ bcrypt และค้นด้วย Index ได้)
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckRefresh - ตรวจว่า Refresh Token ใช้ได้หรือไม่
+// previous = Token ที่ส่งมาคือ Token ก่อนหน้าที่ถูกหมุนไปแล้ว ถ้าส่งมาหลังพ้น RefreshReuseGrace
+// ถือว่า Token หลุด (revoke = true ต้องยกเลิกทั้ง Session ทั้งฝั่งผู้โจมตีและเจ้าของ)
+func CheckRefresh(session RefreshSession, previous bool, now time.Time) (revoke bool, err error) {
+	if session.RevokedAt != nil {
+		return false, newValidationError("refresh_token", "session_revoked", "Session นี้ถูกยกเลิกแล้ว กรุณา Login ใหม่")
+	}
+	if !now.Before(session.ExpiresAt) {
+		return false, newValidationError("refresh_token", "session_expired", "Session หมดอายุ กรุณา Login ใหม่")
+	}
+	if previous {
+		if session.RotatedAt != nil && now.Sub(*session.RotatedAt) <= RefreshReuseGrace {
+			return false, newValidationError("refresh_token", "token_rotated", "Refresh Token นี้ถูกเปลี่ยนไปแล้ว ใช้ Token ล่าสุด")
+		}
+		return true, newValidationError("refresh_token", "token_reused", "Refresh Token ถูกใช้ซ้ำ ยกเลิก Session นี้แล้ว กรุณา Login ใหม่")
+	}
+	return false, nil
+}
+
+// TruncateRunes - ตัดข้อความให้ไม่เกิน max ตัวอักษร (นับแบบ rune เหมือน varchar(n) ของ PostgreSQL)
+// ไม่ตัดกลางตัวอักษรหลาย Byte (เช่น ภาษาไทยใน User-Agent) และแทน Byte ที่ไม่ใช่ UTF-8 ที่ Database ไม่รับ
+func TruncateRunes(s string, max int) string {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	count := 0
+	for i := range s {
+		if count == max {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
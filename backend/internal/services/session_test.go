@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestCheckRefresh - การหมุน Refresh Token และการตรวจจับ Token ที่ถูกใช้ซ้ำ
+func TestCheckRefresh(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	justRotated := now.Add(-10 * time.Second)
+	longAgo := now.Add(-time.Hour)
+	revoked := now.Add(-time.Minute)
+	active := RefreshSession{ExpiresAt: now.Add(time.Hour), RotatedAt: &justRotated}
+
+	tests := []struct {
+		name     string
+		session  RefreshSession
+		previous bool
+		revoke   bool
+		code     string
+	}{
+		{"Token ปัจจุบัน", active, false, false, ""},
+		{"Session ถูกยกเลิก", RefreshSession{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}, false, false, "session_revoked"},
+		{"Session หมดอายุ", RefreshSession{ExpiresAt: now}, false, false, "session_expired"},
+		{"Token เก่าในช่วงผ่อนผัน (หลายแท็บ)", active, true, false, "token_rotated"},
+		{"Token เก่าหลังพ้นช่วงผ่อนผัน", RefreshSession{ExpiresAt: now.Add(time.Hour), RotatedAt: &longAgo}, true, true, "token_reused"},
+		{"Session ถูกยกเลิกแล้วไม่ต้องยกเลิกซ้ำ", RefreshSession{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked, RotatedAt: &longAgo}, true, false, "session_revoked"},
+	}
+	for _, tc := range tests {
+		revoke, err := CheckRefresh(tc.session, tc.previous, now)
+		if revoke != tc.revoke {
+			t.Errorf("%s: revoke = %v, want %v", tc.name, revoke, tc.revoke)
+		}
+		if tc.code == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		verr, ok := err.(*ValidationError)
+		if !ok || verr.Code != tc.code {
+			t.Errorf("%s: Expected %s but got %v", tc.name, tc.code, err)
+		}
+	}
+}
+
+// TestRefreshToken - Token สุ่มไม่ซ้ำ และ Hash คงที่ (ใช้ค้นใน Database)
+func TestRefreshToken(t *testing.T) {
+	a, err := NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewRefreshToken()
+	if a == b || len(a) != 43 {
+		t.Errorf("NewRefreshToken = %q, %q", a, b)
+	}
+	if HashRefreshToken(a) != HashRefreshToken(a) || HashRefreshToken(a) == HashRefreshToken(b) || len(HashRefreshToken(a)) != 64 {
+		t.Errorf("HashRefreshToken ต้องคงที่และต่างกันตาม Token")
+	}
+}
+
+// TestTruncateRunes - นับเป็นตัวอักษรไม่ใช่ Byte และไม่ตัดกลางตัวอักษรหลาย Byte
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"สั้นกว่า max", "curl/8.0", 64, "curl/8.0"},
+		{"ASCII ตัดพอดี", "Mozilla/5.0", 7, "Mozilla"},
+		{"ภาษาไทยนับเป็นตัวอักษร", "สวัสดีครับ", 6, "สวัสดี"},
+		{"ภาษาไทยไม่เกิน max (Byte เกิน)", "สวัสดี", 6, "สวัสดี"},
+		{"Emoji", "ab🚀cd", 3, "ab🚀"},
+		{"Byte ที่ไม่ใช่ UTF-8", "ab\xffcd", 3, "ab�"},
+		{"max 0", "abc", 0, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := TruncateRunes(tc.s, tc.max)
+			if got != tc.want {
+				t.Errorf("TruncateRunes(%q, %d) = %q, want %q", tc.s, tc.max, got, tc.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateRunes(%q, %d) = %q ไม่ใช่ UTF-8", tc.s, tc.max, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package services - Multi-level Take Profit
+// คำนวณฝั่ง TP หลายจุด (ถ่วงน้ำหนักเหมือน StopLoss) พร้อม PnL สุทธิหลังหัก Fee
+package services
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// TakeProfit - แต่ละจุด TP ที่ตั้งไว้ พร้อมน้ำหนัก
+type TakeProfit struct {
+	Price  decimal.Decimal `json:"price"`  // ราคา TP ที่ตั้งไว้
+	Weight decimal.Decimal `json:"weight"` // น้ำหนัก (เช่น 0.5 = ขาย 50% ของไม้ที่จุดนี้)
+}
+
+// TakeProfitBreakdown - ผลคำนวณแยกของแต่ละจุด TP
+type TakeProfitBreakdown struct {
+	Price       decimal.Decimal `json:"price"`        // ราคา TP
+	Weight      decimal.Decimal `json:"weight"`       // น้ำหนักที่ Normalize แล้ว (รวมกันได้ 1)
+	Quantity    decimal.Decimal `json:"quantity"`     // จำนวนเหรียญที่ขายที่จุดนี้
+	Distance    decimal.Decimal `json:"distance"`     // ระยะห่างจาก Entry
+	GrossProfit decimal.Decimal `json:"gross_profit"` // กำไรก่อนหัก Fee (USD)
+	Fees        decimal.Decimal `json:"fees"`         // Fee เข้า + ออก ของส่วนนี้ (USD)
+	NetProfit   decimal.Decimal `json:"net_profit"`   // กำไรสุทธิ (USD)
+	RMultiple   decimal.Decimal `json:"r_multiple"`   // กำไรสุทธิของจุดนี้ เทียบกับ Risk ทั้งไม้
+}
+
+// takeProfitPlan - ผลรวมของฝั่ง TP ทั้งหมด
+type takeProfitPlan struct {
+	WeightedAvgTP  decimal.Decimal
+	ExpectedReward decimal.Decimal
+	Breakdown      []TakeProfitBreakdown
+}
+
+// isLongSetup - เดาทิศทางจาก SL: ถ้า SL อยู่ต่ำกว่า Entry = LONG
+func isLongSetup(entryPrice, weightedAvgSL decimal.Decimal) bool {
+	return weightedAvgSL.LessThan(entryPrice)
+}
+
+// calculateWeightedTP - คำนวณ TP เฉลี่ยแบบถ่วงน้ำหนัก (สูตรเดียวกับ calculateWeightedSL)
+func calculateWeightedTP(takeProfits []TakeProfit) decimal.Decimal {
+	totalWeight := decimal.Zero
+	weightedSum := decimal.Zero
+
+	for _, tp := range takeProfits {
+		weightedSum = weightedSum.Add(tp.Price.Mul(tp.Weight))
+		totalWeight = totalWeight.Add(tp.Weight)
+	}
+
+	if totalWeight.IsZero() {
+		return decimal.Zero
+	}
+	return weightedSum.Div(totalWeight)
+}
+
+// buildTakeProfitPlan - แตกกำไรราย TP ตามจำนวนเหรียญที่คำนวณได้
+// riskAmount ใช้หา R-Multiple ของแต่ละจุด
+func buildTakeProfitPlan(input CalculationInput, positionQuantity, riskAmount decimal.Decimal) takeProfitPlan {
+	totalWeight := decimal.Zero
+	for _, tp := range input.TakeProfits {
+		totalWeight = totalWeight.Add(tp.Weight)
+	}
+	if totalWeight.IsZero() {
+		return takeProfitPlan{}
+	}
+
+	plan := takeProfitPlan{
+		WeightedAvgTP: calculateWeightedTP(input.TakeProfits),
+		Breakdown:     make([]TakeProfitBreakdown, 0, len(input.TakeProfits)),
+	}
+
+	for _, tp := range input.TakeProfits {
+		weight := tp.Weight.Div(totalWeight)
+		qty := positionQuantity.Mul(weight)
+		distance := tp.Price.Sub(input.EntryPrice).Abs()
+		gross := qty.Mul(distance)
+		fees := qty.Mul(input.EntryPrice.Add(tp.Price)).Mul(input.FeeRate)
+		net := gross.Sub(fees)
+		plan.ExpectedReward = plan.ExpectedReward.Add(net)
+
+		rMultiple := decimal.Zero
+		if riskAmount.IsPositive() {
+			rMultiple = net.Div(riskAmount)
+		}
+
+		plan.Breakdown = append(plan.Breakdown, TakeProfitBreakdown{
+			Price:       tp.Price,
+			Weight:      weight.Round(6),
+			Quantity:    qty.Round(6),
+			Distance:    distance.Round(8),
+			GrossProfit: gross.Round(4),
+			Fees:        fees.Round(4),
+			NetProfit:   net.Round(4),
+			RMultiple:   rMultiple.Round(4),
+		})
+	}
+
+	return plan
+}
+
+// validateTakeProfits - เช็ค TP แต่ละจุด: ราคา/น้ำหนักต้อง > 0 และต้องอยู่ฝั่งกำไร
+// (LONG → TP สูงกว่า Entry, SHORT → TP ต่ำกว่า Entry)
+func validateTakeProfits(input CalculationInput) error {
+	isLong := isLongSetup(input.EntryPrice, calculateWeightedSL(input.StopLosses))
+
+	for i, tp := range input.TakeProfits {
+		if !tp.Price.IsPositive() {
+			return newValidationError(fmt.Sprintf("take_profits[%d].price", i), "must_be_positive", "TakeProfit ราคาต้องมากกว่า 0")
+		}
+		if !tp.Weight.IsPositive() {
+			return newValidationError(fmt.Sprintf("take_profits[%d].weight", i), "must_be_positive", "TakeProfit น้ำหนักต้องมากกว่า 0")
+		}
+		if (isLong && tp.Price.LessThanOrEqual(input.EntryPrice)) || (!isLong && tp.Price.GreaterThanOrEqual(input.EntryPrice)) {
+			return newValidationError(fmt.Sprintf("take_profits[%d].price", i), "wrong_side", "TakeProfit ต้องอยู่ฝั่งกำไรของ Entry (ตรงข้ามกับ SL)")
+		}
+	}
+
+	return nil
+}
+
+// BlendedRiskReward - คำนวณ R:R แบบรวม Fee จาก SL/TP หลายจุด โดยไม่ต้องรู้ขนาดไม้
+// สูตร: (กำไรสุทธิเฉลี่ยต่อเหรียญ) / (ขาดทุนรวม Fee ต่อเหรียญ)
+// ใช้ใน Journal เพื่อคำนวณ RiskRewardRatio ฝั่ง Server แทนการเชื่อค่าจาก Client
+func BlendedRiskReward(entryPrice decimal.Decimal, stopLosses []StopLoss, takeProfits []TakeProfit, feeRate decimal.Decimal) (decimal.Decimal, error) {
+	input := CalculationInput{
+		Balance:     decimalOne,
+		RiskPercent: decimalHundred,
+		EntryPrice:  entryPrice,
+		StopLosses:  stopLosses,
+		TakeProfits: takeProfits,
+		FeeRate:     feeRate,
+	}
+	if err := validateInput(input); err != nil {
+		return decimal.Zero, err
+	}
+	if len(takeProfits) == 0 {
+		return decimal.Zero, newValidationError("take_profits", "required", "ต้องระบุ TakeProfit อย่างน้อย 1 จุด")
+	}
+
+	weightedAvgSL := calculateWeightedSL(stopLosses)
+	riskPerUnit := entryPrice.Sub(weightedAvgSL).Abs().Add(entryPrice.Add(weightedAvgSL).Mul(feeRate))
+	if riskPerUnit.IsZero() {
+		return decimal.Zero, newValidationError("stop_losses", "zero_distance", "ระยะห่าง Entry-SL เท่ากับ 0 คำนวณไม่ได้")
+	}
+
+	// ใช้ Qty = 1 เหรียญ แล้วหารด้วย Risk ต่อเหรียญ ก็จะได้ R:R ตรงๆ
+	plan := buildTakeProfitPlan(input, decimalOne, riskPerUnit)
+	return plan.ExpectedReward.Div(riskPerUnit).Round(4), nil
+}
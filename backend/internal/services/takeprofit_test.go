@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+)
+
+// TestCalculatePositionSizeTakeProfits - ทดสอบฝั่ง TP หลายจุด
+func TestCalculatePositionSizeTakeProfits(t *testing.T) {
+	// ทุน 1000, Risk 1% (10$), Entry 100, SL 99 → Qty 10
+	// TP1: 102 (50%) กำไร 5 x 2 = 10$, TP2: 104 (50%) กำไร 5 x 4 = 20$
+	// ExpectedReward = 30$, R:R = 30 / 10 = 3
+	res, err := CalculatePositionSize(CalculationInput{
+		Balance:     d(1000),
+		RiskPercent: d(1.0),
+		EntryPrice:  d(100),
+		StopLosses:  []StopLoss{{Price: d(99), Weight: d(1)}},
+		TakeProfits: []TakeProfit{
+			{Price: d(102), Weight: d(0.5)},
+			{Price: d(104), Weight: d(0.5)},
+		},
+		Leverage: d(1),
+	})
+	if err != nil {
+		t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+	}
+
+	assertDecimal(t, "AvgTP", res.WeightedAvgTP, d(103), 0)
+	assertDecimal(t, "ExpectedReward", res.ExpectedReward, d(30), 0)
+	assertDecimal(t, "R:R", res.RiskRewardRatio, d(3), 0)
+	if len(res.TakeProfitBreakdown) != 2 || !res.TakeProfitBreakdown[1].NetProfit.Equal(d(20)) || !res.TakeProfitBreakdown[1].RMultiple.Equal(d(2)) {
+		t.Errorf("Unexpected TP breakdown: %+v", res.TakeProfitBreakdown)
+	}
+}
+
+// TestBlendedRiskReward - R:R ต้องหัก Fee ทั้งฝั่ง TP และ SL
+func TestBlendedRiskReward(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   float64
+		sl      []StopLoss
+		tp      []TakeProfit
+		feeRate float64
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:  "SHORT ไม่มี Fee: Entry 100, SL 102, TP 94 → R:R 3",
+			entry: 100,
+			sl:    []StopLoss{{Price: d(102), Weight: d(1)}},
+			tp:    []TakeProfit{{Price: d(94), Weight: d(1)}},
+			want:  3,
+		},
+		{
+			// Risk/unit = 1 + (100+99)*0.001 = 1.199
+			// Reward/unit = 2 - (100+102)*0.001 = 1.798
+			name:    "LONG มี Fee 0.1%",
+			entry:   100,
+			sl:      []StopLoss{{Price: d(99), Weight: d(1)}},
+			tp:      []TakeProfit{{Price: d(102), Weight: d(1)}},
+			feeRate: 0.001,
+			want:    1.798 / 1.199,
+		},
+		{
+			name:    "TP อยู่ฝั่งเดียวกับ SL ต้อง Error",
+			entry:   100,
+			sl:      []StopLoss{{Price: d(99), Weight: d(1)}},
+			tp:      []TakeProfit{{Price: d(98), Weight: d(1)}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := BlendedRiskReward(d(tc.entry), tc.sl, tc.tp, d(tc.feeRate))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ควรมี error แต่ไม่มี")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ไม่ควรมี error แต่ได้: %v", err)
+			}
+			assertDecimal(t, "R:R", got, d(tc.want), 0.0001)
+		})
+	}
+}
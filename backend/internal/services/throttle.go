@@ -0,0 +1,200 @@
+// Package services - ป้องกันการเดารหัสผ่าน/OTP (Brute-force)
+// นับครั้งที่ล้มเหลวต่อ Key (เช่น บัญชี หรือ IP) เกินกำหนดแล้วล็อก โดยระยะล็อกเพิ่มเป็นเท่าตัวทุกครั้งที่โดนล็อกซ้ำ
+package services
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// AttemptRecord - สถานะการพยายามของ Key หนึ่ง
+type AttemptRecord struct {
+	Failures    int       // ครั้งที่ผิดติดกันภายใน Window (นับใหม่หลังโดนล็อก)
+	Lockouts    int       // จำนวนครั้งที่โดนล็อกติดกัน (ใช้คำนวณระยะล็อกแบบทวีคูณ)
+	LastFailure time.Time // ผิดครั้งล่าสุด
+	LockedUntil time.Time // ล็อกถึงเมื่อไร (Zero = ไม่ล็อก)
+}
+
+// AttemptStore - ที่เก็บ AttemptRecord (เปลี่ยนเป็น Redis ฯลฯ ได้ถ้ารันหลาย Instance)
+// Update ต้องอ่าน-แก้-เขียนแบบ Atomic ต่อ Key
+type AttemptStore interface {
+	Load(key string) (AttemptRecord, bool)
+	Update(key string, fn func(rec *AttemptRecord)) AttemptRecord
+	Delete(key string)
+}
+
+// ThrottlePolicy - กติกาการล็อก
+type ThrottlePolicy struct {
+	MaxFailures int           // ผิดได้กี่ครั้งก่อนล็อก
+	Window      time.Duration // ไม่ผิดเลยนานเท่านี้ นับ Failures ใหม่
+	BaseLockout time.Duration // ระยะล็อกครั้งแรก (ครั้งถัดไป x2 เรื่อยๆ)
+	MaxLockout  time.Duration // ระยะล็อกสูงสุด
+	ResetAfter  time.Duration // ไม่ผิดเลยนานเท่านี้ ลืมประวัติการล็อกทั้งหมด
+}
+
+// Throttle - ตัวนับความพยายามตาม ThrottlePolicy
+type Throttle struct {
+	Store  AttemptStore
+	Policy ThrottlePolicy
+}
+
+// NewThrottle - สร้าง Throttle (store = nil ใช้ MemoryAttemptStore)
+func NewThrottle(store AttemptStore, policy ThrottlePolicy) *Throttle {
+	if store == nil {
+		store = NewMemoryAttemptStore(policy.ResetAfter)
+	}
+	return &Throttle{Store: store, Policy: policy}
+}
+
+// Check - เวลาที่ยังโดนล็อกอยู่ของ Key (0 = พยายามได้)
+func (t *Throttle) Check(key string, now time.Time) time.Duration {
+	rec, ok := t.Store.Load(key)
+	if !ok || !now.Before(rec.LockedUntil) {
+		return 0
+	}
+	return rec.LockedUntil.Sub(now)
+}
+
+// Fail - บันทึกการพยายามที่ล้มเหลว คืนระยะล็อกถ้าครั้งนี้ทำให้โดนล็อก (0 = ยังไม่ล็อก)
+func (t *Throttle) Fail(key string, now time.Time) time.Duration {
+	rec := t.Store.Update(key, func(rec *AttemptRecord) {
+		t.fail(rec, now)
+	})
+	if !now.Before(rec.LockedUntil) {
+		return 0
+	}
+	return rec.LockedUntil.Sub(now)
+}
+
+// Reset - ล้างประวัติของ Key (เช่น Login สำเร็จ)
+func (t *Throttle) Reset(key string) {
+	t.Store.Delete(key)
+}
+
+// fail - เพิ่ม Failures ของ rec และล็อกเมื่อครบ MaxFailures
+func (t *Throttle) fail(rec *AttemptRecord, now time.Time) {
+	p := t.Policy
+	if !rec.LastFailure.IsZero() {
+		idle := now.Sub(rec.LastFailure)
+		if p.ResetAfter > 0 && idle > p.ResetAfter {
+			*rec = AttemptRecord{}
+		} else if p.Window > 0 && idle > p.Window {
+			rec.Failures = 0
+		}
+	}
+	rec.LastFailure = now
+	if now.Before(rec.LockedUntil) {
+		return // ยังล็อกอยู่ ไม่ต้องนับเพิ่ม
+	}
+
+	rec.Failures++
+	if p.MaxFailures <= 0 || rec.Failures < p.MaxFailures {
+		return
+	}
+	rec.Failures = 0
+	rec.Lockouts++
+	rec.LockedUntil = now.Add(LockoutDuration(p, rec.Lockouts))
+}
+
+// LockoutDuration - ระยะล็อกของการโดนล็อกครั้งที่ n (BaseLockout x 2^(n-1) ไม่เกิน MaxLockout)
+func LockoutDuration(p ThrottlePolicy, n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := 1; i < n; i++ {
+		if p.MaxLockout > 0 && d >= p.MaxLockout {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxLockout > 0 && d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
+
+// ============================================
+// In-memory Store
+// ============================================
+
+// memoryPruneInterval - ล้าง Record ที่หมดอายุทุกกี่นาที (ทำระหว่าง Update)
+const memoryPruneInterval = time.Minute
+
+// MemoryAttemptStore - AttemptStore ในหน่วยความจำ (ใช้ได้กับ Instance เดียว ข้อมูลหายเมื่อ Restart)
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	records   map[string]AttemptRecord
+	ttl       time.Duration // Record ที่ไม่ผิดเลยนานเกินนี้และไม่ล็อกอยู่ถูกลบทิ้ง (0 = ไม่ลบ)
+	lastPrune time.Time
+}
+
+// NewMemoryAttemptStore - สร้าง MemoryAttemptStore
+func NewMemoryAttemptStore(ttl time.Duration) *MemoryAttemptStore {
+	return &MemoryAttemptStore{
+		records: make(map[string]AttemptRecord),
+		ttl:     ttl,
+	}
+}
+
+// Load - อ่าน Record ของ Key
+func (s *MemoryAttemptStore) Load(key string) (AttemptRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[key]
+	return rec, ok
+}
+
+// Update - แก้ Record ของ Key ภายใต้ Lock
+func (s *MemoryAttemptStore) Update(key string, fn func(rec *AttemptRecord)) AttemptRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.records[key]
+	fn(&rec)
+	s.records[key] = rec
+	s.prune(rec.LastFailure)
+	return rec
+}
+
+// Delete - ลบ Record ของ Key
+func (s *MemoryAttemptStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+// prune - ลบ Record ที่หมดอายุ ณ เวลา now ของผู้เรียก Update (เรียกขณะถือ Lock อยู่)
+func (s *MemoryAttemptStore) prune(now time.Time) {
+	if s.ttl <= 0 || now.Sub(s.lastPrune) < memoryPruneInterval {
+		return
+	}
+	s.lastPrune = now
+	for key, rec := range s.records {
+		if now.Sub(rec.LastFailure) > s.ttl && !now.Before(rec.LockedUntil) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// ============================================
+// OTP
+// ============================================
+
+// MaxOTPAttempts - ใส่ OTP ผิดได้กี่ครั้งก่อน OTP นั้นใช้ไม่ได้ (ต้องขอใหม่)
+const MaxOTPAttempts = 5
+
+// CheckOTP - ตรวจ OTP ที่ผู้ใช้ใส่ (เทียบแบบ Constant-time)
+// invalidate = ควรลบ OTP ทิ้ง (หมดอายุ หรือผิดครบ MaxOTPAttempts รวมครั้งนี้แล้ว)
+func CheckOTP(stored *string, expiresAt *time.Time, failures int, given string, now time.Time) (ok, invalidate bool) {
+	if stored == nil {
+		return false, false
+	}
+	if expiresAt == nil || !now.Before(*expiresAt) || failures >= MaxOTPAttempts {
+		return false, true
+	}
+	if subtle.ConstantTimeCompare([]byte(*stored), []byte(given)) == 1 {
+		return true, false
+	}
+	return false, failures+1 >= MaxOTPAttempts
+}
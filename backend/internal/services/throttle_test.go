@@ -0,0 +1,131 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestThrottleLockout - ล็อกเมื่อผิดครบ MaxFailures และระยะล็อกเพิ่มเป็นเท่าตัวเมื่อโดนซ้ำ
+func TestThrottleLockout(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	throttle := NewThrottle(nil, ThrottlePolicy{
+		MaxFailures: 3,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  5 * time.Minute,
+		ResetAfter:  24 * time.Hour,
+	})
+
+	for i := 1; i <= 2; i++ {
+		if locked := throttle.Fail("user:bob", now); locked != 0 {
+			t.Fatalf("failure %d: locked %s too early", i, locked)
+		}
+	}
+	if got := throttle.Fail("user:bob", now); got != time.Minute {
+		t.Fatalf("3rd failure lockout = %s, want 1m", got)
+	}
+	if got := throttle.Check("user:bob", now.Add(30*time.Second)); got != 30*time.Second {
+		t.Errorf("Check during lockout = %s, want 30s", got)
+	}
+	if got := throttle.Check("user:alice", now); got != 0 {
+		t.Errorf("other key locked: %s", got)
+	}
+
+	// ผิดระหว่างล็อกไม่นับเพิ่ม
+	throttle.Fail("user:bob", now.Add(10*time.Second))
+	if rec, _ := throttle.Store.Load("user:bob"); rec.Failures != 0 || rec.Lockouts != 1 {
+		t.Errorf("failure during lockout counted: %+v", rec)
+	}
+
+	// หมดล็อกแล้วผิดครบอีกรอบ ล็อกนานขึ้นเป็น 2 เท่า
+	after := now.Add(2 * time.Minute)
+	if got := throttle.Check("user:bob", after); got != 0 {
+		t.Fatalf("still locked after lockout: %s", got)
+	}
+	throttle.Fail("user:bob", after)
+	throttle.Fail("user:bob", after)
+	if got := throttle.Fail("user:bob", after); got != 2*time.Minute {
+		t.Errorf("2nd lockout = %s, want 2m", got)
+	}
+
+	throttle.Reset("user:bob")
+	if got := throttle.Check("user:bob", after); got != 0 {
+		t.Errorf("locked after Reset: %s", got)
+	}
+}
+
+// TestThrottleWindow - ไม่ผิดนานเกิน Window นับใหม่ และเกิน ResetAfter ลืมประวัติการล็อก
+func TestThrottleWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	throttle := NewThrottle(nil, ThrottlePolicy{
+		MaxFailures: 2,
+		Window:      10 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+		ResetAfter:  24 * time.Hour,
+	})
+
+	throttle.Fail("ip:1.2.3.4", now)
+	if got := throttle.Fail("ip:1.2.3.4", now.Add(11*time.Minute)); got != 0 {
+		t.Errorf("failures outside window locked: %s", got)
+	}
+
+	throttle.Fail("ip:1.2.3.4", now.Add(12*time.Minute)) // ล็อกครั้งที่ 1
+	later := now.Add(48 * time.Hour)
+	throttle.Fail("ip:1.2.3.4", later)
+	if got := throttle.Fail("ip:1.2.3.4", later); got != time.Minute {
+		t.Errorf("lockout after ResetAfter = %s, want base 1m", got)
+	}
+}
+
+// TestLockoutDuration - ทวีคูณจาก BaseLockout และไม่เกิน MaxLockout
+func TestLockoutDuration(t *testing.T) {
+	p := ThrottlePolicy{BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 0},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+	for _, tc := range tests {
+		if got := LockoutDuration(p, tc.n); got != tc.want {
+			t.Errorf("LockoutDuration(%d) = %s, want %s", tc.n, got, tc.want)
+		}
+	}
+}
+
+// TestCheckOTP - OTP ถูก/ผิด/หมดอายุ และใช้ไม่ได้เมื่อผิดครบ MaxOTPAttempts
+func TestCheckOTP(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	otp := "123456"
+	valid := now.Add(5 * time.Minute)
+	expired := now.Add(-time.Second)
+
+	tests := []struct {
+		name           string
+		stored         *string
+		expiresAt      *time.Time
+		failures       int
+		given          string
+		wantOK         bool
+		wantInvalidate bool
+	}{
+		{"ถูกต้อง", &otp, &valid, 0, "123456", true, false},
+		{"ผิด", &otp, &valid, 0, "000000", false, false},
+		{"ผิดครั้งสุดท้าย", &otp, &valid, MaxOTPAttempts - 1, "000000", false, true},
+		{"ผิดครบแล้วใส่ถูกก็ไม่ผ่าน", &otp, &valid, MaxOTPAttempts, "123456", false, true},
+		{"หมดอายุ", &otp, &expired, 0, "123456", false, true},
+		{"ยังไม่ได้ขอ OTP", nil, nil, 0, "123456", false, false},
+	}
+	for _, tc := range tests {
+		ok, invalidate := CheckOTP(tc.stored, tc.expiresAt, tc.failures, tc.given, now)
+		if ok != tc.wantOK || invalidate != tc.wantInvalidate {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", tc.name, ok, invalidate, tc.wantOK, tc.wantInvalidate)
+		}
+	}
+}
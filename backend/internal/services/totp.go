@@ -0,0 +1,149 @@
+// Package services - TOTP (RFC 6238) สำหรับ 2FA และ Recovery Code แบบใช้ครั้งเดียว
+// ใช้กับแอป Authenticator ทั่วไป (Google Authenticator, Authy ฯลฯ): SHA-1, 6 หลัก, รอบละ 30 วินาที
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ค่ามาตรฐานของ TOTP
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	TOTPSkew   = 1 // ยอมรับรหัสของรอบก่อน/หลังได้กี่รอบ (นาฬิกามือถือคลาดเคลื่อน)
+
+	RecoveryCodeCount = 10
+)
+
+// totpEncoding - Base32 ไม่มี Padding (รูปแบบที่แอป Authenticator ใช้)
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret - Secret แบบสุ่ม 160 bit (Base32)
+func NewTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPStep - เลขรอบของเวลา t (นับจาก Unix Epoch รอบละ TOTPPeriod)
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCodeAt - รหัส TOTP ของรอบ step
+func TOTPCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic Truncation (RFC 4226 ข้อ 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// VerifyTOTP - ตรวจรหัสภายในช่วง ±TOTPSkew รอบ คืนเลขรอบที่ตรง
+// lastStep = รอบล่าสุดที่เคยใช้ไปแล้ว รหัสของรอบนั้นหรือก่อนหน้าใช้ซ้ำไม่ได้ (กัน Replay)
+func VerifyTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := TOTPCodeAt(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI - otpauth:// URI สำหรับทำ QR Code ให้แอป Authenticator สแกน
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// ============================================
+// Recovery Codes
+// ============================================
+
+// recoveryAlphabet - ตัดตัวที่สับสนง่ายออก (0/o, 1/l/i)
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// NewRecoveryCodes - Recovery Code แบบสุ่ม n ชุด รูปแบบ xxxxx-xxxxx (แสดงให้ผู้ใช้ครั้งเดียว เก็บแค่ Hash)
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, v := range buf {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			// 256 % 31 ทำให้บางตัวออกบ่อยกว่าเล็กน้อย ยังเหลือความสุ่ม ~49 bit ต่อรหัส
+			b.WriteByte(recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode - Hash ของ Recovery Code (ไม่สนตัวพิมพ์/ขีด/ช่องว่างที่ผู้ใช้พิมพ์มา)
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsTOTPCode - รหัสที่ผู้ใช้ส่งมาเป็นรหัส TOTP (ตัวเลขล้วน) หรือ Recovery Code
+func IsTOTPCode(code string) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret - Secret ของ Test Vector ใน RFC 6238 ("12345678901234567890" เป็น Base32)
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestTOTPCodeAt - ตรง Test Vector ของ RFC 6238 (SHA-1, ตัด 6 หลักท้าย)
+func TestTOTPCodeAt(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range tests {
+		got, err := TOTPCodeAt(rfc6238Secret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCodeAt(%d): %v", tc.unix, err)
+		}
+		if got != tc.want {
+			t.Errorf("TOTPCodeAt(%d) = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+// TestVerifyTOTP - ยอมรับรอบข้างเคียง ปฏิเสธรหัสเก่าเกิน และรหัสที่ใช้ไปแล้ว
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := TOTPStep(now)
+	prev, _ := TOTPCodeAt(rfc6238Secret, step-1)
+	tooOld, _ := TOTPCodeAt(rfc6238Secret, step-2)
+
+	if got, ok := VerifyTOTP(rfc6238Secret, "081 804", now, 0); !ok || got != step {
+		t.Errorf("current code: got (%d, %v), want (%d, true)", got, ok, step)
+	}
+	if got, ok := VerifyTOTP(rfc6238Secret, prev, now, 0); !ok || got != step-1 {
+		t.Errorf("previous step code: got (%d, %v)", got, ok)
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, tooOld, now, 0); ok {
+		t.Error("code two steps old accepted")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "081804", now, step); ok {
+		t.Error("replayed code accepted")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "12345", now, 0); ok {
+		t.Error("short code accepted")
+	}
+}
+
+// TestTOTPURI - URI ที่แอป Authenticator อ่านได้
+func TestTOTPURI(t *testing.T) {
+	got := TOTPURI("MMRRDiKub", "bob", "ABC")
+	want := "otpauth://totp/MMRRDiKub:bob?algorithm=SHA1&digits=6&issuer=MMRRDiKub&period=30&secret=ABC"
+	if got != want {
+		t.Errorf("TOTPURI = %s, want %s", got, want)
+	}
+}
+
+// TestRecoveryCodes - รูปแบบ ไม่ซ้ำกัน และ Hash ไม่สนตัวพิมพ์/ขีด
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("bad format %q", code)
+		}
+		if IsTOTPCode(code) {
+			t.Errorf("recovery code %q looks like a TOTP code", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	code := codes[0]
+	if HashRecoveryCode(code) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" ") {
+		t.Error("hash depends on case/dash")
+	}
+}
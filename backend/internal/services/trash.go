@@ -0,0 +1,72 @@
+// Package services - Trash Bin (ถังขยะ)
+// ระยะเวลาเก็บ Trade ที่ลบแล้ว, เวลาที่จะถูกลบถาวร และเงื่อนไขของการกู้คืน/ลบถาวร
+package services
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ค่า Default ของถังขยะ
+const (
+	DefaultTrashRetentionDays = 30
+	DefaultTrashPurgeInterval = time.Hour
+)
+
+// ParseTrashRetention - ระยะเวลาเก็บในถังขยะจากจำนวนวัน (ค่าว่าง = Default 30 วัน, 0 = ไม่ลบอัตโนมัติ)
+// ok = false เมื่อค่าไม่ถูกต้อง (ติดลบ/ไม่ใช่ตัวเลข) และใช้ค่า Default แทน
+func ParseTrashRetention(raw string) (retention time.Duration, ok bool) {
+	days := DefaultTrashRetentionDays
+	ok = true
+	if raw = strings.TrimSpace(raw); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			ok = false
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour, ok
+}
+
+// ParseDurationSetting - ระยะเวลาจากค่าตั้งค่า (เช่น 30m, 720h) ค่าว่าง = fallback
+// ok = false เมื่อค่าไม่ถูกต้องหรือไม่เป็นบวก และใช้ fallback แทน
+func ParseDurationSetting(raw string, fallback time.Duration) (value time.Duration, ok bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return fallback, false
+	}
+	return value, true
+}
+
+// TrashPurgeCutoff - Trade ที่ถูกลบก่อนเวลานี้ต้องลบถาวร (ok = false เมื่อปิดการลบอัตโนมัติ)
+func TrashPurgeCutoff(now time.Time, retention time.Duration) (cutoff time.Time, ok bool) {
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	return now.Add(-retention), true
+}
+
+// TrashPurgeAt - เวลาที่ Trade ในถังขยะจะถูกลบถาวร (nil = ไม่ลบอัตโนมัติ)
+func TrashPurgeAt(deletedAt time.Time, retention time.Duration) *time.Time {
+	if retention <= 0 {
+		return nil
+	}
+	purgeAt := deletedAt.Add(retention)
+	return &purgeAt
+}
+
+// InTrash - Trade อยู่ในถังขยะหรือไม่ (กู้คืน/ลบถาวรได้เฉพาะ Trade ที่ลบแล้วเท่านั้น)
+func InTrash(deletedAt *time.Time) bool {
+	return deletedAt != nil && !deletedAt.IsZero()
+}
+
+// TrashExpired - Trade ในถังขยะเกินระยะเวลาเก็บแล้วหรือไม่ (ถูกลบก่อน cutoff)
+func TrashExpired(deletedAt *time.Time, cutoff time.Time) bool {
+	return InTrash(deletedAt) && deletedAt.Before(cutoff)
+}
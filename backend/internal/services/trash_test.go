@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTrashRetention - Default, 0 = ปิด และค่าที่ไม่ถูกต้อง
+func TestParseTrashRetention(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name   string
+		raw    string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"ไม่ตั้ง = 30 วัน", "", 30 * day, true},
+		{"ตั้ง 7 วัน", " 7 ", 7 * day, true},
+		{"0 = ไม่ลบอัตโนมัติ", "0", 0, true},
+		{"ติดลบ = Default", "-1", 30 * day, false},
+		{"ไม่ใช่ตัวเลข = Default", "7d", 30 * day, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseTrashRetention(tc.raw)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("ParseTrashRetention(%q) = (%s, %v), want (%s, %v)", tc.raw, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+// TestParseDurationSetting - ค่าว่างใช้ fallback, ค่าผิด/ไม่เป็นบวกใช้ fallback และแจ้ง ok = false
+func TestParseDurationSetting(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"ไม่ตั้ง", "", time.Hour, true},
+		{"30 นาที", "30m", 30 * time.Minute, true},
+		{"มีช่องว่าง", " 6h ", 6 * time.Hour, true},
+		{"ไม่มีหน่วย", "30", time.Hour, false},
+		{"ศูนย์", "0s", time.Hour, false},
+		{"ติดลบ", "-5m", time.Hour, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseDurationSetting(tc.raw, time.Hour)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("ParseDurationSetting(%q) = (%s, %v), want (%s, %v)", tc.raw, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+// TestTrashPurgeCutoff - Trade ที่ลบก่อน cutoff เท่านั้นที่ถูกลบถาวร
+func TestTrashPurgeCutoff(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+
+	cutoff, ok := TrashPurgeCutoff(now, retention)
+	if !ok || !cutoff.Equal(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("cutoff = (%s, %v)", cutoff, ok)
+	}
+	if _, ok := TrashPurgeCutoff(now, 0); ok {
+		t.Error("retention 0 ต้องปิดการลบอัตโนมัติ")
+	}
+
+	expired := cutoff.Add(-time.Second)
+	fresh := cutoff.Add(time.Second)
+	if !TrashExpired(&expired, cutoff) {
+		t.Error("ลบก่อน cutoff ต้องหมดอายุ")
+	}
+	if TrashExpired(&fresh, cutoff) || TrashExpired(&cutoff, cutoff) {
+		t.Error("ลบตั้งแต่ cutoff เป็นต้นไปยังไม่หมดอายุ")
+	}
+	// ผู้ใช้กู้คืนไประหว่างรอ Purge แล้ว
+	if TrashExpired(nil, cutoff) {
+		t.Error("Trade ที่ไม่ได้อยู่ในถังขยะต้องไม่ถูกลบถาวร")
+	}
+}
+
+// TestTrashPurgeAt - เวลาที่จะลบถาวร = เวลาที่ลบ + ระยะเวลาเก็บ
+func TestTrashPurgeAt(t *testing.T) {
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if got := TrashPurgeAt(deletedAt, 7*24*time.Hour); got == nil || !got.Equal(time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("purge_at = %v", got)
+	}
+	if got := TrashPurgeAt(deletedAt, 0); got != nil {
+		t.Errorf("retention 0: purge_at = %v, want nil", got)
+	}
+}
+
+// TestInTrash - กู้คืน/ลบถาวรได้เฉพาะ Trade ที่ลบแล้ว
+func TestInTrash(t *testing.T) {
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if !InTrash(&deletedAt) {
+		t.Error("Trade ที่ลบแล้วต้องอยู่ในถังขยะ")
+	}
+	if InTrash(nil) || InTrash(&time.Time{}) {
+		t.Error("Trade ที่ยังไม่ลบต้องกู้คืน/ลบถาวรไม่ได้")
+	}
+}
@@ -0,0 +1,30 @@
+// Package money - ตั้งค่าตัวเลขเงิน (decimal.Decimal) ที่ใช้ทั้งระบบ
+// ทำไมไม่ใช้ float64? เพราะ 0.1 + 0.2 != 0.3 ผลรวม PnL จะเพี้ยนสะสม และเหรียญราคาเล็ก (SHIB/PEPE) ทศนิยมหาย
+// decimal.Decimal อ่าน/เขียน DB ได้ตรงๆ (numeric) และ JSON รับได้ทั้งตัวเลขและ String
+package money
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ConfigureJSON - ตั้งค่ารูปแบบ JSON ตอนส่งออกจาก ENV "MONEY_JSON_FORMAT"
+//   - "number" (Default): 1234.5     ← Frontend เดิมใช้ได้เลย
+//   - "string":           "1234.5"   ← ไม่เสียความแม่นยำใน JavaScript (Number มีแค่ ~15 หลัก)
+//
+// ตอนรับเข้ารองรับทั้งสองแบบเสมอ ไม่ว่าจะตั้งค่าไว้แบบไหน
+func ConfigureJSON() {
+	format := strings.ToLower(strings.TrimSpace(os.Getenv("MONEY_JSON_FORMAT")))
+	switch format {
+	case "string":
+		decimal.MarshalJSONWithoutQuotes = false
+	case "", "number":
+		decimal.MarshalJSONWithoutQuotes = true
+	default:
+		log.Printf("⚠️ MONEY_JSON_FORMAT=%q ไม่รู้จัก ใช้ค่า Default (number)", format)
+		decimal.MarshalJSONWithoutQuotes = true
+	}
+}
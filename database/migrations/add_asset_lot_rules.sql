@@ -0,0 +1,17 @@
+-- ============================================
+-- Migration: เพิ่มกฎ Lot Step / Min Notional ให้ตาราง assets
+-- ใช้กับ Position Size Calculator (ปัดจำนวนเหรียญแบบที่ Exchange ทำจริง)
+-- ============================================
+
+ALTER TABLE assets
+ADD COLUMN IF NOT EXISTS quantity_step DECIMAL(24,12) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS min_notional DECIMAL(18,4) DEFAULT 0;
+
+-- ค่าตาม Binance USDⓈ-M Futures
+UPDATE assets SET tick_size = 0.1, quantity_step = 0.001, min_notional = 100 WHERE symbol = 'BTCUSDT';
+UPDATE assets SET quantity_step = 0.001, min_notional = 20 WHERE symbol = 'ETHUSDT';
+UPDATE assets SET quantity_step = 1, min_notional = 5 WHERE symbol = 'SOLUSDT';
+UPDATE assets SET quantity_step = 0.01 WHERE symbol = 'XAUUSD';
+
+-- Verify
+SELECT symbol, tick_size, quantity_step, min_notional FROM assets ORDER BY symbol;
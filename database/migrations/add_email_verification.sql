@@ -0,0 +1,25 @@
+-- ============================================
+-- Migration: ยืนยันอีเมลตอนสมัครสมาชิก
+-- is_verified มีใน schema.sql อยู่แล้ว (เพิ่มให้ฐานข้อมูลที่สร้างด้วย GORM)
+-- verify_otp: รหัส 6 หลักอายุ 30 นาที ใส่ผิดครบ 5 ครั้งต้องขอใหม่
+-- ฟีเจอร์ที่ต้องยืนยันก่อนตั้งด้วย ENV EMAIL_VERIFICATION_REQUIRED_FOR
+-- ============================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_verified BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verify_otp VARCHAR(6);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verify_otp_expires_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verify_otp_attempts INT DEFAULT 0;
+
+-- บัญชีที่สมัครก่อนมีการยืนยันอีเมลถือว่ายืนยันแล้ว (ไม่งั้นโดนกัน AI/Import/Backup ทันทีที่ Deploy)
+-- ทำครั้งเดียวตอนเพิ่มคอลัมน์ verify_otp_sent_at รันซ้ำแล้วไม่กระทบบัญชีใหม่ที่ยังไม่ยืนยัน
+-- (เหมือน handlers.MigrateAuthModels)
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'users' AND column_name = 'verify_otp_sent_at'
+    ) THEN
+        ALTER TABLE users ADD COLUMN verify_otp_sent_at TIMESTAMP;
+        UPDATE users SET is_verified = TRUE WHERE is_verified IS NOT TRUE;
+    END IF;
+END $$;
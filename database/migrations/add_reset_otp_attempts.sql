@@ -0,0 +1,6 @@
+-- ============================================
+-- Migration: จำนวนครั้งที่ใส่ OTP ลืมรหัสผ่านผิด
+-- ผิดครบ 5 ครั้ง (services.MaxOTPAttempts) OTP ถูกลบทิ้ง ต้องขอใหม่ (นับใหม่ทุกครั้งที่ขอ OTP)
+-- ============================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reset_otp_attempts INT DEFAULT 0;
@@ -0,0 +1,21 @@
+-- ============================================
+-- Migration: ทยอยเข้าไม้ (Entry Fills / DCA)
+-- เก็บการเข้าไม้แต่ละครั้ง ราคาเฉลี่ย/จำนวนใน trades จะคำนวณใหม่จากตารางนี้
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS trade_entries (
+    id SERIAL PRIMARY KEY,
+    trade_id INT NOT NULL REFERENCES trades(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    price DECIMAL(24,8) NOT NULL,           -- ราคาที่ได้จริง
+    quantity DECIMAL(24,12) NOT NULL,       -- จำนวนเหรียญ
+    fee DECIMAL(18,4),                      -- Fee ของ Fill นี้
+    filled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_entries_trade_id ON trade_entries(trade_id);
+CREATE INDEX IF NOT EXISTS idx_trade_entries_user_id ON trade_entries(user_id);
+
+-- Verify
+SELECT trade_id, COUNT(*) AS fills, SUM(quantity) AS total_qty FROM trade_entries GROUP BY trade_id;
@@ -0,0 +1,49 @@
+-- ============================================
+-- Migration: ทยอยปิดไม้ (Partial Exits)
+-- ตารางลูก trade_exits + คอลัมน์สรุปใน trades
+-- ============================================
+
+ALTER TABLE trades
+ADD COLUMN IF NOT EXISTS remaining_quantity DECIMAL(24,12),
+ADD COLUMN IF NOT EXISTS realized_pnl DECIMAL(18,4) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS avg_exit_price DECIMAL(24,8);
+
+-- เพิ่มคอลัมน์ก่อนแล้วค่อยตั้ง Default แถวเดิมจึงยังเป็น NULL ให้ Backfill ด้านล่างแยกได้
+ALTER TABLE trades
+ALTER COLUMN remaining_quantity SET DEFAULT 0,
+ALTER COLUMN avg_exit_price SET DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS trade_exits (
+    id SERIAL PRIMARY KEY,
+    trade_id INT NOT NULL REFERENCES trades(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    type VARCHAR(10) DEFAULT 'MANUAL',      -- TP, SL, MANUAL
+    status VARCHAR(20) DEFAULT 'PLANNED',   -- PLANNED, EXECUTED, CANCELLED
+    price DECIMAL(24,8),                    -- ราคาที่วางแผนไว้
+    percent DECIMAL(10,4),                  -- % ของไม้
+    quantity DECIMAL(24,12) NOT NULL,       -- จำนวนเหรียญ
+    executed_price DECIMAL(24,8),
+    fee DECIMAL(18,4),
+    gross_pnl DECIMAL(18,4),
+    realized_pnl DECIMAL(18,4),
+    executed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_exits_trade_id ON trade_exits(trade_id);
+CREATE INDEX IF NOT EXISTS idx_trade_exits_user_id ON trade_exits(user_id);
+
+-- ไม้เดิมยังไม่เคยขายผ่าน /exits (ต้องไม่เหลือ NULL: Backend อ่าน NULL เป็นตัวเลขไม่ได้)
+-- ปิด/ยกเลิกแล้ว = ไม่เหลือเหรียญ, ไม้อื่น = ยังถือทั้งไม้ (เหมือน handlers.backfillExitColumns)
+UPDATE trades
+SET remaining_quantity = CASE WHEN status IN ('CLOSED', 'CANCELLED', 'WIN', 'LOSS', 'BREAK_EVEN') THEN 0
+        ELSE COALESCE(NULLIF(quantity, 0), position_size / NULLIF(entry_price, 0), 0) END
+WHERE remaining_quantity IS NULL;
+
+UPDATE trades SET avg_exit_price = COALESCE(exit_price, 0) WHERE avg_exit_price IS NULL;
+UPDATE trades SET realized_pnl = 0 WHERE realized_pnl IS NULL;
+
+-- Verify
+SELECT id, status, quantity, remaining_quantity, realized_pnl FROM trades ORDER BY id DESC LIMIT 10;
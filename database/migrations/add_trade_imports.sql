@@ -0,0 +1,32 @@
+-- ============================================
+-- Migration: Import Trades จากไฟล์ของ Exchange
+-- trade_imports = ประวัติการนำเข้าแต่ละครั้ง
+-- imported_fills = Trade ID ของ Exchange ที่นำเข้าแล้ว ใช้กันนำเข้าซ้ำ (ต่อ User + แหล่งข้อมูล)
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS trade_imports (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    source VARCHAR(30) NOT NULL,            -- binance_futures, bybit
+    filename VARCHAR(255),
+    fills INT DEFAULT 0,                    -- จำนวน Fill ในไฟล์
+    trades_created INT DEFAULT 0,
+    skipped INT DEFAULT 0,                  -- ไม้ที่ข้ามเพราะเคยนำเข้าแล้ว
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_imports_user_id ON trade_imports(user_id);
+
+CREATE TABLE IF NOT EXISTS imported_fills (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    source VARCHAR(30) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,      -- Trade ID ของ Exchange (ส่วนที่แบ่งจาก Fill ที่กลับฝั่งต่อท้าย "#open")
+    trade_id INT NOT NULL,                  -- ลบออกเมื่อ Trade ถูกลบถาวร
+    import_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_imported_fills_external ON imported_fills(user_id, source, external_id);
+CREATE INDEX IF NOT EXISTS idx_imported_fills_trade_id ON imported_fills(trade_id);
+CREATE INDEX IF NOT EXISTS idx_imported_fills_import_id ON imported_fills(import_id);
@@ -0,0 +1,42 @@
+-- ============================================
+-- Migration: Trade Lifecycle
+-- status: PLANNED → OPEN → PARTIALLY_CLOSED → CLOSED (+ CANCELLED)
+-- ผลของไม้ที่ปิดแล้ว (WIN/LOSS/BREAK_EVEN) ย้ายไปอยู่ใน outcome
+-- ============================================
+
+ALTER TABLE trades
+ADD COLUMN IF NOT EXISTS outcome VARCHAR(20),
+ADD COLUMN IF NOT EXISTS planned_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS partially_closed_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+
+-- ย้ายข้อมูลเก่า (status = WIN/LOSS/BREAK_EVEN → status = CLOSED, outcome = ค่าเดิม)
+-- ทำใน handlers.MigrateTradeModels ที่เดียว (รันทุกครั้งที่ Backend เริ่ม) ไฟล์นี้ไม่ต้องทำซ้ำ
+
+CREATE INDEX IF NOT EXISTS idx_trades_user_outcome ON trades(user_id, outcome);
+
+-- View: User Stats (นับผลจาก outcome แทน status)
+CREATE OR REPLACE VIEW v_user_stats AS
+SELECT 
+    user_id,
+    COUNT(*) AS total_trades,
+    COUNT(*) FILTER (WHERE outcome = 'WIN') AS win_count,
+    COUNT(*) FILTER (WHERE outcome = 'LOSS') AS loss_count,
+    COUNT(*) FILTER (WHERE status IN ('OPEN', 'PARTIALLY_CLOSED')) AS open_count,
+    COALESCE(SUM(pnl), 0) AS total_pnl,
+    COALESCE(AVG(pnl), 0) AS avg_pnl,
+    CASE 
+        WHEN COUNT(*) FILTER (WHERE outcome IN ('WIN', 'LOSS')) > 0 
+        THEN ROUND(
+            COUNT(*) FILTER (WHERE outcome = 'WIN')::DECIMAL / 
+            COUNT(*) FILTER (WHERE outcome IN ('WIN', 'LOSS')) * 100, 
+            2
+        )
+        ELSE 0 
+    END AS win_rate
+FROM trades
+WHERE deleted_at IS NULL
+GROUP BY user_id;
+
+-- Verify (หลัง Backend เริ่มแล้ว ไม่ควรเหลือ status = WIN/LOSS/BREAK_EVEN)
+SELECT status, outcome, COUNT(*) FROM trades GROUP BY status, outcome ORDER BY status;
@@ -0,0 +1,12 @@
+-- ============================================
+-- Migration: R-Multiple ของไม้ที่ปิดแล้ว
+-- r_multiple = Net PnL / initial_risk (Risk ตอนเปิดไม้: max_loss หรือ |entry − SL| × quantity)
+-- initial_risk บันทึกครั้งเดียวตอนเปิดไม้ ไม่เปลี่ยนตาม max_loss ที่คำนวณใหม่ตอนเติมไม้
+-- Backend คำนวณใหม่ทุกครั้งที่ปิด/แก้ไม้ ไม้ที่ยังไม่ปิดหรือไม่รู้ Risk เป็น NULL
+-- ============================================
+
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS r_multiple DECIMAL(10,4);
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS initial_risk DECIMAL(18,4);
+
+-- ค่าย้อนหลังของ initial_risk และ r_multiple คำนวณใน handlers.MigrateTradeModels ที่เดียว
+-- (ใช้สูตรเดียวกับ services.InitialRisk ไม่เขียนซ้ำใน SQL)
@@ -0,0 +1,37 @@
+-- ============================================
+-- Migration: ประวัติการแก้ไข Trade (Audit Trail)
+-- ทุกการสร้าง/แก้ไข/ลบ/ย้อนกลับ เก็บเป็น Revision พร้อมฟิลด์ที่เปลี่ยน ผู้แก้ และ IP
+-- ตารางนี้ Append-only: Trigger ด้านล่างกัน UPDATE/DELETE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS trade_revisions (
+    id SERIAL PRIMARY KEY,
+    trade_id INT NOT NULL,                  -- ไม่ผูก FK เพื่อให้ประวัติอยู่ต่อแม้ Trade ถูกลบถาวร
+    revision INT NOT NULL,                  -- ลำดับของ Trade นี้ (1, 2, 3, ...)
+    user_id INT NOT NULL,                   -- เจ้าของ Trade
+    actor_user_id INT NOT NULL,             -- คนที่แก้
+    action VARCHAR(20) NOT NULL,            -- CREATE, UPDATE, DELETE, REVERT
+    changes JSONB,                          -- [{field, old, new}]
+    snapshot JSONB,                         -- Trade ทั้งก้อนหลังแก้
+    reverted_from INT,                      -- ย้อนกลับมาจาก Revision ไหน
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trade_revisions_trade_revision ON trade_revisions(trade_id, revision);
+CREATE INDEX IF NOT EXISTS idx_trade_revisions_user_id ON trade_revisions(user_id);
+
+-- กันการแก้/ลบประวัติจากฝั่ง Database
+CREATE OR REPLACE FUNCTION prevent_trade_revision_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'trade_revisions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trade_revisions_append_only ON trade_revisions;
+CREATE TRIGGER trade_revisions_append_only
+    BEFORE UPDATE OR DELETE ON trade_revisions
+    FOR EACH ROW EXECUTE FUNCTION prevent_trade_revision_change();
+
+-- Verify
+SELECT trade_id, COUNT(*) AS revisions, MAX(created_at) AS last_change FROM trade_revisions GROUP BY trade_id;
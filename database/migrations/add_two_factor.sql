@@ -0,0 +1,21 @@
+-- ============================================
+-- Migration: Two-Factor Authentication (TOTP)
+-- totp_pending_secret: Secret ที่รอยืนยันรหัสแรกตอนเปิด 2FA
+-- totp_last_step: รอบ TOTP ล่าสุดที่ใช้ไปแล้ว (รหัสเดิมใช้ซ้ำไม่ได้)
+-- user_recovery_codes: เก็บเฉพาะ SHA-256 ของ Recovery Code ใช้ได้ครั้งเดียว (used_at)
+-- ============================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_pending_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
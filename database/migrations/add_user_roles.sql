@@ -0,0 +1,15 @@
+-- ============================================
+-- Migration: Role ของผู้ใช้และสถานะบัญชี (Admin API)
+-- role: user / admin (ตั้ง Admin คนแรกด้วย ENV ADMIN_USERNAMES หรือ UPDATE ตรงๆ)
+-- is_active = false: ถูกระงับ Login/Refresh ไม่ได้
+-- must_reset_password = true: Admin สั่งให้ตั้งรหัสผ่านใหม่ผ่าน Forgot Password ก่อน Login
+-- ============================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active BOOLEAN DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_reset_password BOOLEAN DEFAULT FALSE;
+
+UPDATE users SET role = 'user' WHERE role IS NULL;
+UPDATE users SET is_active = TRUE WHERE is_active IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
@@ -0,0 +1,23 @@
+-- ============================================
+-- Migration: Session ของการ Login (Refresh Token แบบหมุนเวียน)
+-- Access Token (JWT) อายุสั้นผูกกับ Session ผ่าน claim "sid"
+-- เก็บเฉพาะ SHA-256 ของ Refresh Token; previous_token_hash ใช้ตรวจจับ Token ที่ถูกขโมยไปใช้ซ้ำ
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    refresh_token_hash VARCHAR(64) NOT NULL,
+    previous_token_hash VARCHAR(64),
+    user_agent VARCHAR(255),
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,              -- ต่ออายุทุกครั้งที่ Refresh (REFRESH_TOKEN_TTL)
+    rotated_at TIMESTAMP,                       -- เวลาที่ออก Refresh Token ปัจจุบัน
+    revoked_at TIMESTAMP                        -- Logout / ยกเลิกอุปกรณ์ / เปลี่ยนรหัสผ่าน
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_refresh_token_hash ON user_sessions(refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous_token_hash ON user_sessions(previous_token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
@@ -0,0 +1,6 @@
+-- ============================================
+-- Migration: Timezone ของผู้ใช้
+-- ใช้แบ่งวันของ Calendar/Analytics และตัวกรอง date_from/date_to (ชื่อแบบ IANA เช่น Asia/Bangkok)
+-- ============================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) DEFAULT 'UTC';
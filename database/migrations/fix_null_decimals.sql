@@ -0,0 +1,38 @@
+-- ============================================
+-- Migration: เติมค่าให้คอลัมน์ตัวเลขที่เป็น NULL
+-- Backend อ่านคอลัมน์เหล่านี้เป็น decimal.Decimal ซึ่งอ่าน NULL ไม่ได้
+-- (แถวที่สร้างนอก Backend เช่น SQL ตรงๆ หรือระบบเดิม)
+-- ใช้ Default ของคอลัมน์ถ้ามี ไม่มีใช้ 0 (Backend ทำแบบเดียวกันตอน Migrate)
+-- ============================================
+
+UPDATE trades SET
+    exit_price = COALESCE(exit_price, 0),
+    stop_loss = COALESCE(stop_loss, 0),
+    take_profit = COALESCE(take_profit, 0),
+    quantity = COALESCE(quantity, 0),
+    risk_percent = COALESCE(risk_percent, 0),
+    max_win = COALESCE(max_win, 0),
+    max_loss = COALESCE(max_loss, 0),
+    risk_reward_ratio = COALESCE(risk_reward_ratio, 0),
+    fee = COALESCE(fee, 0),
+    pnl = COALESCE(pnl, 0),
+    pnl_percent = COALESCE(pnl_percent, 0)
+WHERE exit_price IS NULL OR stop_loss IS NULL OR take_profit IS NULL OR quantity IS NULL
+   OR risk_percent IS NULL OR max_win IS NULL OR max_loss IS NULL OR risk_reward_ratio IS NULL
+   OR fee IS NULL OR pnl IS NULL OR pnl_percent IS NULL;
+
+UPDATE trade_entries SET fee = COALESCE(fee, 0) WHERE fee IS NULL;
+
+UPDATE trade_exits SET
+    executed_price = COALESCE(executed_price, 0),
+    fee = COALESCE(fee, 0),
+    gross_pnl = COALESCE(gross_pnl, 0),
+    realized_pnl = COALESCE(realized_pnl, 0)
+WHERE executed_price IS NULL OR fee IS NULL OR gross_pnl IS NULL OR realized_pnl IS NULL;
+
+UPDATE users SET portfolio_balance = COALESCE(portfolio_balance, 1000) WHERE portfolio_balance IS NULL;
+
+UPDATE assets SET
+    quantity_step = COALESCE(quantity_step, 0),
+    min_notional = COALESCE(min_notional, 0)
+WHERE quantity_step IS NULL OR min_notional IS NULL;
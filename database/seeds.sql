@@ -13,14 +13,14 @@ ON CONFLICT (name) DO NOTHING;
 
 -- 2. Seed Assets
 -- Crypto
-INSERT INTO assets (symbol, type, tick_size) VALUES
-('BTCUSDT', 'Crypto', 0.0100000000),
-('ETHUSDT', 'Crypto', 0.0100000000),
-('SOLUSDT', 'Crypto', 0.0100000000);
+INSERT INTO assets (symbol, type, tick_size, quantity_step, min_notional) VALUES
+('BTCUSDT', 'Crypto', 0.1000000000, 0.001, 100),
+('ETHUSDT', 'Crypto', 0.0100000000, 0.001, 20),
+('SOLUSDT', 'Crypto', 0.0100000000, 1, 5);
 
 -- Forex (CFD typically)
-INSERT INTO assets (symbol, type, tick_size) VALUES
-('XAUUSD', 'Forex', 0.0100000000); -- Gold
+INSERT INTO assets (symbol, type, tick_size, quantity_step, min_notional) VALUES
+('XAUUSD', 'Forex', 0.0100000000, 0.01, 0); -- Gold (Lot step 0.01 oz)
 
 -- Example User (Mock)
 -- PasswordHash: 'mock_hash' (In prod, use real bcrypt hash)
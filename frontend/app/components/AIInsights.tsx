@@ -4,7 +4,8 @@ import { useState, useEffect } from 'react';
 import { motion } from 'framer-motion';
 import { cn } from '../lib/cn';
 import { AlertTriangle, ShieldAlert, Target, Lightbulb, Loader2 } from 'lucide-react';
-import { aiAPI } from '../utils/api';
+import { aiAPI, isEmailNotVerified } from '../utils/api';
+import EmailVerificationNotice from './EmailVerificationNotice';
 
 // สร้าง Interface รับข้อมูลจริง
 export interface Insight {
@@ -18,6 +19,7 @@ export interface Insight {
 export default function AIInsights() {
     const [insights, setInsights] = useState<Insight[]>([]);
     const [isLoading, setIsLoading] = useState(true);
+    const [needsVerification, setNeedsVerification] = useState(false); // 403 email_not_verified
 
     // 🚀 ยิง API ไปให้ Golang + Gemini วิเคราะห์เมื่อเปิดหน้าเว็บ
     useEffect(() => {
@@ -29,6 +31,7 @@ export default function AIInsights() {
                 }
             } catch (error) {
                 console.error("Failed to load insights", error);
+                setNeedsVerification(isEmailNotVerified(error));
             } finally {
                 setIsLoading(false);
             }
@@ -46,6 +49,10 @@ export default function AIInsights() {
         );
     }
 
+    if (needsVerification) {
+        return <EmailVerificationNotice message="กรุณายืนยันอีเมลก่อนดู AI Behavior Insights" />;
+    }
+
     if (insights.length === 0) {
         return null; // ถ้าไม่มีข้อมูลเทรดเลย ก็ไม่ต้องโชว์
     }
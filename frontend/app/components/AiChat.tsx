@@ -1,7 +1,8 @@
 'use client';
 
 import { useState, useRef, useEffect } from 'react';
-import { aiAPI, AIChatMessage } from '../utils/api';
+import { aiAPI, AIChatMessage, isEmailNotVerified } from '../utils/api';
+import EmailVerificationNotice from './EmailVerificationNotice';
 import { useLanguage } from '../context/LanguageContext';
 import { Brain, Send, Loader2, Info } from 'lucide-react';
 import { motion, AnimatePresence } from 'framer-motion';
@@ -19,6 +20,7 @@ export default function AiChat() {
     ]);
     const [input, setInput] = useState('');
     const [isLoading, setIsLoading] = useState(false);
+    const [needsVerification, setNeedsVerification] = useState(false); // 403 email_not_verified
 
     const messagesEndRef = useRef<HTMLDivElement>(null);
 
@@ -64,6 +66,9 @@ export default function AiChat() {
             let errMsg = '⚠️ ไม่สามารถเชื่อมต่อ AI ได้ (เซิร์ฟเวอร์อาจจะกำลังประมวลผลหนัก)';
             if (error.response?.status === 401) {
                 errMsg = '🔒 กรุณา Login ก่อนแชทครับพี่';
+            } else if (isEmailNotVerified(error)) {
+                errMsg = '📧 กรุณายืนยันอีเมลก่อนใช้งาน AI ครับ (กดปุ่ม "ยืนยันอีเมล" ด้านล่าง)';
+                setNeedsVerification(true);
             }
             setMessages(prev => [...prev, { role: 'assistant', content: errMsg }]);
         } finally {
@@ -156,6 +161,11 @@ export default function AiChat() {
 
             {/* ✏️ Input Area - ส่วนพิมพ์ข้อความถาม */}
             <div className="p-3 sm:p-5 bg-[#0a0a0a] border-t border-gray-800 shrink-0 z-10">
+                {needsVerification && (
+                    <div className="mb-3">
+                        <EmailVerificationNotice />
+                    </div>
+                )}
                 <form onSubmit={handleSend} className="relative flex flex-col sm:flex-row gap-3">
                     <div className="relative flex-1">
                         {/* ช่องพิมพ์ข้อความ (Textarea) */}
@@ -8,7 +8,7 @@
 import { useState, useMemo, useEffect, useCallback } from 'react';
 import { useRouter } from 'next/navigation';
 import { motion, AnimatePresence } from 'framer-motion';
-import { tradeAPI, aiAPI } from '../utils/api';
+import { tradeAPI, aiAPI, isEmailNotVerified } from '../utils/api';
 import { formatPrice, formatUSD, formatPercent } from '../utils/format';
 import { calculateTradeMetrics, formatRR } from '../utils/tradeCalculations';
 import { cn } from '../lib/cn';
@@ -641,7 +641,9 @@ export default function Calculator() {
             }
         } catch (err: any) {
             console.error('❌ AI error:', err);
-            setAiError('ไม่สามารถเชื่อมต่อ Backend ได้ ลองรีเฟรชหน้าเว็บ');
+            setAiError(isEmailNotVerified(err)
+                ? err.message
+                : 'ไม่สามารถเชื่อมต่อ Backend ได้ ลองรีเฟรชหน้าเว็บ');
         } finally {
             setAiLoading(false);
         }
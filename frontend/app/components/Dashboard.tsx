@@ -49,12 +49,38 @@ interface SortConfig {
     direction: 'ASC' | 'DESC';
 }
 
+// ค่าใน Dropdown กรองสถานะ: ผลลัพธ์ (WIN/LOSS/BREAK_EVEN) ส่งเป็น outcome นอกนั้นส่งเป็น status
+const OUTCOMES = ['WIN', 'LOSS', 'BREAK_EVEN'];
+
+// outcomeFromPnL - ผลของไม้จาก PnL (ใช้แสดงก่อนบันทึก Server คำนวณซ้ำเอง)
+const outcomeFromPnL = (pnl: number) => (pnl > 0 ? 'WIN' : pnl < 0 ? 'LOSS' : 'BREAK_EVEN');
+
+// tradeBadge - ป้ายสถานะของไม้: ปิดแล้วแสดงผล (outcome) ยังไม่ปิดแสดงสถานะ
+const tradeBadge = (trade: Trade) => {
+    if (trade.status === 'CLOSED' && trade.outcome) {
+        return {
+            label: trade.outcome,
+            className: trade.outcome === 'WIN' ? 'bg-green-600/30 text-green-400' :
+                trade.outcome === 'LOSS' ? 'bg-red-600/30 text-red-400' :
+                    'bg-gray-600/30 text-gray-400',
+        };
+    }
+    return {
+        label: trade.status,
+        className: trade.status === 'CANCELLED' || trade.status === 'CLOSED' ? 'bg-gray-600/30 text-gray-400' :
+            trade.status === 'PLANNED' ? 'bg-blue-600/30 text-blue-400' :
+                'bg-yellow-600/30 text-yellow-400',
+    };
+};
+
 interface EditModalData {
     trade: Trade;
     exitPrice: number;
     pnl: number;
     pnlPercent: number;
-    status: string;
+    status: string;  // OPEN, CLOSED, CANCELLED
+    outcome: string; // WIN, LOSS, BREAK_EVEN (เมื่อ status = CLOSED)
+    overridePnl: boolean; // ผู้ใช้แก้ PnL/ผลเอง (ไม่งั้น Server คำนวณจาก Exit Price)
     exitTime: string;
     tpHit: string;
     slHit: string;
@@ -113,7 +139,8 @@ export default function Dashboard() {
 
         try {
             const response = await tradeAPI.getAll({
-                status: statusFilter !== 'all' ? statusFilter : undefined,
+                status: statusFilter !== 'all' && !OUTCOMES.includes(statusFilter) ? statusFilter : undefined,
+                outcome: OUTCOMES.includes(statusFilter) ? statusFilter : undefined,
                 side: sideFilter !== 'all' ? sideFilter : undefined,
                 date_from: dateFrom || undefined,
                 date_to: dateTo || undefined,
@@ -145,6 +172,7 @@ export default function Dashboard() {
                 t.pair.toLowerCase().includes(query) ||
                 t.side.toLowerCase().includes(query) ||
                 t.status.toLowerCase().includes(query) ||
+                (t.outcome || '').toLowerCase().includes(query) ||
                 (t.entry_reason || '').toLowerCase().includes(query) ||
                 (t.notes || '').toLowerCase().includes(query) ||
                 t.entry_price.toString().includes(query) ||
@@ -186,12 +214,18 @@ export default function Dashboard() {
         if (!editModal) return;
         setSaving(true);
         try {
-            await tradeAPI.update(editModal.trade.id, {
-                exit_price: editModal.exitPrice,
+            // PnL/ผลที่แก้เองส่งพร้อม override_pnl ไม่งั้นส่งแค่ Exit Price ให้ Server คำนวณ
+            const manual = editModal.overridePnl ? {
+                override_pnl: true,
                 pnl: editModal.pnl,
                 pnl_percent: editModal.pnlPercent,
+                outcome: editModal.status === 'CLOSED' ? editModal.outcome || undefined : undefined,
+            } : {};
+            await tradeAPI.update(editModal.trade.id, {
+                exit_price: editModal.exitPrice,
+                ...manual,
                 status: editModal.status,
-                notes: `${editModal.notes} | ${editModal.status === 'WIN' ? `Hit: ${editModal.tpHit}` : editModal.status === 'LOSS' ? `Hit: ${editModal.slHit}` : ''}`.trim(),
+                notes: `${editModal.notes} | ${editModal.outcome === 'WIN' ? `Hit: ${editModal.tpHit}` : editModal.outcome === 'LOSS' ? `Hit: ${editModal.slHit}` : ''}`.trim(),
                 exit_time: editModal.exitTime ? new Date(editModal.exitTime).toISOString() : undefined,
             });
             
@@ -221,17 +255,15 @@ export default function Dashboard() {
             }
         }
 
-        let status = 'OPEN';
-        if (pnl > 0) status = 'WIN';
-        else if (pnl < 0) status = 'LOSS';
-        else if (pnl === 0 && newExitPrice > 0) status = 'BREAK_EVEN';
-
+        // ใส่ราคาออก = ปิดไม้ (Server คำนวณ PnL/ผลจริงเองตอนบันทึก)
         setEditModal(prev => prev ? {
             ...prev,
             exitPrice: newExitPrice,
             pnl: pnl - (trade.fee || 0),
             pnlPercent,
-            status,
+            status: newExitPrice > 0 ? 'CLOSED' : prev.status,
+            outcome: newExitPrice > 0 ? outcomeFromPnL(pnl - (trade.fee || 0)) : prev.outcome,
+            overridePnl: false,
         } : null);
     };
 
@@ -252,7 +284,7 @@ export default function Dashboard() {
         try {
             const headers = [
                 'ID', 'Date', 'Pair', 'Side', 'Entry', 'Exit', 'Size',
-                'PnL', 'PnL%', 'Status', 'R:R', 'Score', 'Reason', 'Notes'
+                'PnL', 'PnL%', 'Status', 'Outcome', 'R:R', 'Score', 'Reason', 'Notes'
             ];
 
             const rows = filteredTrades.map(t => [
@@ -266,6 +298,7 @@ export default function Dashboard() {
                 t.pnl?.toFixed(2) || '',
                 t.pnl_percent?.toFixed(2) || '',
                 t.status,
+                t.outcome || '',
                 t.risk_reward_ratio?.toFixed(2) || '',
                 t.setup_score || '',
                 (t.entry_reason || '').replace(/,/g, ';'),
@@ -506,8 +539,10 @@ export default function Dashboard() {
                     >
                         <option value="all">All</option>
                         <option value="OPEN">🟡 Open</option>
+                        <option value="CLOSED">🏁 Closed</option>
                         <option value="WIN">🟢 Win</option>
                         <option value="LOSS">🔴 Loss</option>
+                        <option value="BREAK_EVEN">⚪ Break Even</option>
                     </select>
                     <select
                         value={sideFilter}
@@ -635,14 +670,8 @@ export default function Dashboard() {
                                                 </span>
                                             </td>
                                             <td className="px-3 py-3">
-                                                <span className={cn(
-                                                    'px-2 py-1 rounded text-xs font-bold',
-                                                    trade.status === 'WIN' ? 'bg-green-600/30 text-green-400' :
-                                                        trade.status === 'LOSS' ? 'bg-red-600/30 text-red-400' :
-                                                            trade.status === 'BREAK_EVEN' ? 'bg-gray-600/30 text-gray-400' :
-                                                                'bg-yellow-600/30 text-yellow-400'
-                                                )}>
-                                                    {trade.status}
+                                                <span className={cn('px-2 py-1 rounded text-xs font-bold', tradeBadge(trade).className)}>
+                                                    {tradeBadge(trade).label}
                                                 </span>
                                             </td>
                                             <td className="px-3 py-3 sticky right-0 bg-white dark:bg-[var(--surface)]">
@@ -654,6 +683,8 @@ export default function Dashboard() {
                                                             pnl: trade.pnl || 0,
                                                             pnlPercent: trade.pnl_percent || 0,
                                                             status: trade.status,
+                                                            outcome: trade.outcome || '',
+                                                            overridePnl: false,
                                                             exitTime: trade.exit_time || '',
                                                             tpHit: '', slHit: '',
                                                             notes: trade.notes || '',
@@ -798,7 +829,8 @@ export default function Dashboard() {
                                         onChange={(e) => setEditModal(prev => prev ? {
                                             ...prev,
                                             pnl: parseFloat(e.target.value) || 0,
-                                            status: parseFloat(e.target.value) > 0 ? 'WIN' : parseFloat(e.target.value) < 0 ? 'LOSS' : 'BREAK_EVEN'
+                                            outcome: outcomeFromPnL(parseFloat(e.target.value) || 0),
+                                            overridePnl: true,
                                         } : null)}
                                         className={cn(
                                             'w-full px-3 py-2 rounded-lg bg-white dark:bg-[var(--surface)] border outline-none text-xl font-bold',
@@ -815,11 +847,29 @@ export default function Dashboard() {
                                         onChange={(e) => setEditModal(prev => prev ? { ...prev, status: e.target.value } : null)}
                                         className="w-full px-3 py-2 rounded-lg bg-white dark:bg-[var(--surface)] border border-gray-200 dark:border-[var(--border)] outline-none text-sm"
                                     >
+                                        {/* สถานะที่เปลี่ยนเองไม่ได้ (PLANNED/PARTIALLY_CLOSED) แสดงไว้ให้คงค่าเดิม */}
+                                        {!['OPEN', 'CLOSED', 'CANCELLED'].includes(editModal.trade.status) && (
+                                            <option value={editModal.trade.status}>{editModal.trade.status}</option>
+                                        )}
                                         <option value="OPEN">🟡 OPEN</option>
-                                        <option value="WIN">🟢 WIN</option>
-                                        <option value="LOSS">🔴 LOSS</option>
-                                        <option value="BREAK_EVEN">⚪ BREAK EVEN</option>
+                                        <option value="CLOSED">🏁 CLOSED</option>
+                                        <option value="CANCELLED">⚫ CANCELLED</option>
                                     </select>
+                                    {editModal.status === 'CLOSED' && (
+                                        <>
+                                            <label className="block text-xs text-gray-400 mt-3 mb-2">Outcome</label>
+                                            <select
+                                                value={editModal.outcome}
+                                                onChange={(e) => setEditModal(prev => prev ? { ...prev, outcome: e.target.value, overridePnl: true } : null)}
+                                                className="w-full px-3 py-2 rounded-lg bg-white dark:bg-[var(--surface)] border border-gray-200 dark:border-[var(--border)] outline-none text-sm"
+                                            >
+                                                <option value="">--</option>
+                                                <option value="WIN">🟢 WIN</option>
+                                                <option value="LOSS">🔴 LOSS</option>
+                                                <option value="BREAK_EVEN">⚪ BREAK EVEN</option>
+                                            </select>
+                                        </>
+                                    )}
                                 </div>
 
                                 <div className="bg-gray-50 dark:bg-[var(--background)] rounded-xl p-3 sm:p-4 border border-gray-200 dark:border-[var(--border)]">
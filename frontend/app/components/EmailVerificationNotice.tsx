@@ -0,0 +1,21 @@
+'use client';
+/**
+ * EmailVerificationNotice - แจ้งเตือนเมื่อ API ตอบ 403 email_not_verified
+ * พาไปหน้า /verify-email (เติมอีเมลที่ Login อยู่ให้) แล้วกลับมาหน้าเดิม
+ */
+
+import Link from 'next/link';
+import { MailWarning } from 'lucide-react';
+import { verifyEmailPath } from '../utils/api';
+
+export default function EmailVerificationNotice({ message }: { message?: string }) {
+    return (
+        <div className="flex items-center gap-2 p-3 rounded-xl bg-yellow-500/10 border border-yellow-500/30 text-yellow-500 text-xs sm:text-sm">
+            <MailWarning className="w-4 h-4 flex-shrink-0" />
+            <span className="flex-1">{message || 'กรุณายืนยันอีเมลก่อนใช้งานฟีเจอร์นี้'}</span>
+            <Link href={verifyEmailPath()} className="font-semibold underline whitespace-nowrap">
+                ยืนยันอีเมล
+            </Link>
+        </div>
+    );
+}
@@ -9,6 +9,7 @@ import { useRouter } from 'next/navigation';
 import { useTheme } from '../context/ThemeContext';
 import { useLanguage, languages } from '../context/LanguageContext';
 import { cn } from '../lib/cn';
+import { authAPI } from '../utils/api';
 import { User, Wallet, LogOut, ChevronDown, BarChart3, Search, X, Key } from 'lucide-react';
 import { motion, AnimatePresence } from 'framer-motion';
 
@@ -43,9 +44,8 @@ export default function Navbar() {
         return () => document.removeEventListener('click', handleClick);
     }, []);
 
-    const handleLogout = () => {
-        localStorage.removeItem('token');
-        localStorage.removeItem('username');
+    const handleLogout = async () => {
+        await authAPI.logout();
         setIsLoggedIn(false);
         router.refresh();
         router.push('/');
@@ -34,13 +34,29 @@ function LoginContent() {
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState('');
     const [showPassword, setShowPassword] = useState(false);
+    // 2FA: รหัสผ่านถูกแล้ว รอรหัสจากแอป Authenticator (หรือ Recovery Code)
+    const [challengeToken, setChallengeToken] = useState('');
+    const [code, setCode] = useState('');
+
+    // เก็บ Token แล้ว Redirect ไปหน้าที่เคยอยู่ (ถ้ามี redirect param)
+    const completeLogin = (data: { token: string; refresh_token: string; user: { username: string; email: string } }) => {
+        // เก็บ Token (Access Token อายุสั้น ต่ออายุด้วย Refresh Token อัตโนมัติใน api.ts)
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refresh_token', data.refresh_token);
+        localStorage.setItem('username', data.user.username);
+        localStorage.setItem('email', data.user.email); // ใช้เติมอีเมลในหน้ายืนยันอีเมล
+
+        const redirectTo = searchParams.get('redirect') || '/';
+        router.push(redirectTo);
+        router.refresh();
+    };
 
     // Handle Submit พร้อม Redirect Support
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
         setError('');
 
-        if (!username || !password) {
+        if (challengeToken ? !code : !username || !password) {
             setError(t('fillAllFields'));
             return;
         }
@@ -48,18 +64,24 @@ function LoginContent() {
         setLoading(true);
 
         try {
-            const response = await authAPI.login({ username, password });
-            const { token, user } = response.data;
+            if (challengeToken) {
+                const response = await authAPI.verifyTwoFactor({ challenge_token: challengeToken, code });
+                completeLogin(response.data);
+                return;
+            }
 
-            // เก็บ Token
-            localStorage.setItem('token', token);
-            localStorage.setItem('username', user.username);
-
-            // Redirect ไปหน้าที่เคยอยู่ (ถ้ามี redirect param)
-            const redirectTo = searchParams.get('redirect') || '/';
-            router.push(redirectTo);
-            router.refresh();
+            const response = await authAPI.login({ username, password });
+            if (response.data.two_factor_required) {
+                setChallengeToken(response.data.challenge_token);
+                return;
+            }
+            completeLogin(response.data);
         } catch (err: any) {
+            // Challenge หมดอายุ: กลับไปกรอกรหัสผ่านใหม่
+            if (err.response?.data?.code === 'invalid_challenge') {
+                setChallengeToken('');
+                setCode('');
+            }
             setError(err.response?.data?.error || 'Username หรือ Password ไม่ถูกต้อง');
         } finally {
             setLoading(false);
@@ -98,48 +120,70 @@ function LoginContent() {
 
                     {/* Form */}
                     <form onSubmit={handleSubmit} className="space-y-3 sm:space-y-4">
-                        {/* Username */}
-                        <div>
-                            <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
-                                <User className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" />
-                                Username
-                            </label>
-                            <input
-                                type="text"
-                                value={username}
-                                onChange={(e) => setUsername(e.target.value)}
-                                placeholder="Username"
-                                className="w-full px-3 sm:px-4 py-2.5 sm:py-3 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none transition-all"
-                            />
-                        </div>
-
-                        {/* Password */}
-                        <div>
-                            <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
-                                <Lock className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" />
-                                Password
-                            </label>
-                            <div className="relative">
+                        {challengeToken ? (
+                            /* 2FA Code */
+                            <div>
+                                <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
+                                    <Lock className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" />
+                                    รหัส 2FA (หรือ Recovery Code)
+                                </label>
                                 <input
-                                    type={showPassword ? 'text' : 'password'}
-                                    value={password}
-                                    onChange={(e) => setPassword(e.target.value)}
-                                    placeholder="Password"
-                                    className="w-full px-3 sm:px-4 py-2.5 sm:py-3 pr-10 sm:pr-12 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none transition-all"
+                                    type="text"
+                                    inputMode="text"
+                                    autoComplete="one-time-code"
+                                    autoFocus
+                                    value={code}
+                                    onChange={(e) => setCode(e.target.value)}
+                                    placeholder="123456"
+                                    className="w-full px-3 sm:px-4 py-2.5 sm:py-3 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none transition-all tracking-widest text-center"
                                 />
-                                <button
-                                    type="button"
-                                    onClick={() => setShowPassword(!showPassword)}
-                                    className="absolute right-2 sm:right-3 top-1/2 -translate-y-1/2 p-1 rounded-lg hover:bg-accent/20 transition-all"
-                                >
-                                    {showPassword ? (
-                                        <EyeOff className="w-4 h-4 sm:w-5 sm:h-5 text-muted" />
-                                    ) : (
-                                        <Eye className="w-4 h-4 sm:w-5 sm:h-5 text-muted" />
-                                    )}
-                                </button>
                             </div>
-                        </div>
+                        ) : (
+                            <>
+                            {/* Username */}
+                            <div>
+                                <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
+                                    <User className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" />
+                                    Username
+                                </label>
+                                <input
+                                    type="text"
+                                    value={username}
+                                    onChange={(e) => setUsername(e.target.value)}
+                                    placeholder="Username"
+                                    className="w-full px-3 sm:px-4 py-2.5 sm:py-3 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none transition-all"
+                                />
+                            </div>
+
+                            {/* Password */}
+                            <div>
+                                <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
+                                    <Lock className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" />
+                                    Password
+                                </label>
+                                <div className="relative">
+                                    <input
+                                        type={showPassword ? 'text' : 'password'}
+                                        value={password}
+                                        onChange={(e) => setPassword(e.target.value)}
+                                        placeholder="Password"
+                                        className="w-full px-3 sm:px-4 py-2.5 sm:py-3 pr-10 sm:pr-12 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none transition-all"
+                                    />
+                                    <button
+                                        type="button"
+                                        onClick={() => setShowPassword(!showPassword)}
+                                        className="absolute right-2 sm:right-3 top-1/2 -translate-y-1/2 p-1 rounded-lg hover:bg-accent/20 transition-all"
+                                    >
+                                        {showPassword ? (
+                                            <EyeOff className="w-4 h-4 sm:w-5 sm:h-5 text-muted" />
+                                        ) : (
+                                            <Eye className="w-4 h-4 sm:w-5 sm:h-5 text-muted" />
+                                        )}
+                                    </button>
+                                </div>
+                            </div>
+                            </>
+                        )}
 
                         {/* Error */}
                         {error && (
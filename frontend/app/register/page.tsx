@@ -72,14 +72,16 @@ function RegisterContent() {
         setLoading(true);
 
         try {
-            await authAPI.register({ username, email, password });
+            const response = await authAPI.register({ username, email, password });
             setSuccess(true);
 
-            // Redirect to login with redirect param
+            // ไปหน้ายืนยันอีเมล (ส่งรหัสไปแล้ว) แล้วค่อยไป Login พร้อม redirect param
+            const params = new URLSearchParams({ email: response.data?.email || email, sent: '1' });
             const redirectTo = searchParams.get('redirect');
+            if (redirectTo) params.set('redirect', redirectTo);
             setTimeout(() => {
-                router.push(redirectTo ? `/login?redirect=${redirectTo}` : '/login');
-            }, 2000);
+                router.push(`/verify-email?${params.toString()}`);
+            }, 1500);
 
         } catch (err: any) {
             if (!err.response) {
@@ -134,6 +136,7 @@ function RegisterContent() {
                                 <Check className="w-6 h-6 sm:w-8 sm:h-8 text-profit" />
                             </div>
                             <h2 className="text-lg sm:text-xl font-bold text-profit mb-2">{t('savedSuccess')} 🎉</h2>
+                            <p className="text-muted text-xs sm:text-sm mb-3">กรุณายืนยันอีเมลด้วยรหัสที่ส่งไปให้</p>
                             <Loader2 className="w-5 h-5 sm:w-6 sm:h-6 animate-spin mx-auto text-accent" />
                         </motion.div>
                     ) : (
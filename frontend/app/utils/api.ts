@@ -32,16 +32,20 @@ export interface CreateTradeData {
 
 export interface UpdateTradeData {
     exit_price?: number;
+    // pnl / pnl_percent / outcome ต้องส่งพร้อม override_pnl: true (ไม่งั้น Server ตอบ 400 override_required)
+    override_pnl?: boolean;
     pnl?: number;
     pnl_percent?: number;
-    status?: string; // OPEN, WIN, LOSS, BREAK_EVEN
+    status?: string;  // OPEN, CLOSED, CANCELLED
+    outcome?: string; // WIN, LOSS, BREAK_EVEN (ใช้เมื่อ override_pnl = true, ไม่ส่ง = Server คำนวณเอง)
     notes?: string;
     exit_time?: string;
     closed_at?: string;
 }
 
 export interface TradeFilter {
-    status?: string;
+    status?: string;  // PLANNED, OPEN, PARTIALLY_CLOSED, CLOSED, CANCELLED
+    outcome?: string; // WIN, LOSS, BREAK_EVEN (เฉพาะไม้ที่ปิดแล้ว)
     pair?: string;
     side?: string;
     date_from?: string;
@@ -73,7 +77,8 @@ export interface Trade {
     setup_score: number;
     pnl: number;
     pnl_percent: number;
-    status: string;
+    status: string;  // PLANNED, OPEN, PARTIALLY_CLOSED, CLOSED, CANCELLED
+    outcome: string; // WIN, LOSS, BREAK_EVEN (มีเฉพาะ status = CLOSED)
     notes: string;
     tags: string;
     entry_time: string;
@@ -144,6 +149,46 @@ api.interceptors.request.use(
     }
 );
 
+// ============================================
+// Refresh Token
+// ============================================
+// Access Token อายุสั้น (15 นาที) เมื่อได้ 401 จะขอ Token ใหม่ด้วย Refresh Token แล้วยิง Request เดิมซ้ำ 1 ครั้ง
+// หลาย Request ที่ได้ 401 พร้อมกันใช้การ Refresh ครั้งเดียวกัน (Refresh Token เปลี่ยนทุกครั้งที่ใช้)
+let refreshPromise: Promise<string | null> | null = null;
+
+const clearSession = () => {
+    localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    localStorage.removeItem('username');
+    localStorage.removeItem('email');
+};
+
+const refreshAccessToken = (): Promise<string | null> => {
+    if (!refreshPromise) {
+        const refreshToken = localStorage.getItem('refresh_token');
+        refreshPromise = (refreshToken
+            ? axios.post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+                .then((response) => {
+                    const { token, refresh_token } = response.data;
+                    localStorage.setItem('token', token);
+                    localStorage.setItem('refresh_token', refresh_token);
+                    return token as string;
+                })
+                .catch((error: AxiosError<{ code?: string }>) => {
+                    // อีกแท็บเพิ่ง Refresh ไปแล้ว: ใช้ Token ล่าสุดที่อีกแท็บเก็บไว้
+                    if (error.response?.data?.code === 'token_rotated') {
+                        return localStorage.getItem('token');
+                    }
+                    return null;
+                })
+            : Promise.resolve(null)
+        ).finally(() => {
+            refreshPromise = null;
+        });
+    }
+    return refreshPromise;
+};
+
 // ============================================
 // Response Interceptor
 // ============================================
@@ -152,7 +197,7 @@ api.interceptors.response.use(
         console.log(`✅ API Response: ${response.status} ${response.config.url}`);
         return response;
     },
-    (error: AxiosError) => {
+    async (error: AxiosError) => {
         if (!error.response) {
             console.error('🔥 NETWORK ERROR: Backend unreachable!');
             error.message = 'Network Error: ไม่สามารถเชื่อมต่อ Backend ได้';
@@ -161,12 +206,23 @@ api.interceptors.response.use(
             const data = error.response.data as { error?: string };
             console.error(`❌ API Error: ${status}`, data);
 
-            if (status === 401) {
-                console.warn('🔓 Unauthorized - clearing token');
-                if (typeof window !== 'undefined') {
-                    localStorage.removeItem('token');
-                    localStorage.removeItem('username');
+            if (isEmailNotVerified(error)) {
+                error.message = data?.error || 'กรุณายืนยันอีเมลก่อนใช้งานฟีเจอร์นี้';
+            }
+
+            if (status === 401 && typeof window !== 'undefined') {
+                const original = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined;
+                // 401 ของ Login/ยืนยัน 2FA คือรหัสผิด ไม่ใช่ Token หมดอายุ
+                if (original && !original._retried && !original.url?.includes('/login') && !original.url?.includes('/auth/2fa/verify')) {
+                    original._retried = true;
+                    const token = await refreshAccessToken();
+                    if (token) {
+                        original.headers.Authorization = `Bearer ${token}`;
+                        return api(original);
+                    }
                 }
+                console.warn('🔓 Unauthorized - clearing token');
+                clearSession();
             }
         }
 
@@ -174,6 +230,24 @@ api.interceptors.response.use(
     }
 );
 
+// ============================================
+// Email Verification
+// ============================================
+// 403 + code "email_not_verified" = ฟีเจอร์นี้ (AI/Import/Backup ตาม EMAIL_VERIFICATION_REQUIRED_FOR) ต้องยืนยันอีเมลก่อน
+export const isEmailNotVerified = (error: unknown): boolean =>
+    axios.isAxiosError(error) &&
+    error.response?.status === 403 &&
+    (error.response.data as { code?: string } | undefined)?.code === 'email_not_verified';
+
+// ลิงก์ไปหน้ายืนยันอีเมล (ใส่อีเมลที่ Login อยู่ให้) แล้วกลับมาหน้าปัจจุบัน
+export const verifyEmailPath = (): string => {
+    if (typeof window === 'undefined') return '/verify-email';
+    const params = new URLSearchParams({ redirect: window.location.pathname });
+    const email = localStorage.getItem('email');
+    if (email) params.set('email', email);
+    return `/verify-email?${params.toString()}`;
+};
+
 // ============================================
 // Auth API
 // ============================================
@@ -187,6 +261,38 @@ export const authAPI = {
         console.log('🔐 Logging in user:', data.username);
         return api.post('/login', data);
     },
+
+    // ยืนยันอีเมลด้วยรหัสที่ส่งไปตอนสมัคร / ขอรหัสใหม่
+    verifyEmail: (data: { email: string; code: string }) => api.post('/auth/verify-email', data),
+    resendVerification: (email: string) => api.post('/auth/verify-email/resend', { email }),
+
+    // Login ขั้นที่สองเมื่อเปิด 2FA (challenge_token จาก login)
+    verifyTwoFactor: (data: { challenge_token: string; code: string }) => api.post('/auth/2fa/verify', data),
+
+    // เปิด/ปิด 2FA
+    getTwoFactorStatus: () => api.get('/auth/2fa'),
+    setupTwoFactor: () => api.post('/auth/2fa/setup'),
+    confirmTwoFactor: (code: string) => api.post('/auth/2fa/confirm', { code }),
+    disableTwoFactor: (data: { password: string; code: string }) => api.post('/auth/2fa/disable', data),
+    regenerateRecoveryCodes: (data: { password: string; code: string }) => api.post('/auth/2fa/recovery-codes', data),
+
+    // ยกเลิก Session ที่ Server (Token ที่ถูกขโมยไปใช้ต่อไม่ได้) แล้วล้าง Token ในเครื่อง
+    logout: async () => {
+        const refreshToken = localStorage.getItem('refresh_token');
+        try {
+            if (refreshToken) {
+                await axios.post(`${API_BASE_URL}/auth/logout`, { refresh_token: refreshToken });
+            }
+        } catch (error) {
+            console.warn('⚠️ Logout request failed:', error);
+        } finally {
+            clearSession();
+        }
+    },
+
+    // อุปกรณ์ที่ Login อยู่ และยกเลิกทีละเครื่อง
+    getSessions: () => api.get('/auth/sessions'),
+    revokeSession: (id: number) => api.delete(`/auth/sessions/${id}`),
 };
 
 // ============================================
@@ -0,0 +1,256 @@
+'use client';
+/**
+ * Verify Email Page - หน้ายืนยันอีเมลด้วยรหัส 6 หลักที่ส่งไปตอนสมัคร
+ * ✅ ขอรหัสใหม่ได้ (รอ 60 วินาทีต่อครั้ง) | ✅ Redirect Support | ✅ Mobile-First
+ */
+
+import { useState, useEffect } from 'react';
+import { useRouter, useSearchParams } from 'next/navigation';
+import Link from 'next/link';
+import { motion } from 'framer-motion';
+import { authAPI } from '../utils/api';
+import { ThemeProvider } from '../context/ThemeContext';
+import {
+    Mail,
+    KeyRound,
+    Loader2,
+    AlertCircle,
+    Check,
+    RefreshCw,
+    ShieldCheck,
+    ArrowRight
+} from 'lucide-react';
+
+// ขอรหัสใหม่ได้ไม่ถี่กว่านี้ (ตรงกับ emailVerifyResendCooldown ของ Backend)
+const RESEND_COOLDOWN = 60;
+
+function VerifyEmailContent() {
+    const router = useRouter();
+    const searchParams = useSearchParams();
+
+    const [email, setEmail] = useState(searchParams.get('email') || '');
+    const [code, setCode] = useState('');
+    const [loading, setLoading] = useState(false);
+    const [resending, setResending] = useState(false);
+    const [error, setError] = useState('');
+    const [notice, setNotice] = useState('');
+    const [success, setSuccess] = useState(false);
+    // มาจากหน้าสมัคร = เพิ่งส่งรหัสไป ต้องรอก่อนขอใหม่
+    const [countdown, setCountdown] = useState(searchParams.get('sent') ? RESEND_COOLDOWN : 0);
+
+    useEffect(() => {
+        if (countdown <= 0) return;
+        const timer = setTimeout(() => setCountdown(countdown - 1), 1000);
+        return () => clearTimeout(timer);
+    }, [countdown]);
+
+    // ยืนยันแล้ว: Login อยู่ = กลับหน้าที่มา, ยังไม่ Login = ไปหน้า Login
+    const goNext = () => {
+        const redirectTo = searchParams.get('redirect');
+        const loggedIn = typeof window !== 'undefined' && !!localStorage.getItem('token');
+        if (loggedIn) {
+            router.push(redirectTo || '/');
+        } else {
+            router.push(redirectTo ? `/login?redirect=${redirectTo}` : '/login');
+        }
+    };
+
+    const handleVerify = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setError('');
+        setNotice('');
+
+        if (!email || !code) {
+            setError('กรุณากรอกอีเมลและรหัสยืนยัน');
+            return;
+        }
+
+        setLoading(true);
+        try {
+            await authAPI.verifyEmail({ email, code: code.trim() });
+            setSuccess(true);
+            setTimeout(goNext, 1500);
+        } catch (err: any) {
+            setError(err.response?.data?.error || err.message || 'Error');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const handleResend = async () => {
+        if (countdown > 0 || resending) return;
+        setError('');
+        setNotice('');
+
+        if (!email) {
+            setError('กรุณากรอกอีเมล');
+            return;
+        }
+
+        setResending(true);
+        try {
+            const response = await authAPI.resendVerification(email);
+            setNotice(response.data?.message || 'ส่งรหัสใหม่แล้ว');
+            setCountdown(RESEND_COOLDOWN);
+        } catch (err: any) {
+            const retryAfter = err.response?.data?.retry_after;
+            if (retryAfter) setCountdown(retryAfter);
+            setError(err.response?.data?.error || err.message || 'Error');
+        } finally {
+            setResending(false);
+        }
+    };
+
+    return (
+        <div className="min-h-screen bg-background flex items-center justify-center p-3 sm:p-4">
+            {/* Background */}
+            <div className="absolute inset-0 overflow-hidden pointer-events-none">
+                <div className="absolute top-1/4 left-1/4 w-64 sm:w-96 h-64 sm:h-96 bg-accent/20 rounded-full blur-3xl" />
+                <div className="absolute bottom-1/4 right-1/4 w-64 sm:w-96 h-64 sm:h-96 bg-profit/10 rounded-full blur-3xl" />
+            </div>
+
+            {/* Card */}
+            <motion.div
+                initial={{ opacity: 0, y: 30, scale: 0.95 }}
+                animate={{ opacity: 1, y: 0, scale: 1 }}
+                transition={{ duration: 0.5 }}
+                className="relative w-full max-w-sm sm:max-w-md"
+            >
+                <div className="glass rounded-2xl sm:rounded-3xl p-5 sm:p-8 border-2 border-accent/30">
+                    {/* Logo */}
+                    <div className="text-center mb-5 sm:mb-8">
+                        <motion.div
+                            initial={{ scale: 0 }}
+                            animate={{ scale: 1 }}
+                            transition={{ delay: 0.2, type: 'spring' }}
+                            className="w-12 h-12 sm:w-16 sm:h-16 mx-auto mb-3 sm:mb-4 rounded-xl sm:rounded-2xl bg-accent/20 flex items-center justify-center"
+                        >
+                            <ShieldCheck className="w-6 h-6 sm:w-8 sm:h-8 text-accent" />
+                        </motion.div>
+                        <h1 className="text-xl sm:text-2xl font-bold text-gradient">ยืนยันอีเมล</h1>
+                        <p className="text-muted text-xs sm:text-sm mt-1">กรอกรหัส 6 หลักที่ส่งไปทางอีเมล (อายุ 30 นาที)</p>
+                    </div>
+
+                    {/* Success */}
+                    {success ? (
+                        <motion.div
+                            initial={{ opacity: 0, scale: 0.9 }}
+                            animate={{ opacity: 1, scale: 1 }}
+                            className="text-center py-6 sm:py-8"
+                        >
+                            <div className="w-12 h-12 sm:w-16 sm:h-16 mx-auto mb-3 sm:mb-4 rounded-full bg-profit/20 flex items-center justify-center">
+                                <Check className="w-6 h-6 sm:w-8 sm:h-8 text-profit" />
+                            </div>
+                            <h2 className="text-lg sm:text-xl font-bold text-profit mb-2">ยืนยันอีเมลเรียบร้อยแล้ว ✅</h2>
+                            <Loader2 className="w-5 h-5 sm:w-6 sm:h-6 animate-spin mx-auto text-accent" />
+                        </motion.div>
+                    ) : (
+                        <form onSubmit={handleVerify} className="space-y-3 sm:space-y-4">
+                            {/* Email */}
+                            <div>
+                                <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
+                                    <Mail className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" /> Email
+                                </label>
+                                <input
+                                    type="email"
+                                    value={email}
+                                    onChange={(e) => setEmail(e.target.value)}
+                                    placeholder="email@example.com"
+                                    className="w-full px-3 sm:px-4 py-2.5 sm:py-3 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none"
+                                />
+                            </div>
+
+                            {/* Code */}
+                            <div>
+                                <label className="block text-xs sm:text-sm font-medium mb-1.5 sm:mb-2 text-muted">
+                                    <KeyRound className="w-3 h-3 sm:w-4 sm:h-4 inline mr-1" /> รหัสยืนยัน
+                                </label>
+                                <input
+                                    type="text"
+                                    inputMode="numeric"
+                                    autoComplete="one-time-code"
+                                    autoFocus
+                                    maxLength={6}
+                                    value={code}
+                                    onChange={(e) => setCode(e.target.value)}
+                                    placeholder="123456"
+                                    className="w-full px-3 sm:px-4 py-2.5 sm:py-3 text-sm sm:text-base rounded-lg sm:rounded-xl glass border border-glass-border focus:border-accent outline-none tracking-widest text-center"
+                                />
+                            </div>
+
+                            {/* Notice */}
+                            {notice && (
+                                <div className="flex items-center gap-2 p-2.5 sm:p-3 rounded-lg sm:rounded-xl bg-profit/20 text-profit text-xs sm:text-sm">
+                                    <Check className="w-4 h-4 flex-shrink-0" />
+                                    <span>{notice}</span>
+                                </div>
+                            )}
+
+                            {/* Error */}
+                            {error && (
+                                <motion.div
+                                    initial={{ opacity: 0, y: -10 }}
+                                    animate={{ opacity: 1, y: 0 }}
+                                    className="flex items-center gap-2 p-2.5 sm:p-3 rounded-lg sm:rounded-xl bg-loss/20 text-loss text-xs sm:text-sm"
+                                >
+                                    <AlertCircle className="w-4 h-4 flex-shrink-0" />
+                                    <span>{error}</span>
+                                </motion.div>
+                            )}
+
+                            {/* Submit */}
+                            <motion.button
+                                whileHover={{ scale: 1.02 }}
+                                whileTap={{ scale: 0.98 }}
+                                type="submit"
+                                disabled={loading}
+                                className="w-full py-2.5 sm:py-3 rounded-lg sm:rounded-xl font-semibold text-sm sm:text-base bg-accent text-white flex items-center justify-center gap-2 disabled:opacity-50"
+                            >
+                                {loading ? (
+                                    <><Loader2 className="w-4 h-4 sm:w-5 sm:h-5 animate-spin" /> กำลังยืนยัน...</>
+                                ) : (
+                                    <><ShieldCheck className="w-4 h-4 sm:w-5 sm:h-5" /> ยืนยันอีเมล</>
+                                )}
+                            </motion.button>
+
+                            {/* Resend */}
+                            <button
+                                type="button"
+                                onClick={handleResend}
+                                disabled={countdown > 0 || resending}
+                                className="w-full py-2 text-xs sm:text-sm text-accent hover:underline flex items-center justify-center gap-1.5 disabled:opacity-50 disabled:no-underline"
+                            >
+                                <RefreshCw className={resending ? 'w-3 h-3 sm:w-4 sm:h-4 animate-spin' : 'w-3 h-3 sm:w-4 sm:h-4'} />
+                                {countdown > 0 ? `ขอรหัสใหม่ได้ใน ${countdown} วินาที` : 'ไม่ได้รับรหัส? ขอรหัสใหม่'}
+                            </button>
+                        </form>
+                    )}
+
+                    {/* Links */}
+                    {!success && (
+                        <div className="text-center mt-4 sm:mt-6 space-y-2 sm:space-y-3">
+                            <button
+                                type="button"
+                                onClick={goNext}
+                                className="text-xs sm:text-sm text-muted hover:text-accent transition-all inline-flex items-center gap-1"
+                            >
+                                ยืนยันภายหลัง <ArrowRight className="w-3 h-3 sm:w-4 sm:h-4" />
+                            </button>
+                            <Link href="/" className="text-xs sm:text-sm text-muted hover:text-accent transition-all block">
+                                ← Back
+                            </Link>
+                        </div>
+                    )}
+                </div>
+            </motion.div>
+        </div>
+    );
+}
+
+export default function VerifyEmailPage() {
+    return (
+        <ThemeProvider>
+            <VerifyEmailContent />
+        </ThemeProvider>
+    );
+}